@@ -2,6 +2,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"os/signal"
@@ -11,6 +12,7 @@ import (
 	"github.com/Waldir-TG/api-medical-heart-v1/internal/repositories"
 	"github.com/Waldir-TG/api-medical-heart-v1/internal/routes"
 	"github.com/Waldir-TG/api-medical-heart-v1/internal/services"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
@@ -42,6 +44,22 @@ func main() {
 	patientRepo := repositories.NewPatientRepo(database)
 	deviceRepo := repositories.NewDeviceRepository(database)
 	heartReadingRepo := repositories.NewHeartReadingRepository(database)
+	annotationRepo := repositories.NewAnnotationRepository(database)
+	alertRepo := repositories.NewAlertRepository(database)
+	firmwareRepo := repositories.NewFirmwareRepository(database)
+	ecgRepo := repositories.NewEcgRepository(database)
+	findingRepo := repositories.NewFindingRepository(database)
+	ruleRepo := repositories.NewRuleRepository(database)
+	thresholdRepo := repositories.NewThresholdRepository(database)
+	baselineRepo := repositories.NewBaselineRepository(database)
+	readingProcessingRepo := repositories.NewReadingProcessingRepository(database)
+	jobRepo := repositories.NewJobRepository(database)
+	maintenanceRepo := repositories.NewMaintenanceRepository(database)
+	reportRepo := repositories.NewReportRepository(database)
+	vitalRepo := repositories.NewVitalRepository(database)
+	localeRepo := repositories.NewLocaleRepository(database)
+	exportRepo := repositories.NewExportRepository(database)
+	fhirRepo := repositories.NewFHIRRepository(database)
 
 	// Inicializar servicios
 	authService := services.NewAuthService(userRepo, sessionRepo)
@@ -49,10 +67,47 @@ func main() {
 	doctorService := services.NewDoctorService(doctorRepo)
 	patientService := services.NewPatientService(patientRepo)
 	deviceService := services.NewDeviceService(deviceRepo)
-	heartReadingService := services.NewHeartReadingService(heartReadingRepo)
+	localeService := services.NewLocaleService(localeRepo)
+	arrhythmiaService := services.NewArrhythmiaService(findingRepo, alertRepo, services.LoadScreeningConfig())
+	ruleService := services.NewRuleService(ruleRepo, heartReadingRepo, alertRepo, services.LoadAlertMinQuality())
+	thresholdService := services.NewThresholdService(thresholdRepo, heartReadingRepo, alertRepo, localeService)
+	baselineService := services.NewBaselineService(baselineRepo, heartReadingRepo, localeService, services.LoadBaselineConfig())
+	annotationService := services.NewAnnotationService(annotationRepo, heartReadingRepo)
+	heartReadingService := services.NewHeartReadingService(heartReadingRepo, arrhythmiaService, ruleService, thresholdService, baselineService, annotationService, localeService, services.LoadQualityConfig())
+	firmwareService := services.NewFirmwareService(firmwareRepo, deviceRepo)
+	ecgService := services.NewEcgService(ecgRepo, heartReadingRepo, arrhythmiaService)
+	deviceMonitorService := services.NewDeviceMonitorService(deviceRepo, alertRepo, services.LoadDeviceMonitorConfig())
+	readingWorkerService := services.NewReadingWorkerService(readingProcessingRepo, heartReadingService, services.LoadReadingWorkerConfig())
+	reportService := services.NewReportService(reportRepo, thresholdService, localeService)
+	vitalService := services.NewVitalService(vitalRepo, heartReadingRepo, localeService)
+	exportService := services.NewExportService(exportRepo, heartReadingRepo, localeService, services.LoadExportConfig())
+	importService := services.NewImportService(heartReadingRepo, localeService, services.LoadQualityConfig(), services.LoadImportConfig())
+	fhirService := services.NewFHIRService(fhirRepo, vitalService, patientService, deviceService, doctorService, localeService, services.LoadQualityConfig())
+	maintenanceService := services.NewMaintenanceService(maintenanceRepo, jobRepo, services.LoadRetentionConfig())
+	jobScheduler, err := services.NewJobScheduler(
+		jobRepo,
+		services.LoadJobSchedulerConfig(),
+		maintenanceService,
+		deviceMonitorService,
+		baselineService,
+		reportService,
+	)
+	if err != nil {
+		log.Fatalf("Failed to configure job scheduler: %v", err)
+	}
+	jobService := services.NewJobService(jobRepo, jobScheduler)
+
+	// Tareas en segundo plano (se cancelan al apagar el servidor)
+	bgCtx, cancelBackground := context.WithCancel(context.Background())
+	defer cancelBackground()
+	go readingWorkerService.Run(bgCtx)
+	go jobScheduler.Run(bgCtx)
+	go exportService.Run(bgCtx)
 
 	// Configurar aplicación Fiber
 	app := fiber.New(fiber.Config{
+		// Las importaciones suben archivos completos; los exports grandes de Apple Health van por cmd/reading-import
+		BodyLimit: utils.GetEnvInt("HTTP_BODY_LIMIT", 4*1024*1024),
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			code := fiber.StatusInternalServerError
 
@@ -107,11 +162,24 @@ func main() {
 
 	// Configurar rutas
 	routes.SetupAuthRoutes(app, authService)
-	routes.SetupUserRoutes(app, authService, userService)
+	users := routes.SetupUserRoutes(app, authService, userService)
 	routes.SetupDoctorRoutes(app, doctorService)
-	routes.SetupPatientRoutes(app, authService, patientService)
+	patients := routes.SetupPatientRoutes(app, authService, patientService)
 	routes.SetupDeviceRoutes(app, authService, deviceService)
-	routes.SetupHeartReadingRoutes(app, authService, heartReadingService)
+	routes.SetupFirmwareRoutes(app, authService, firmwareService)
+	heartReadings := routes.SetupHeartReadingRoutes(app, authService, heartReadingService, readingWorkerService, localeService)
+	routes.SetupEcgRoutes(app, heartReadings, authService, ecgService)
+	routes.SetupRuleRoutes(app, authService, ruleService)
+	routes.SetupThresholdRoutes(patients, thresholdService)
+	routes.SetupReportRoutes(patients, reportService)
+	routes.SetupVitalRoutes(app, authService, vitalService, localeService)
+	routes.SetupAnnotationRoutes(app, authService, annotationService, localeService)
+	routes.SetupLocaleRoutes(patients, users, localeService)
+	routes.SetupExportRoutes(heartReadings, exportService, localeService)
+	routes.SetupImportRoutes(heartReadings, importService)
+	routes.SetupFHIRRoutes(app, authService, fhirService, localeService)
+	routes.SetupJobRoutes(app, authService, jobService)
+
 	// Iniciar servidor
 	go func() {
 		port := os.Getenv("PORT")
@@ -130,7 +198,12 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
+	cancelBackground()
 	if err := app.Shutdown(); err != nil {
 		log.Fatalf("Server shutdown failed: %v", err)
 	}
+	// Espera a que el worker y los trabajos en curso terminen antes de cerrar la base de datos
+	<-readingWorkerService.Done()
+	<-jobScheduler.Done()
+	<-exportService.Done()
 }
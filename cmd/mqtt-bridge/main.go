@@ -0,0 +1,69 @@
+// cmd/mqtt-bridge/main.go
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/db"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/mqttbridge"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/repositories"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/services"
+	"github.com/joho/godotenv"
+)
+
+// Puente MQTT opcional: recibe lecturas y estados de los gateways BLE y los
+// procesa con los mismos servicios que la API HTTP.
+func main() {
+	// Cargar variables de entorno
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: .env file not found")
+	}
+
+	// Inicializar conexión a base de datos
+	database, err := db.InitDB()
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	// Inicializar repositorios y servicios
+	deviceRepo := repositories.NewDeviceRepository(database)
+	heartReadingRepo := repositories.NewHeartReadingRepository(database)
+	annotationRepo := repositories.NewAnnotationRepository(database)
+	findingRepo := repositories.NewFindingRepository(database)
+	ruleRepo := repositories.NewRuleRepository(database)
+	thresholdRepo := repositories.NewThresholdRepository(database)
+	baselineRepo := repositories.NewBaselineRepository(database)
+	alertRepo := repositories.NewAlertRepository(database)
+	localeRepo := repositories.NewLocaleRepository(database)
+
+	deviceService := services.NewDeviceService(deviceRepo)
+	localeService := services.NewLocaleService(localeRepo)
+	arrhythmiaService := services.NewArrhythmiaService(findingRepo, alertRepo, services.LoadScreeningConfig())
+	ruleService := services.NewRuleService(ruleRepo, heartReadingRepo, alertRepo, services.LoadAlertMinQuality())
+	thresholdService := services.NewThresholdService(thresholdRepo, heartReadingRepo, alertRepo, localeService)
+	baselineService := services.NewBaselineService(baselineRepo, heartReadingRepo, localeService, services.LoadBaselineConfig())
+	annotationService := services.NewAnnotationService(annotationRepo, heartReadingRepo)
+	heartReadingService := services.NewHeartReadingService(heartReadingRepo, arrhythmiaService, ruleService, thresholdService, baselineService, annotationService, localeService, services.LoadQualityConfig())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bridge := mqttbridge.NewBridge(mqttbridge.LoadConfig(), heartReadingService, deviceService)
+	if err := bridge.Start(ctx); err != nil {
+		log.Fatalf("Failed to start MQTT bridge: %v", err)
+	}
+
+	// Graceful shutdown
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
+
+	log.Println("Shutting down MQTT bridge...")
+	cancel()
+	bridge.Stop()
+}
@@ -0,0 +1,147 @@
+// cmd/reading-import/main.go
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path"
+	"strings"
+	"syscall"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/db"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/importer"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/repositories"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/services"
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+)
+
+// Importa lecturas históricas de un paciente desde un archivo: CSV con
+// mapeo de columnas, export.xml de Apple Health (también dentro del
+// export.zip), o JSON de Google Fit o Fitbit. Sirve para los archivos que
+// superan el límite de subida de la API. Con -dry-run solo valida el archivo.
+// Imprime el informe en JSON y termina con código 1 si la importación falla.
+//
+//	go run ./cmd/reading-import -patient <uuid> -format apple_health -dry-run export.zip
+//	go run ./cmd/reading-import -patient <uuid> -format csv -map time=Fecha,bpm=Pulso -time-format "02/01/2006 15:04" datos.csv
+func main() {
+	patientFlag := flag.String("patient", "", "patient (UUID) that receives the readings")
+	format := flag.String("format", "", "file format: "+strings.Join(importer.Formats, ", "))
+	mapping := flag.String("map", "", "CSV column mapping, field=column separated by commas (fields: "+strings.Join(importer.CSVFields(), ", ")+")")
+	timeFormat := flag.String("time-format", "", `CSV time format: a Go layout, "unix" or "unix_ms" (RFC 3339 by default)`)
+	timezone := flag.String("tz", "", "zone of times without an offset (the patient's by default)")
+	source := flag.String("source", "", "source of the readings when the file does not give it")
+	readingType := flag.String("reading-type", "", "reading type when the file does not give it (resting by default)")
+	dryRun := flag.Bool("dry-run", false, "only validate the file")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: .env file not found")
+	}
+
+	patientID, err := uuid.Parse(*patientFlag)
+	if err != nil {
+		log.Fatalf("-patient is required: %v", err)
+	}
+	if flag.NArg() != 1 {
+		log.Fatal("Exactly one file to import is required")
+	}
+
+	request := &models.ReadingImportRequest{
+		PatientID:   patientID,
+		Format:      *format,
+		TimeFormat:  *timeFormat,
+		Timezone:    *timezone,
+		Source:      *source,
+		ReadingType: *readingType,
+		DryRun:      *dryRun,
+	}
+	if *mapping != "" {
+		request.Mapping = map[string]string{}
+		for _, pair := range strings.Split(*mapping, ",") {
+			field, column, ok := strings.Cut(pair, "=")
+			if !ok {
+				log.Fatalf("Invalid -map entry %q, expected field=column", pair)
+			}
+			request.Mapping[strings.TrimSpace(field)] = strings.TrimSpace(column)
+		}
+	}
+
+	file, err := openImportFile(flag.Arg(0), *format)
+	if err != nil {
+		log.Fatalf("Opening %s: %v", flag.Arg(0), err)
+	}
+	defer file.Close()
+
+	database, err := db.InitDB()
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	heartReadingRepo := repositories.NewHeartReadingRepository(database)
+	localeService := services.NewLocaleService(repositories.NewLocaleRepository(database))
+	importService := services.NewImportService(heartReadingRepo, localeService, services.LoadQualityConfig(), services.LoadImportConfig())
+
+	report, err := importService.ImportReadings(ctx, file, request)
+	if report != nil {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Printf("Writing report: %v", err)
+		}
+	}
+	if err != nil {
+		log.Printf("Import failed: %v", err)
+		stop()
+		database.Close()
+		os.Exit(1)
+	}
+}
+
+// openImportFile opens the file to import. For Apple Health it also accepts
+// the export.zip produced by the Health app.
+func openImportFile(name, format string) (io.ReadCloser, error) {
+	if format != importer.FormatAppleHealth || !strings.EqualFold(path.Ext(name), ".zip") {
+		return os.Open(name)
+	}
+
+	archive, err := zip.OpenReader(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range archive.File {
+		if path.Base(entry.Name) == "export.xml" {
+			file, err := entry.Open()
+			if err != nil {
+				archive.Close()
+				return nil, err
+			}
+			return &zipEntry{ReadCloser: file, archive: archive}, nil
+		}
+	}
+	archive.Close()
+	return nil, fmt.Errorf("export.xml not found in the archive")
+}
+
+// zipEntry closes the archive together with the entry
+type zipEntry struct {
+	io.ReadCloser
+	archive *zip.ReadCloser
+}
+
+func (e *zipEntry) Close() error {
+	e.ReadCloser.Close()
+	return e.archive.Close()
+}
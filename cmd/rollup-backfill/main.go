@@ -0,0 +1,103 @@
+// cmd/rollup-backfill/main.go
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/db"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/repositories"
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+)
+
+// Reconstruye los agregados de frecuencia cardíaca (minuto, hora y día) a
+// partir de heart_readings, por tramos de días. Sin -from/-to recorre todo el
+// histórico. Puede ejecutarse con la API en marcha: cada tramo se reconstruye
+// paciente a paciente y solo retiene la escritura de los agregados del
+// paciente en curso.
+//
+//	go run ./cmd/rollup-backfill -from 2024-01-01 -to 2024-07-01 -patient <uuid>
+func main() {
+	patientFlag := flag.String("patient", "", "only this patient (UUID)")
+	fromFlag := flag.String("from", "", "first day to rebuild, YYYY-MM-DD (UTC)")
+	toFlag := flag.String("to", "", "day after the last one to rebuild, YYYY-MM-DD (UTC)")
+	chunkDays := flag.Int("chunk", 7, "days rebuilt per transaction")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: .env file not found")
+	}
+
+	var patientID *uuid.UUID
+	if *patientFlag != "" {
+		id, err := uuid.Parse(*patientFlag)
+		if err != nil {
+			log.Fatalf("Invalid -patient: %v", err)
+		}
+		patientID = &id
+	}
+	if *chunkDays < 1 {
+		log.Fatal("-chunk must be at least 1")
+	}
+
+	database, err := db.InitDB()
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	rollupRepo := repositories.NewRollupRepository(database)
+
+	first, last, ok, err := rollupRepo.GetReadingTimeRange(ctx, patientID)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !ok && (*fromFlag == "" || *toFlag == "") {
+		log.Println("No readings to aggregate")
+		return
+	}
+
+	day := 24 * time.Hour
+	from := first.UTC().Truncate(day)
+	to := last.UTC().Truncate(day).Add(day)
+	if *fromFlag != "" {
+		if from, err = time.Parse(time.DateOnly, *fromFlag); err != nil {
+			log.Fatalf("Invalid -from: %v", err)
+		}
+	}
+	if *toFlag != "" {
+		if to, err = time.Parse(time.DateOnly, *toFlag); err != nil {
+			log.Fatalf("Invalid -to: %v", err)
+		}
+	}
+	if !from.Before(to) {
+		log.Fatal("-from must be before -to")
+	}
+
+	log.Printf("Rebuilding rollups from %s to %s", from.Format(time.DateOnly), to.Format(time.DateOnly))
+	started := time.Now()
+	var total int64
+	for start := from; start.Before(to); start = start.AddDate(0, 0, *chunkDays) {
+		end := start.AddDate(0, 0, *chunkDays)
+		if end.After(to) {
+			end = to
+		}
+
+		days, err := rollupRepo.RebuildRollups(ctx, patientID, start, end)
+		if err != nil {
+			log.Fatalf("Rebuilding %s - %s: %v", start.Format(time.DateOnly), end.Format(time.DateOnly), err)
+		}
+		total += days
+		log.Printf("%s - %s: %d patient-days", start.Format(time.DateOnly), end.Format(time.DateOnly), days)
+	}
+	log.Printf("Done: %d patient-days in %s", total, time.Since(started).Round(time.Millisecond))
+}
@@ -0,0 +1,270 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// Latidos mínimos para evaluar irregularidad y fibrilación auricular
+	minScreeningBeats = 32
+	// Umbrales de Dash et al. (2009) para la detección de FA
+	afNormalizedRMSSD = 0.10
+	afShannonEntropy  = 0.70
+	// Proporción de latidos ectópicos a partir de la cual el ritmo se considera irregular
+	irregularEctopicRatio = 0.10
+	// Latidos usados en la mediana móvil de la frecuencia para detectar episodios
+	episodeSmoothingBeats = 5
+)
+
+const (
+	EpisodeBradycardia = "bradycardia"
+	EpisodeTachycardia = "tachycardia"
+)
+
+// ScreeningConfig holds the heart rate limits used to detect episodes
+type ScreeningConfig struct {
+	BradycardiaBPM     float64
+	TachycardiaBPM     float64
+	MinEpisodeDuration float64 // ms
+}
+
+// DefaultScreeningConfig returns the adult limits used when a patient has no
+// specific thresholds
+func DefaultScreeningConfig() ScreeningConfig {
+	return ScreeningConfig{
+		BradycardiaBPM:     50,
+		TachycardiaBPM:     100,
+		MinEpisodeDuration: 10000,
+	}
+}
+
+// Episode is a sustained run of beats below or above the configured limits
+type Episode struct {
+	Type       string  // bradycardia o tachycardia
+	StartMs    float64 // desde el inicio de la serie RR
+	DurationMs float64
+	Beats      int
+	MinBPM     float64
+	MaxBPM     float64
+	MeanBPM    float64
+}
+
+// RhythmScreening is the outcome of screening an RR series. Irregularity and
+// AF are only evaluated (Conclusive) when enough beats are available.
+type RhythmScreening struct {
+	BeatCount         int
+	RejectedCount     int // intervalos fuera del rango fisiológico
+	EctopicCount      int
+	MeanHR            float64
+	NormalizedRMSSD   float64
+	ShannonEntropy    float64
+	TurningPointRatio float64
+	Conclusive        bool
+	Irregular         bool
+	AFSuspected       bool
+	Episodes          []Episode
+}
+
+// ScreenRR screens RR intervals (ms) for irregular rhythm, atrial
+// fibrillation and bradycardia/tachycardia episodes.
+//
+// AF screening follows Dash et al. (2009): the rhythm is flagged when the
+// normalized RMSSD, the Shannon entropy of the RR histogram and the turning
+// point ratio all look random rather than respiratory-modulated sinus rhythm.
+func ScreenRR(rr []float64, config ScreeningConfig) (*RhythmScreening, error) {
+	valid := make([]float64, 0, len(rr))
+	rejected := 0
+	for _, v := range rr {
+		if v < minRRMs || v > maxRRMs {
+			rejected++
+			continue
+		}
+		valid = append(valid, v)
+	}
+	if len(valid) < 3 {
+		return nil, ErrNotEnoughIntervals
+	}
+
+	s := &RhythmScreening{
+		BeatCount:     len(valid),
+		RejectedCount: rejected,
+		MeanHR:        60000 / mean(valid),
+	}
+
+	_, ectopic := CleanRR(valid)
+	s.EctopicCount = ectopic
+
+	if len(valid) >= minScreeningBeats {
+		s.Conclusive = true
+		s.NormalizedRMSSD = rmssd(valid) / mean(valid)
+		s.ShannonEntropy = shannonEntropy(valid)
+		s.TurningPointRatio = turningPointRatio(valid)
+
+		s.AFSuspected = s.NormalizedRMSSD > afNormalizedRMSSD &&
+			s.ShannonEntropy > afShannonEntropy &&
+			turningPointsLookRandom(valid)
+		s.Irregular = s.AFSuspected ||
+			float64(ectopic)/float64(len(valid)) >= irregularEctopicRatio
+	}
+
+	s.Episodes = detectEpisodes(rr, config)
+	return s, nil
+}
+
+// detectEpisodes walks the RR series and reports runs whose smoothed heart
+// rate stays beyond the limits for at least MinEpisodeDuration. Implausible
+// intervals still advance the clock but end the current run.
+func detectEpisodes(rr []float64, config ScreeningConfig) []Episode {
+	smoothed := smoothedHR(rr)
+
+	var episodes []Episode
+	var current *Episode
+	var runRR float64
+	elapsed := 0.0
+
+	closeRun := func() {
+		if current != nil && current.DurationMs >= config.MinEpisodeDuration {
+			current.MeanBPM = 60000 * float64(current.Beats) / runRR
+			episodes = append(episodes, *current)
+		}
+		current = nil
+		runRR = 0
+	}
+
+	for i, v := range rr {
+		hr := smoothed[i]
+		kind := ""
+		switch {
+		case hr == 0:
+		case hr < config.BradycardiaBPM:
+			kind = EpisodeBradycardia
+		case hr > config.TachycardiaBPM:
+			kind = EpisodeTachycardia
+		}
+
+		if current != nil && current.Type != kind {
+			closeRun()
+		}
+		if kind != "" {
+			if current == nil {
+				current = &Episode{Type: kind, StartMs: elapsed, MinBPM: hr, MaxBPM: hr}
+			}
+			current.DurationMs += v
+			current.Beats++
+			current.MinBPM = math.Min(current.MinBPM, hr)
+			current.MaxBPM = math.Max(current.MaxBPM, hr)
+			runRR += v
+		}
+		elapsed += v
+	}
+	closeRun()
+
+	return episodes
+}
+
+// smoothedHR returns the heart rate of each beat as the median over a short
+// window of valid neighbouring intervals, or 0 for implausible intervals, so
+// a single ectopic beat does not split an episode
+func smoothedHR(rr []float64) []float64 {
+	half := episodeSmoothingBeats / 2
+	hr := make([]float64, len(rr))
+	window := make([]float64, 0, episodeSmoothingBeats)
+	for i, v := range rr {
+		if v < minRRMs || v > maxRRMs {
+			continue
+		}
+		window = window[:0]
+		for j := max(i-half, 0); j <= min(i+half, len(rr)-1); j++ {
+			if rr[j] >= minRRMs && rr[j] <= maxRRMs {
+				window = append(window, rr[j])
+			}
+		}
+		hr[i] = 60000 / median(window)
+	}
+	return hr
+}
+
+// shannonEntropy returns the normalized entropy of a 16-bin RR histogram
+// after dropping the 8 shortest and 8 longest intervals
+func shannonEntropy(rr []float64) float64 {
+	const bins = 16
+	const trim = 8
+
+	sorted := append([]float64(nil), rr...)
+	sort.Float64s(sorted)
+	if len(sorted) > 2*trim+bins {
+		sorted = sorted[trim : len(sorted)-trim]
+	}
+
+	lo, hi := sorted[0], sorted[len(sorted)-1]
+	if hi == lo {
+		return 0
+	}
+
+	var counts [bins]int
+	for _, v := range sorted {
+		b := int((v - lo) / (hi - lo) * bins)
+		if b == bins {
+			b--
+		}
+		counts[b]++
+	}
+
+	var entropy float64
+	for _, c := range counts {
+		if c == 0 {
+			continue
+		}
+		p := float64(c) / float64(len(sorted))
+		entropy -= p * math.Log(p)
+	}
+	return entropy / math.Log(bins)
+}
+
+// turningPointRatio returns the number of turning points divided by the
+// number expected in a random series
+func turningPointRatio(rr []float64) float64 {
+	expected := float64(2*len(rr)-4) / 3
+	return float64(turningPoints(rr)) / expected
+}
+
+// turningPointsLookRandom reports whether the series has at least as many
+// turning points as the lower bound of the 95% interval expected for an
+// independent random series. Sinus rhythm, modulated by respiration, changes
+// direction far less often.
+func turningPointsLookRandom(rr []float64) bool {
+	n := float64(len(rr))
+	expected := (2*n - 4) / 3
+	sigma := math.Sqrt((16*n - 29) / 90)
+	return float64(turningPoints(rr)) > expected-1.96*sigma
+}
+
+func turningPoints(rr []float64) int {
+	count := 0
+	for i := 1; i < len(rr)-1; i++ {
+		if (rr[i] > rr[i-1] && rr[i] > rr[i+1]) || (rr[i] < rr[i-1] && rr[i] < rr[i+1]) {
+			count++
+		}
+	}
+	return count
+}
+
+func rmssd(rr []float64) float64 {
+	var sum float64
+	for i := 1; i < len(rr); i++ {
+		d := rr[i] - rr[i-1]
+		sum += d * d
+	}
+	return math.Sqrt(sum / float64(len(rr)-1))
+}
+
+func median(x []float64) float64 {
+	sorted := append([]float64(nil), x...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
@@ -0,0 +1,109 @@
+package analytics
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestScreenRR(t *testing.T) {
+	type want struct {
+		conclusive  bool
+		irregular   bool
+		afSuspected bool
+		episodes    []string
+	}
+
+	// 60 latidos a 75 lpm (~48 s), subida en 10 latidos, 60 latidos a 130 lpm
+	// (~27.7 s), bajada en 10 latidos y 60 latidos a 75 lpm
+	tachycardia := slices.Concat(sinusRR(60, 75, 4), rampRR(10, 75, 130), sinusRR(60, 130, 5),
+		rampRR(10, 130, 75), sinusRR(60, 75, 6))
+
+	type screenCase struct {
+		name  string
+		rr    []float64
+		want  want
+		check func(t *testing.T, s *RhythmScreening)
+	}
+
+	tests := []screenCase{
+		{
+			name: "normal sinus rhythm",
+			rr:   sinusRR(120, 70, 1),
+			want: want{conclusive: true},
+		},
+		{
+			name: "ectopic beats",
+			rr:   ectopicRR(120, 70, 6, 2),
+			want: want{conclusive: true, irregular: true},
+			check: func(t *testing.T, s *RhythmScreening) {
+				if s.EctopicCount == 0 {
+					t.Error("EctopicCount = 0, want premature beats detected")
+				}
+			},
+		},
+		{
+			name: "bradycardia episode",
+			rr:   sinusRR(60, 42, 3),
+			want: want{conclusive: true, episodes: []string{EpisodeBradycardia}},
+			check: func(t *testing.T, s *RhythmScreening) {
+				if d := s.Episodes[0].DurationMs; d <= 80000 {
+					t.Errorf("episode duration = %.0f ms, want the whole series (> 80000)", d)
+				}
+			},
+		},
+		{
+			name: "tachycardia episode",
+			rr:   tachycardia,
+			want: want{conclusive: true, episodes: []string{EpisodeTachycardia}},
+			check: func(t *testing.T, s *RhythmScreening) {
+				e := s.Episodes[0]
+				if e.StartMs < 48000 || e.StartMs > 48000+6500 || e.DurationMs < 27700 || e.DurationMs > 27700+8000 {
+					t.Errorf("episode = %.0f ms for %.0f ms, want it to start on the ramp and cover the 27700 ms plateau",
+						e.StartMs, e.DurationMs)
+				}
+			},
+		},
+		{
+			name: "too few beats",
+			rr:   sinusRR(10, 70, 7),
+			want: want{},
+		},
+	}
+	for seed := int64(1); seed <= 5; seed++ {
+		tests = append(tests, screenCase{
+			name: fmt.Sprintf("atrial fibrillation seed %d", seed),
+			rr:   atrialFibrillationRR(120, 90, seed),
+			want: want{conclusive: true, irregular: true, afSuspected: true},
+		})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ScreenRR(tt.rr, DefaultScreeningConfig())
+			if err != nil {
+				t.Fatalf("ScreenRR() error = %v", err)
+			}
+
+			got := want{conclusive: s.Conclusive, irregular: s.Irregular, afSuspected: s.AFSuspected}
+			for _, e := range s.Episodes {
+				got.episodes = append(got.episodes, e.Type)
+			}
+			if got.conclusive != tt.want.conclusive || got.irregular != tt.want.irregular ||
+				got.afSuspected != tt.want.afSuspected || !slices.Equal(got.episodes, tt.want.episodes) {
+				t.Fatalf("ScreenRR() = %+v (nRMSSD=%.3f ShE=%.2f TPR=%.2f), want %+v",
+					got, s.NormalizedRMSSD, s.ShannonEntropy, s.TurningPointRatio, tt.want)
+			}
+			if tt.check != nil {
+				tt.check(t, s)
+			}
+		})
+	}
+}
+
+func TestScreenRRNotEnoughIntervals(t *testing.T) {
+	// Solo dos intervalos dentro del rango fisiológico
+	if _, err := ScreenRR([]float64{800, 810, 50, 9000}, DefaultScreeningConfig()); err != ErrNotEnoughIntervals {
+		t.Errorf("ScreenRR() error = %v, want ErrNotEnoughIntervals", err)
+	}
+}
@@ -0,0 +1,47 @@
+package analytics
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// nextPowerOfTwo returns the smallest power of two >= n
+func nextPowerOfTwo(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
+
+// fft computes the discrete Fourier transform of x in place using the
+// iterative radix-2 Cooley-Tukey algorithm. len(x) must be a power of two.
+func fft(x []complex128) {
+	n := len(x)
+
+	// Permutación por inversión de bits
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				u := x[start+k]
+				v := x[start+k+size/2] * w
+				x[start+k] = u + v
+				x[start+k+size/2] = u - v
+				w *= step
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package analytics
+
+import (
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"testing"
+)
+
+func TestNextPowerOfTwo(t *testing.T) {
+	tests := []struct{ n, want int }{
+		{0, 1}, {1, 1}, {2, 2}, {3, 4}, {4, 4}, {5, 8}, {1000, 1024}, {1024, 1024}, {1025, 2048},
+	}
+	for _, tt := range tests {
+		if got := nextPowerOfTwo(tt.n); got != tt.want {
+			t.Errorf("nextPowerOfTwo(%d) = %d, want %d", tt.n, got, tt.want)
+		}
+	}
+}
+
+// dft is the direct O(n²) discrete Fourier transform used as reference
+func dft(x []complex128) []complex128 {
+	n := len(x)
+	out := make([]complex128, n)
+	for k := range out {
+		for j, v := range x {
+			out[k] += v * cmplx.Exp(complex(0, -2*math.Pi*float64(k*j)/float64(n)))
+		}
+	}
+	return out
+}
+
+func TestFFT(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	random := func(n int) []complex128 {
+		x := make([]complex128, n)
+		for i := range x {
+			x[i] = complex(rng.NormFloat64(), rng.NormFloat64())
+		}
+		return x
+	}
+	impulse := make([]complex128, 8)
+	impulse[0] = 1
+	// Coseno con 3 ciclos en 16 muestras
+	tone := make([]complex128, 16)
+	for i := range tone {
+		tone[i] = complex(math.Cos(2*math.Pi*3*float64(i)/16), 0)
+	}
+
+	tests := []struct {
+		name string
+		x    []complex128
+	}{
+		{"single sample", []complex128{2 + 1i}},
+		{"impulse", impulse},
+		{"tone", tone},
+		{"random 32", random(32)},
+		{"random 256", random(256)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := dft(tt.x)
+			got := append([]complex128(nil), tt.x...)
+			fft(got)
+			for k := range got {
+				if cmplx.Abs(got[k]-want[k]) > 1e-9 {
+					t.Fatalf("X[%d] = %v, want %v", k, got[k], want[k])
+				}
+			}
+		})
+	}
+
+	// Toda la energía del coseno en los índices 3 y 13
+	x := append([]complex128(nil), tone...)
+	fft(x)
+	for k, v := range x {
+		want := 0.0
+		if k == 3 || k == 13 {
+			want = 8
+		}
+		if math.Abs(cmplx.Abs(v)-want) > 1e-9 {
+			t.Errorf("|X[%d]| = %.6f, want %.0f", k, cmplx.Abs(v), want)
+		}
+	}
+}
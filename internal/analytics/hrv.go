@@ -0,0 +1,241 @@
+// Package analytics contains the signal processing used to derive clinical
+// metrics from raw device data. Everything here is pure and deterministic.
+package analytics
+
+import (
+	"errors"
+	"math"
+)
+
+const (
+	// Límites fisiológicos para un intervalo RR válido (ms)
+	minRRMs = 300.0
+	maxRRMs = 2000.0
+	// Un latido que difiere más de este porcentaje del anterior se considera ectópico
+	maxSuccessiveChange = 0.20
+
+	// Frecuencia de remuestreo para el análisis espectral (Hz)
+	resampleHz = 4.0
+	// Duración mínima de registro para estimar las bandas LF/HF (s)
+	minSpectralDuration = 120.0
+)
+
+// Bandas de frecuencia estándar (Task Force of the ESC/NASPE, 1996)
+var (
+	lfBand = [2]float64{0.04, 0.15}
+	hfBand = [2]float64{0.15, 0.40}
+)
+
+var ErrNotEnoughIntervals = errors.New("at least 3 valid RR intervals are required")
+
+// HRV holds time and frequency domain heart rate variability metrics.
+// Frequency domain values are nil when the recording is too short.
+type HRV struct {
+	IntervalCount int      // intervalos NN utilizados
+	RejectedCount int      // intervalos descartados como artefactos o ectópicos
+	MeanRR        float64  // ms
+	MeanHR        float64  // lpm
+	SDNN          float64  // ms
+	RMSSD         float64  // ms
+	PNN50         float64  // %
+	LFPower       *float64 // ms²
+	HFPower       *float64 // ms²
+	LFHFRatio     *float64
+}
+
+// CleanRR removes physiologically implausible intervals and ectopic beats,
+// returning the normal-to-normal (NN) series and the number of rejected intervals
+func CleanRR(rr []float64) (nn []float64, rejected int) {
+	nn = make([]float64, 0, len(rr))
+	for _, v := range rr {
+		if v < minRRMs || v > maxRRMs {
+			rejected++
+			continue
+		}
+		if len(nn) > 0 {
+			prev := nn[len(nn)-1]
+			if math.Abs(v-prev)/prev > maxSuccessiveChange {
+				rejected++
+				continue
+			}
+		}
+		nn = append(nn, v)
+	}
+	return nn, rejected
+}
+
+// ComputeHRV computes standard HRV metrics from RR intervals in milliseconds
+func ComputeHRV(rr []float64) (*HRV, error) {
+	nn, rejected := CleanRR(rr)
+	if len(nn) < 3 {
+		return nil, ErrNotEnoughIntervals
+	}
+
+	h := &HRV{
+		IntervalCount: len(nn),
+		RejectedCount: rejected,
+	}
+
+	h.MeanRR = mean(nn)
+	h.MeanHR = 60000 / h.MeanRR
+	h.SDNN = stdDev(nn, h.MeanRR)
+
+	var sumSq float64
+	var nn50 int
+	for i := 1; i < len(nn); i++ {
+		d := nn[i] - nn[i-1]
+		sumSq += d * d
+		if math.Abs(d) > 50 {
+			nn50++
+		}
+	}
+	h.RMSSD = math.Sqrt(sumSq / float64(len(nn)-1))
+	h.PNN50 = 100 * float64(nn50) / float64(len(nn)-1)
+
+	if lf, hf, ok := spectralPower(nn); ok {
+		h.LFPower = &lf
+		h.HFPower = &hf
+		if hf > 0 {
+			ratio := lf / hf
+			h.LFHFRatio = &ratio
+		}
+	}
+
+	return h, nil
+}
+
+// spectralPower estimates LF and HF power by resampling the NN tachogram at
+// 4 Hz with a cubic spline, removing the linear trend, applying a Hann window and integrating the
+// periodogram over each band
+func spectralPower(nn []float64) (lf, hf float64, ok bool) {
+	// Instantes de cada latido en segundos
+	t := make([]float64, len(nn))
+	var acc float64
+	for i, v := range nn {
+		acc += v / 1000
+		t[i] = acc
+	}
+	duration := t[len(t)-1] - t[0]
+	if duration < minSpectralDuration {
+		return 0, 0, false
+	}
+
+	samples := resampleSpline(t, nn, resampleHz)
+	detrend(samples)
+
+	n := nextPowerOfTwo(len(samples))
+	x := make([]complex128, n)
+	var windowPower float64
+	for i, v := range samples {
+		w := 0.5 * (1 - math.Cos(2*math.Pi*float64(i)/float64(len(samples)-1)))
+		windowPower += w * w
+		x[i] = complex(v*w, 0)
+	}
+	fft(x)
+
+	// Densidad espectral unilateral en ms²/Hz
+	df := resampleHz / float64(n)
+	scale := 1 / (resampleHz * windowPower)
+	for k := 1; k < n/2; k++ {
+		f := float64(k) * df
+		re, im := real(x[k]), imag(x[k])
+		psd := 2 * scale * (re*re + im*im)
+		switch {
+		case f >= lfBand[0] && f < lfBand[1]:
+			lf += psd * df
+		case f >= hfBand[0] && f < hfBand[1]:
+			hf += psd * df
+		}
+	}
+
+	return lf, hf, true
+}
+
+// resampleSpline interpolates the irregularly sampled series (t, v) onto an
+// even grid at the given frequency using a natural cubic spline
+func resampleSpline(t, v []float64, hz float64) []float64 {
+	n := len(t)
+
+	// Segundas derivadas del spline natural (algoritmo de Thomas)
+	m := make([]float64, n)
+	c := make([]float64, n)
+	d := make([]float64, n)
+	for i := 1; i < n-1; i++ {
+		h0, h1 := t[i]-t[i-1], t[i+1]-t[i]
+		a, b, cc := h0, 2*(h0+h1), h1
+		rhs := 6 * ((v[i+1]-v[i])/h1 - (v[i]-v[i-1])/h0)
+		if i > 1 {
+			b -= a * c[i-1]
+			rhs -= a * d[i-1]
+		}
+		c[i] = cc / b
+		d[i] = rhs / b
+	}
+	for i := n - 2; i >= 1; i-- {
+		m[i] = d[i] - c[i]*m[i+1]
+	}
+
+	step := 1 / hz
+	count := int((t[n-1]-t[0])/step) + 1
+	out := make([]float64, count)
+
+	j := 0
+	for i := range out {
+		ti := t[0] + float64(i)*step
+		for j < n-2 && t[j+1] < ti {
+			j++
+		}
+		h := t[j+1] - t[j]
+		if h <= 0 {
+			out[i] = v[j]
+			continue
+		}
+		a := (t[j+1] - ti) / h
+		b := (ti - t[j]) / h
+		out[i] = a*v[j] + b*v[j+1] + ((a*a*a-a)*m[j]+(b*b*b-b)*m[j+1])*h*h/6
+	}
+	return out
+}
+
+// detrend removes the least-squares linear trend from x in place
+func detrend(x []float64) {
+	n := float64(len(x))
+	var sumX, sumY, sumXY, sumXX float64
+	for i, v := range x {
+		fi := float64(i)
+		sumX += fi
+		sumY += v
+		sumXY += fi * v
+		sumXX += fi * fi
+	}
+	denom := n*sumXX - sumX*sumX
+	slope := 0.0
+	if denom != 0 {
+		slope = (n*sumXY - sumX*sumY) / denom
+	}
+	intercept := (sumY - slope*sumX) / n
+	for i := range x {
+		x[i] -= intercept + slope*float64(i)
+	}
+}
+
+func mean(x []float64) float64 {
+	var sum float64
+	for _, v := range x {
+		sum += v
+	}
+	return sum / float64(len(x))
+}
+
+// stdDev returns the sample standard deviation of x around m
+func stdDev(x []float64, m float64) float64 {
+	if len(x) < 2 {
+		return 0
+	}
+	var sum float64
+	for _, v := range x {
+		d := v - m
+		sum += d * d
+	}
+	return math.Sqrt(sum / float64(len(x)-1))
+}
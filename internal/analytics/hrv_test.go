@@ -0,0 +1,140 @@
+package analytics
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestComputeHRVTimeDomain(t *testing.T) {
+	tests := []struct {
+		name                string
+		rr                  []float64
+		meanRR, sdnn, rmssd float64
+		pnn50               float64
+	}{
+		// Media 820; desviaciones -20, -10, -30, 40, 20 (suma de cuadrados 3400);
+		// diferencias sucesivas 10, -20, 70, -20 (suma de cuadrados 5800), una > 50 ms
+		{"hand computed", []float64{800, 810, 790, 860, 840}, 820, math.Sqrt(3400.0 / 4), math.Sqrt(5800.0 / 4), 25},
+		{"constant", []float64{1000, 1000, 1000}, 1000, 0, 0, 0},
+		// Alternancia de ±50 ms: todas las diferencias son de 100 ms
+		{"alternating", []float64{600, 700, 600, 700}, 650, math.Sqrt(10000.0 / 3), 100, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := ComputeHRV(tt.rr)
+			if err != nil {
+				t.Fatalf("ComputeHRV() error = %v", err)
+			}
+			if h.IntervalCount != len(tt.rr) || h.RejectedCount != 0 {
+				t.Errorf("intervals = %d (rejected %d), want %d (rejected 0)", h.IntervalCount, h.RejectedCount, len(tt.rr))
+			}
+			for _, m := range []struct {
+				name      string
+				got, want float64
+			}{
+				{"MeanRR", h.MeanRR, tt.meanRR},
+				{"MeanHR", h.MeanHR, 60000 / tt.meanRR},
+				{"SDNN", h.SDNN, tt.sdnn},
+				{"RMSSD", h.RMSSD, tt.rmssd},
+				{"PNN50", h.PNN50, tt.pnn50},
+			} {
+				if math.Abs(m.got-m.want) > 1e-9 {
+					t.Errorf("%s = %.6f, want %.6f", m.name, m.got, m.want)
+				}
+			}
+			if h.LFPower != nil || h.HFPower != nil {
+				t.Error("spectral power computed for a recording shorter than 2 minutes")
+			}
+		})
+	}
+}
+
+func TestComputeHRVNotEnoughIntervals(t *testing.T) {
+	// Solo quedan dos intervalos tras descartar el artefacto y el ectópico
+	if _, err := ComputeHRV([]float64{800, 3000, 810, 500}); !errors.Is(err, ErrNotEnoughIntervals) {
+		t.Errorf("ComputeHRV() error = %v, want ErrNotEnoughIntervals", err)
+	}
+}
+
+func TestCleanRR(t *testing.T) {
+	tests := []struct {
+		name     string
+		rr       []float64
+		nn       []float64
+		rejected int
+	}{
+		{"clean", []float64{800, 820, 810}, []float64{800, 820, 810}, 0},
+		{"out of physiological range", []float64{250, 800, 2500, 810}, []float64{800, 810}, 2},
+		{"lower range limit", []float64{299, 300, 310}, []float64{300, 310}, 1},
+		{"upper range limit", []float64{2001, 2000, 1950}, []float64{2000, 1950}, 1},
+		{"premature beat", []float64{800, 600, 820, 810}, []float64{800, 820, 810}, 1},
+		// El latido prematuro y la pausa compensatoria que lo sigue
+		{"premature beat with compensatory pause", []float64{800, 560, 1040, 800, 790}, []float64{800, 800, 790}, 2},
+		{"exactly 20% change", []float64{800, 960}, []float64{800, 960}, 0},
+		{"empty", nil, []float64{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nn, rejected := CleanRR(tt.rr)
+			if !slices.Equal(nn, tt.nn) || rejected != tt.rejected {
+				t.Errorf("CleanRR() = %v (rejected %d), want %v (rejected %d)", nn, rejected, tt.nn, tt.rejected)
+			}
+		})
+	}
+}
+
+// modulatedRR returns the RR intervals (ms) of a tachogram around 1000 ms
+// modulated by a sine of the given frequency (Hz) and amplitude (ms), for
+// the given number of seconds
+func modulatedRR(seconds, hz, amplitude float64) []float64 {
+	var rr []float64
+	for t := 0.0; t < seconds; {
+		v := 1000 + amplitude*math.Sin(2*math.Pi*hz*t)
+		rr = append(rr, v)
+		t += v / 1000
+	}
+	return rr
+}
+
+func TestComputeHRVSpectralBands(t *testing.T) {
+	const amplitude = 50.0
+	// Potencia de una sinusoide: A²/2
+	power := amplitude * amplitude / 2
+
+	tests := []struct {
+		name    string
+		hz      float64
+		inLF    bool
+		minimum float64 // cociente mínimo entre la banda esperada y la otra
+	}{
+		{"0.1 Hz (Mayer waves)", 0.1, true, 20},
+		{"0.25 Hz (respiration)", 0.25, false, 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := ComputeHRV(modulatedRR(300, tt.hz, amplitude))
+			if err != nil {
+				t.Fatalf("ComputeHRV() error = %v", err)
+			}
+			if h.LFPower == nil || h.HFPower == nil || h.LFHFRatio == nil {
+				t.Fatal("spectral power not computed for a 5 minute recording")
+			}
+
+			band, other := *h.LFPower, *h.HFPower
+			if !tt.inLF {
+				band, other = other, band
+			}
+			if band < tt.minimum*other {
+				t.Errorf("LF = %.1f ms², HF = %.1f ms², want the %.2f Hz power in its band", *h.LFPower, *h.HFPower, tt.hz)
+			}
+			if math.Abs(band-power)/power > 0.2 {
+				t.Errorf("band power = %.1f ms², want about %.1f ms²", band, power)
+			}
+		})
+	}
+}
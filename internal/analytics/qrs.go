@@ -0,0 +1,264 @@
+package analytics
+
+import (
+	"math"
+)
+
+const (
+	// Banda de paso del filtro de Pan–Tompkins (Hz)
+	qrsLowCutHz  = 5.0
+	qrsHighCutHz = 15.0
+	// Ventana de integración móvil (s)
+	qrsIntegrationWindow = 0.150
+	// Periodo refractario tras un latido (s)
+	qrsRefractory = 0.200
+	// Un pico dentro de este intervalo tras un latido puede ser una onda T (s)
+	qrsTWaveWindow = 0.360
+	// Fase de aprendizaje inicial de los umbrales (s)
+	qrsLearningPeriod = 2.0
+)
+
+// DetectRPeaks locates R peaks in an ECG strip using the Pan–Tompkins
+// algorithm (band-pass, derivative, squaring and moving window integration
+// followed by adaptive dual thresholds with search-back). Samples may be in
+// any unit; sampleRate is in Hz. It returns the sample index of each R peak.
+//
+// Filtering is applied forwards and backwards, so the detected indices are
+// not delayed with respect to the input.
+func DetectRPeaks(samples []float64, sampleRate float64) []int {
+	n := len(samples)
+	if n == 0 || sampleRate <= 2*qrsHighCutHz {
+		return nil
+	}
+
+	filtered := bandPass(samples, sampleRate, qrsLowCutHz, qrsHighCutHz)
+
+	// Derivada de cinco puntos centrada, cuadrado e integración
+	squared := make([]float64, n)
+	for i := 2; i < n-2; i++ {
+		d := (-filtered[i-2] - 2*filtered[i-1] + 2*filtered[i+1] + filtered[i+2]) * sampleRate / 8
+		squared[i] = d * d
+	}
+	integrated := movingAverage(squared, int(math.Round(qrsIntegrationWindow*sampleRate)))
+
+	refractory := int(qrsRefractory * sampleRate)
+	tWave := int(qrsTWaveWindow * sampleRate)
+	searchHalf := int(math.Round(qrsIntegrationWindow * sampleRate / 2))
+
+	candidates := localMaxima(integrated, refractory/2)
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	// Inicialización de los umbrales con la fase de aprendizaje
+	learning := int(math.Min(qrsLearningPeriod*sampleRate, float64(n)))
+	var learnMax, learnSum float64
+	for i := 0; i < learning; i++ {
+		learnMax = math.Max(learnMax, integrated[i])
+		learnSum += integrated[i]
+	}
+	spki := 0.25 * learnMax
+	npki := 0.5 * learnSum / float64(learning)
+	threshold := npki + 0.25*(spki-npki)
+
+	var beats []int
+	var lastSlope float64
+	rrAverage := 0.0
+	lastBeat := -refractory
+
+	accept := func(idx int, searchBack bool) {
+		peak := integrated[idx]
+		if searchBack {
+			spki = 0.25*peak + 0.75*spki
+		} else {
+			spki = 0.125*peak + 0.875*spki
+		}
+		if len(beats) > 0 {
+			rr := float64(idx - beats[len(beats)-1])
+			if rrAverage == 0 {
+				rrAverage = rr
+			} else {
+				rrAverage = 0.875*rrAverage + 0.125*rr
+			}
+		}
+		beats = append(beats, idx)
+		lastBeat = idx
+		lastSlope = maxSlope(filtered, idx, searchHalf)
+	}
+
+	for ci, idx := range candidates {
+		peak := integrated[idx]
+
+		// Búsqueda retrospectiva de latidos perdidos
+		if rrAverage > 0 && float64(idx-lastBeat) > 1.66*rrAverage {
+			best := -1
+			for _, prev := range candidates[:ci] {
+				if prev-lastBeat <= refractory {
+					continue
+				}
+				if integrated[prev] > 0.5*threshold && (best < 0 || integrated[prev] > integrated[best]) {
+					best = prev
+				}
+			}
+			if best >= 0 {
+				accept(best, true)
+				threshold = npki + 0.25*(spki-npki)
+			}
+		}
+
+		if idx-lastBeat <= refractory {
+			continue
+		}
+
+		if peak > threshold {
+			// Descartar ondas T: poca pendiente poco después de un latido
+			if len(beats) > 0 && idx-lastBeat < tWave && maxSlope(filtered, idx, searchHalf) < 0.5*lastSlope {
+				npki = 0.125*peak + 0.875*npki
+			} else {
+				accept(idx, false)
+			}
+		} else {
+			npki = 0.125*peak + 0.875*npki
+		}
+		threshold = npki + 0.25*(spki-npki)
+	}
+
+	// Situar cada latido en el máximo absoluto de la señal filtrada
+	peaks := make([]int, 0, len(beats))
+	for _, b := range beats {
+		start := max(b-searchHalf, 0)
+		end := min(b+searchHalf, n-1)
+		best := start
+		for i := start + 1; i <= end; i++ {
+			if math.Abs(filtered[i]) > math.Abs(filtered[best]) {
+				best = i
+			}
+		}
+		if len(peaks) > 0 && best-peaks[len(peaks)-1] <= refractory {
+			continue
+		}
+		peaks = append(peaks, best)
+	}
+	return peaks
+}
+
+// RRFromPeaks converts R peak sample indices to RR intervals in milliseconds
+func RRFromPeaks(peaks []int, sampleRate float64) []float64 {
+	if len(peaks) < 2 {
+		return nil
+	}
+	rr := make([]float64, len(peaks)-1)
+	for i := 1; i < len(peaks); i++ {
+		rr[i-1] = float64(peaks[i]-peaks[i-1]) * 1000 / sampleRate
+	}
+	return rr
+}
+
+// bandPass applies second order Butterworth high-pass and low-pass sections
+// forwards and backwards (zero phase)
+func bandPass(x []float64, fs, low, high float64) []float64 {
+	hp := newBiquad(fs, low, true)
+	lp := newBiquad(fs, high, false)
+
+	y := make([]float64, len(x))
+	copy(y, x)
+	for _, f := range []biquad{hp, lp} {
+		f.filter(y)
+		reverse(y)
+		f.filter(y)
+		reverse(y)
+	}
+	return y
+}
+
+// biquad is a direct form I second order section (RBJ cookbook, Q = 1/√2)
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+}
+
+func newBiquad(fs, cutoff float64, highPass bool) biquad {
+	w0 := 2 * math.Pi * cutoff / fs
+	alpha := math.Sin(w0) / math.Sqrt2
+	cosw := math.Cos(w0)
+	a0 := 1 + alpha
+
+	var b0, b1, b2 float64
+	if highPass {
+		b0, b1, b2 = (1+cosw)/2, -(1 + cosw), (1+cosw)/2
+	} else {
+		b0, b1, b2 = (1-cosw)/2, 1-cosw, (1-cosw)/2
+	}
+	return biquad{
+		b0: b0 / a0, b1: b1 / a0, b2: b2 / a0,
+		a1: -2 * cosw / a0, a2: (1 - alpha) / a0,
+	}
+}
+
+func (f biquad) filter(x []float64) {
+	var x1, x2, y1, y2 float64
+	for i, v := range x {
+		y := f.b0*v + f.b1*x1 + f.b2*x2 - f.a1*y1 - f.a2*y2
+		x2, x1 = x1, v
+		y2, y1 = y1, y
+		x[i] = y
+	}
+}
+
+// movingAverage returns the centered moving average over width samples
+func movingAverage(x []float64, width int) []float64 {
+	if width < 1 {
+		width = 1
+	}
+	half := width / 2
+	out := make([]float64, len(x))
+	var sum float64
+	for i := 0; i < len(x)+half; i++ {
+		if i < len(x) {
+			sum += x[i]
+		}
+		if i-width >= 0 {
+			sum -= x[i-width]
+		}
+		if c := i - half; c >= 0 && c < len(x) {
+			out[c] = sum / float64(width)
+		}
+	}
+	return out
+}
+
+// localMaxima returns the indices of peaks that are the maximum within
+// ±distance samples
+func localMaxima(x []float64, distance int) []int {
+	var peaks []int
+	for i := 1; i < len(x)-1; i++ {
+		if x[i] <= 0 || x[i] < x[i-1] || x[i] <= x[i+1] {
+			continue
+		}
+		isMax := true
+		for j := max(i-distance, 0); j <= min(i+distance, len(x)-1); j++ {
+			if x[j] > x[i] {
+				isMax = false
+				break
+			}
+		}
+		if isMax {
+			peaks = append(peaks, i)
+		}
+	}
+	return peaks
+}
+
+// maxSlope returns the largest absolute first difference around idx
+func maxSlope(x []float64, idx, half int) float64 {
+	var slope float64
+	for i := max(idx-half, 1); i <= min(idx+half, len(x)-1); i++ {
+		slope = math.Max(slope, math.Abs(x[i]-x[i-1]))
+	}
+	return slope
+}
+
+func reverse(x []float64) {
+	for i, j := 0, len(x)-1; i < j; i, j = i+1, j-1 {
+		x[i], x[j] = x[j], x[i]
+	}
+}
@@ -0,0 +1,74 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDetectRPeaks(t *testing.T) {
+	tests := []struct {
+		name       string
+		rr         []float64
+		sampleRate float64
+		seed       int64
+	}{
+		{name: "sinus at 250 Hz", rr: sinusRR(40, 72, 8), sampleRate: 250, seed: 9},
+		{name: "sinus at 360 Hz", rr: sinusRR(40, 72, 8), sampleRate: 360, seed: 9},
+		{name: "sinus at 512 Hz", rr: sinusRR(40, 72, 8), sampleRate: 512, seed: 9},
+		{name: "bradycardia at 250 Hz", rr: sinusRR(30, 42, 12), sampleRate: 250, seed: 13},
+		{name: "tachycardia at 500 Hz", rr: sinusRR(60, 150, 14), sampleRate: 500, seed: 15},
+		{name: "atrial fibrillation at 500 Hz", rr: atrialFibrillationRR(60, 100, 10), sampleRate: 500, seed: 11},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			peaks := DetectRPeaks(ecgSamples(tt.rr, tt.sampleRate, tt.seed), tt.sampleRate)
+			if len(peaks) != len(tt.rr) {
+				t.Fatalf("detected %d R peaks, want %d", len(peaks), len(tt.rr))
+			}
+
+			// El primer intervalo va del inicio de la señal al primer latido
+			detected := RRFromPeaks(peaks, tt.sampleRate)
+			tolerance := 2*1000/tt.sampleRate + 1
+			for i, v := range detected {
+				if diff := math.Abs(v - tt.rr[i+1]); diff > tolerance {
+					t.Errorf("RR[%d] = %.1f ms, want %.1f ms (±%.1f)", i, v, tt.rr[i+1], tolerance)
+				}
+			}
+		})
+	}
+}
+
+func TestScreenDetectedPeaks(t *testing.T) {
+	tests := []struct {
+		rhythm      string
+		rr          []float64
+		afSuspected bool
+	}{
+		{rhythm: "sinus", rr: sinusRR(60, 70, 16), afSuspected: false},
+		{rhythm: "atrial fibrillation", rr: atrialFibrillationRR(60, 100, 10), afSuspected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rhythm, func(t *testing.T) {
+			peaks := DetectRPeaks(ecgSamples(tt.rr, 500, 11), 500)
+			s, err := ScreenRR(RRFromPeaks(peaks, 500), DefaultScreeningConfig())
+			if err != nil {
+				t.Fatalf("ScreenRR() error = %v", err)
+			}
+			if s.AFSuspected != tt.afSuspected {
+				t.Errorf("AFSuspected = %v, want %v (nRMSSD=%.3f)", s.AFSuspected, tt.afSuspected, s.NormalizedRMSSD)
+			}
+		})
+	}
+}
+
+// ecgSamples renders rr as an ECG strip and returns it as DetectRPeaks input
+func ecgSamples(rr []float64, sampleRate float64, seed int64) []float64 {
+	ecg := syntheticECG(rr, sampleRate, 200, seed)
+	samples := make([]float64, len(ecg))
+	for i, v := range ecg {
+		samples[i] = float64(v)
+	}
+	return samples
+}
@@ -0,0 +1,160 @@
+package analytics
+
+import (
+	"math"
+	"time"
+)
+
+// Indicadores de calidad de una lectura
+const (
+	QualityImplausible = "implausible_value"
+	QualityRapidChange = "rapid_change"
+	QualityDuplicate   = "duplicate_timestamp"
+	QualityFlatline    = "flatline"
+	QualityClockSkew   = "clock_skew"
+)
+
+// Penalización de cada indicador: la puntuación es el producto de (1 - penalización)
+var qualityPenalties = map[string]float64{
+	QualityImplausible: 0.6,
+	QualityDuplicate:   0.5,
+	QualityFlatline:    0.4,
+	QualityRapidChange: 0.3,
+	QualityClockSkew:   0.2,
+}
+
+// QualityConfig holds the limits used to assess the quality of a reading
+type QualityConfig struct {
+	MinBPM             int
+	MaxBPM             int
+	MaxJumpBPM         int           // cambio máximo respecto a la lectura anterior del dispositivo
+	JumpWindow         time.Duration // solo se comparan lecturas separadas menos que esto
+	FlatlineCount      int           // lecturas idénticas seguidas (incluida la nueva) que indican un valor congelado
+	DuplicateTolerance time.Duration // diferencia de hora por debajo de la cual dos lecturas son la misma
+	MaxClockSkew       time.Duration // adelanto máximo tolerado respecto a la hora del servidor
+}
+
+// DefaultQualityConfig returns limits suited to adult wearables
+func DefaultQualityConfig() QualityConfig {
+	return QualityConfig{
+		MinBPM:             25,
+		MaxBPM:             250,
+		MaxJumpBPM:         40,
+		JumpWindow:         time.Minute,
+		FlatlineCount:      10,
+		DuplicateTolerance: time.Second,
+		MaxClockSkew:       2 * time.Minute,
+	}
+}
+
+// QualitySample is the part of a reading the assessor compares against
+type QualitySample struct {
+	Time time.Time
+	BPM  int
+}
+
+// QualityInput is a new reading together with the previous readings of the
+// same device, newest first
+type QualityInput struct {
+	Sample            QualitySample
+	OxygenLevel       *int
+	SystolicPressure  *int
+	DiastolicPressure *int
+	Temperature       *float64
+	Previous          []QualitySample
+	Now               time.Time // hora del servidor al recibir la lectura
+}
+
+// QualityAssessment is the server-side score (0-1) of a reading and the
+// checks it failed
+type QualityAssessment struct {
+	Score float64
+	Flags []string
+}
+
+// AssessQuality checks a reading for implausible values, jumps that are too
+// fast relative to the previous reading of the same device, duplicate
+// timestamps, flat-lining values and a clock ahead of the server's
+func AssessQuality(input QualityInput, config QualityConfig) QualityAssessment {
+	flags := []string{}
+	sample := input.Sample
+
+	if !plausible(input, config) {
+		flags = append(flags, QualityImplausible)
+	}
+
+	if sample.Time.After(input.Now.Add(config.MaxClockSkew)) {
+		flags = append(flags, QualityClockSkew)
+	}
+
+	var duplicate bool
+	var previous *QualitySample
+	for i := range input.Previous {
+		gap := sample.Time.Sub(input.Previous[i].Time)
+		if gap.Abs() <= config.DuplicateTolerance {
+			duplicate = true
+			continue
+		}
+		if gap > 0 && previous == nil {
+			previous = &input.Previous[i]
+		}
+	}
+	if duplicate {
+		flags = append(flags, QualityDuplicate)
+	}
+
+	if previous != nil && sample.Time.Sub(previous.Time) <= config.JumpWindow &&
+		absInt(sample.BPM-previous.BPM) > config.MaxJumpBPM {
+		flags = append(flags, QualityRapidChange)
+	}
+
+	if config.FlatlineCount > 1 && len(input.Previous) >= config.FlatlineCount-1 {
+		flat := true
+		for _, p := range input.Previous[:config.FlatlineCount-1] {
+			if p.BPM != sample.BPM {
+				flat = false
+				break
+			}
+		}
+		if flat {
+			flags = append(flags, QualityFlatline)
+		}
+	}
+
+	score := 1.0
+	for _, flag := range flags {
+		score *= 1 - qualityPenalties[flag]
+	}
+	return QualityAssessment{Score: math.Round(score*100) / 100, Flags: flags}
+}
+
+// MeetsQuality reports whether a reading with the given quality score reaches
+// minQuality. Readings without a score count as full quality.
+func MeetsQuality(score *float64, minQuality float64) bool {
+	return score == nil || *score >= minQuality
+}
+
+// plausible checks the reading against physiological limits
+func plausible(input QualityInput, config QualityConfig) bool {
+	if input.Sample.BPM < config.MinBPM || input.Sample.BPM > config.MaxBPM {
+		return false
+	}
+	if input.OxygenLevel != nil && *input.OxygenLevel < 50 {
+		return false
+	}
+	if input.SystolicPressure != nil && input.DiastolicPressure != nil &&
+		*input.DiastolicPressure >= *input.SystolicPressure {
+		return false
+	}
+	if input.Temperature != nil && (*input.Temperature < 30 || *input.Temperature > 43) {
+		return false
+	}
+	return true
+}
+
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
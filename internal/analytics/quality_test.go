@@ -0,0 +1,118 @@
+package analytics
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAssessQuality(t *testing.T) {
+	config := DefaultQualityConfig()
+	now := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	sample := func(secondsAgo, bpm int) QualitySample {
+		return QualitySample{Time: now.Add(-time.Duration(secondsAgo) * time.Second), BPM: bpm}
+	}
+	// La misma frecuencia en las lecturas anteriores, la más reciente primero
+	flat := func(n, bpm int) []QualitySample {
+		previous := make([]QualitySample, n)
+		for i := range previous {
+			previous[i] = sample(10*(i+1), bpm)
+		}
+		return previous
+	}
+	intp := func(v int) *int { return &v }
+	floatp := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name  string
+		input QualityInput
+		score float64
+		flags []string
+	}{
+		// Sin lecturas anteriores, RR ni otros signos vitales no hay nada que comparar
+		{"bpm only", QualityInput{Sample: sample(0, 72)}, 1, nil},
+		{"steady series", QualityInput{Sample: sample(0, 72), Previous: []QualitySample{sample(10, 70), sample(20, 74)}}, 1, nil},
+
+		// Una banda por indicador
+		{"implausible bpm", QualityInput{Sample: sample(0, 260)}, 0.4, []string{QualityImplausible}},
+		{"implausible bpm at the limit", QualityInput{Sample: sample(0, 250)}, 1, nil},
+		{"implausible oxygen", QualityInput{Sample: sample(0, 72), OxygenLevel: intp(45)}, 0.4, []string{QualityImplausible}},
+		{"implausible pressure", QualityInput{Sample: sample(0, 72), SystolicPressure: intp(80), DiastolicPressure: intp(90)}, 0.4, []string{QualityImplausible}},
+		{"implausible temperature", QualityInput{Sample: sample(0, 72), Temperature: floatp(44)}, 0.4, []string{QualityImplausible}},
+		{"duplicate timestamp", QualityInput{Sample: sample(0, 72), Previous: []QualitySample{sample(1, 74)}}, 0.5, []string{QualityDuplicate}},
+		{"flatline", QualityInput{Sample: sample(0, 72), Previous: flat(config.FlatlineCount-1, 72)}, 0.6, []string{QualityFlatline}},
+		{"one short of a flatline", QualityInput{Sample: sample(0, 72), Previous: flat(config.FlatlineCount-2, 72)}, 1, nil},
+		{"rapid change", QualityInput{Sample: sample(0, 120), Previous: []QualitySample{sample(30, 72)}}, 0.7, []string{QualityRapidChange}},
+		{"change outside the jump window", QualityInput{Sample: sample(0, 120), Previous: []QualitySample{sample(90, 72)}}, 1, nil},
+		{"change at the jump limit", QualityInput{Sample: sample(0, 112), Previous: []QualitySample{sample(30, 72)}}, 1, nil},
+		{"clock skew", QualityInput{Sample: sample(-180, 72)}, 0.8, []string{QualityClockSkew}},
+		{"clock skew within tolerance", QualityInput{Sample: sample(-60, 72)}, 1, nil},
+
+		// Los indicadores se multiplican
+		{"rapid change and clock skew", QualityInput{Sample: sample(-180, 120), Previous: []QualitySample{sample(-150, 72)}}, 0.56,
+			[]string{QualityClockSkew, QualityRapidChange}},
+		{"duplicate and implausible", QualityInput{Sample: sample(0, 20), Previous: []QualitySample{sample(0, 20)}}, 0.2,
+			[]string{QualityImplausible, QualityDuplicate}},
+		{"rapid change after a duplicate", QualityInput{Sample: sample(0, 130), Previous: []QualitySample{sample(0, 130), sample(20, 72)}}, 0.35,
+			[]string{QualityDuplicate, QualityRapidChange}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.input.Now = now
+			got := AssessQuality(tt.input, config)
+			if got.Score != tt.score || !slices.Equal(got.Flags, tt.flags) {
+				t.Errorf("AssessQuality() = %.2f %v, want %.2f %v", got.Score, got.Flags, tt.score, tt.flags)
+			}
+			if got.Flags == nil {
+				t.Error("Flags = nil, want an empty list")
+			}
+		})
+	}
+}
+
+func TestMeetsQuality(t *testing.T) {
+	// Con el mínimo por defecto de las alertas (ALERT_MIN_QUALITY)
+	const minQuality = 0.5
+	score := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name  string
+		score *float64
+		want  bool
+	}{
+		{"not scored", nil, true},
+		{"full quality", score(1), true},
+		{"at the floor", score(0.5), true},
+		{"just below the floor", score(0.49), false},
+		{"implausible", score(qualityScore(QualityImplausible)), false},
+		{"duplicate", score(qualityScore(QualityDuplicate)), true},
+		{"flatline", score(qualityScore(QualityFlatline)), true},
+		{"rapid change", score(qualityScore(QualityRapidChange)), true},
+		{"clock skew", score(qualityScore(QualityClockSkew)), true},
+		{"duplicate and clock skew", score(qualityScore(QualityDuplicate, QualityClockSkew)), false},
+		{"flatline and rapid change", score(qualityScore(QualityFlatline, QualityRapidChange)), false},
+		{"zero", score(0), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MeetsQuality(tt.score, minQuality); got != tt.want {
+				t.Errorf("MeetsQuality(%v, %g) = %v, want %v", tt.score, minQuality, got, tt.want)
+			}
+		})
+	}
+
+	if !MeetsQuality(score(0), 0) {
+		t.Error("MeetsQuality(0, 0) = false, want every reading with a floor of 0")
+	}
+}
+
+// qualityScore is the score AssessQuality gives a reading with the flags
+func qualityScore(flags ...string) float64 {
+	score := 1.0
+	for _, flag := range flags {
+		score *= 1 - qualityPenalties[flag]
+	}
+	return score
+}
@@ -0,0 +1,108 @@
+package analytics
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Synthetic signals used by the screening tests. Every generator is seeded so
+// the same call always returns the same signal.
+
+// sinusRR returns n RR intervals (ms) of sinus rhythm at meanHR with
+// respiratory sinus arrhythmia and a little beat-to-beat noise
+func sinusRR(n int, meanHR float64, seed int64) []float64 {
+	rng := rand.New(rand.NewSource(seed))
+	base := 60000 / meanHR
+
+	rr := make([]float64, n)
+	t := 0.0
+	for i := range rr {
+		// Respiración a 0.25 Hz con ±4% de modulación
+		rr[i] = base*(1+0.04*math.Sin(2*math.Pi*0.25*t/1000)) + rng.NormFloat64()*5
+		t += rr[i]
+	}
+	return rr
+}
+
+// atrialFibrillationRR returns n irregularly irregular RR intervals (ms)
+// around meanHR, as seen in atrial fibrillation
+func atrialFibrillationRR(n int, meanHR float64, seed int64) []float64 {
+	rng := rand.New(rand.NewSource(seed))
+	base := 60000 / meanHR
+
+	rr := make([]float64, n)
+	for i := range rr {
+		rr[i] = base * (0.6 + 0.8*rng.Float64())
+	}
+	return rr
+}
+
+// ectopicRR returns sinus rhythm with a premature beat followed by a
+// compensatory pause every `every` beats
+func ectopicRR(n int, meanHR float64, every int, seed int64) []float64 {
+	rr := sinusRR(n, meanHR, seed)
+	for i := every; i+1 < n; i += every {
+		rr[i] *= 0.6
+		rr[i+1] *= 1.4
+	}
+	return rr
+}
+
+// syntheticECG renders an ECG strip with one PQRST complex per RR interval,
+// baseline wander and white noise. Amplitudes are in millivolts and the
+// result is quantized with gain (ADC counts per mV).
+func syntheticECG(rr []float64, sampleRate, gain float64, seed int64) []int16 {
+	rng := rand.New(rand.NewSource(seed))
+
+	// Ondas gaussianas: desplazamiento respecto a R (s), amplitud (mV) y anchura (s)
+	waves := []struct{ offset, amplitude, width float64 }{
+		{-0.20, 0.15, 0.025},  // P
+		{-0.03, -0.10, 0.010}, // Q
+		{0.00, 1.20, 0.012},   // R
+		{0.03, -0.25, 0.010},  // S
+		{0.25, 0.30, 0.040},   // T
+	}
+
+	total := 0.0
+	beats := make([]float64, len(rr))
+	for i, v := range rr {
+		total += v / 1000
+		beats[i] = total
+	}
+	total += 0.5
+
+	signal := make([]float64, int(total*sampleRate))
+	for i := range signal {
+		t := float64(i) / sampleRate
+		signal[i] = 0.1*math.Sin(2*math.Pi*0.3*t) + rng.NormFloat64()*0.02
+	}
+	for _, beat := range beats {
+		start := max(int((beat-0.5)*sampleRate), 0)
+		end := min(int((beat+0.5)*sampleRate), len(signal)-1)
+		for i := start; i <= end; i++ {
+			t := float64(i) / sampleRate
+			for _, w := range waves {
+				d := t - beat - w.offset
+				signal[i] += w.amplitude * math.Exp(-d*d/(2*w.width*w.width))
+			}
+		}
+	}
+
+	samples := make([]int16, len(signal))
+	for i, mv := range signal {
+		counts := math.Round(mv * gain)
+		samples[i] = int16(math.Max(math.MinInt16, math.Min(math.MaxInt16, counts)))
+	}
+	return samples
+}
+
+// rampRR returns n RR intervals (ms) whose rate changes linearly from fromHR to
+// toHR, as at the onset or end of sinus tachycardia
+func rampRR(n int, fromHR, toHR float64) []float64 {
+	rr := make([]float64, n)
+	for i := range rr {
+		hr := fromHR + (toHR-fromHR)*float64(i+1)/float64(n+1)
+		rr[i] = 60000 / hr
+	}
+	return rr
+}
@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/services"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type AnnotationController struct {
+	annotationService *services.AnnotationService
+	localeService     *services.LocaleService
+}
+
+func NewAnnotationController(
+	annotationService *services.AnnotationService,
+	localeService *services.LocaleService,
+) *AnnotationController {
+	return &AnnotationController{
+		annotationService: annotationService,
+		localeService:     localeService,
+	}
+}
+
+// annotationErrorResponse maps service errors to HTTP responses
+func annotationErrorResponse(ctx *fiber.Ctx, err error) error {
+	var validationErr *utils.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Invalid annotation",
+			"fields": validationErr.Fields,
+		})
+	case errors.Is(err, services.ErrAnnotationNotFound), errors.Is(err, services.ErrHeartReadingNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+// CreateAnnotation annotates a reading or a time range of a patient
+func (c *AnnotationController) CreateAnnotation(ctx *fiber.Ctx) error {
+	var request models.AnnotationCreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	annotation, err := c.annotationService.CreateAnnotation(ctx.Context(), &request, currentUserID(ctx))
+	if err != nil {
+		return annotationErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(annotation)
+}
+
+// GetPatientAnnotations returns a patient's annotations overlapping
+// start_time/end_time, optionally filtered by category or reading_id
+func (c *AnnotationController) GetPatientAnnotations(ctx *fiber.Ctx) error {
+	patientID, err := uuid.Parse(ctx.Params("patientId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid patient ID",
+		})
+	}
+
+	params := &models.AnnotationQueryParams{PatientID: patientID}
+	if params.StartTime, params.EndTime, err = parseTimeRange(ctx, patientLocation(ctx, c.localeService, patientID)); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if category := ctx.Query("category"); category != "" {
+		params.Category = &category
+	}
+	if readingIDStr := ctx.Query("reading_id"); readingIDStr != "" {
+		readingID, err := uuid.Parse(readingIDStr)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid reading_id parameter",
+			})
+		}
+		params.ReadingID = &readingID
+	}
+
+	annotations, err := c.annotationService.GetAnnotations(ctx.Context(), params)
+	if err != nil {
+		return annotationErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(annotations)
+}
+
+// GetAnnotation returns an annotation by ID
+func (c *AnnotationController) GetAnnotation(ctx *fiber.Ctx) error {
+	annotationID, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid annotation ID",
+		})
+	}
+
+	annotation, err := c.annotationService.GetAnnotation(ctx.Context(), annotationID)
+	if err != nil {
+		return annotationErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(annotation)
+}
+
+// UpdateAnnotation updates an annotation
+func (c *AnnotationController) UpdateAnnotation(ctx *fiber.Ctx) error {
+	annotationID, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid annotation ID",
+		})
+	}
+
+	var request models.AnnotationUpdateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	annotation, err := c.annotationService.UpdateAnnotation(ctx.Context(), annotationID, &request)
+	if err != nil {
+		return annotationErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(annotation)
+}
+
+// DeleteAnnotation deletes an annotation
+func (c *AnnotationController) DeleteAnnotation(ctx *fiber.Ctx) error {
+	annotationID, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid annotation ID",
+		})
+	}
+
+	if err := c.annotationService.DeleteAnnotation(ctx.Context(), annotationID); err != nil {
+		return annotationErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Annotation deleted successfully",
+	})
+}
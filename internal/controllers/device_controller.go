@@ -1,8 +1,13 @@
 package controllers
 
 import (
+	"errors"
+	"strconv"
+	"time"
+
 	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
 	"github.com/Waldir-TG/api-medical-heart-v1/internal/services"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 )
@@ -17,9 +22,88 @@ func NewDeviceController(deviceService *services.DeviceService) *DeviceControlle
 	}
 }
 
+// GetDevices lists the device fleet with filters, serial number search, sorting and cursor pagination
 func (c *DeviceController) GetDevices(ctx *fiber.Ctx) error {
-	devices, err := c.deviceService.GetDevices(ctx.Context())
+	params := &models.DeviceQueryParams{
+		SortBy:    ctx.Query("sort_by", "registered_at"),
+		SortOrder: ctx.Query("sort_order", "desc"),
+		Limit:     50,
+	}
+
+	if deviceType := ctx.Query("device_type"); deviceType != "" {
+		params.DeviceType = &deviceType
+	}
+
+	if search := ctx.Query("search"); search != "" {
+		params.Search = &search
+	}
+
+	if cursor := ctx.Query("cursor"); cursor != "" {
+		params.Cursor = &cursor
+	}
+
+	for name, target := range map[string]**bool{"is_active": &params.IsActive, "assigned": &params.Assigned} {
+		if value := ctx.Query(name); value != "" {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "Invalid " + name + " parameter",
+				})
+			}
+			*target = &parsed
+		}
+	}
+
+	if batteryStr := ctx.Query("battery_below"); batteryStr != "" {
+		battery, err := strconv.Atoi(batteryStr)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid battery_below parameter",
+			})
+		}
+		params.BatteryBelow = &battery
+	}
+
+	if beforeStr := ctx.Query("last_sync_before"); beforeStr != "" {
+		before, err := time.Parse(time.RFC3339, beforeStr)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid last_sync_before format. Use RFC3339 format.",
+			})
+		}
+		params.LastSyncBefore = &before
+	}
+
+	// last_sync_older_than acepta una duración relativa, por ejemplo "24h"
+	if olderThanStr := ctx.Query("last_sync_older_than"); olderThanStr != "" {
+		olderThan, err := time.ParseDuration(olderThanStr)
+		if err != nil || olderThan <= 0 {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid last_sync_older_than parameter. Use a duration such as 24h.",
+			})
+		}
+		before := time.Now().Add(-olderThan)
+		params.LastSyncBefore = &before
+	}
+
+	if limitStr := ctx.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid limit parameter",
+			})
+		}
+		params.Limit = limit
+	}
+
+	devices, err := c.deviceService.ListDevices(ctx.Context(), params)
 	if err != nil {
+		var validationErr *utils.ValidationError
+		if errors.As(err, &validationErr) || errors.Is(err, utils.ErrInvalidCursor) {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
@@ -78,7 +162,7 @@ func (c *DeviceController) RegisterDevice(ctx *fiber.Ctx) error {
 		})
 	}
 
-	deviceID, err := c.deviceService.RegisterDevice(ctx.Context(), &request)
+	deviceID, err := c.deviceService.RegisterDevice(ctx.Context(), &request, currentUserID(ctx))
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
@@ -108,6 +192,11 @@ func (c *DeviceController) UpdateDevice(ctx *fiber.Ctx) error {
 
 	message, err := c.deviceService.UpdateDevice(ctx.Context(), deviceID, &request)
 	if err != nil {
+		if errors.Is(err, services.ErrPatientChangeNotAllowed) {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
@@ -126,19 +215,21 @@ func (c *DeviceController) UpdateDeviceSync(ctx *fiber.Ctx) error {
 		})
 	}
 
-	type SyncRequest struct {
-		BatteryLevel int `json:"battery_level"`
-	}
-
-	var request SyncRequest
+	var request models.DeviceSyncRequest
 	if err := ctx.BodyParser(&request); err != nil {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Invalid request body",
 		})
 	}
 
-	err = c.deviceService.UpdateDeviceSync(ctx.Context(), deviceID, request.BatteryLevel)
+	err = c.deviceService.UpdateDeviceSync(ctx.Context(), deviceID, &request)
 	if err != nil {
+		var validationErr *utils.ValidationError
+		if errors.As(err, &validationErr) {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
@@ -149,6 +240,132 @@ func (c *DeviceController) UpdateDeviceSync(ctx *fiber.Ctx) error {
 	})
 }
 
+func (c *DeviceController) GetDeviceTelemetry(ctx *fiber.Ctx) error {
+	deviceID, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid device ID",
+		})
+	}
+
+	params := &models.DeviceTelemetryQueryParams{
+		DeviceID: deviceID,
+		Limit:    500,
+	}
+
+	if params.StartTime, params.EndTime, err = parseTimeRange(ctx, queryLocation(ctx)); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if limitStr := ctx.Query("limit"); limitStr != "" {
+		parsedLimit, err := strconv.Atoi(limitStr)
+		if err != nil || parsedLimit < 1 || parsedLimit > 10000 {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid limit parameter",
+			})
+		}
+		params.Limit = parsedLimit
+	}
+
+	telemetry, err := c.deviceService.GetDeviceTelemetry(ctx.Context(), params)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(telemetry)
+}
+
+// AssignDevice explicitly reassigns a device to another patient, keeping the history
+func (c *DeviceController) AssignDevice(ctx *fiber.Ctx) error {
+	deviceID, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid device ID",
+		})
+	}
+
+	var request models.DeviceAssignRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := c.deviceService.AssignDevice(ctx.Context(), deviceID, &request, currentUserID(ctx)); err != nil {
+		if errors.Is(err, services.ErrDeviceNotFound) {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Device not found",
+			})
+		}
+		if errors.Is(err, services.ErrPatientNotFound) {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Patient not found",
+			})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Device assignment updated successfully",
+	})
+}
+
+func (c *DeviceController) GetDeviceAssignments(ctx *fiber.Ctx) error {
+	deviceID, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid device ID",
+		})
+	}
+
+	assignments, err := c.deviceService.GetDeviceAssignments(ctx.Context(), deviceID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(assignments)
+}
+
+// GetDeviceAssignmentAt resolves which patient a device was on at the given time
+func (c *DeviceController) GetDeviceAssignmentAt(ctx *fiber.Ctx) error {
+	deviceID, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid device ID",
+		})
+	}
+
+	at, err := time.Parse(time.RFC3339, ctx.Query("time"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid time format. Use RFC3339 format.",
+		})
+	}
+
+	assignment, err := c.deviceService.GetDeviceAssignmentAt(ctx.Context(), deviceID, at)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if assignment == nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Device was not assigned to a patient at that time",
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(assignment)
+}
+
 func (c *DeviceController) DeactivateDevice(ctx *fiber.Ctx) error {
 	deviceID, err := uuid.Parse(ctx.Params("id"))
 	if err != nil {
@@ -167,4 +384,4 @@ func (c *DeviceController) DeactivateDevice(ctx *fiber.Ctx) error {
 	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message": message,
 	})
-}
\ No newline at end of file
+}
@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/ecg"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/services"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultEcgPlotPoints = 2000
+	maxEcgPlotPoints     = 20000
+)
+
+type EcgController struct {
+	ecgService *services.EcgService
+}
+
+func NewEcgController(ecgService *services.EcgService) *EcgController {
+	return &EcgController{
+		ecgService: ecgService,
+	}
+}
+
+// UploadRecording accepts an ECG strip either as a multipart "file" field
+// with the metadata as form fields, or as a binary body with the metadata
+// in the query string
+func (c *EcgController) UploadRecording(ctx *fiber.Ctx) error {
+	readingID, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid reading ID",
+		})
+	}
+
+	var request models.EcgUploadRequest
+	var payload []byte
+
+	if strings.HasPrefix(ctx.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		if err := ctx.BodyParser(&request); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid form fields",
+			})
+		}
+
+		fileHeader, err := ctx.FormFile("file")
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Missing ECG file",
+			})
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Unable to read ECG file",
+			})
+		}
+		defer file.Close()
+
+		if payload, err = io.ReadAll(file); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Unable to read ECG file",
+			})
+		}
+	} else {
+		if err := ctx.QueryParser(&request); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid query parameters",
+			})
+		}
+		payload = ctx.Body()
+	}
+
+	recording, err := c.ecgService.UploadRecording(ctx.Context(), readingID, &request, payload)
+	if err != nil {
+		var validationErr *utils.ValidationError
+		switch {
+		case errors.As(err, &validationErr):
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":  "Invalid ECG recording",
+				"fields": validationErr.Fields,
+			})
+		case errors.Is(err, services.ErrHeartReadingNotFound):
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(recording)
+}
+
+// GetRecordingsByReading lists the ECG strips attached to a heart reading
+func (c *EcgController) GetRecordingsByReading(ctx *fiber.Ctx) error {
+	readingID, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid reading ID",
+		})
+	}
+
+	recordings, err := c.ecgService.GetRecordingsByReading(ctx.Context(), readingID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(recordings)
+}
+
+// GetRecording downloads an ECG strip. format=raw returns the stored ECG1
+// encoding, format=csv the full strip in millivolts and format=json (default)
+// a min/max downsampled series for plotting.
+func (c *EcgController) GetRecording(ctx *fiber.Ctx) error {
+	recordingID, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid recording ID",
+		})
+	}
+
+	format := ctx.Query("format", "json")
+	switch format {
+	case "raw":
+		metadata, data, err := c.ecgService.GetRecording(ctx.Context(), recordingID)
+		if err != nil {
+			return ecgErrorResponse(ctx, err)
+		}
+		ctx.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.ecg"`, metadata.ID))
+		ctx.Set(fiber.HeaderContentType, fiber.MIMEOctetStream)
+		return ctx.Status(fiber.StatusOK).Send(data)
+
+	case "csv":
+		metadata, recording, err := c.ecgService.DecodeRecording(ctx.Context(), recordingID)
+		if err != nil {
+			return ecgErrorResponse(ctx, err)
+		}
+		var buf bytes.Buffer
+		if err := ecg.WriteCSV(&buf, recording); err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		ctx.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.csv"`, metadata.ID))
+		ctx.Set(fiber.HeaderContentType, "text/csv")
+		return ctx.Status(fiber.StatusOK).Send(buf.Bytes())
+
+	case "json":
+		maxPoints := defaultEcgPlotPoints
+		if mp := ctx.Query("max_points"); mp != "" {
+			maxPoints, err = strconv.Atoi(mp)
+			if err != nil || maxPoints < 2 || maxPoints > maxEcgPlotPoints {
+				return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": fmt.Sprintf("max_points must be between 2 and %d", maxEcgPlotPoints),
+				})
+			}
+		}
+
+		metadata, recording, err := c.ecgService.DecodeRecording(ctx.Context(), recordingID)
+		if err != nil {
+			return ecgErrorResponse(ctx, err)
+		}
+		return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+			"recording": metadata,
+			"points":    ecg.Downsample(recording, maxPoints),
+		})
+	}
+
+	return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error": "format must be one of raw, csv, json",
+	})
+}
+
+func ecgErrorResponse(ctx *fiber.Ctx, err error) error {
+	if errors.Is(err, services.ErrEcgRecordingNotFound) {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/export"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/services"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type ExportController struct {
+	exportService *services.ExportService
+	localeService *services.LocaleService
+}
+
+func NewExportController(exportService *services.ExportService, localeService *services.LocaleService) *ExportController {
+	return &ExportController{
+		exportService: exportService,
+		localeService: localeService,
+	}
+}
+
+// exportErrorResponse maps service errors to HTTP responses
+func exportErrorResponse(ctx *fiber.Ctx, err error) error {
+	var validationErr *utils.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Invalid export request",
+			"fields": validationErr.Fields,
+		})
+	case errors.Is(err, services.ErrPatientNotFound), errors.Is(err, services.ErrExportJobNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrExportNotReady):
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	default:
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+}
+
+// ExportPatientReadings streams a patient's readings as CSV, a JSON array or
+// NDJSON. Readings are read from the database and written to the client one
+// at a time, so the export is never held in memory.
+func (c *ExportController) ExportPatientReadings(ctx *fiber.Ctx) error {
+	patientID, err := uuid.Parse(ctx.Params("patientId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid patient ID",
+		})
+	}
+
+	request := &models.ReadingExportRequest{
+		PatientID: patientID,
+		Format:    ctx.Query("format", export.FormatCSV),
+		Timezone:  ctx.Query("tz"),
+	}
+	if columns := ctx.Query("columns"); columns != "" {
+		request.Columns = strings.Split(columns, ",")
+	}
+	request.StartTime, request.EndTime, err = parseTimeRange(ctx, patientLocation(ctx, c.localeService, patientID))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := c.exportService.ValidateExport(ctx.Context(), request); err != nil {
+		return exportErrorResponse(ctx, err)
+	}
+
+	ctx.Set(fiber.HeaderContentType, export.ContentType(request.Format))
+	ctx.Attachment(services.ExportFileName(patientID, request.Format, time.Now()))
+
+	// El cuerpo se escribe después de que el handler retorna: la consulta no
+	// puede depender del contexto de la petición
+	timeout := c.exportService.StreamTimeout()
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		streamCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		if _, err := c.exportService.WriteExport(streamCtx, w, request); err != nil {
+			log.Printf("Export of patient %s readings interrupted: %v", patientID, err)
+		}
+	})
+	return nil
+}
+
+// CreateExportJob queues an export of a patient's readings to a file that can
+// be downloaded once the job completes
+func (c *ExportController) CreateExportJob(ctx *fiber.Ctx) error {
+	patientID, err := uuid.Parse(ctx.Params("patientId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid patient ID",
+		})
+	}
+
+	var request models.ReadingExportRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	request.PatientID = patientID
+
+	job, err := c.exportService.CreateJob(ctx.Context(), &request, currentUserID(ctx))
+	if err != nil {
+		return exportErrorResponse(ctx, err)
+	}
+	return ctx.Status(fiber.StatusAccepted).JSON(job)
+}
+
+// GetPatientExportJobs returns a patient's most recent export jobs
+func (c *ExportController) GetPatientExportJobs(ctx *fiber.Ctx) error {
+	patientID, err := uuid.Parse(ctx.Params("patientId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid patient ID",
+		})
+	}
+
+	jobs, err := c.exportService.GetPatientJobs(ctx.Context(), patientID)
+	if err != nil {
+		return exportErrorResponse(ctx, err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(jobs)
+}
+
+// GetExportJob returns the status of an export job
+func (c *ExportController) GetExportJob(ctx *fiber.Ctx) error {
+	jobID, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid export job ID",
+		})
+	}
+
+	job, err := c.exportService.GetJob(ctx.Context(), jobID)
+	if err != nil {
+		return exportErrorResponse(ctx, err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(job)
+}
+
+// DownloadExportJob sends the file produced by a completed export job
+func (c *ExportController) DownloadExportJob(ctx *fiber.Ctx) error {
+	jobID, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid export job ID",
+		})
+	}
+
+	job, err := c.exportService.GetJob(ctx.Context(), jobID)
+	if err != nil {
+		return exportErrorResponse(ctx, err)
+	}
+	path, err := c.exportService.JobFile(job)
+	if err != nil {
+		return exportErrorResponse(ctx, err)
+	}
+
+	ctx.Set(fiber.HeaderContentType, export.ContentType(job.Format))
+	ctx.Attachment(services.ExportFileName(job.PatientID, job.Format, job.CreatedAt))
+	return ctx.SendFile(path)
+}
@@ -0,0 +1,328 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/fhir"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/services"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type FHIRController struct {
+	fhirService   *services.FHIRService
+	localeService *services.LocaleService
+	startedAt     time.Time
+}
+
+func NewFHIRController(fhirService *services.FHIRService, localeService *services.LocaleService) *FHIRController {
+	return &FHIRController{
+		fhirService:   fhirService,
+		localeService: localeService,
+		startedAt:     time.Now().UTC(),
+	}
+}
+
+// fhirResource writes a FHIR resource
+func fhirResource(ctx *fiber.Ctx, status int, resource any) error {
+	return ctx.Status(status).JSON(resource, fhir.ContentType)
+}
+
+// fhirError writes an OperationOutcome with a single error
+func fhirError(ctx *fiber.Ctx, status int, code, diagnostics string) error {
+	return fhirResource(ctx, status, fhir.NewOperationOutcome(fhir.Issue{Severity: "error", Code: code, Diagnostics: diagnostics}))
+}
+
+// fhirErrorResponse maps service errors to OperationOutcome responses
+func fhirErrorResponse(ctx *fiber.Ctx, err error) error {
+	var validationErr *utils.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		issues := make([]fhir.Issue, len(validationErr.Fields))
+		for i, field := range validationErr.Fields {
+			issues[i] = fhir.Issue{Severity: "error", Code: "invalid", Diagnostics: field}
+		}
+		return fhirResource(ctx, fiber.StatusBadRequest, fhir.NewOperationOutcome(issues...))
+	case errors.Is(err, utils.ErrInvalidCursor):
+		return fhirError(ctx, fiber.StatusBadRequest, "invalid", err.Error())
+	case errors.Is(err, services.ErrObservationNotFound), errors.Is(err, services.ErrPatientNotFound),
+		errors.Is(err, services.ErrDeviceNotFound), errors.Is(err, services.ErrDoctorNotFound):
+		return fhirError(ctx, fiber.StatusNotFound, "not-found", err.Error())
+	default:
+		return fhirError(ctx, fiber.StatusInternalServerError, "exception", err.Error())
+	}
+}
+
+// searchset wraps resources in a searchset Bundle. next is the cursor of
+// the next page, if any.
+func (c *FHIRController) searchset(ctx *fiber.Ctx, resourceType string, ids []string, resources []any, next *string) error {
+	bundle := &fhir.Bundle{ResourceType: "Bundle", Type: "searchset", Entry: []fhir.BundleEntry{}}
+	self := ctx.BaseURL() + ctx.OriginalURL()
+	bundle.Link = append(bundle.Link, fhir.BundleLink{Relation: "self", URL: self})
+	if next != nil {
+		// La página siguiente repite la búsqueda con el cursor
+		if u, err := url.Parse(ctx.OriginalURL()); err == nil {
+			query := u.Query()
+			query.Set("_cursor", *next)
+			u.RawQuery = query.Encode()
+			bundle.Link = append(bundle.Link, fhir.BundleLink{Relation: "next", URL: ctx.BaseURL() + u.String()})
+		}
+	} else {
+		total := len(resources)
+		if ctx.Query("_cursor") == "" {
+			bundle.Total = &total
+		}
+	}
+
+	for i, resource := range resources {
+		entry, err := fhir.Entry(ctx.BaseURL()+"/fhir/"+resourceType+"/"+ids[i], resource)
+		if err != nil {
+			return fhirErrorResponse(ctx, err)
+		}
+		bundle.Entry = append(bundle.Entry, entry)
+	}
+	return fhirResource(ctx, fiber.StatusOK, bundle)
+}
+
+// Metadata returns the CapabilityStatement of the FHIR facade
+func (c *FHIRController) Metadata(ctx *fiber.Ctx) error {
+	return fhirResource(ctx, fiber.StatusOK, fhir.NewCapabilityStatement(c.startedAt.Format(time.RFC3339)))
+}
+
+// GetObservation returns an Observation by id
+func (c *FHIRController) GetObservation(ctx *fiber.Ctx) error {
+	observation, err := c.fhirService.GetObservation(ctx.Context(), ctx.Params("id"))
+	if err != nil {
+		return fhirErrorResponse(ctx, err)
+	}
+	return fhirResource(ctx, fiber.StatusOK, observation)
+}
+
+// SearchObservations searches a patient's vital signs Observations. It
+// supports patient (required), date (repeatable, with eq/gt/ge/lt/le
+// prefixes), code (LOINC codes, comma separated), _count, _sort (date or
+// -date) and _cursor for the next page.
+func (c *FHIRController) SearchObservations(ctx *fiber.Ctx) error {
+	params := &models.MeasurementQueryParams{SortOrder: "desc", Limit: 50}
+
+	patient := ctx.Query("patient", ctx.Query("subject"))
+	if patient != "" {
+		if !strings.Contains(patient, "/") {
+			patient = "Patient/" + patient
+		}
+		patientID, err := fhir.ParseReference(&fhir.Reference{Reference: patient}, "Patient")
+		if err != nil {
+			return fhirError(ctx, fiber.StatusBadRequest, "invalid", "patient: "+err.Error())
+		}
+		params.PatientID = &patientID
+	}
+
+	if dates := ctx.Context().QueryArgs().PeekMulti("date"); len(dates) > 0 {
+		// Las fechas sin zona horaria se leen en la del paciente
+		loc := time.UTC
+		if params.PatientID != nil {
+			loc = c.localeService.PatientLocation(ctx.Context(), *params.PatientID)
+		}
+		for _, date := range dates {
+			if err := fhir.ApplyDateParam(string(date), loc, &params.StartTime, &params.EndTime); err != nil {
+				return fhirError(ctx, fiber.StatusBadRequest, "invalid", "date: "+err.Error())
+			}
+		}
+	}
+
+	if codes := ctx.Query("code"); codes != "" {
+		for _, token := range strings.Split(codes, ",") {
+			system, code, hasSystem := strings.Cut(token, "|")
+			if !hasSystem {
+				code = system
+			} else if system != "" && system != fhir.SystemLOINC {
+				continue
+			}
+			if observationType, ok := fhir.ObservationTypeForCode(code); ok {
+				params.ObservationTypes = append(params.ObservationTypes, observationType)
+			}
+		}
+		if len(params.ObservationTypes) == 0 {
+			// Ningún código conocido: no hay resultados posibles
+			return c.searchset(ctx, "Observation", nil, nil, nil)
+		}
+	}
+
+	if value := ctx.Query("_count"); value != "" {
+		count, err := strconv.Atoi(value)
+		if err != nil || count < 1 || count > 1000 {
+			return fhirError(ctx, fiber.StatusBadRequest, "invalid", "_count must be between 1 and 1000")
+		}
+		params.Limit = count
+	}
+	switch ctx.Query("_sort", "-date") {
+	case "date":
+		params.SortOrder = "asc"
+	case "-date":
+		params.SortOrder = "desc"
+	default:
+		return fhirError(ctx, fiber.StatusBadRequest, "not-supported", "_sort supports date and -date")
+	}
+	if cursor := ctx.Query("_cursor"); cursor != "" {
+		params.Cursor = &cursor
+	}
+
+	observations, next, err := c.fhirService.SearchObservations(ctx.Context(), params)
+	if err != nil {
+		return fhirErrorResponse(ctx, err)
+	}
+	ids := make([]string, len(observations))
+	resources := make([]any, len(observations))
+	for i, observation := range observations {
+		ids[i], resources[i] = observation.ID, observation
+	}
+	return c.searchset(ctx, "Observation", ids, resources, next)
+}
+
+// parseResourceID parses the id of a Patient, Device or Practitioner
+func parseResourceID(ctx *fiber.Ctx) (uuid.UUID, bool) {
+	id, err := uuid.Parse(ctx.Params("id"))
+	return id, err == nil
+}
+
+// GetPatient returns a Patient by id
+func (c *FHIRController) GetPatient(ctx *fiber.Ctx) error {
+	patientID, ok := parseResourceID(ctx)
+	if !ok {
+		return fhirErrorResponse(ctx, services.ErrPatientNotFound)
+	}
+	patient, err := c.fhirService.GetPatient(ctx.Context(), patientID)
+	if err != nil {
+		return fhirErrorResponse(ctx, err)
+	}
+	return fhirResource(ctx, fiber.StatusOK, patient)
+}
+
+// SearchPatients returns every Patient
+func (c *FHIRController) SearchPatients(ctx *fiber.Ctx) error {
+	patients, err := c.fhirService.SearchPatients(ctx.Context())
+	if err != nil {
+		return fhirErrorResponse(ctx, err)
+	}
+	ids := make([]string, len(patients))
+	resources := make([]any, len(patients))
+	for i, patient := range patients {
+		ids[i], resources[i] = patient.ID, patient
+	}
+	return c.searchset(ctx, "Patient", ids, resources, nil)
+}
+
+// GetDevice returns a Device by id
+func (c *FHIRController) GetDevice(ctx *fiber.Ctx) error {
+	deviceID, ok := parseResourceID(ctx)
+	if !ok {
+		return fhirErrorResponse(ctx, services.ErrDeviceNotFound)
+	}
+	device, err := c.fhirService.GetDevice(ctx.Context(), deviceID)
+	if err != nil {
+		return fhirErrorResponse(ctx, err)
+	}
+	return fhirResource(ctx, fiber.StatusOK, device)
+}
+
+// SearchDevices returns the Devices of the patient given by the patient
+// parameter, or every Device
+func (c *FHIRController) SearchDevices(ctx *fiber.Ctx) error {
+	var patientID *uuid.UUID
+	if patient := ctx.Query("patient"); patient != "" {
+		if !strings.Contains(patient, "/") {
+			patient = "Patient/" + patient
+		}
+		id, err := fhir.ParseReference(&fhir.Reference{Reference: patient}, "Patient")
+		if err != nil {
+			return fhirError(ctx, fiber.StatusBadRequest, "invalid", "patient: "+err.Error())
+		}
+		patientID = &id
+	}
+
+	devices, err := c.fhirService.SearchDevices(ctx.Context(), patientID)
+	if err != nil {
+		return fhirErrorResponse(ctx, err)
+	}
+	ids := make([]string, len(devices))
+	resources := make([]any, len(devices))
+	for i, device := range devices {
+		ids[i], resources[i] = device.ID, device
+	}
+	return c.searchset(ctx, "Device", ids, resources, nil)
+}
+
+// GetPractitioner returns a Practitioner by id
+func (c *FHIRController) GetPractitioner(ctx *fiber.Ctx) error {
+	doctorID, ok := parseResourceID(ctx)
+	if !ok {
+		return fhirErrorResponse(ctx, services.ErrDoctorNotFound)
+	}
+	practitioner, err := c.fhirService.GetPractitioner(ctx.Context(), doctorID)
+	if err != nil {
+		return fhirErrorResponse(ctx, err)
+	}
+	return fhirResource(ctx, fiber.StatusOK, practitioner)
+}
+
+// SearchPractitioners returns every Practitioner
+func (c *FHIRController) SearchPractitioners(ctx *fiber.Ctx) error {
+	practitioners, err := c.fhirService.SearchPractitioners(ctx.Context())
+	if err != nil {
+		return fhirErrorResponse(ctx, err)
+	}
+	ids := make([]string, len(practitioners))
+	resources := make([]any, len(practitioners))
+	for i, practitioner := range practitioners {
+		ids[i], resources[i] = practitioner.ID, practitioner
+	}
+	return c.searchset(ctx, "Practitioner", ids, resources, nil)
+}
+
+// Transaction imports the Observations of a transaction Bundle and returns
+// the transaction-response Bundle
+func (c *FHIRController) Transaction(ctx *fiber.Ctx) error {
+	response, err := c.fhirService.ProcessTransaction(ctx.Context(), ctx.Body(), currentUserID(ctx))
+	if err != nil {
+		return fhirErrorResponse(ctx, err)
+	}
+	return fhirResource(ctx, fiber.StatusOK, response)
+}
+
+// Validate runs the $validate operation: it checks the posted resource, or
+// the "resource" parameter of a Parameters resource, against the bundled
+// profiles
+func (c *FHIRController) Validate(ctx *fiber.Ctx) error {
+	body := ctx.Body()
+
+	var parameters struct {
+		ResourceType string `json:"resourceType"`
+		Parameter    []struct {
+			Name     string          `json:"name"`
+			Resource json.RawMessage `json:"resource"`
+		} `json:"parameter"`
+	}
+	if err := json.Unmarshal(body, &parameters); err != nil {
+		return fhirError(ctx, fiber.StatusBadRequest, "structure", "Invalid JSON body")
+	}
+	if parameters.ResourceType == "Parameters" {
+		body = nil
+		for _, p := range parameters.Parameter {
+			if p.Name == "resource" {
+				body = p.Resource
+			}
+		}
+		if body == nil {
+			return fhirError(ctx, fiber.StatusBadRequest, "required", "Parameters must include a resource parameter")
+		}
+	}
+
+	return fhirResource(ctx, fiber.StatusOK, c.fhirService.Validate(ctx.Params("type"), body))
+}
@@ -0,0 +1,222 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/services"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type FirmwareController struct {
+	firmwareService *services.FirmwareService
+}
+
+func NewFirmwareController(firmwareService *services.FirmwareService) *FirmwareController {
+	return &FirmwareController{
+		firmwareService: firmwareService,
+	}
+}
+
+func (c *FirmwareController) GetReleases(ctx *fiber.Ctx) error {
+	var deviceType *string
+	if dt := ctx.Query("device_type"); dt != "" {
+		deviceType = &dt
+	}
+
+	releases, err := c.firmwareService.GetReleases(ctx.Context(), deviceType)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(releases)
+}
+
+func (c *FirmwareController) CreateRelease(ctx *fiber.Ctx) error {
+	var request models.FirmwareReleaseCreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	releaseID, err := c.firmwareService.CreateRelease(ctx.Context(), &request, currentUserID(ctx))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":     "Firmware release created successfully",
+		"firmware_id": releaseID,
+	})
+}
+
+func (c *FirmwareController) CreateRollout(ctx *fiber.Ctx) error {
+	firmwareID, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid firmware ID",
+		})
+	}
+
+	var request models.FirmwareRolloutCreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	rolloutID, err := c.firmwareService.CreateRollout(ctx.Context(), firmwareID, &request, currentUserID(ctx))
+	if err != nil {
+		if errors.Is(err, services.ErrFirmwareNotFound) {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":    "Firmware rollout created successfully",
+		"rollout_id": rolloutID,
+	})
+}
+
+func (c *FirmwareController) GetRollout(ctx *fiber.Ctx) error {
+	rolloutID, err := uuid.Parse(ctx.Params("rolloutId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid rollout ID",
+		})
+	}
+
+	summary, err := c.firmwareService.GetRolloutSummary(ctx.Context(), rolloutID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if summary == nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Firmware rollout not found",
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(summary)
+}
+
+func (c *FirmwareController) UpdateRollout(ctx *fiber.Ctx) error {
+	rolloutID, err := uuid.Parse(ctx.Params("rolloutId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid rollout ID",
+		})
+	}
+
+	var request models.FirmwareRolloutUpdateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := c.firmwareService.UpdateRollout(ctx.Context(), rolloutID, &request); err != nil {
+		if errors.Is(err, services.ErrRolloutNotFound) {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Firmware rollout updated successfully",
+	})
+}
+
+func (c *FirmwareController) GetVersionDistribution(ctx *fiber.Ctx) error {
+	var deviceType *string
+	if dt := ctx.Query("device_type"); dt != "" {
+		deviceType = &dt
+	}
+
+	distribution, err := c.firmwareService.GetVersionDistribution(ctx.Context(), deviceType)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(distribution)
+}
+
+// CheckForUpdate is polled by devices to find out whether an update is assigned to them
+func (c *FirmwareController) CheckForUpdate(ctx *fiber.Ctx) error {
+	deviceID, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid device ID",
+		})
+	}
+
+	check, err := c.firmwareService.CheckForUpdate(ctx.Context(), deviceID)
+	if err != nil {
+		if errors.Is(err, services.ErrDeviceNotFound) {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Device not found",
+			})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(check)
+}
+
+// ReportUpdate records whether a device installed a rollout successfully
+func (c *FirmwareController) ReportUpdate(ctx *fiber.Ctx) error {
+	deviceID, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid device ID",
+		})
+	}
+
+	var request models.FirmwareUpdateReportRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := c.firmwareService.ReportUpdate(ctx.Context(), deviceID, &request); err != nil {
+		if errors.Is(err, services.ErrRolloutNotFound) || errors.Is(err, services.ErrDeviceNotFound) {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, services.ErrDeviceNotInRollout) {
+			return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Firmware update reported successfully",
+	})
+}
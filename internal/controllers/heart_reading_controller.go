@@ -1,22 +1,29 @@
 package controllers
 
 import (
+	"errors"
 	"strconv"
-	"time"
+	"strings"
 
 	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
 	"github.com/Waldir-TG/api-medical-heart-v1/internal/services"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 )
 
 type HeartReadingController struct {
 	heartReadingService *services.HeartReadingService
+	localeService       *services.LocaleService
 }
 
-func NewHeartReadingController(heartReadingService *services.HeartReadingService) *HeartReadingController {
+func NewHeartReadingController(
+	heartReadingService *services.HeartReadingService,
+	localeService *services.LocaleService,
+) *HeartReadingController {
 	return &HeartReadingController{
 		heartReadingService: heartReadingService,
+		localeService:       localeService,
 	}
 }
 
@@ -29,18 +36,27 @@ func (c *HeartReadingController) CreateHeartReading(ctx *fiber.Ctx) error {
 		})
 	}
 
-	if err := c.heartReadingService.CreateHeartReading(ctx.Context(), &request); err != nil {
+	reading, err := c.heartReadingService.CreateHeartReading(ctx.Context(), &request)
+	if err != nil {
+		var validationErr *utils.ValidationError
+		if errors.As(err, &validationErr) {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
 	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"message": "Heart reading created successfully",
+		"message":    "Heart reading created successfully",
+		"reading_id": reading.ID,
 	})
 }
 
-// GetPatientHeartReadings retrieves heart readings for a specific patient
+// GetPatientHeartReadings returns a filtered page of a patient's heart readings.
+// Pages are requested with the next_cursor of the previous response.
 func (c *HeartReadingController) GetPatientHeartReadings(ctx *fiber.Ctx) error {
 	patientID, err := uuid.Parse(ctx.Params("patientId"))
 	if err != nil {
@@ -49,59 +65,107 @@ func (c *HeartReadingController) GetPatientHeartReadings(ctx *fiber.Ctx) error {
 		})
 	}
 
-	// Parse query parameters
-	var startTime, endTime *time.Time
-	if startTimeStr := ctx.Query("start_time"); startTimeStr != "" {
-		parsedTime, err := time.Parse(time.RFC3339, startTimeStr)
-		if err != nil {
-			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid start_time format. Use RFC3339 format.",
-			})
+	params := &models.HeartReadingQueryParams{
+		PatientID: patientID,
+		SortOrder: ctx.Query("sort_order", "desc"),
+		Limit:     100,
+	}
+
+	if params.StartTime, params.EndTime, err = parseTimeRange(ctx, patientLocation(ctx, c.localeService, patientID)); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	for name, target := range map[string]**string{
+		"reading_type": &params.ReadingType,
+		"entry_method": &params.EntryMethod,
+		"source":       &params.Source,
+		"cursor":       &params.Cursor,
+	} {
+		if value := ctx.Query(name); value != "" {
+			*target = &value
 		}
-		startTime = &parsedTime
 	}
 
-	if endTimeStr := ctx.Query("end_time"); endTimeStr != "" {
-		parsedTime, err := time.Parse(time.RFC3339, endTimeStr)
+	if deviceIDStr := ctx.Query("device_id"); deviceIDStr != "" {
+		deviceID, err := uuid.Parse(deviceIDStr)
 		if err != nil {
 			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid end_time format. Use RFC3339 format.",
+				"error": "Invalid device_id parameter",
 			})
 		}
-		endTime = &parsedTime
+		params.DeviceID = &deviceID
 	}
 
-	var limit, offset *int
-	if limitStr := ctx.Query("limit"); limitStr != "" {
-		parsedLimit, err := strconv.Atoi(limitStr)
+	for name, target := range map[string]**int{"min_bpm": &params.MinBPM, "max_bpm": &params.MaxBPM} {
+		if value := ctx.Query(name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "Invalid " + name + " parameter",
+				})
+			}
+			*target = &parsed
+		}
+	}
+
+	for name, target := range map[string]*bool{"irregular_only": &params.IrregularOnly, "include_total": &params.IncludeTotal} {
+		if value := ctx.Query(name); value != "" {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "Invalid " + name + " parameter",
+				})
+			}
+			*target = parsed
+		}
+	}
+
+	if reliabilityStr := ctx.Query("min_reliability"); reliabilityStr != "" {
+		reliability, err := strconv.ParseFloat(reliabilityStr, 64)
 		if err != nil {
 			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid limit parameter",
+				"error": "Invalid min_reliability parameter",
 			})
 		}
-		limit = &parsedLimit
+		params.MinReliability = &reliability
 	}
 
-	if offsetStr := ctx.Query("offset"); offsetStr != "" {
-		parsedOffset, err := strconv.Atoi(offsetStr)
+	if limitStr := ctx.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
 		if err != nil {
 			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid offset parameter",
+				"error": "Invalid limit parameter",
 			})
 		}
-		offset = &parsedOffset
+		params.Limit = limit
 	}
 
-	params := &models.HeartReadingQueryParams{
-		PatientID: patientID,
-		StartTime: startTime,
-		EndTime:   endTime,
-		Limit:     limit,
-		Offset:    offset,
+	// include=device,entered_by incrusta la información del dispositivo y de quien registró la lectura
+	if include := ctx.Query("include"); include != "" {
+		for _, part := range strings.Split(include, ",") {
+			switch strings.TrimSpace(part) {
+			case "device":
+				params.IncludeDevice = true
+			case "entered_by":
+				params.IncludeEnteredBy = true
+			default:
+				return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "Invalid include parameter. Use device and/or entered_by.",
+				})
+			}
+		}
 	}
 
 	readings, err := c.heartReadingService.GetPatientHeartReadings(ctx.Context(), params)
 	if err != nil {
+		var validationErr *utils.ValidationError
+		if errors.As(err, &validationErr) || errors.Is(err, utils.ErrInvalidCursor) {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
@@ -110,7 +174,9 @@ func (c *HeartReadingController) GetPatientHeartReadings(ctx *fiber.Ctx) error {
 	return ctx.Status(fiber.StatusOK).JSON(readings)
 }
 
-// GetHeartRateStats retrieves heart rate statistics for a patient
+// GetHeartRateStats retrieves heart rate statistics for a patient over at
+// most 366 days, by default the last 366. exclude_artifacts=true leaves out readings annotated as artifacts and
+// min_quality leaves out readings with a lower server quality score.
 func (c *HeartReadingController) GetHeartRateStats(ctx *fiber.Ctx) error {
 	patientID, err := uuid.Parse(ctx.Params("patientId"))
 	if err != nil {
@@ -119,30 +185,41 @@ func (c *HeartReadingController) GetHeartRateStats(ctx *fiber.Ctx) error {
 		})
 	}
 
-	// Parse query parameters
-	var startTime, endTime *time.Time
-	if startTimeStr := ctx.Query("start_time"); startTimeStr != "" {
-		parsedTime, err := time.Parse(time.RFC3339, startTimeStr)
-		if err != nil {
-			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid start_time format. Use RFC3339 format.",
-			})
-		}
-		startTime = &parsedTime
+	startTime, endTime, err := parseTimeRange(ctx, patientLocation(ctx, c.localeService, patientID))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
 	}
 
-	if endTimeStr := ctx.Query("end_time"); endTimeStr != "" {
-		parsedTime, err := time.Parse(time.RFC3339, endTimeStr)
+	var filter models.ReadingFilter
+	if value := ctx.Query("exclude_artifacts"); value != "" {
+		filter.ExcludeArtifacts, err = strconv.ParseBool(value)
 		if err != nil {
 			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid end_time format. Use RFC3339 format.",
+				"error": "Invalid exclude_artifacts parameter",
 			})
 		}
-		endTime = &parsedTime
+	}
+	if filter.MinQuality, err = parseMinQuality(ctx); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
 	}
 
-	stats, err := c.heartReadingService.GetHeartRateStats(ctx.Context(), patientID, startTime, endTime)
+	stats, err := c.heartReadingService.GetHeartRateStats(ctx.Context(), patientID, startTime, endTime, filter)
 	if err != nil {
+		var validationErr *utils.ValidationError
+		if errors.As(err, &validationErr) {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, services.ErrPatientNotFound) {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
@@ -151,7 +228,11 @@ func (c *HeartReadingController) GetHeartRateStats(ctx *fiber.Ctx) error {
 	return ctx.Status(fiber.StatusOK).JSON(stats)
 }
 
-// GetHeartRateTrends retrieves heart rate trends for a patient
+// GetHeartRateTrends retrieves heart rate trends for a patient along with the
+// annotations of the same range. resolution is a calendar unit or a fixed
+// interval such as 15m or 6h, tz the IANA zone whose local days and weeks
+// delimit the periods (default: the patient's), fill_gaps=true adds empty periods and
+// percentiles=true adds p5/p50/p95 (computed from the raw readings).
 func (c *HeartReadingController) GetHeartRateTrends(ctx *fiber.Ctx) error {
 	patientID, err := uuid.Parse(ctx.Params("patientId"))
 	if err != nil {
@@ -160,32 +241,30 @@ func (c *HeartReadingController) GetHeartRateTrends(ctx *fiber.Ctx) error {
 		})
 	}
 
-	// Parse query parameters
-	var startTime, endTime *time.Time
-	if startTimeStr := ctx.Query("start_time"); startTimeStr != "" {
-		parsedTime, err := time.Parse(time.RFC3339, startTimeStr)
-		if err != nil {
-			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid start_time format. Use RFC3339 format.",
-			})
-		}
-		startTime = &parsedTime
+	startTime, endTime, err := parseTimeRange(ctx, patientLocation(ctx, c.localeService, patientID))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
 	}
 
-	if endTimeStr := ctx.Query("end_time"); endTimeStr != "" {
-		parsedTime, err := time.Parse(time.RFC3339, endTimeStr)
-		if err != nil {
+	trends, err := c.heartReadingService.GetHeartRateTrends(ctx.Context(), &models.HeartRateTrendQueryParams{
+		PatientID:   patientID,
+		StartTime:   startTime,
+		EndTime:     endTime,
+		Resolution:  ctx.Query("resolution", "day"),
+		Timezone:    ctx.Query("tz"),
+		FillGaps:    ctx.QueryBool("fill_gaps"),
+		Percentiles: ctx.QueryBool("percentiles"),
+	})
+	if err != nil {
+		var validationErr *utils.ValidationError
+		if errors.As(err, &validationErr) {
 			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid end_time format. Use RFC3339 format.",
+				"error":  "Invalid request",
+				"fields": validationErr.Fields,
 			})
 		}
-		endTime = &parsedTime
-	}
-
-	resolution := ctx.Query("resolution", "day")
-
-	trends, err := c.heartReadingService.GetHeartRateTrends(ctx.Context(), patientID, startTime, endTime, resolution)
-	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
@@ -194,7 +273,12 @@ func (c *HeartReadingController) GetHeartRateTrends(ctx *fiber.Ctx) error {
 	return ctx.Status(fiber.StatusOK).JSON(trends)
 }
 
-// GetHeartRateAnomalies retrieves heart rate anomalies for a patient
+// GetHeartRateAnomalies retrieves heart rate anomalies for a patient.
+// mode=range (default) uses the fixed expected range of the database function
+// with threshold; mode=baseline flags deviations from the patient's personal
+// baseline using Tukey fences with factor k; mode=rules evaluates the
+// patient's declarative rule set. Each mode has its own response shape. In
+// every mode min_quality ignores readings with a lower server quality score.
 func (c *HeartReadingController) GetHeartRateAnomalies(ctx *fiber.Ctx) error {
 	patientID, err := uuid.Parse(ctx.Params("patientId"))
 	if err != nil {
@@ -203,26 +287,63 @@ func (c *HeartReadingController) GetHeartRateAnomalies(ctx *fiber.Ctx) error {
 		})
 	}
 
-	// Parse query parameters
-	var startTime, endTime *time.Time
-	if startTimeStr := ctx.Query("start_time"); startTimeStr != "" {
-		parsedTime, err := time.Parse(time.RFC3339, startTimeStr)
+	startTime, endTime, err := parseTimeRange(ctx, patientLocation(ctx, c.localeService, patientID))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	minQuality, err := parseMinQuality(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	switch ctx.Query("mode", "range") {
+	case "rules":
+		violations, err := c.heartReadingService.EvaluateAnomalyRules(ctx.Context(), patientID, startTime, endTime, minQuality)
+		if err != nil {
+			var validationErr *utils.ValidationError
+			if errors.As(err, &validationErr) {
+				return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error":  "Invalid request",
+					"fields": validationErr.Fields,
+				})
+			}
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return ctx.Status(fiber.StatusOK).JSON(violations)
+	case "baseline":
+		k, err := strconv.ParseFloat(ctx.Query("k", "1.5"), 64)
 		if err != nil {
 			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid start_time format. Use RFC3339 format.",
+				"error": "Invalid k parameter",
 			})
 		}
-		startTime = &parsedTime
-	}
 
-	if endTimeStr := ctx.Query("end_time"); endTimeStr != "" {
-		parsedTime, err := time.Parse(time.RFC3339, endTimeStr)
+		anomalies, err := c.heartReadingService.GetBaselineAnomalies(ctx.Context(), patientID, startTime, endTime, k, minQuality)
 		if err != nil {
-			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid end_time format. Use RFC3339 format.",
+			var validationErr *utils.ValidationError
+			if errors.As(err, &validationErr) {
+				return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error":  "Invalid request",
+					"fields": validationErr.Fields,
+				})
+			}
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
 			})
 		}
-		endTime = &parsedTime
+		return ctx.Status(fiber.StatusOK).JSON(anomalies)
+	case "range":
+	default:
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "mode must be one of rules, baseline, range",
+		})
 	}
 
 	thresholdStr := ctx.Query("threshold", "0.2")
@@ -233,7 +354,7 @@ func (c *HeartReadingController) GetHeartRateAnomalies(ctx *fiber.Ctx) error {
 		})
 	}
 
-	anomalies, err := c.heartReadingService.GetHeartRateAnomalies(ctx.Context(), patientID, startTime, endTime, threshold)
+	anomalies, err := c.heartReadingService.GetHeartRateAnomalies(ctx.Context(), patientID, startTime, endTime, threshold, models.ReadingFilter{MinQuality: minQuality})
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
@@ -243,6 +364,61 @@ func (c *HeartReadingController) GetHeartRateAnomalies(ctx *fiber.Ctx) error {
 	return ctx.Status(fiber.StatusOK).JSON(anomalies)
 }
 
+// GetPatientBaseline retrieves the patient's personal heart rate baselines.
+// refresh=true recomputes them before returning.
+func (c *HeartReadingController) GetPatientBaseline(ctx *fiber.Ctx) error {
+	patientID, err := uuid.Parse(ctx.Params("patientId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid patient ID",
+		})
+	}
+
+	baselines, err := c.heartReadingService.GetBaselines(ctx.Context(), patientID, ctx.QueryBool("refresh"))
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(baselines)
+}
+
+// GetHeartRateVariability retrieves server-computed HRV metrics aggregated per time window
+func (c *HeartReadingController) GetHeartRateVariability(ctx *fiber.Ctx) error {
+	patientID, err := uuid.Parse(ctx.Params("patientId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid patient ID",
+		})
+	}
+
+	startTime, endTime, err := parseTimeRange(ctx, patientLocation(ctx, c.localeService, patientID))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	window := ctx.Query("window", "day")
+	switch window {
+	case "hour", "day", "week", "month":
+	default:
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid window parameter. Use hour, day, week or month.",
+		})
+	}
+
+	summaries, err := c.heartReadingService.GetHRVSummaries(ctx.Context(), patientID, startTime, endTime, window)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(summaries)
+}
+
 // GetHeartReadingByID retrieves a specific heart reading by ID
 func (c *HeartReadingController) GetHeartReadingByID(ctx *fiber.Ctx) error {
 	readingID, err := uuid.Parse(ctx.Params("id"))
@@ -268,6 +444,25 @@ func (c *HeartReadingController) GetHeartReadingByID(ctx *fiber.Ctx) error {
 	return ctx.Status(fiber.StatusOK).JSON(reading)
 }
 
+// GetHeartReadingFindings retrieves the arrhythmia screening findings of a heart reading
+func (c *HeartReadingController) GetHeartReadingFindings(ctx *fiber.Ctx) error {
+	readingID, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid reading ID",
+		})
+	}
+
+	findings, err := c.heartReadingService.GetFindingsByReading(ctx.Context(), readingID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(findings)
+}
+
 // UpdateHeartReading updates an existing heart reading
 func (c *HeartReadingController) UpdateHeartReading(ctx *fiber.Ctx) error {
 	readingID, err := uuid.Parse(ctx.Params("id"))
@@ -291,10 +486,8 @@ func (c *HeartReadingController) UpdateHeartReading(ctx *fiber.Ctx) error {
 		})
 	}
 
-	if err := c.heartReadingService.UpdateHeartReading(ctx.Context(), readingID, patientID, &request); err != nil {
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+	if err := c.heartReadingService.UpdateHeartReading(ctx.Context(), readingID, patientID, &request, currentUserID(ctx)); err != nil {
+		return readingChangeErrorResponse(ctx, err)
 	}
 
 	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -302,15 +495,70 @@ func (c *HeartReadingController) UpdateHeartReading(ctx *fiber.Ctx) error {
 	})
 }
 
-// ProcessUnprocessedReadings processes any unprocessed heart readings
-func (c *HeartReadingController) ProcessUnprocessedReadings(ctx *fiber.Ctx) error {
-	if err := c.heartReadingService.ProcessUnprocessedReadings(ctx.Context()); err != nil {
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": err.Error(),
+// DeleteHeartReading soft-deletes a heart reading. The body must give the reason.
+func (c *HeartReadingController) DeleteHeartReading(ctx *fiber.Ctx) error {
+	readingID, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid reading ID",
 		})
 	}
 
+	patientID, err := uuid.Parse(ctx.Params("patientId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid patient ID",
+		})
+	}
+
+	var request models.HeartReadingDeleteRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := c.heartReadingService.DeleteHeartReading(ctx.Context(), readingID, patientID, &request, currentUserID(ctx)); err != nil {
+		return readingChangeErrorResponse(ctx, err)
+	}
+
 	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message": "Unprocessed readings processed successfully",
+		"message": "Heart reading deleted successfully",
+	})
+}
+
+// GetHeartReadingHistory returns every recorded edit and deletion of a reading
+func (c *HeartReadingController) GetHeartReadingHistory(ctx *fiber.Ctx) error {
+	readingID, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid reading ID",
+		})
+	}
+
+	history, err := c.heartReadingService.GetHeartReadingHistory(ctx.Context(), readingID)
+	if err != nil {
+		return readingChangeErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(history)
+}
+
+// readingChangeErrorResponse maps errors of reading edits, deletions and history
+func readingChangeErrorResponse(ctx *fiber.Ctx, err error) error {
+	var validationErr *utils.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Invalid request",
+			"fields": validationErr.Fields,
+		})
+	case errors.Is(err, services.ErrHeartReadingNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": err.Error(),
 	})
 }
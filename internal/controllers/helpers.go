@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/services"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// currentUserID devuelve el ID del usuario autenticado (establecido por AuthMiddleware)
+func currentUserID(ctx *fiber.Ctx) *uuid.UUID {
+	user, ok := ctx.Locals("user").(*models.User)
+	if !ok || user == nil {
+		return nil
+	}
+	return &user.ID
+}
+
+// parseTimeRange lee el rango de la query: start_time y end_time (RFC3339),
+// date=YYYY-MM-DD (un día completo) o range (today, yesterday, this_week,
+// this_month, last_24h, last_7d, last_4w...). location da la zona en la que se
+// cuentan los días y solo se consulta si hace falta; todo es opcional.
+func parseTimeRange(ctx *fiber.Ctx, location func() (*time.Location, error)) (startTime, endTime *time.Time, err error) {
+	date, relative := ctx.Query("date"), ctx.Query("range")
+	if date == "" && relative == "" {
+		for name, target := range map[string]**time.Time{"start_time": &startTime, "end_time": &endTime} {
+			if value := ctx.Query(name); value != "" {
+				parsed, err := time.Parse(time.RFC3339, value)
+				if err != nil {
+					return nil, nil, fmt.Errorf("Invalid %s format. Use RFC3339 format.", name)
+				}
+				*target = &parsed
+			}
+		}
+		return startTime, endTime, nil
+	}
+
+	if (date != "" && relative != "") || ctx.Query("start_time") != "" || ctx.Query("end_time") != "" {
+		return nil, nil, errors.New("Use either start_time/end_time, date or range.")
+	}
+	loc, err := location()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var start, end time.Time
+	if date != "" {
+		if start, end, err = utils.ParseDateRange(date, loc); err != nil {
+			return nil, nil, errors.New("Invalid date format. Use YYYY-MM-DD.")
+		}
+	} else if start, end, err = utils.ParseRelativeRange(relative, time.Now(), loc); err != nil {
+		return nil, nil, errors.New("Invalid range. Use today, yesterday, this_week, this_month or last_<n>h, last_<n>d, last_<n>w.")
+	}
+	return &start, &end, nil
+}
+
+// queryLocation da la zona horaria del parámetro tz (UTC por defecto) para
+// los rangos que no son de un paciente
+func queryLocation(ctx *fiber.Ctx) func() (*time.Location, error) {
+	return func() (*time.Location, error) {
+		name := ctx.Query("tz", "UTC")
+		loc, err := time.LoadLocation(name)
+		if err != nil || name == "Local" {
+			return nil, errors.New("Invalid tz parameter. Use an IANA time zone such as America/Lima.")
+		}
+		return loc, nil
+	}
+}
+
+// patientLocation da la zona horaria del parámetro tz o, si no se indica, la
+// del paciente
+func patientLocation(ctx *fiber.Ctx, localeService *services.LocaleService, patientID uuid.UUID) func() (*time.Location, error) {
+	return func() (*time.Location, error) {
+		if ctx.Query("tz") != "" {
+			return queryLocation(ctx)()
+		}
+		return localeService.PatientLocation(ctx.Context(), patientID), nil
+	}
+}
+
+// parseMinQuality lee min_quality (0-1) de la query; nil si no se indica
+func parseMinQuality(ctx *fiber.Ctx) (*float64, error) {
+	value := ctx.Query("min_quality")
+	if value == "" {
+		return nil, nil
+	}
+	minQuality, err := strconv.ParseFloat(value, 64)
+	if err != nil || minQuality < 0 || minQuality > 1 {
+		return nil, errors.New("Invalid min_quality parameter. Use a number between 0 and 1.")
+	}
+	return &minQuality, nil
+}
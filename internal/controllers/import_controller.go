@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/services"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type ImportController struct {
+	importService *services.ImportService
+}
+
+func NewImportController(importService *services.ImportService) *ImportController {
+	return &ImportController{
+		importService: importService,
+	}
+}
+
+// importErrorResponse maps service errors to HTTP responses. The report, if
+// any, tells how far the import got.
+func importErrorResponse(ctx *fiber.Ctx, err error, report *models.ReadingImportReport) error {
+	var validationErr *utils.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Invalid import",
+			"fields": validationErr.Fields,
+			"report": report,
+		})
+	case errors.Is(err, services.ErrPatientNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	default:
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  err.Error(),
+			"report": report,
+		})
+	}
+}
+
+// ImportPatientReadings imports a file of historical readings sent as the
+// "file" field of a multipart form. The other form fields are those of
+// ReadingImportRequest; mapping is a JSON object. With dry_run=true the file
+// is only validated and the report tells what would be imported.
+func (c *ImportController) ImportPatientReadings(ctx *fiber.Ctx) error {
+	patientID, err := uuid.Parse(ctx.Params("patientId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid patient ID",
+		})
+	}
+
+	header, err := ctx.FormFile("file")
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "A file is required in the \"file\" field",
+		})
+	}
+
+	request := &models.ReadingImportRequest{
+		PatientID:   patientID,
+		EnteredBy:   currentUserID(ctx),
+		Format:      ctx.FormValue("format"),
+		TimeFormat:  ctx.FormValue("time_format"),
+		Timezone:    ctx.FormValue("timezone"),
+		Source:      ctx.FormValue("source"),
+		ReadingType: ctx.FormValue("reading_type"),
+	}
+	if value := ctx.FormValue("dry_run"); value != "" {
+		if request.DryRun, err = strconv.ParseBool(value); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid dry_run value",
+			})
+		}
+	}
+	if value := ctx.FormValue("mapping"); value != "" {
+		if err := json.Unmarshal([]byte(value), &request.Mapping); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid mapping. Use a JSON object of field to column name.",
+			})
+		}
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to read the uploaded file",
+		})
+	}
+	defer file.Close()
+
+	report, err := c.importService.ImportReadings(ctx.Context(), file, request)
+	if err != nil {
+		return importErrorResponse(ctx, err, report)
+	}
+
+	status := fiber.StatusCreated
+	if request.DryRun {
+		status = fiber.StatusOK
+	}
+	return ctx.Status(status).JSON(report)
+}
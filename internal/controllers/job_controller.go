@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type JobController struct {
+	jobService *services.JobService
+}
+
+func NewJobController(jobService *services.JobService) *JobController {
+	return &JobController{
+		jobService: jobService,
+	}
+}
+
+func (c *JobController) GetJobs(ctx *fiber.Ctx) error {
+	jobs, err := c.jobService.GetJobs(ctx.Context())
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(jobs)
+}
+
+func (c *JobController) GetJob(ctx *fiber.Ctx) error {
+	job, err := c.jobService.GetJob(ctx.Context(), ctx.Params("name"))
+	if err != nil {
+		return jobErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(job)
+}
+
+// TriggerJob starts a job outside its schedule
+func (c *JobController) TriggerJob(ctx *fiber.Ctx) error {
+	runID, err := c.jobService.TriggerJob(ctx.Context(), ctx.Params("name"))
+	if err != nil {
+		return jobErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "Job started",
+		"run_id":  runID,
+	})
+}
+
+// UpdateJob pauses or resumes a job's schedule
+func (c *JobController) UpdateJob(ctx *fiber.Ctx) error {
+	var request struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := ctx.BodyParser(&request); err != nil || request.Enabled == nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := c.jobService.SetJobEnabled(ctx.Context(), ctx.Params("name"), *request.Enabled); err != nil {
+		return jobErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Job updated successfully",
+	})
+}
+
+func (c *JobController) GetJobRuns(ctx *fiber.Ctx) error {
+	limit := 50
+	if limitStr := ctx.Query("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 || parsed > 500 {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid limit parameter",
+			})
+		}
+		limit = parsed
+	}
+
+	runs, err := c.jobService.GetJobRuns(ctx.Context(), ctx.Params("name"), limit)
+	if err != nil {
+		return jobErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(runs)
+}
+
+func jobErrorResponse(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrJobNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrJobRunning):
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
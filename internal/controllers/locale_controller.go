@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/services"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type LocaleController struct {
+	localeService *services.LocaleService
+}
+
+func NewLocaleController(localeService *services.LocaleService) *LocaleController {
+	return &LocaleController{
+		localeService: localeService,
+	}
+}
+
+// localeErrorResponse maps service errors to HTTP responses
+func localeErrorResponse(ctx *fiber.Ctx, err error) error {
+	var validationErr *utils.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Invalid locale settings",
+			"fields": validationErr.Fields,
+		})
+	case errors.Is(err, services.ErrPatientNotFound), errors.Is(err, services.ErrUserNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	default:
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+}
+
+// GetPatientLocale returns the time zone and locale of a patient
+func (c *LocaleController) GetPatientLocale(ctx *fiber.Ctx) error {
+	patientID, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid patient ID",
+		})
+	}
+
+	settings, err := c.localeService.GetPatientLocale(ctx.Context(), patientID)
+	if err != nil {
+		return localeErrorResponse(ctx, err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(settings)
+}
+
+// UpdatePatientLocale changes the time zone and/or locale of a patient. The
+// patient's days, weeks and night schedules are counted in the new zone.
+func (c *LocaleController) UpdatePatientLocale(ctx *fiber.Ctx) error {
+	patientID, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid patient ID",
+		})
+	}
+
+	var request models.LocaleSettingsUpdateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	settings, err := c.localeService.UpdatePatientLocale(ctx.Context(), patientID, &request)
+	if err != nil {
+		return localeErrorResponse(ctx, err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(settings)
+}
+
+// GetProfileLocale returns the time zone and locale of the authenticated user
+func (c *LocaleController) GetProfileLocale(ctx *fiber.Ctx) error {
+	userID := currentUserID(ctx)
+	if userID == nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User not found in context",
+		})
+	}
+
+	settings, err := c.localeService.GetUserLocale(ctx.Context(), *userID)
+	if err != nil {
+		return localeErrorResponse(ctx, err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(settings)
+}
+
+// UpdateProfileLocale changes the time zone and/or locale of the
+// authenticated user
+func (c *LocaleController) UpdateProfileLocale(ctx *fiber.Ctx) error {
+	userID := currentUserID(ctx)
+	if userID == nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User not found in context",
+		})
+	}
+
+	var request models.LocaleSettingsUpdateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	settings, err := c.localeService.UpdateUserLocale(ctx.Context(), *userID, &request)
+	if err != nil {
+		return localeErrorResponse(ctx, err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(settings)
+}
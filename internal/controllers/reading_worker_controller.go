@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type ReadingWorkerController struct {
+	readingWorkerService *services.ReadingWorkerService
+}
+
+func NewReadingWorkerController(readingWorkerService *services.ReadingWorkerService) *ReadingWorkerController {
+	return &ReadingWorkerController{
+		readingWorkerService: readingWorkerService,
+	}
+}
+
+// ProcessUnprocessedReadings runs the reading worker once on this replica
+func (c *ReadingWorkerController) ProcessUnprocessedReadings(ctx *fiber.Ctx) error {
+	processed, failed, err := c.readingWorkerService.RunOnce(ctx.Context())
+	if err != nil {
+		if errors.Is(err, services.ErrReadingWorkerBusy) {
+			return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":   "Unprocessed readings processed successfully",
+		"processed": processed,
+		"failed":    failed,
+	})
+}
+
+// GetStatus returns the reading worker's status and last run metrics
+func (c *ReadingWorkerController) GetStatus(ctx *fiber.Ctx) error {
+	status, err := c.readingWorkerService.GetStatus(ctx.Context())
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(status)
+}
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/services"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type ReportController struct {
+	reportService *services.ReportService
+}
+
+func NewReportController(reportService *services.ReportService) *ReportController {
+	return &ReportController{
+		reportService: reportService,
+	}
+}
+
+// GetDailyReports returns a patient's daily reports between from and to (YYYY-MM-DD)
+func (c *ReportController) GetDailyReports(ctx *fiber.Ctx) error {
+	patientID, err := uuid.Parse(ctx.Params("patientId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid patient ID",
+		})
+	}
+
+	var from, to *time.Time
+	if fromStr := ctx.Query("from"); fromStr != "" {
+		parsed, err := time.Parse(time.DateOnly, fromStr)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid from format. Use YYYY-MM-DD",
+			})
+		}
+		from = &parsed
+	}
+	if toStr := ctx.Query("to"); toStr != "" {
+		parsed, err := time.Parse(time.DateOnly, toStr)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid to format. Use YYYY-MM-DD",
+			})
+		}
+		to = &parsed
+	}
+
+	reports, err := c.reportService.GetDailyReports(ctx.Context(), patientID, from, to)
+	if err != nil {
+		var validationErr *utils.ValidationError
+		if errors.As(err, &validationErr) {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":  "Invalid date range",
+				"fields": validationErr.Fields,
+			})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(reports)
+}
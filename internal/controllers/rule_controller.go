@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/services"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type RuleController struct {
+	ruleService *services.RuleService
+}
+
+func NewRuleController(ruleService *services.RuleService) *RuleController {
+	return &RuleController{
+		ruleService: ruleService,
+	}
+}
+
+func (c *RuleController) GetDefaultRuleSet(ctx *fiber.Ctx) error {
+	record, err := c.ruleService.GetRuleSet(ctx.Context(), nil)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(record)
+}
+
+func (c *RuleController) SaveDefaultRuleSet(ctx *fiber.Ctx) error {
+	return c.saveRuleSet(ctx, nil)
+}
+
+// GetPatientRuleSet returns the rule set that applies to a patient and where
+// it comes from (patient, default or builtin)
+func (c *RuleController) GetPatientRuleSet(ctx *fiber.Ctx) error {
+	patientID, err := uuid.Parse(ctx.Params("patientId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid patient ID",
+		})
+	}
+
+	record, err := c.ruleService.GetRuleSet(ctx.Context(), &patientID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(record)
+}
+
+func (c *RuleController) SavePatientRuleSet(ctx *fiber.Ctx) error {
+	patientID, err := uuid.Parse(ctx.Params("patientId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid patient ID",
+		})
+	}
+
+	return c.saveRuleSet(ctx, &patientID)
+}
+
+func (c *RuleController) DeletePatientRuleSet(ctx *fiber.Ctx) error {
+	patientID, err := uuid.Parse(ctx.Params("patientId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid patient ID",
+		})
+	}
+
+	if err := c.ruleService.DeleteRuleSet(ctx.Context(), patientID); err != nil {
+		if errors.Is(err, services.ErrRuleSetNotFound) {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Patient rule set removed, the default rule set applies",
+	})
+}
+
+// saveRuleSet accepts the rule set as JSON or, with a YAML content type, as YAML
+func (c *RuleController) saveRuleSet(ctx *fiber.Ctx, patientID *uuid.UUID) error {
+	format := "json"
+	if strings.Contains(ctx.Get(fiber.HeaderContentType), "yaml") {
+		format = "yaml"
+	}
+
+	record, err := c.ruleService.SaveRuleSet(ctx.Context(), patientID, ctx.Body(), format, currentUserID(ctx))
+	if err != nil {
+		var validationErr *utils.ValidationError
+		if errors.As(err, &validationErr) {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":  "Invalid rule set",
+				"fields": validationErr.Fields,
+			})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(record)
+}
@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/services"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type ThresholdController struct {
+	thresholdService *services.ThresholdService
+}
+
+func NewThresholdController(thresholdService *services.ThresholdService) *ThresholdController {
+	return &ThresholdController{
+		thresholdService: thresholdService,
+	}
+}
+
+func (c *ThresholdController) GetProfiles(ctx *fiber.Ctx) error {
+	patientID, err := uuid.Parse(ctx.Params("patientId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid patient ID",
+		})
+	}
+
+	profiles, err := c.thresholdService.GetProfiles(ctx.Context(), patientID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(profiles)
+}
+
+func (c *ThresholdController) CreateProfile(ctx *fiber.Ctx) error {
+	patientID, err := uuid.Parse(ctx.Params("patientId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid patient ID",
+		})
+	}
+
+	var request models.ThresholdProfileRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	profile, err := c.thresholdService.CreateProfile(ctx.Context(), patientID, &request, currentUserID(ctx))
+	if err != nil {
+		return thresholdErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(profile)
+}
+
+func (c *ThresholdController) UpdateProfile(ctx *fiber.Ctx) error {
+	patientID, err := uuid.Parse(ctx.Params("patientId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid patient ID",
+		})
+	}
+
+	profileID, err := uuid.Parse(ctx.Params("profileId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid profile ID",
+		})
+	}
+
+	var request models.ThresholdProfileRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	profile, err := c.thresholdService.UpdateProfile(ctx.Context(), patientID, profileID, &request, currentUserID(ctx))
+	if err != nil {
+		return thresholdErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(profile)
+}
+
+func (c *ThresholdController) DeleteProfile(ctx *fiber.Ctx) error {
+	patientID, err := uuid.Parse(ctx.Params("patientId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid patient ID",
+		})
+	}
+
+	profileID, err := uuid.Parse(ctx.Params("profileId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid profile ID",
+		})
+	}
+
+	if err := c.thresholdService.DeleteProfile(ctx.Context(), patientID, profileID); err != nil {
+		return thresholdErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Threshold profile deleted successfully",
+	})
+}
+
+// ResolveThresholds returns the limits that apply to a reading of
+// reading_type taken at time (default: now)
+func (c *ThresholdController) ResolveThresholds(ctx *fiber.Ctx) error {
+	patientID, err := uuid.Parse(ctx.Params("patientId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid patient ID",
+		})
+	}
+
+	readingType := ctx.Query("reading_type", "resting")
+	if readingType != "resting" && readingType != "active" && readingType != "sleep" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "reading_type must be one of resting, active, sleep",
+		})
+	}
+
+	at := time.Now()
+	if atStr := ctx.Query("time"); atStr != "" {
+		at, err = time.Parse(time.RFC3339, atStr)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid time format. Use RFC3339 format.",
+			})
+		}
+	}
+
+	thresholds, err := c.thresholdService.ResolveThresholds(ctx.Context(), patientID, readingType, at)
+	if err != nil {
+		return thresholdErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(thresholds)
+}
+
+func thresholdErrorResponse(ctx *fiber.Ctx, err error) error {
+	var validationErr *utils.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Invalid threshold profile",
+			"fields": validationErr.Fields,
+		})
+	case errors.Is(err, services.ErrThresholdProfileNotFound), errors.Is(err, services.ErrPatientNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
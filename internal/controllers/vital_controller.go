@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/services"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type VitalController struct {
+	vitalService  *services.VitalService
+	localeService *services.LocaleService
+}
+
+func NewVitalController(vitalService *services.VitalService, localeService *services.LocaleService) *VitalController {
+	return &VitalController{
+		vitalService:  vitalService,
+		localeService: localeService,
+	}
+}
+
+// vitalErrorResponse maps service errors to HTTP responses
+func vitalErrorResponse(ctx *fiber.Ctx, err error) error {
+	var validationErr *utils.ValidationError
+	if errors.As(err, &validationErr) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Invalid vital sign request",
+			"fields": validationErr.Fields,
+		})
+	}
+	if errors.Is(err, utils.ErrInvalidCursor) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+// CreateObservation records a standalone vital sign observation
+func (c *VitalController) CreateObservation(ctx *fiber.Ctx) error {
+	var request models.VitalObservationCreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if request.EnteredBy == nil && request.EntryMethod == "manual" {
+		request.EnteredBy = currentUserID(ctx)
+	}
+
+	observation, err := c.vitalService.CreateObservation(ctx.Context(), &request)
+	if err != nil {
+		return vitalErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(observation)
+}
+
+// GetObservations returns a page of a patient's vital sign observations
+func (c *VitalController) GetObservations(ctx *fiber.Ctx) error {
+	patientID, err := uuid.Parse(ctx.Params("patientId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid patient ID",
+		})
+	}
+
+	params := &models.VitalQueryParams{
+		PatientID: patientID,
+		SortOrder: ctx.Query("sort_order", "desc"),
+		Limit:     100,
+	}
+	if params.StartTime, params.EndTime, err = parseTimeRange(ctx, patientLocation(ctx, c.localeService, patientID)); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if observationType := ctx.Query("type"); observationType != "" {
+		params.ObservationType = &observationType
+	}
+	if cursor := ctx.Query("cursor"); cursor != "" {
+		params.Cursor = &cursor
+	}
+	if limitStr := ctx.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid limit parameter",
+			})
+		}
+		params.Limit = limit
+	}
+
+	observations, err := c.vitalService.GetObservations(ctx.Context(), params)
+	if err != nil {
+		return vitalErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(observations)
+}
+
+// GetVitalStats returns statistics for one vital sign of a patient
+func (c *VitalController) GetVitalStats(ctx *fiber.Ctx) error {
+	patientID, err := uuid.Parse(ctx.Params("patientId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid patient ID",
+		})
+	}
+	startTime, endTime, err := parseTimeRange(ctx, patientLocation(ctx, c.localeService, patientID))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	stats, err := c.vitalService.GetVitalStats(ctx.Context(), patientID, ctx.Params("type"), startTime, endTime)
+	if err != nil {
+		return vitalErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(stats)
+}
+
+// GetVitalTrends returns one vital sign of a patient aggregated by period
+func (c *VitalController) GetVitalTrends(ctx *fiber.Ctx) error {
+	patientID, err := uuid.Parse(ctx.Params("patientId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid patient ID",
+		})
+	}
+	startTime, endTime, err := parseTimeRange(ctx, patientLocation(ctx, c.localeService, patientID))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	trends, err := c.vitalService.GetVitalTrends(ctx.Context(), patientID, ctx.Params("type"), startTime, endTime, ctx.Query("resolution", "hour"))
+	if err != nil {
+		return vitalErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(trends)
+}
+
+// GetTimeline returns heart readings and vital signs of a patient on a single
+// time axis. ?types=heart_rate,spo2 limits the series returned.
+func (c *VitalController) GetTimeline(ctx *fiber.Ctx) error {
+	patientID, err := uuid.Parse(ctx.Params("patientId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid patient ID",
+		})
+	}
+	startTime, endTime, err := parseTimeRange(ctx, patientLocation(ctx, c.localeService, patientID))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var types []string
+	if typesStr := ctx.Query("types"); typesStr != "" {
+		for _, t := range strings.Split(typesStr, ",") {
+			types = append(types, strings.TrimSpace(t))
+		}
+	}
+
+	points, err := c.vitalService.GetTimeline(ctx.Context(), patientID, startTime, endTime, types)
+	if err != nil {
+		return vitalErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(points)
+}
@@ -0,0 +1,12 @@
+-- Historial de telemetría de dispositivos (batería, firmware, señal, sincronización)
+CREATE TABLE IF NOT EXISTS device_telemetry (
+    id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    device_id        UUID NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+    battery_level    INTEGER CHECK (battery_level BETWEEN 0 AND 100),
+    firmware_version VARCHAR(50),
+    signal_strength  INTEGER, -- RSSI en dBm
+    synced_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_device_telemetry_device_time
+    ON device_telemetry (device_id, synced_at DESC);
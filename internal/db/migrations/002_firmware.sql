@@ -0,0 +1,37 @@
+-- Catálogo de firmware por tipo de dispositivo
+CREATE TABLE IF NOT EXISTS firmware_releases (
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    device_type   VARCHAR(50) NOT NULL,
+    version       VARCHAR(50) NOT NULL, -- versión semántica (MAJOR.MINOR.PATCH)
+    release_notes TEXT,
+    checksum      CHAR(64) NOT NULL,    -- SHA-256 del binario
+    download_url  TEXT NOT NULL,
+    created_by    UUID REFERENCES users(id),
+    released_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (device_type, version)
+);
+
+-- Despliegues escalonados: por porcentaje de la flota o por lista explícita de dispositivos
+CREATE TABLE IF NOT EXISTS firmware_rollouts (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    firmware_id UUID NOT NULL REFERENCES firmware_releases(id) ON DELETE CASCADE,
+    percentage  INTEGER CHECK (percentage BETWEEN 0 AND 100),
+    device_ids  UUID[],
+    status      VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'paused', 'completed', 'cancelled')),
+    created_by  UUID REFERENCES users(id),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (percentage IS NOT NULL OR device_ids IS NOT NULL)
+);
+
+-- Resultado de la actualización informado por cada dispositivo
+CREATE TABLE IF NOT EXISTS firmware_update_reports (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    rollout_id  UUID NOT NULL REFERENCES firmware_rollouts(id) ON DELETE CASCADE,
+    device_id   UUID NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+    status      VARCHAR(20) NOT NULL CHECK (status IN ('success', 'failure')),
+    message     TEXT,
+    reported_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_firmware_update_reports_rollout
+    ON firmware_update_reports (rollout_id, device_id);
@@ -0,0 +1,25 @@
+-- Historial de asignaciones dispositivo-paciente
+CREATE TABLE IF NOT EXISTS device_assignments (
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    device_id     UUID NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+    patient_id    UUID NOT NULL REFERENCES patients(id),
+    assigned_from TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    assigned_to   TIMESTAMPTZ, -- NULL mientras la asignación siga vigente
+    changed_by    UUID REFERENCES users(id),
+    reason        TEXT,
+    CHECK (assigned_to IS NULL OR assigned_to >= assigned_from)
+);
+
+-- Un dispositivo solo puede tener una asignación abierta
+CREATE UNIQUE INDEX IF NOT EXISTS idx_device_assignments_open
+    ON device_assignments (device_id) WHERE assigned_to IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_device_assignments_device_time
+    ON device_assignments (device_id, assigned_from);
+
+-- Asignaciones vigentes de los dispositivos existentes
+INSERT INTO device_assignments (device_id, patient_id, assigned_from)
+SELECT d.id, d.patient_id, d.registered_at
+FROM devices d
+WHERE d.patient_id IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM device_assignments a WHERE a.device_id = d.id);
@@ -0,0 +1,22 @@
+-- Métricas de variabilidad de la frecuencia cardíaca calculadas en el servidor
+-- a partir de los intervalos RR enviados por el dispositivo
+CREATE TABLE IF NOT EXISTS heart_reading_hrv (
+    reading_id     UUID PRIMARY KEY,
+    patient_id     UUID NOT NULL REFERENCES patients(id),
+    time           TIMESTAMPTZ NOT NULL,
+    rr_intervals   REAL[] NOT NULL, -- ms, tal como se recibieron
+    interval_count INTEGER NOT NULL,
+    rejected_count INTEGER NOT NULL,
+    mean_rr        DOUBLE PRECISION NOT NULL,
+    mean_hr        DOUBLE PRECISION NOT NULL,
+    sdnn           DOUBLE PRECISION NOT NULL,
+    rmssd          DOUBLE PRECISION NOT NULL,
+    pnn50          DOUBLE PRECISION NOT NULL,
+    lf_power       DOUBLE PRECISION,
+    hf_power       DOUBLE PRECISION,
+    lf_hf_ratio    DOUBLE PRECISION,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_heart_reading_hrv_patient_time
+    ON heart_reading_hrv (patient_id, time DESC);
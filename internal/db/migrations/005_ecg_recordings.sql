@@ -0,0 +1,19 @@
+-- Tiras de ECG de una derivación vinculadas a una lectura cardíaca
+CREATE TABLE IF NOT EXISTS ecg_recordings (
+    id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    reading_id     UUID NOT NULL,
+    patient_id     UUID NOT NULL REFERENCES patients(id),
+    device_id      UUID REFERENCES devices(id),
+    lead           VARCHAR(10) NOT NULL DEFAULT 'I',
+    sample_rate_hz REAL NOT NULL CHECK (sample_rate_hz > 0),
+    gain           REAL NOT NULL CHECK (gain > 0), -- cuentas ADC por mV
+    sample_count   INTEGER NOT NULL,
+    duration_ms    BIGINT NOT NULL,
+    encoding       VARCHAR(20) NOT NULL DEFAULT 'ecg1-delta-deflate',
+    data           BYTEA NOT NULL,
+    recorded_at    TIMESTAMPTZ NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ecg_recordings_reading ON ecg_recordings (reading_id);
+CREATE INDEX IF NOT EXISTS idx_ecg_recordings_patient_time ON ecg_recordings (patient_id, recorded_at DESC);
@@ -0,0 +1,20 @@
+-- Hallazgos del cribado de arritmias calculado en el servidor
+CREATE TABLE IF NOT EXISTS reading_findings (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    reading_id      UUID NOT NULL,
+    patient_id      UUID NOT NULL REFERENCES patients(id),
+    source          VARCHAR(10) NOT NULL CHECK (source IN ('rr', 'ecg')),
+    finding_type    VARCHAR(40) NOT NULL,
+    start_offset_ms BIGINT,           -- episodios: inicio desde el comienzo de la serie
+    duration_ms     BIGINT,
+    min_bpm         DOUBLE PRECISION,
+    max_bpm         DOUBLE PRECISION,
+    mean_bpm        DOUBLE PRECISION,
+    details         JSONB NOT NULL DEFAULT '{}',
+    reading_time    TIMESTAMPTZ NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_reading_findings_reading ON reading_findings (reading_id);
+CREATE INDEX IF NOT EXISTS idx_reading_findings_patient_time
+    ON reading_findings (patient_id, reading_time DESC);
@@ -0,0 +1,12 @@
+-- Reglas de detección de anomalías en formato declarativo (JSON).
+-- La fila con patient_id NULL es el conjunto por defecto.
+CREATE TABLE IF NOT EXISTS rule_sets (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    patient_id UUID REFERENCES patients(id) ON DELETE CASCADE,
+    definition JSONB NOT NULL,
+    updated_by UUID REFERENCES users(id),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_rule_sets_patient ON rule_sets (patient_id) WHERE patient_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_rule_sets_default ON rule_sets ((patient_id IS NULL)) WHERE patient_id IS NULL;
@@ -0,0 +1,24 @@
+-- Perfiles de umbrales de frecuencia cardíaca por tipo de lectura y horario.
+-- Sustituyen a patients.min_heart_rate/max_heart_rate cuando alguno coincide.
+CREATE TABLE IF NOT EXISTS patient_threshold_profiles (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    patient_id   UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    name         VARCHAR(100) NOT NULL,
+    reading_type VARCHAR(20) CHECK (reading_type IN ('resting', 'active', 'sleep')), -- NULL: cualquiera
+    days_of_week SMALLINT[],          -- 0 = domingo; NULL: todos los días
+    start_time   TIME,                -- NULL junto con end_time: todo el día
+    end_time     TIME,                -- si end_time <= start_time la franja cruza la medianoche
+    timezone     VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    min_bpm      INTEGER NOT NULL,
+    max_bpm      INTEGER NOT NULL,
+    priority     INTEGER NOT NULL DEFAULT 0,
+    active       BOOLEAN NOT NULL DEFAULT TRUE,
+    set_by       UUID REFERENCES users(id),
+    set_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (min_bpm < max_bpm),
+    CHECK ((start_time IS NULL) = (end_time IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_threshold_profiles_patient
+    ON patient_threshold_profiles (patient_id) WHERE active;
@@ -0,0 +1,14 @@
+-- Línea base personal de frecuencia cardíaca por paciente y tipo de lectura,
+-- recalculada periódicamente sobre los últimos N días
+CREATE TABLE IF NOT EXISTS patient_baselines (
+    patient_id   UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    reading_type VARCHAR(20) NOT NULL,
+    window_days  INTEGER NOT NULL,
+    sample_count INTEGER NOT NULL,
+    median_bpm   DOUBLE PRECISION NOT NULL,
+    q1_bpm       DOUBLE PRECISION NOT NULL,
+    q3_bpm       DOUBLE PRECISION NOT NULL,
+    hourly       JSONB NOT NULL DEFAULT '[]', -- perfil circadiano por hora local del paciente (patients.timezone)
+    computed_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (patient_id, reading_type)
+);
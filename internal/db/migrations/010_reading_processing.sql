@@ -0,0 +1,12 @@
+-- Fallos al procesar lecturas: el worker reintenta cada lectura con espera
+-- exponencial hasta agotar los intentos
+CREATE TABLE IF NOT EXISTS reading_processing_failures (
+    reading_id      UUID PRIMARY KEY,
+    attempts        INTEGER NOT NULL DEFAULT 1,
+    last_error      TEXT NOT NULL,
+    next_attempt_at TIMESTAMPTZ NOT NULL,
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_heart_readings_unprocessed
+    ON heart_readings (time) WHERE processed = false;
@@ -0,0 +1,32 @@
+-- Trabajos periódicos del planificador. El lease (locked_by/locked_until)
+-- garantiza que cada ejecución corre en una sola réplica.
+CREATE TABLE IF NOT EXISTS scheduled_jobs (
+    name         VARCHAR(100) PRIMARY KEY,
+    description  TEXT,
+    schedule     VARCHAR(100) NOT NULL,
+    enabled      BOOLEAN NOT NULL DEFAULT true,
+    next_run_at  TIMESTAMPTZ NOT NULL,
+    locked_by    VARCHAR(255),
+    locked_until TIMESTAMPTZ,
+    last_run_at  TIMESTAMPTZ,
+    last_status  VARCHAR(20),
+    last_error   TEXT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Historial de ejecuciones
+CREATE TABLE IF NOT EXISTS job_runs (
+    id          BIGSERIAL PRIMARY KEY,
+    job_name    VARCHAR(100) NOT NULL REFERENCES scheduled_jobs(name) ON DELETE CASCADE,
+    trigger     VARCHAR(20) NOT NULL CHECK (trigger IN ('schedule', 'manual')),
+    instance    VARCHAR(255) NOT NULL,
+    status      VARCHAR(20) NOT NULL CHECK (status IN ('running', 'succeeded', 'failed', 'cancelled')),
+    summary     TEXT,
+    error       TEXT,
+    started_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_runs_job_started
+    ON job_runs (job_name, started_at DESC);
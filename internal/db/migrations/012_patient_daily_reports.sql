@@ -0,0 +1,17 @@
+-- Resumen diario por paciente generado por el planificador
+CREATE TABLE IF NOT EXISTS patient_daily_reports (
+    patient_id      UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    report_date     DATE NOT NULL,
+    reading_count   INTEGER NOT NULL,
+    min_bpm         INTEGER,
+    max_bpm         INTEGER,
+    avg_bpm         DOUBLE PRECISION,
+    resting_avg_bpm DOUBLE PRECISION,
+    low_count       INTEGER NOT NULL DEFAULT 0, -- lecturas bajo el límite aplicable
+    high_count      INTEGER NOT NULL DEFAULT 0,
+    irregular_count INTEGER NOT NULL DEFAULT 0,
+    alert_count     INTEGER NOT NULL DEFAULT 0,
+    avg_oxygen      DOUBLE PRECISION,
+    generated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (patient_id, report_date)
+);
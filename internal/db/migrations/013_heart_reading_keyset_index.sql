@@ -0,0 +1,3 @@
+-- Paginación por conjunto de claves sobre (time, id) del listado de lecturas
+CREATE INDEX IF NOT EXISTS idx_heart_readings_patient_time_id
+    ON heart_readings (patient_id, time DESC, id DESC);
@@ -0,0 +1,21 @@
+-- Observaciones de signos vitales independientes de las lecturas cardíacas
+-- (SpO2, presión arterial, temperatura, peso, frecuencia respiratoria)
+CREATE TABLE IF NOT EXISTS vital_observations (
+    id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    patient_id       UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    device_id        UUID REFERENCES devices(id) ON DELETE SET NULL,
+    observation_type VARCHAR(30) NOT NULL
+        CHECK (observation_type IN ('spo2', 'blood_pressure', 'temperature', 'weight', 'respiratory_rate')),
+    value            DOUBLE PRECISION NOT NULL,  -- sistólica en la presión arterial
+    secondary_value  DOUBLE PRECISION,           -- diastólica en la presión arterial
+    unit             VARCHAR(20) NOT NULL,
+    entry_method     VARCHAR(20) NOT NULL CHECK (entry_method IN ('device', 'manual', 'imported')),
+    source           VARCHAR(50) NOT NULL,
+    entered_by       UUID REFERENCES users(id) ON DELETE SET NULL,
+    notes            TEXT,
+    time             TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_vital_observations_patient_type_time
+    ON vital_observations (patient_id, observation_type, time DESC, id DESC);
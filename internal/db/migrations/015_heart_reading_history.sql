@@ -0,0 +1,19 @@
+-- Borrado lógico de lecturas: la fila se conserva con quién, cuándo y por qué
+ALTER TABLE heart_readings ADD COLUMN IF NOT EXISTS deleted_at      TIMESTAMPTZ;
+ALTER TABLE heart_readings ADD COLUMN IF NOT EXISTS deleted_by      UUID REFERENCES users(id);
+ALTER TABLE heart_readings ADD COLUMN IF NOT EXISTS deletion_reason TEXT;
+
+-- Historial de cambios de cada lectura: una versión por edición o borrado con
+-- los valores anteriores y nuevos de los campos modificados
+CREATE TABLE IF NOT EXISTS heart_reading_versions (
+    id          BIGSERIAL PRIMARY KEY,
+    reading_id  UUID NOT NULL,
+    version     INTEGER NOT NULL,
+    change_type TEXT NOT NULL CHECK (change_type IN ('update', 'delete')),
+    changed_by  UUID REFERENCES users(id),
+    changed_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    reason      TEXT,
+    old_values  JSONB NOT NULL DEFAULT '{}',
+    new_values  JSONB NOT NULL DEFAULT '{}',
+    UNIQUE (reading_id, version)
+);
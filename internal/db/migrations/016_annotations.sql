@@ -0,0 +1,23 @@
+-- Anotaciones clínicas sobre una lectura concreta o sobre un intervalo de
+-- tiempo del paciente. Las de una lectura guardan la hora de la lectura como
+-- inicio y fin para poder buscarlas por rango igual que las demás.
+CREATE TABLE IF NOT EXISTS annotations (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    reading_id UUID,
+    start_time TIMESTAMPTZ NOT NULL,
+    end_time   TIMESTAMPTZ NOT NULL,
+    category   VARCHAR(20) NOT NULL
+        CHECK (category IN ('note', 'medication', 'artifact', 'diagnosis', 'event')),
+    text       TEXT NOT NULL,
+    author_id  UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (end_time >= start_time)
+);
+
+CREATE INDEX IF NOT EXISTS idx_annotations_patient_time
+    ON annotations (patient_id, start_time, end_time);
+
+CREATE INDEX IF NOT EXISTS idx_annotations_reading
+    ON annotations (reading_id) WHERE reading_id IS NOT NULL;
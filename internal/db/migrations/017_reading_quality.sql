@@ -0,0 +1,8 @@
+-- Calidad de cada lectura evaluada por el servidor al recibirla. Las lecturas
+-- anteriores quedan con quality_score nulo y cuentan como de calidad plena.
+ALTER TABLE heart_readings ADD COLUMN IF NOT EXISTS quality_score DOUBLE PRECISION;
+ALTER TABLE heart_readings ADD COLUMN IF NOT EXISTS quality_flags TEXT[] NOT NULL DEFAULT '{}';
+
+-- Lecturas anteriores del mismo dispositivo para comparar la nueva
+CREATE INDEX IF NOT EXISTS idx_heart_readings_device_time
+    ON heart_readings (device_id, time DESC) WHERE device_id IS NOT NULL;
@@ -0,0 +1,33 @@
+-- Agregados de frecuencia cardíaca por minuto, hora y día (UTC) para las
+-- tendencias de rangos largos. Se mantienen al guardar, editar o borrar cada
+-- lectura; cmd/rollup-backfill los reconstruye a partir de heart_readings.
+-- La media se obtiene como bpm_sum / reading_count para poder combinar cubos.
+CREATE TABLE IF NOT EXISTS heart_rate_rollup_minute (
+    patient_id    UUID NOT NULL,
+    bucket_start  TIMESTAMPTZ NOT NULL,
+    reading_count INTEGER NOT NULL,
+    bpm_sum       BIGINT NOT NULL,
+    bpm_min       INTEGER NOT NULL,
+    bpm_max       INTEGER NOT NULL,
+    PRIMARY KEY (patient_id, bucket_start)
+);
+
+CREATE TABLE IF NOT EXISTS heart_rate_rollup_hour (
+    patient_id    UUID NOT NULL,
+    bucket_start  TIMESTAMPTZ NOT NULL,
+    reading_count INTEGER NOT NULL,
+    bpm_sum       BIGINT NOT NULL,
+    bpm_min       INTEGER NOT NULL,
+    bpm_max       INTEGER NOT NULL,
+    PRIMARY KEY (patient_id, bucket_start)
+);
+
+CREATE TABLE IF NOT EXISTS heart_rate_rollup_day (
+    patient_id    UUID NOT NULL,
+    bucket_start  TIMESTAMPTZ NOT NULL,
+    reading_count INTEGER NOT NULL,
+    bpm_sum       BIGINT NOT NULL,
+    bpm_min       INTEGER NOT NULL,
+    bpm_max       INTEGER NOT NULL,
+    PRIMARY KEY (patient_id, bucket_start)
+);
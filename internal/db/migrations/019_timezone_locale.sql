@@ -0,0 +1,12 @@
+-- Zona horaria (IANA) y configuración regional de pacientes y usuarios. Los
+-- días, las semanas y los horarios nocturnos de un paciente se cuentan en su
+-- zona horaria.
+ALTER TABLE patients ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+ALTER TABLE patients ADD COLUMN IF NOT EXISTS locale VARCHAR(35) NOT NULL DEFAULT 'es';
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale VARCHAR(35) NOT NULL DEFAULT 'es';
+
+-- Un perfil de umbrales sin zona horaria sigue la del paciente
+ALTER TABLE patient_threshold_profiles ALTER COLUMN timezone DROP NOT NULL;
+ALTER TABLE patient_threshold_profiles ALTER COLUMN timezone DROP DEFAULT;
@@ -0,0 +1,27 @@
+-- Exportaciones asíncronas de las lecturas de un paciente. El archivo
+-- generado se guarda en EXPORT_DIR como <id>.<formato> hasta expires_at.
+CREATE TABLE IF NOT EXISTS export_jobs (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    patient_id   UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    requested_by UUID REFERENCES users(id),
+    format       VARCHAR(10) NOT NULL CHECK (format IN ('csv', 'json', 'ndjson')),
+    columns      TEXT[] NOT NULL,
+    start_time   TIMESTAMPTZ,
+    end_time     TIMESTAMPTZ,
+    timezone     VARCHAR(64) NOT NULL,
+    status       VARCHAR(20) NOT NULL DEFAULT 'pending'
+                 CHECK (status IN ('pending', 'running', 'completed', 'failed', 'expired')),
+    row_count    BIGINT,
+    file_size    BIGINT,
+    error        TEXT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    started_at   TIMESTAMPTZ,
+    finished_at  TIMESTAMPTZ,
+    expires_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_export_jobs_pending
+    ON export_jobs (created_at) WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_export_jobs_patient
+    ON export_jobs (patient_id, created_at DESC);
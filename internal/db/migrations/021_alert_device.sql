@@ -0,0 +1,7 @@
+-- Dispositivo que originó una alerta, para no agrupar las alertas de
+-- dispositivo de un mismo paciente
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS device_id UUID REFERENCES devices(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_alerts_device_open
+    ON alerts (device_id, alert_type, created_at DESC)
+    WHERE device_id IS NOT NULL AND NOT acknowledged;
@@ -0,0 +1,10 @@
+-- Claves de los mensajes MQTT ya procesados. La clave se inserta en la misma
+-- transacción que la lectura o el estado que entrega el mensaje, así una
+-- reentrega QoS 1 choca con la restricción única aunque el puente se reinicie.
+CREATE TABLE IF NOT EXISTS ingested_messages (
+    message_key TEXT PRIMARY KEY,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ingested_messages_received
+    ON ingested_messages (received_at);
@@ -0,0 +1,46 @@
+-- Lecturas que cuentan para estadísticas, tendencias y anomalías: sin las
+-- borradas y sin las que excluye el filtro de la consulta. El filtro se pasa
+-- con set_config local a la transacción:
+--   heart.exclude_artifacts = 'on' omite las lecturas anotadas como artefacto
+--   heart.min_quality       = puntuación de calidad mínima ('' sin mínimo)
+CREATE OR REPLACE VIEW active_heart_readings AS
+SELECT *
+FROM heart_readings
+WHERE deleted_at IS NULL
+  AND (COALESCE(current_setting('heart.exclude_artifacts', true), '') <> 'on'
+       OR NOT EXISTS (
+           SELECT 1 FROM annotations a
+           WHERE a.patient_id = heart_readings.patient_id
+             AND a.category = 'artifact'
+             AND (a.reading_id = heart_readings.id
+                  OR (a.reading_id IS NULL AND heart_readings.time BETWEEN a.start_time AND a.end_time))
+       ))
+  AND COALESCE(heart_readings.quality_score, 1)
+      >= COALESCE(NULLIF(current_setting('heart.min_quality', true), '')::float8, 0);
+
+-- Las funciones de estadísticas, tendencia y anomalías del esquema base leen
+-- la vista en lugar de la tabla. Solo cambia la tabla de origen: sus reglas
+-- (rangos, umbrales, columnas devueltas) se conservan tal cual.
+DO $$
+DECLARE
+    fn      regprocedure;
+    def     text;
+    patched text;
+BEGIN
+    FOR fn IN
+        SELECT p.oid::regprocedure
+        FROM pg_proc p
+        WHERE p.proname IN ('calculate_heart_rate_stats', 'get_heart_rate_trend', 'detect_heart_rate_anomalies')
+    LOOP
+        def := pg_get_functiondef(fn);
+        patched := regexp_replace(def, '\mheart_readings\M', 'active_heart_readings', 'g');
+        IF patched = def THEN
+            -- Ya migrada, o la función no lee heart_readings
+            IF def !~ '\mactive_heart_readings\M' THEN
+                RAISE EXCEPTION 'function % does not read heart_readings', fn;
+            END IF;
+            CONTINUE;
+        END IF;
+        EXECUTE patched;
+    END LOOP;
+END $$;
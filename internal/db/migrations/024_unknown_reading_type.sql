@@ -0,0 +1,35 @@
+-- Tipo de lectura 'unknown' para las lecturas importadas (p. ej. observaciones
+-- FHIR) que no indican si el paciente estaba en reposo, activo o dormido: así
+-- no cuentan como lecturas en reposo. La restricción del esquema base sobre
+-- reading_type se sustituye sea cual sea su nombre; si la columna es un tipo
+-- enumerado se le añade el valor.
+DO $$
+DECLARE
+    enum_type regtype;
+    con       name;
+BEGIN
+    SELECT a.atttypid::regtype INTO enum_type
+    FROM pg_attribute a
+    JOIN pg_type t ON t.oid = a.atttypid
+    WHERE a.attrelid = 'heart_readings'::regclass
+      AND a.attname = 'reading_type'
+      AND t.typtype = 'e';
+
+    IF enum_type IS NOT NULL THEN
+        EXECUTE format('ALTER TYPE %s ADD VALUE IF NOT EXISTS %L', enum_type, 'unknown');
+        RETURN;
+    END IF;
+
+    FOR con IN
+        SELECT c.conname
+        FROM pg_constraint c
+        WHERE c.conrelid = 'heart_readings'::regclass
+          AND c.contype = 'c'
+          AND pg_get_constraintdef(c.oid) ~ '\mreading_type\M'
+    LOOP
+        EXECUTE format('ALTER TABLE heart_readings DROP CONSTRAINT %I', con);
+    END LOOP;
+
+    ALTER TABLE heart_readings ADD CONSTRAINT heart_readings_reading_type_check
+        CHECK (reading_type IN ('resting', 'active', 'sleep', 'unknown'));
+END $$;
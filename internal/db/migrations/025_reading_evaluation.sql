@@ -0,0 +1,15 @@
+-- Evaluación de umbrales y reglas de cada lectura, separada de processed, que
+-- vuelve a ser del procedimiento process_unprocessed_readings. Las lecturas
+-- existentes cuentan como evaluadas para no alertar sobre el histórico.
+ALTER TABLE heart_readings ADD COLUMN IF NOT EXISTS alerts_evaluated BOOLEAN NOT NULL DEFAULT true;
+ALTER TABLE heart_readings ALTER COLUMN alerts_evaluated SET DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_heart_readings_unevaluated
+    ON heart_readings (time) WHERE NOT alerts_evaluated;
+
+-- Lectura que originó cada alerta: volver a evaluar una lectura (un lote del
+-- worker que se revierte) no repite sus alertas
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS reading_id UUID;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_alerts_reading_type
+    ON alerts (reading_id, alert_type) WHERE reading_id IS NOT NULL;
@@ -0,0 +1,181 @@
+// Package ecg implements the compact binary format used to store single-lead
+// ECG strips and the conversions used when serving them.
+//
+// Layout (little endian):
+//
+//	magic       [4]byte  "ECG1"
+//	version     uint8    1
+//	sample rate float32  Hz
+//	gain        float32  ADC counts per millivolt
+//	samples     uint32   number of samples
+//	payload     DEFLATE-compressed zigzag varints of the sample deltas
+package ecg
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	formatVersion = 1
+	headerSize    = 4 + 1 + 4 + 4 + 4
+
+	// MaxSamples limits a single strip (about 30 minutes at 512 Hz)
+	MaxSamples = 1_000_000
+)
+
+var magic = [4]byte{'E', 'C', 'G', '1'}
+
+var ErrInvalidFormat = errors.New("invalid ECG data")
+
+// Recording is a decoded single-lead ECG strip
+type Recording struct {
+	SampleRate float64 // Hz
+	Gain       float64 // ADC counts per mV
+	Samples    []int16
+}
+
+// DurationMs returns the length of the strip in milliseconds
+func (r *Recording) DurationMs() int64 {
+	if r.SampleRate <= 0 {
+		return 0
+	}
+	return int64(math.Round(float64(len(r.Samples)) * 1000 / r.SampleRate))
+}
+
+// Millivolts converts a raw sample to millivolts
+func (r *Recording) Millivolts(sample int16) float64 {
+	return float64(sample) / r.Gain
+}
+
+// Validate checks the recording metadata. Sample rate and gain must be finite
+// and fit the float32 fields of the encoded header.
+func (r *Recording) Validate() error {
+	switch {
+	case math.IsNaN(r.SampleRate) || r.SampleRate < 50 || r.SampleRate > 2000:
+		return fmt.Errorf("sample rate must be between 50 and 2000 Hz")
+	case math.IsNaN(r.Gain) || r.Gain <= 0 || r.Gain > math.MaxFloat32:
+		return fmt.Errorf("gain must be a finite number greater than zero")
+	case len(r.Samples) == 0:
+		return fmt.Errorf("recording has no samples")
+	case len(r.Samples) > MaxSamples:
+		return fmt.Errorf("recording exceeds %d samples", MaxSamples)
+	}
+	return nil
+}
+
+// Encode serializes a recording: consecutive samples are delta encoded,
+// zigzag mapped to unsigned varints and compressed with DEFLATE
+func Encode(r *Recording) ([]byte, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.Write(magic[:])
+	buf.WriteByte(formatVersion)
+	binary.Write(&buf, binary.LittleEndian, float32(r.SampleRate))
+	binary.Write(&buf, binary.LittleEndian, float32(r.Gain))
+	binary.Write(&buf, binary.LittleEndian, uint32(len(r.Samples)))
+
+	fw, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+
+	varint := make([]byte, binary.MaxVarintLen32)
+	prev := int32(0)
+	for _, s := range r.Samples {
+		delta := int32(s) - prev
+		prev = int32(s)
+		n := binary.PutUvarint(varint, uint64(zigzag(delta)))
+		if _, err := fw.Write(varint[:n]); err != nil {
+			return nil, err
+		}
+	}
+	if err := fw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decode parses data produced by Encode
+func Decode(data []byte) (*Recording, error) {
+	if len(data) < headerSize || !bytes.Equal(data[:4], magic[:]) {
+		return nil, ErrInvalidFormat
+	}
+	if data[4] != formatVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidFormat, data[4])
+	}
+
+	sampleRate := math.Float32frombits(binary.LittleEndian.Uint32(data[5:9]))
+	gain := math.Float32frombits(binary.LittleEndian.Uint32(data[9:13]))
+	count := binary.LittleEndian.Uint32(data[13:17])
+	if count > MaxSamples {
+		return nil, ErrInvalidFormat
+	}
+	// Una cabecera corrupta no debe producir milivoltios NaN o infinitos
+	if !isFinitePositive(sampleRate) || !isFinitePositive(gain) {
+		return nil, fmt.Errorf("%w: invalid sample rate or gain", ErrInvalidFormat)
+	}
+
+	rec := &Recording{
+		SampleRate: float64(sampleRate),
+		Gain:       float64(gain),
+		Samples:    make([]int16, count),
+	}
+
+	reader := bufio.NewReader(flate.NewReader(bytes.NewReader(data[headerSize:])))
+	prev := int32(0)
+	for i := range rec.Samples {
+		u, err := binary.ReadUvarint(reader)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFormat, err)
+		}
+		prev += unzigzag(uint32(u))
+		if prev < math.MinInt16 || prev > math.MaxInt16 {
+			return nil, ErrInvalidFormat
+		}
+		rec.Samples[i] = int16(prev)
+	}
+
+	return rec, nil
+}
+
+// ParseInt16LE interprets a raw body of little endian int16 samples
+func ParseInt16LE(data []byte) ([]int16, error) {
+	if len(data)%2 != 0 {
+		return nil, fmt.Errorf("%w: odd number of bytes for int16 samples", ErrInvalidFormat)
+	}
+	if len(data)/2 > MaxSamples {
+		return nil, fmt.Errorf("recording exceeds %d samples", MaxSamples)
+	}
+
+	samples := make([]int16, len(data)/2)
+	for i := range samples {
+		samples[i] = int16(binary.LittleEndian.Uint16(data[2*i:]))
+	}
+	return samples, nil
+}
+
+func isFinitePositive(f float32) bool {
+	return f > 0 && !math.IsInf(float64(f), 1)
+}
+
+func zigzag(v int32) uint32 {
+	return uint32((v << 1) ^ (v >> 31))
+}
+
+func unzigzag(u uint32) int32 {
+	return int32(u>>1) ^ -int32(u&1)
+}
@@ -0,0 +1,75 @@
+package ecg
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestRecordingValidate(t *testing.T) {
+	samples := []int16{0, 10, -10}
+
+	tests := []struct {
+		name       string
+		sampleRate float64
+		gain       float64
+		samples    []int16
+		wantErr    bool
+	}{
+		{"valid", 250, 200, samples, false},
+		{"sample rate too low", 49, 200, samples, true},
+		{"sample rate too high", 2001, 200, samples, true},
+		{"sample rate NaN", math.NaN(), 200, samples, true},
+		{"sample rate +Inf", math.Inf(1), 200, samples, true},
+		{"sample rate -Inf", math.Inf(-1), 200, samples, true},
+		{"gain zero", 250, 0, samples, true},
+		{"gain negative", 250, -1, samples, true},
+		{"gain NaN", 250, math.NaN(), samples, true},
+		{"gain +Inf", 250, math.Inf(1), samples, true},
+		{"gain -Inf", 250, math.Inf(-1), samples, true},
+		{"gain overflows float32", 250, math.MaxFloat64, samples, true},
+		{"no samples", 250, 200, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Recording{SampleRate: tt.sampleRate, Gain: tt.gain, Samples: tt.samples}
+			if err := r.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	want := &Recording{SampleRate: 512, Gain: 200, Samples: []int16{0, 120, -340, math.MaxInt16, math.MinInt16, 5}}
+
+	data, err := Encode(want)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	got, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	if got.SampleRate != want.SampleRate || got.Gain != want.Gain || !slices.Equal(got.Samples, want.Samples) {
+		t.Errorf("Decode() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeRejectsNonFiniteHeader(t *testing.T) {
+	data, err := Encode(&Recording{SampleRate: 250, Gain: 200, Samples: []int16{1, 2, 3}})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	// Ganancia NaN en la cabecera (float32 little endian)
+	corrupt := slices.Clone(data)
+	copy(corrupt[9:13], []byte{0x00, 0x00, 0xc0, 0x7f})
+
+	if _, err := Decode(corrupt); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("Decode() error = %v, want ErrInvalidFormat", err)
+	}
+}
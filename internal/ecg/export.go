@@ -0,0 +1,79 @@
+package ecg
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+)
+
+// Point is a single plotted sample
+type Point struct {
+	T  float64 `json:"t"`  // seconds from the start of the strip
+	MV float64 `json:"mv"` // millivolts
+}
+
+// WriteCSV writes the recording as "time_s,mv" rows
+func WriteCSV(w io.Writer, r *Recording) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("time_s,mv\n")
+
+	line := make([]byte, 0, 32)
+	for i, s := range r.Samples {
+		line = line[:0]
+		line = strconv.AppendFloat(line, float64(i)/r.SampleRate, 'f', 4, 64)
+		line = append(line, ',')
+		line = strconv.AppendFloat(line, r.Millivolts(s), 'f', 4, 64)
+		line = append(line, '\n')
+		if _, err := bw.Write(line); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Downsample reduces the recording to at most maxPoints points for plotting.
+// Each bucket contributes its minimum and maximum sample in time order, which
+// keeps QRS peaks visible where plain decimation would drop them.
+func Downsample(r *Recording, maxPoints int) []Point {
+	n := len(r.Samples)
+	if maxPoints <= 0 || n <= maxPoints {
+		points := make([]Point, n)
+		for i, s := range r.Samples {
+			points[i] = Point{T: float64(i) / r.SampleRate, MV: r.Millivolts(s)}
+		}
+		return points
+	}
+
+	buckets := maxPoints / 2
+	if buckets < 1 {
+		buckets = 1
+	}
+	points := make([]Point, 0, buckets*2)
+	for b := 0; b < buckets; b++ {
+		start := b * n / buckets
+		end := (b + 1) * n / buckets
+		if start >= end {
+			continue
+		}
+
+		minIdx, maxIdx := start, start
+		for i := start + 1; i < end; i++ {
+			if r.Samples[i] < r.Samples[minIdx] {
+				minIdx = i
+			}
+			if r.Samples[i] > r.Samples[maxIdx] {
+				maxIdx = i
+			}
+		}
+
+		first, second := minIdx, maxIdx
+		if first > second {
+			first, second = second, first
+		}
+		points = append(points, Point{T: float64(first) / r.SampleRate, MV: r.Millivolts(r.Samples[first])})
+		if second != first {
+			points = append(points, Point{T: float64(second) / r.SampleRate, MV: r.Millivolts(r.Samples[second])})
+		}
+	}
+	return points
+}
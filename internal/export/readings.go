@@ -0,0 +1,256 @@
+// Package export writes heart readings in the formats requested for external
+// analysis: CSV, a JSON array or newline-delimited JSON. Readings are written
+// one at a time so that exports of any size run in constant memory.
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/google/uuid"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+)
+
+// Formats lists the supported export formats
+var Formats = []string{FormatCSV, FormatJSON, FormatNDJSON}
+
+// column is one exportable field of a heart reading
+type column struct {
+	name  string
+	value func(r *models.HeartReading, loc *time.Location) any
+}
+
+var columns = []column{
+	{"id", func(r *models.HeartReading, _ *time.Location) any { return r.ID }},
+	{"time", func(r *models.HeartReading, loc *time.Location) any { return r.Time.In(loc) }},
+	{"bpm", func(r *models.HeartReading, _ *time.Location) any { return r.BPM }},
+	{"reading_type", func(r *models.HeartReading, _ *time.Location) any { return r.ReadingType }},
+	{"entry_method", func(r *models.HeartReading, _ *time.Location) any { return r.EntryMethod }},
+	{"source", func(r *models.HeartReading, _ *time.Location) any { return r.Source }},
+	{"device_id", func(r *models.HeartReading, _ *time.Location) any { return r.DeviceID }},
+	{"entered_by", func(r *models.HeartReading, _ *time.Location) any { return r.EnteredBy }},
+	{"variability", func(r *models.HeartReading, _ *time.Location) any { return r.Variability }},
+	{"irregularity_detected", func(r *models.HeartReading, _ *time.Location) any { return r.IrregularityDetected }},
+	{"oxygen_level", func(r *models.HeartReading, _ *time.Location) any { return r.OxygenLevel }},
+	{"systolic_pressure", func(r *models.HeartReading, _ *time.Location) any { return r.SystolicPressure }},
+	{"diastolic_pressure", func(r *models.HeartReading, _ *time.Location) any { return r.DiastolicPressure }},
+	{"temperature", func(r *models.HeartReading, _ *time.Location) any { return r.Temperature }},
+	{"activity_level", func(r *models.HeartReading, _ *time.Location) any { return r.ActivityLevel }},
+	{"reliability_score", func(r *models.HeartReading, _ *time.Location) any { return r.ReliabilityScore }},
+	{"quality_score", func(r *models.HeartReading, _ *time.Location) any { return r.QualityScore }},
+	{"quality_flags", func(r *models.HeartReading, _ *time.Location) any { return r.QualityFlags }},
+	{"notes", func(r *models.HeartReading, _ *time.Location) any { return r.Notes }},
+}
+
+// DefaultColumns are exported when no columns are chosen
+var DefaultColumns = []string{
+	"time", "bpm", "reading_type", "source", "irregularity_detected",
+	"oxygen_level", "systolic_pressure", "diastolic_pressure", "quality_score",
+}
+
+// ColumnNames lists every exportable column in its default order
+func ColumnNames() []string {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.name
+	}
+	return names
+}
+
+// ValidateColumns checks that every name is an exportable column, without
+// repetitions
+func ValidateColumns(names []string) error {
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if findColumn(name) == nil {
+			return fmt.Errorf("unknown column %q", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("column %q is repeated", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// ContentType returns the MIME type of a format
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	default:
+		return "application/json"
+	}
+}
+
+func findColumn(name string) *column {
+	for i := range columns {
+		if columns[i].name == name {
+			return &columns[i]
+		}
+	}
+	return nil
+}
+
+// ReadingWriter writes heart readings in one format. Close must be called to
+// complete the output.
+type ReadingWriter struct {
+	w       *bufio.Writer
+	csv     *csv.Writer
+	format  string
+	columns []*column
+	loc     *time.Location
+	count   int64
+	record  []string // fila CSV reutilizada
+	line    []byte   // objeto JSON reutilizado
+}
+
+// NewReadingWriter returns a writer of the given columns. Times are written in
+// loc.
+func NewReadingWriter(w io.Writer, format string, names []string, loc *time.Location) (*ReadingWriter, error) {
+	if err := ValidateColumns(names); err != nil {
+		return nil, err
+	}
+	rw := &ReadingWriter{w: bufio.NewWriter(w), format: format, loc: loc, record: make([]string, len(names))}
+	for _, name := range names {
+		rw.columns = append(rw.columns, findColumn(name))
+	}
+
+	switch format {
+	case FormatCSV:
+		rw.csv = csv.NewWriter(rw.w)
+		if err := rw.csv.Write(names); err != nil {
+			return nil, err
+		}
+	case FormatJSON:
+		if _, err := rw.w.WriteString("["); err != nil {
+			return nil, err
+		}
+	case FormatNDJSON:
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+	return rw, nil
+}
+
+// Write writes one reading
+func (rw *ReadingWriter) Write(r *models.HeartReading) error {
+	if rw.csv != nil {
+		for i, c := range rw.columns {
+			rw.record[i] = csvValue(c.value(r, rw.loc))
+		}
+		rw.count++
+		return rw.csv.Write(rw.record)
+	}
+
+	line := rw.line[:0]
+	if rw.format == FormatJSON {
+		if rw.count > 0 {
+			line = append(line, ',')
+		}
+		line = append(line, '\n')
+	}
+	line = append(line, '{')
+	for i, c := range rw.columns {
+		if i > 0 {
+			line = append(line, ',')
+		}
+		value, err := json.Marshal(c.value(r, rw.loc))
+		if err != nil {
+			return err
+		}
+		line = strconv.AppendQuote(line, c.name)
+		line = append(line, ':')
+		line = append(line, value...)
+	}
+	line = append(line, '}')
+	if rw.format == FormatNDJSON {
+		line = append(line, '\n')
+	}
+	rw.line = line
+
+	if _, err := rw.w.Write(line); err != nil {
+		return err
+	}
+	rw.count++
+	return nil
+}
+
+// Count returns the number of readings written
+func (rw *ReadingWriter) Count() int64 {
+	return rw.count
+}
+
+// Close completes the output and flushes it
+func (rw *ReadingWriter) Close() error {
+	if rw.csv != nil {
+		rw.csv.Flush()
+		if err := rw.csv.Error(); err != nil {
+			return err
+		}
+	}
+	if rw.format == FormatJSON {
+		closing := "]\n"
+		if rw.count > 0 {
+			closing = "\n]\n"
+		}
+		if _, err := rw.w.WriteString(closing); err != nil {
+			return err
+		}
+	}
+	return rw.w.Flush()
+}
+
+// csvValue formats a column value for CSV: empty for null, lists joined with "|"
+func csvValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case []string:
+		return strings.Join(v, "|")
+	case uuid.UUID:
+		return v.String()
+	case *uuid.UUID:
+		if v == nil {
+			return ""
+		}
+		return v.String()
+	case *int:
+		if v == nil {
+			return ""
+		}
+		return strconv.Itoa(*v)
+	case *float64:
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*v, 'f', -1, 64)
+	case *string:
+		if v == nil {
+			return ""
+		}
+		return *v
+	default:
+		return fmt.Sprint(v)
+	}
+}
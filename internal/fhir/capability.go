@@ -0,0 +1,87 @@
+package fhir
+
+type CapabilityStatement struct {
+	ResourceType string           `json:"resourceType"`
+	Status       string           `json:"status"`
+	Date         string           `json:"date"`
+	Kind         string           `json:"kind"`
+	Software     *Software        `json:"software,omitempty"`
+	FHIRVersion  string           `json:"fhirVersion"`
+	Format       []string         `json:"format"`
+	Rest         []CapabilityRest `json:"rest"`
+}
+
+type Software struct {
+	Name string `json:"name"`
+}
+
+type CapabilityRest struct {
+	Mode        string               `json:"mode"`
+	Resource    []CapabilityResource `json:"resource"`
+	Interaction []Interaction        `json:"interaction,omitempty"`
+}
+
+type CapabilityResource struct {
+	Type             string        `json:"type"`
+	SupportedProfile []string      `json:"supportedProfile,omitempty"`
+	Interaction      []Interaction `json:"interaction"`
+	SearchParam      []SearchParam `json:"searchParam,omitempty"`
+	Operation        []Operation   `json:"operation,omitempty"`
+}
+
+type Interaction struct {
+	Code string `json:"code"`
+}
+
+type SearchParam struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type Operation struct {
+	Name       string `json:"name"`
+	Definition string `json:"definition"`
+}
+
+// NewCapabilityStatement describes what the FHIR facade supports: reading
+// and searching every resource, validating them, and importing
+// Observations with transaction Bundles
+func NewCapabilityStatement(date string) *CapabilityStatement {
+	read := []Interaction{{Code: "read"}, {Code: "search-type"}}
+	validate := []Operation{{Name: "validate", Definition: "http://hl7.org/fhir/OperationDefinition/Resource-validate"}}
+	resource := func(resourceType string, params ...SearchParam) CapabilityResource {
+		return CapabilityResource{
+			Type:             resourceType,
+			SupportedProfile: SupportedProfiles(resourceType),
+			Interaction:      read,
+			SearchParam:      params,
+			Operation:        validate,
+		}
+	}
+
+	return &CapabilityStatement{
+		ResourceType: "CapabilityStatement",
+		Status:       "active",
+		Date:         date,
+		Kind:         "instance",
+		Software:     &Software{Name: "api-medical-heart"},
+		FHIRVersion:  "4.0.1",
+		Format:       []string{"json"},
+		Rest: []CapabilityRest{{
+			Mode: "server",
+			Resource: []CapabilityResource{
+				resource("Observation",
+					SearchParam{Name: "patient", Type: "reference"},
+					SearchParam{Name: "date", Type: "date"},
+					SearchParam{Name: "code", Type: "token"},
+					SearchParam{Name: "_count", Type: "number"},
+					SearchParam{Name: "_sort", Type: "string"},
+				),
+				resource("Patient"),
+				resource("Device", SearchParam{Name: "patient", Type: "reference"}),
+				resource("Practitioner"),
+			},
+			Interaction: []Interaction{{Code: "transaction"}},
+		}},
+	}
+}
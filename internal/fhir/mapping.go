@@ -0,0 +1,357 @@
+package fhir
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/google/uuid"
+)
+
+// ErrInvalidID is returned for resource ids this server never issues
+var ErrInvalidID = errors.New("invalid resource id")
+
+// loincCode describes how one kind of measurement is coded
+type loincCode struct {
+	code    string
+	display string
+	profile string
+	unit    string
+	ucum    string
+}
+
+var observationCodes = map[string]loincCode{
+	"heart_rate":               {CodeHeartRate, "Heart rate", ProfileHeartRate, "beats/minute", "/min"},
+	models.VitalSpO2:           {CodeOxygenSaturation, "Oxygen saturation in Arterial blood by Pulse oximetry", ProfileOxygenSat, "%", "%"},
+	models.VitalBloodPressure:  {CodeBloodPressure, "Blood pressure panel with all children optional", ProfileBloodPressure, "", ""},
+	"systolic_blood_pressure":  {CodeSystolicPressure, "Systolic blood pressure", "", "mmHg", "mm[Hg]"},
+	"diastolic_blood_pressure": {CodeDiastolicPressure, "Diastolic blood pressure", "", "mmHg", "mm[Hg]"},
+}
+
+// ObservationTypeForCode returns the measurement observation type of a
+// LOINC code, or false for codes this server does not store
+func ObservationTypeForCode(code string) (string, bool) {
+	switch code {
+	case CodeHeartRate:
+		return "heart_rate", true
+	case CodeOxygenSaturation:
+		return models.VitalSpO2, true
+	case CodeBloodPressure:
+		return models.VitalBloodPressure, true
+	}
+	return "", false
+}
+
+// ProfileForCode returns the vital signs profile of a LOINC code
+func ProfileForCode(code string) string {
+	if observationType, ok := ObservationTypeForCode(code); ok {
+		return observationCodes[observationType].profile
+	}
+	return ""
+}
+
+// ObservationID builds the id of the Observation of a measurement. A heart
+// reading yields several observations, so the id carries the kind.
+func ObservationID(kind string, id uuid.UUID) string {
+	return kind + "-" + id.String()
+}
+
+// ParseObservationID splits an Observation id into kind and source id
+func ParseObservationID(id string) (string, uuid.UUID, error) {
+	kind, rest, ok := strings.Cut(id, "-")
+	if !ok {
+		return "", uuid.Nil, ErrInvalidID
+	}
+	switch kind {
+	case models.MeasurementHeartRate, models.MeasurementSpO2, models.MeasurementBloodPressure, models.MeasurementVital:
+	default:
+		return "", uuid.Nil, ErrInvalidID
+	}
+	sourceID, err := uuid.Parse(rest)
+	if err != nil {
+		return "", uuid.Nil, ErrInvalidID
+	}
+	return kind, sourceID, nil
+}
+
+// NewReference returns a relative reference to a resource
+func NewReference(resourceType string, id uuid.UUID) *Reference {
+	return &Reference{Reference: resourceType + "/" + id.String()}
+}
+
+// ParseReference returns the id of a reference to a resource of the given
+// type, relative ("Patient/<id>") or absolute (".../Patient/<id>")
+func ParseReference(ref *Reference, resourceType string) (uuid.UUID, error) {
+	if ref == nil || ref.Reference == "" {
+		return uuid.Nil, fmt.Errorf("missing %s reference", resourceType)
+	}
+	prefix := resourceType + "/"
+	value := ref.Reference
+	if i := strings.LastIndex(value, "/"+prefix); i >= 0 {
+		value = value[i+1:]
+	}
+	if !strings.HasPrefix(value, prefix) {
+		return uuid.Nil, fmt.Errorf("reference %q is not a %s", ref.Reference, resourceType)
+	}
+	id, err := uuid.Parse(strings.TrimPrefix(value, prefix))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("reference %q has an invalid id", ref.Reference)
+	}
+	return id, nil
+}
+
+func codeableConcept(c loincCode) CodeableConcept {
+	return CodeableConcept{
+		Coding: []Coding{{System: SystemLOINC, Code: c.code, Display: c.display}},
+		Text:   c.display,
+	}
+}
+
+func quantity(value float64, c loincCode) *Quantity {
+	return &Quantity{Value: &value, Unit: c.unit, System: SystemUCUM, Code: c.ucum}
+}
+
+// ObservationFromMeasurement maps a measurement to a vital signs Observation
+func ObservationFromMeasurement(m *models.Measurement) *Observation {
+	code := observationCodes[m.ObservationType]
+	observation := &Observation{
+		ResourceType: "Observation",
+		ID:           ObservationID(m.Kind, m.ID),
+		Meta:         &Meta{Profile: []string{code.profile}},
+		Status:       "final",
+		Category: []CodeableConcept{{
+			Coding: []Coding{{System: SystemObservationCategory, Code: "vital-signs", Display: "Vital Signs"}},
+		}},
+		Code:              codeableConcept(code),
+		Subject:           NewReference("Patient", m.PatientID),
+		EffectiveDateTime: m.Time.UTC().Format(time.RFC3339Nano),
+	}
+	if m.ReadingType != nil && *m.ReadingType != models.ReadingTypeUnknown {
+		observation.Category = append(observation.Category, CodeableConcept{
+			Coding: []Coding{{System: SystemReadingType, Code: *m.ReadingType}},
+		})
+	}
+	if m.DeviceID != nil {
+		observation.Device = NewReference("Device", *m.DeviceID)
+	}
+	if m.Notes != nil && *m.Notes != "" {
+		observation.Note = []Annotation{{Text: *m.Notes}}
+	}
+
+	if m.ObservationType == models.VitalBloodPressure {
+		systolic := observationCodes["systolic_blood_pressure"]
+		observation.Component = []ObservationComponent{
+			{Code: codeableConcept(systolic), ValueQuantity: quantity(m.Value, systolic)},
+		}
+		if m.SecondaryValue != nil {
+			diastolic := observationCodes["diastolic_blood_pressure"]
+			observation.Component = append(observation.Component,
+				ObservationComponent{Code: codeableConcept(diastolic), ValueQuantity: quantity(*m.SecondaryValue, diastolic)})
+		}
+	} else {
+		observation.ValueQuantity = quantity(m.Value, code)
+	}
+	return observation
+}
+
+// MeasurementFromObservation maps a vital signs Observation back to a
+// measurement. The Observation must have passed validation; the kind of the
+// measurement is left empty, as the caller decides where to store it.
+func MeasurementFromObservation(observation *Observation) (*models.Measurement, error) {
+	var observationType string
+	for _, coding := range observation.Code.Coding {
+		if coding.System != SystemLOINC {
+			continue
+		}
+		if t, ok := ObservationTypeForCode(coding.Code); ok {
+			observationType = t
+			break
+		}
+	}
+	if observationType == "" {
+		return nil, errors.New("code: unsupported observation code")
+	}
+
+	patientID, err := ParseReference(observation.Subject, "Patient")
+	if err != nil {
+		return nil, fmt.Errorf("subject: %w", err)
+	}
+	m := &models.Measurement{PatientID: patientID, ObservationType: observationType}
+
+	if observation.Device != nil {
+		deviceID, err := ParseReference(observation.Device, "Device")
+		if err != nil {
+			return nil, fmt.Errorf("device: %w", err)
+		}
+		m.DeviceID = &deviceID
+	}
+
+	// Las observaciones de un intervalo se fechan en su inicio
+	effective := observation.EffectiveDateTime
+	if effective == "" && observation.EffectivePeriod != nil {
+		effective = observation.EffectivePeriod.Start
+	}
+	if m.Time, err = time.Parse(time.RFC3339Nano, effective); err != nil {
+		return nil, errors.New("effective: must be a date and time with seconds and time zone")
+	}
+
+	if len(observation.Note) > 0 {
+		texts := make([]string, len(observation.Note))
+		for i, note := range observation.Note {
+			texts[i] = note.Text
+		}
+		notes := strings.Join(texts, "\n")
+		m.Notes = &notes
+	}
+
+	if observationType == models.VitalBloodPressure {
+		systolic := componentValue(observation, CodeSystolicPressure)
+		diastolic := componentValue(observation, CodeDiastolicPressure)
+		if systolic == nil || diastolic == nil {
+			return nil, errors.New("component: systolic and diastolic values are required")
+		}
+		m.Value, m.SecondaryValue = *systolic, diastolic
+		return m, nil
+	}
+	if observation.ValueQuantity == nil || observation.ValueQuantity.Value == nil {
+		return nil, errors.New("valueQuantity: value is required")
+	}
+	m.Value = *observation.ValueQuantity.Value
+	if observationType == "heart_rate" {
+		readingType := readingTypeFromObservation(observation)
+		m.ReadingType = &readingType
+	}
+	return m, nil
+}
+
+// readingTypeFromObservation returns the reading type of a heart rate
+// Observation: the one in its reading type category, resting for the resting
+// heart rate LOINC code, or unknown when the Observation does not say
+func readingTypeFromObservation(observation *Observation) string {
+	for _, category := range observation.Category {
+		for _, coding := range category.Coding {
+			switch coding.Code {
+			case models.ReadingTypeResting, models.ReadingTypeActive, models.ReadingTypeSleep:
+				if coding.System == SystemReadingType {
+					return coding.Code
+				}
+			}
+		}
+	}
+	if observation.Code.HasCoding(SystemLOINC, CodeRestingHeartRate) {
+		return models.ReadingTypeResting
+	}
+	return models.ReadingTypeUnknown
+}
+
+func componentValue(observation *Observation, code string) *float64 {
+	for _, component := range observation.Component {
+		if component.Code.HasCoding(SystemLOINC, code) && component.ValueQuantity != nil {
+			return component.ValueQuantity.Value
+		}
+	}
+	return nil
+}
+
+func telecom(phone, email string) []ContactPoint {
+	var points []ContactPoint
+	if phone != "" {
+		points = append(points, ContactPoint{System: "phone", Value: phone})
+	}
+	if email != "" {
+		points = append(points, ContactPoint{System: "email", Value: email})
+	}
+	return points
+}
+
+func humanName(firstName, lastName string) HumanName {
+	name := HumanName{Use: "official", Family: lastName, Text: strings.TrimSpace(firstName + " " + lastName)}
+	if firstName != "" {
+		name.Given = []string{firstName}
+	}
+	return name
+}
+
+// PatientFromDetail maps a patient to a FHIR Patient
+func PatientFromDetail(p *models.PatientDetailBasicResponse) *Patient {
+	active := p.IsActive
+	patient := &Patient{
+		ResourceType: "Patient",
+		ID:           p.PatientID.String(),
+		Active:       &active,
+		Name:         []HumanName{humanName(p.FirstName, p.LastName)},
+		Telecom:      telecom(p.PhoneNumber, p.Email),
+		Gender:       p.Gender, // 'male', 'female' y 'other' coinciden con FHIR
+	}
+	if !p.DateOfBirth.IsZero() {
+		patient.BirthDate = p.DateOfBirth.Format("2006-01-02")
+	}
+	if p.EmergencyContactName != "" || p.EmergencyContactPhone != "" {
+		contact := PatientContact{
+			Name:    &HumanName{Text: p.EmergencyContactName},
+			Telecom: telecom(p.EmergencyContactPhone, ""),
+		}
+		if p.EmergencyContactRelation != "" {
+			contact.Relationship = []CodeableConcept{{Text: p.EmergencyContactRelation}}
+		}
+		patient.Contact = []PatientContact{contact}
+	}
+	return patient
+}
+
+// DeviceFromResponse maps a device to a FHIR Device
+func DeviceFromResponse(d *models.DeviceResponse) *Device {
+	device := &Device{
+		ResourceType: "Device",
+		ID:           d.DeviceID.String(),
+		Status:       "inactive",
+		SerialNumber: d.SerialNumber,
+		Type:         &CodeableConcept{Text: d.DeviceType},
+	}
+	if d.IsActive {
+		device.Status = "active"
+	}
+	if d.SerialNumber != "" {
+		device.Identifier = []Identifier{{
+			Type:  &CodeableConcept{Coding: []Coding{{System: "http://hl7.org/fhir/NamingSystem/device-serial-number", Code: "SNO"}}},
+			Value: d.SerialNumber,
+		}}
+	}
+	if d.FirmwareVersion != "" {
+		device.Version = []DeviceVersion{{Value: d.FirmwareVersion}}
+	}
+	if d.PatientID != nil {
+		device.Patient = NewReference("Patient", *d.PatientID)
+	}
+	return device
+}
+
+// PractitionerFromDoctor maps a doctor to a FHIR Practitioner
+func PractitionerFromDoctor(d *models.DoctorDetailsResponse) *Practitioner {
+	active := d.IsActive
+	practitioner := &Practitioner{
+		ResourceType: "Practitioner",
+		ID:           d.DoctorID.String(),
+		Active:       &active,
+		Name:         []HumanName{humanName(d.FirstName, d.LastName)},
+		Telecom:      telecom(d.PhoneNumber, d.Email),
+	}
+	if d.LicenseNumber != "" {
+		practitioner.Identifier = append(practitioner.Identifier, Identifier{
+			Type:  &CodeableConcept{Coding: []Coding{{System: "http://terminology.hl7.org/CodeSystem/v2-0203", Code: "MD"}}, Text: "Medical License number"},
+			Value: d.LicenseNumber,
+		})
+	}
+	if d.IdentityNumber != "" {
+		practitioner.Identifier = append(practitioner.Identifier, Identifier{
+			Type:  &CodeableConcept{Text: d.IdentityType},
+			Value: d.IdentityNumber,
+		})
+	}
+	if d.Specialty != "" {
+		practitioner.Qualification = []PractitionerQualification{{Code: CodeableConcept{Text: d.Specialty}}}
+	}
+	return practitioner
+}
@@ -0,0 +1,93 @@
+package fhir
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestMeasurementFromObservationReadingType(t *testing.T) {
+	vitalSigns := CodeableConcept{Coding: []Coding{{System: SystemObservationCategory, Code: "vital-signs"}}}
+	heartRate := CodeableConcept{Coding: []Coding{{System: SystemLOINC, Code: CodeHeartRate}}}
+	restingHeartRate := CodeableConcept{Coding: []Coding{
+		{System: SystemLOINC, Code: CodeHeartRate},
+		{System: SystemLOINC, Code: CodeRestingHeartRate},
+	}}
+	readingType := func(system, code string) CodeableConcept {
+		return CodeableConcept{Coding: []Coding{{System: system, Code: code}}}
+	}
+
+	tests := []struct {
+		name     string
+		category []CodeableConcept
+		code     CodeableConcept
+		want     string
+	}{
+		{"no context", []CodeableConcept{vitalSigns}, heartRate, models.ReadingTypeUnknown},
+		{"reading type category", []CodeableConcept{vitalSigns, readingType(SystemReadingType, "active")}, heartRate, models.ReadingTypeActive},
+		{"sleep category", []CodeableConcept{vitalSigns, readingType(SystemReadingType, "sleep")}, heartRate, models.ReadingTypeSleep},
+		{"resting heart rate code", []CodeableConcept{vitalSigns}, restingHeartRate, models.ReadingTypeResting},
+		{"category over code", []CodeableConcept{vitalSigns, readingType(SystemReadingType, "active")}, restingHeartRate, models.ReadingTypeActive},
+		{"unsupported reading type", []CodeableConcept{vitalSigns, readingType(SystemReadingType, "exercise")}, heartRate, models.ReadingTypeUnknown},
+		{"other system", []CodeableConcept{vitalSigns, readingType("http://example.org/context", "resting")}, heartRate, models.ReadingTypeUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value := 72.0
+			observation := &Observation{
+				ResourceType:      "Observation",
+				Status:            "final",
+				Category:          tt.category,
+				Code:              tt.code,
+				Subject:           NewReference("Patient", uuid.New()),
+				EffectiveDateTime: "2026-10-19T08:00:00Z",
+				ValueQuantity:     &Quantity{Value: &value, Unit: "beats/minute", System: SystemUCUM, Code: "/min"},
+			}
+
+			m, err := MeasurementFromObservation(observation)
+			if err != nil {
+				t.Fatalf("MeasurementFromObservation() error = %v", err)
+			}
+			if m.ReadingType == nil || *m.ReadingType != tt.want {
+				t.Errorf("ReadingType = %v, want %s", m.ReadingType, tt.want)
+			}
+		})
+	}
+}
+
+func TestObservationReadingTypeRoundTrip(t *testing.T) {
+	for _, readingType := range []string{models.ReadingTypeResting, models.ReadingTypeActive, models.ReadingTypeSleep, models.ReadingTypeUnknown} {
+		t.Run(readingType, func(t *testing.T) {
+			m := &models.Measurement{
+				Kind:            "hr",
+				ID:              uuid.New(),
+				PatientID:       uuid.New(),
+				ObservationType: "heart_rate",
+				Value:           64,
+				ReadingType:     &readingType,
+				Time:            time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC),
+			}
+
+			observation := ObservationFromMeasurement(m)
+			data, err := json.Marshal(observation)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			if issues := Validate(data); HasErrors(issues) {
+				t.Fatalf("Validate() issues = %+v", issues)
+			}
+
+			got, err := MeasurementFromObservation(observation)
+			if err != nil {
+				t.Fatalf("MeasurementFromObservation() error = %v", err)
+			}
+			if got.ReadingType == nil || *got.ReadingType != readingType {
+				t.Errorf("ReadingType = %v, want %s", got.ReadingType, readingType)
+			}
+		})
+	}
+}
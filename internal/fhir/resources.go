@@ -0,0 +1,241 @@
+// Package fhir maps the API's patients, doctors, devices and measurements to
+// HL7 FHIR R4 resources and back, and validates incoming resources against
+// the profiles bundled in profiles/, without network access.
+package fhir
+
+import "encoding/json"
+
+// ContentType is the media type of FHIR JSON
+const ContentType = "application/fhir+json"
+
+// Sistemas de codificación
+const (
+	SystemLOINC               = "http://loinc.org"
+	SystemUCUM                = "http://unitsofmeasure.org"
+	SystemObservationCategory = "http://terminology.hl7.org/CodeSystem/observation-category"
+	// Sistema propio con el tipo de lectura (resting, active, sleep)
+	SystemReadingType = "https://github.com/Waldir-TG/api-medical-heart-v1/fhir/CodeSystem/reading-type"
+)
+
+// Perfiles FHIR R4 de signos vitales
+const (
+	ProfileVitalSigns    = "http://hl7.org/fhir/StructureDefinition/vitalsigns"
+	ProfileHeartRate     = "http://hl7.org/fhir/StructureDefinition/heartrate"
+	ProfileOxygenSat     = "http://hl7.org/fhir/StructureDefinition/oxygensat"
+	ProfileBloodPressure = "http://hl7.org/fhir/StructureDefinition/bp"
+)
+
+// Códigos LOINC
+const (
+	CodeHeartRate         = "8867-4"
+	CodeRestingHeartRate  = "40443-4"
+	CodeOxygenSaturation  = "59408-5"
+	CodeBloodPressure     = "85354-9"
+	CodeSystolicPressure  = "8480-6"
+	CodeDiastolicPressure = "8462-4"
+)
+
+type Meta struct {
+	Profile []string `json:"profile,omitempty"`
+}
+
+type Coding struct {
+	System  string `json:"system,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+type CodeableConcept struct {
+	Coding []Coding `json:"coding,omitempty"`
+	Text   string   `json:"text,omitempty"`
+}
+
+// HasCoding reports whether the concept contains the code of system
+func (c *CodeableConcept) HasCoding(system, code string) bool {
+	for _, coding := range c.Coding {
+		if coding.System == system && coding.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+type Quantity struct {
+	Value  *float64 `json:"value,omitempty"`
+	Unit   string   `json:"unit,omitempty"`
+	System string   `json:"system,omitempty"`
+	Code   string   `json:"code,omitempty"`
+}
+
+type Reference struct {
+	Reference string `json:"reference,omitempty"`
+	Display   string `json:"display,omitempty"`
+}
+
+type Identifier struct {
+	Type   *CodeableConcept `json:"type,omitempty"`
+	System string           `json:"system,omitempty"`
+	Value  string           `json:"value,omitempty"`
+}
+
+type HumanName struct {
+	Use    string   `json:"use,omitempty"`
+	Text   string   `json:"text,omitempty"`
+	Family string   `json:"family,omitempty"`
+	Given  []string `json:"given,omitempty"`
+}
+
+type ContactPoint struct {
+	System string `json:"system,omitempty"` // 'phone', 'email'
+	Value  string `json:"value,omitempty"`
+	Use    string `json:"use,omitempty"`
+}
+
+type Period struct {
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+}
+
+type Annotation struct {
+	Text string `json:"text"`
+}
+
+// Observation is a FHIR R4 Observation
+type Observation struct {
+	ResourceType      string                 `json:"resourceType"`
+	ID                string                 `json:"id,omitempty"`
+	Meta              *Meta                  `json:"meta,omitempty"`
+	Status            string                 `json:"status"`
+	Category          []CodeableConcept      `json:"category,omitempty"`
+	Code              CodeableConcept        `json:"code"`
+	Subject           *Reference             `json:"subject,omitempty"`
+	EffectiveDateTime string                 `json:"effectiveDateTime,omitempty"`
+	EffectivePeriod   *Period                `json:"effectivePeriod,omitempty"`
+	Device            *Reference             `json:"device,omitempty"`
+	ValueQuantity     *Quantity              `json:"valueQuantity,omitempty"`
+	Component         []ObservationComponent `json:"component,omitempty"`
+	Note              []Annotation           `json:"note,omitempty"`
+}
+
+type ObservationComponent struct {
+	Code          CodeableConcept `json:"code"`
+	ValueQuantity *Quantity       `json:"valueQuantity,omitempty"`
+}
+
+// Patient is a FHIR R4 Patient
+type Patient struct {
+	ResourceType string           `json:"resourceType"`
+	ID           string           `json:"id,omitempty"`
+	Meta         *Meta            `json:"meta,omitempty"`
+	Active       *bool            `json:"active,omitempty"`
+	Name         []HumanName      `json:"name,omitempty"`
+	Telecom      []ContactPoint   `json:"telecom,omitempty"`
+	Gender       string           `json:"gender,omitempty"`
+	BirthDate    string           `json:"birthDate,omitempty"`
+	Contact      []PatientContact `json:"contact,omitempty"`
+}
+
+type PatientContact struct {
+	Relationship []CodeableConcept `json:"relationship,omitempty"`
+	Name         *HumanName        `json:"name,omitempty"`
+	Telecom      []ContactPoint    `json:"telecom,omitempty"`
+}
+
+// Device is a FHIR R4 Device
+type Device struct {
+	ResourceType string           `json:"resourceType"`
+	ID           string           `json:"id,omitempty"`
+	Meta         *Meta            `json:"meta,omitempty"`
+	Identifier   []Identifier     `json:"identifier,omitempty"`
+	Status       string           `json:"status,omitempty"` // 'active', 'inactive'
+	SerialNumber string           `json:"serialNumber,omitempty"`
+	Type         *CodeableConcept `json:"type,omitempty"`
+	Version      []DeviceVersion  `json:"version,omitempty"`
+	Patient      *Reference       `json:"patient,omitempty"`
+}
+
+type DeviceVersion struct {
+	Value string `json:"value"`
+}
+
+// Practitioner is a FHIR R4 Practitioner
+type Practitioner struct {
+	ResourceType  string                      `json:"resourceType"`
+	ID            string                      `json:"id,omitempty"`
+	Meta          *Meta                       `json:"meta,omitempty"`
+	Identifier    []Identifier                `json:"identifier,omitempty"`
+	Active        *bool                       `json:"active,omitempty"`
+	Name          []HumanName                 `json:"name,omitempty"`
+	Telecom       []ContactPoint              `json:"telecom,omitempty"`
+	Qualification []PractitionerQualification `json:"qualification,omitempty"`
+}
+
+type PractitionerQualification struct {
+	Code CodeableConcept `json:"code"`
+}
+
+// Bundle is a FHIR R4 Bundle. Entry resources are kept as raw JSON so that
+// a bundle can carry any resource type.
+type Bundle struct {
+	ResourceType string        `json:"resourceType"`
+	ID           string        `json:"id,omitempty"`
+	Type         string        `json:"type"` // 'searchset', 'transaction', 'transaction-response'
+	Total        *int          `json:"total,omitempty"`
+	Link         []BundleLink  `json:"link,omitempty"`
+	Entry        []BundleEntry `json:"entry,omitempty"`
+}
+
+type BundleLink struct {
+	Relation string `json:"relation"`
+	URL      string `json:"url"`
+}
+
+type BundleEntry struct {
+	FullURL  string          `json:"fullUrl,omitempty"`
+	Resource json.RawMessage `json:"resource,omitempty"`
+	Request  *BundleRequest  `json:"request,omitempty"`
+	Response *BundleResponse `json:"response,omitempty"`
+}
+
+type BundleRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+}
+
+type BundleResponse struct {
+	Status   string            `json:"status"`
+	Location string            `json:"location,omitempty"`
+	Outcome  *OperationOutcome `json:"outcome,omitempty"`
+}
+
+// OperationOutcome reports errors and warnings, as FHIR requires
+type OperationOutcome struct {
+	ResourceType string  `json:"resourceType"`
+	Issue        []Issue `json:"issue"`
+}
+
+// Issue is one problem of an OperationOutcome. Code is a FHIR issue type
+// such as 'invalid', 'required', 'not-found' or 'exception'.
+type Issue struct {
+	Severity    string   `json:"severity"` // 'error', 'warning', 'information'
+	Code        string   `json:"code"`
+	Diagnostics string   `json:"diagnostics,omitempty"`
+	Expression  []string `json:"expression,omitempty"`
+}
+
+// NewOperationOutcome returns an outcome with the given issues
+func NewOperationOutcome(issues ...Issue) *OperationOutcome {
+	if issues == nil {
+		issues = []Issue{}
+	}
+	return &OperationOutcome{ResourceType: "OperationOutcome", Issue: issues}
+}
+
+// Entry wraps a resource in a bundle entry
+func Entry(fullURL string, resource any) (BundleEntry, error) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return BundleEntry{}, err
+	}
+	return BundleEntry{FullURL: fullURL, Resource: data}, nil
+}
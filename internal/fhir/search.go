@@ -0,0 +1,75 @@
+package fhir
+
+import (
+	"fmt"
+	"time"
+)
+
+// dateLayouts are the precisions a date search value may have, each with the
+// step to the start of the next value of the same precision
+var dateLayouts = []struct {
+	layout string
+	next   func(time.Time) time.Time
+}{
+	{"2006", func(t time.Time) time.Time { return t.AddDate(1, 0, 0) }},
+	{"2006-01", func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }},
+	{"2006-01-02", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }},
+	{"2006-01-02T15:04Z07:00", func(t time.Time) time.Time { return t.Add(time.Minute) }},
+	{time.RFC3339, func(t time.Time) time.Time { return t.Add(time.Second) }},
+	{time.RFC3339Nano, func(t time.Time) time.Time { return t.Add(time.Nanosecond) }},
+}
+
+// ApplyDateParam narrows [start, end) to the values matching a FHIR date
+// search parameter such as "ge2024-01-01" or "lt2024-03-01T10:00:00Z".
+// Supported prefixes are eq (the default), gt, ge, lt and le; a value
+// stands for the whole range of its precision. Dates without a time zone
+// are read in loc.
+func ApplyDateParam(value string, loc *time.Location, start, end **time.Time) error {
+	prefix := "eq"
+	if len(value) > 2 && value[0] >= 'a' && value[0] <= 'z' {
+		prefix, value = value[:2], value[2:]
+	}
+
+	var low, high time.Time
+	parsed := false
+	for _, d := range dateLayouts {
+		t, err := time.ParseInLocation(d.layout, value, loc)
+		if err == nil {
+			low, high, parsed = t, d.next(t), true
+			break
+		}
+	}
+	if !parsed {
+		return fmt.Errorf("invalid date %q", value)
+	}
+
+	narrowStart := func(t time.Time) {
+		if *start == nil || t.After(**start) {
+			*start = &t
+		}
+	}
+	narrowEnd := func(t time.Time) {
+		if *end == nil || t.Before(**end) {
+			*end = &t
+		}
+	}
+	switch prefix {
+	case "eq":
+		narrowStart(low)
+		narrowEnd(high)
+	case "gt", "ge":
+		// gt excluye todo el rango del valor
+		if prefix == "gt" {
+			narrowStart(high)
+		} else {
+			narrowStart(low)
+		}
+	case "lt":
+		narrowEnd(low)
+	case "le":
+		narrowEnd(high)
+	default:
+		return fmt.Errorf("unsupported date prefix %q", prefix)
+	}
+	return nil
+}
@@ -0,0 +1,532 @@
+package fhir
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Los perfiles incluidos son versiones recortadas de los StructureDefinition
+// oficiales de R4: solo su diferencial, con cardinalidad, tipos, valores
+// fijos, patrones y slices por patrón
+//
+//go:embed profiles/*.json
+var profileFiles embed.FS
+
+type typeRef struct {
+	Code string `json:"code"`
+}
+
+type discriminator struct {
+	Type string `json:"type"` // 'pattern' o 'value'
+	Path string `json:"path"`
+}
+
+type slicing struct {
+	Discriminator []discriminator `json:"discriminator"`
+	Rules         string          `json:"rules"`
+}
+
+// elementDefinition is the subset of a FHIR ElementDefinition the validator
+// understands. Fixed and Pattern hold the fixed[x] and pattern[x] values.
+type elementDefinition struct {
+	ID      string    `json:"id"`
+	Path    string    `json:"path"`
+	Min     *int      `json:"min"`
+	Max     string    `json:"max"`
+	Type    []typeRef `json:"type"`
+	Slicing *slicing  `json:"slicing"`
+	Fixed   any       `json:"-"`
+	Pattern any       `json:"-"`
+}
+
+func (e *elementDefinition) UnmarshalJSON(data []byte) error {
+	type plain elementDefinition
+	if err := json.Unmarshal(data, (*plain)(e)); err != nil {
+		return err
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	for key, value := range raw {
+		switch {
+		case strings.HasPrefix(key, "fixed"):
+			e.Fixed = value
+		case strings.HasPrefix(key, "pattern"):
+			e.Pattern = value
+		}
+	}
+	return nil
+}
+
+type structureDefinition struct {
+	URL            string `json:"url"`
+	Name           string `json:"name"`
+	Type           string `json:"type"`
+	BaseDefinition string `json:"baseDefinition"`
+	Differential   struct {
+		Element []*elementDefinition `json:"element"`
+	} `json:"differential"`
+}
+
+var profiles = loadProfiles()
+
+func loadProfiles() map[string]*structureDefinition {
+	files, err := profileFiles.ReadDir("profiles")
+	if err != nil {
+		panic(err)
+	}
+	loaded := map[string]*structureDefinition{}
+	for _, file := range files {
+		data, err := profileFiles.ReadFile("profiles/" + file.Name())
+		if err != nil {
+			panic(err)
+		}
+		var sd structureDefinition
+		if err := json.Unmarshal(data, &sd); err != nil {
+			panic(fmt.Sprintf("fhir: profile %s: %v", file.Name(), err))
+		}
+		loaded[sd.URL] = &sd
+	}
+	return loaded
+}
+
+// SupportedProfiles returns the bundled profiles of a resource type
+func SupportedProfiles(resourceType string) []string {
+	var urls []string
+	for url, sd := range profiles {
+		if sd.Type == resourceType {
+			urls = append(urls, url)
+		}
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+// chain returns a profile followed by the bundled profiles it derives from
+func chain(url string) []*structureDefinition {
+	var result []*structureDefinition
+	for sd := profiles[url]; sd != nil; sd = profiles[sd.BaseDefinition] {
+		result = append(result, sd)
+	}
+	return result
+}
+
+// element finds an element by id in a profile or the profiles it derives from
+func element(sds []*structureDefinition, id string) *elementDefinition {
+	for _, sd := range sds {
+		for _, e := range sd.Differential.Element {
+			if e.ID == id {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+// Validate checks a resource against the bundled profiles that apply to it:
+// the ones it declares in meta.profile and, for a vital signs Observation,
+// the profile of its LOINC code. It returns the problems found; no issue
+// of severity error means the resource is valid.
+func Validate(data []byte) []Issue {
+	var root map[string]any
+	if err := json.Unmarshal(data, &root); err != nil {
+		return []Issue{{Severity: "error", Code: "structure", Diagnostics: "resource is not a JSON object: " + err.Error()}}
+	}
+	resourceType, _ := root["resourceType"].(string)
+
+	var urls, unknown []string
+	var result []Issue
+	addProfile := func(url string) {
+		for _, u := range urls {
+			if u == url {
+				return
+			}
+		}
+		urls = append(urls, url)
+	}
+	switch resourceType {
+	case "Observation":
+		addProfile(ProfileVitalSigns)
+		for _, coding := range codings(root) {
+			if coding["system"] == SystemLOINC {
+				if profile := ProfileForCode(fmt.Sprint(coding["code"])); profile != "" {
+					addProfile(profile)
+				}
+			}
+		}
+	case "Patient", "Device", "Practitioner":
+		addProfile("http://hl7.org/fhir/StructureDefinition/" + resourceType)
+	default:
+		return []Issue{{Severity: "error", Code: "not-supported", Diagnostics: fmt.Sprintf("resource type %q is not supported", resourceType)}}
+	}
+	if meta, ok := root["meta"].(map[string]any); ok {
+		declared, _ := meta["profile"].([]any)
+		for _, p := range declared {
+			url := fmt.Sprint(p)
+			if sd := profiles[url]; sd == nil {
+				unknown = append(unknown, url)
+			} else if sd.Type == resourceType {
+				addProfile(url)
+			}
+		}
+	}
+	for _, url := range unknown {
+		result = append(result, Issue{
+			Severity:    "warning",
+			Code:        "not-supported",
+			Diagnostics: fmt.Sprintf("profile %s is not known to this server and was not checked", url),
+			Expression:  []string{resourceType + ".meta.profile"},
+		})
+	}
+
+	for _, url := range urls {
+		sds := chain(url)
+		for _, e := range sds[0].Differential.Element {
+			result = append(result, checkElement(sds, root, resourceType, e)...)
+		}
+	}
+	return result
+}
+
+// HasErrors reports whether any issue is an error
+func HasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Severity == "error" || issue.Severity == "fatal" {
+			return true
+		}
+	}
+	return false
+}
+
+func codings(root map[string]any) []map[string]any {
+	code, _ := root["code"].(map[string]any)
+	list, _ := code["coding"].([]any)
+	var result []map[string]any
+	for _, c := range list {
+		if coding, ok := c.(map[string]any); ok {
+			result = append(result, coding)
+		}
+	}
+	return result
+}
+
+// node is a value of the resource with its FHIRPath location
+type node struct {
+	value any
+	path  string
+	key   string // nombre del elemento en el JSON, p. ej. 'valueQuantity'
+}
+
+type segment struct {
+	name  string
+	slice string
+}
+
+func parseSegments(id string) []segment {
+	parts := strings.Split(id, ".")
+	segments := make([]segment, len(parts))
+	for i, part := range parts {
+		name, slice, _ := strings.Cut(part, ":")
+		segments[i] = segment{name: name, slice: slice}
+	}
+	return segments
+}
+
+// children returns the values of a named element of an object, flattening
+// repeated elements. A choice name such as value[x] matches every typed
+// variant (valueQuantity, valueString...).
+func children(parent node, name string) []node {
+	object, ok := parent.value.(map[string]any)
+	if !ok {
+		return nil
+	}
+	var keys []string
+	if base, isChoice := strings.CutSuffix(name, "[x]"); isChoice {
+		for key := range object {
+			if rest, ok := strings.CutPrefix(key, base); ok && rest != "" && rest[0] >= 'A' && rest[0] <= 'Z' {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+	} else if _, ok := object[name]; ok {
+		keys = []string{name}
+	}
+
+	var result []node
+	for _, key := range keys {
+		path := parent.path + "." + key
+		if list, ok := object[key].([]any); ok {
+			for i, item := range list {
+				result = append(result, node{value: item, path: fmt.Sprintf("%s[%d]", path, i), key: key})
+			}
+			continue
+		}
+		result = append(result, node{value: object[key], path: path, key: key})
+	}
+	return result
+}
+
+// resolve walks the segments from the nodes, keeping only the members of
+// the slices named along the way. sliceID is the element id up to the
+// current segment, used to find slice definitions.
+func resolve(sds []*structureDefinition, nodes []node, segments []segment, sliceID string) []node {
+	for _, seg := range segments {
+		var next []node
+		for _, n := range nodes {
+			next = append(next, children(n, seg.name)...)
+		}
+		sliceID += "." + seg.name
+		if seg.slice != "" {
+			next = sliceMembers(sds, next, sliceID, seg.slice)
+			sliceID += ":" + seg.slice
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// sliceMembers keeps the nodes that belong to a slice, as decided by the
+// discriminators of the sliced element
+func sliceMembers(sds []*structureDefinition, nodes []node, slicedID, sliceName string) []node {
+	sliced := element(sds, slicedID)
+	if sliced == nil || sliced.Slicing == nil {
+		return nil
+	}
+	sliceID := slicedID + ":" + sliceName
+
+	var members []node
+	for _, n := range nodes {
+		matches := true
+		for _, d := range sliced.Slicing.Discriminator {
+			var target *elementDefinition
+			var values []node
+			if d.Path == "$this" {
+				target, values = element(sds, sliceID), []node{n}
+			} else {
+				target = element(sds, sliceID+"."+d.Path)
+				values = resolve(sds, []node{n}, parseSegments(d.Path), sliceID)
+			}
+			if target == nil || !anyMatches(values, target) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			members = append(members, n)
+		}
+	}
+	return members
+}
+
+func anyMatches(values []node, e *elementDefinition) bool {
+	for _, v := range values {
+		if e.Fixed != nil && reflect.DeepEqual(v.value, e.Fixed) {
+			return true
+		}
+		if e.Pattern != nil && matchesPattern(v.value, e.Pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesPattern reports whether value contains everything in pattern:
+// every property of an object pattern and, for arrays, a match for every
+// pattern item
+func matchesPattern(value, pattern any) bool {
+	switch p := pattern.(type) {
+	case map[string]any:
+		object, ok := value.(map[string]any)
+		if !ok {
+			return false
+		}
+		for key, expected := range p {
+			if !matchesPattern(object[key], expected) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		list, ok := value.([]any)
+		if !ok {
+			return false
+		}
+		for _, expected := range p {
+			found := false
+			for _, item := range list {
+				if matchesPattern(item, expected) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(value, pattern)
+	}
+}
+
+func checkElement(sds []*structureDefinition, root map[string]any, resourceType string, e *elementDefinition) []Issue {
+	segments := parseSegments(e.ID)
+	if len(segments) < 2 || segments[0].name != resourceType {
+		return nil
+	}
+	last := segments[len(segments)-1]
+	parentID := resourceType
+	for _, seg := range segments[1 : len(segments)-1] {
+		parentID += "." + seg.name
+		if seg.slice != "" {
+			parentID += ":" + seg.slice
+		}
+	}
+	parents := resolve(sds, []node{{value: root, path: resourceType}}, segments[1:len(segments)-1], resourceType)
+
+	var issues []Issue
+	fail := func(code, path, format string, args ...any) {
+		issues = append(issues, Issue{
+			Severity:    "error",
+			Code:        code,
+			Diagnostics: fmt.Sprintf("%s (%s): ", path, sds[0].Name) + fmt.Sprintf(format, args...),
+			Expression:  []string{path},
+		})
+	}
+
+	for _, parent := range parents {
+		values := children(parent, last.name)
+		if last.slice != "" {
+			values = sliceMembers(sds, values, parentID+"."+last.name, last.slice)
+		}
+
+		path := parent.path + "." + last.name
+		if last.slice != "" {
+			path += ":" + last.slice
+		}
+		if e.Min != nil && len(values) < *e.Min {
+			fail("required", path, "minimum required = %d, but only found %d", *e.Min, len(values))
+		}
+		if e.Max != "" && e.Max != "*" {
+			if max, err := strconv.Atoi(e.Max); err == nil && len(values) > max {
+				fail("structure", path, "maximum allowed = %d, but found %d", max, len(values))
+			}
+		}
+
+		for _, v := range values {
+			if len(e.Type) > 0 {
+				if problem := checkType(v, last.name, e.Type); problem != "" {
+					fail("value", v.path, "%s", problem)
+					continue
+				}
+			}
+			if e.Fixed != nil && !reflect.DeepEqual(v.value, e.Fixed) {
+				fail("value", v.path, "value must be exactly %s", jsonString(e.Fixed))
+			}
+			if e.Pattern != nil && !matchesPattern(v.value, e.Pattern) {
+				fail("value", v.path, "value must match %s", jsonString(e.Pattern))
+			}
+		}
+	}
+	return issues
+}
+
+func jsonString(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// Expresiones regulares de los tipos primitivos de FHIR R4
+var (
+	codePattern     = regexp.MustCompile(`^[^\s]+( [^\s]+)*$`)
+	idPattern       = regexp.MustCompile(`^[A-Za-z0-9\-.]{1,64}$`)
+	datePattern     = regexp.MustCompile(`^([0-9]([0-9]([0-9][1-9]|[1-9]0)|[1-9]00)|[1-9]000)(-(0[1-9]|1[0-2])(-(0[1-9]|[1-2][0-9]|3[0-1]))?)?$`)
+	dateTimePattern = regexp.MustCompile(`^([0-9]([0-9]([0-9][1-9]|[1-9]0)|[1-9]00)|[1-9]000)(-(0[1-9]|1[0-2])(-(0[1-9]|[1-2][0-9]|3[0-1])(T([01][0-9]|2[0-3]):[0-5][0-9]:([0-5][0-9]|60)(\.[0-9]+)?(Z|(\+|-)((0[0-9]|1[0-3]):[0-5][0-9]|14:00)))?)?)?$`)
+)
+
+// checkType checks a value against the allowed types of its element and
+// returns a description of the problem, if any. For a choice element the
+// JSON name selects the type.
+func checkType(v node, name string, types []typeRef) string {
+	candidates := types
+	if base, isChoice := strings.CutSuffix(name, "[x]"); isChoice {
+		suffix := strings.TrimPrefix(v.key, base)
+		candidates = nil
+		for _, t := range types {
+			if strings.EqualFold(t.Code, suffix) {
+				candidates = append(candidates, t)
+			}
+		}
+		if len(candidates) == 0 {
+			allowed := make([]string, len(types))
+			for i, t := range types {
+				allowed[i] = base + strings.ToUpper(t.Code[:1]) + t.Code[1:]
+			}
+			return fmt.Sprintf("%s is not allowed here; expected %s", v.key, strings.Join(allowed, " or "))
+		}
+	}
+
+	var problem string
+	for _, t := range candidates {
+		if problem = checkPrimitive(v.value, t.Code); problem == "" {
+			return ""
+		}
+	}
+	return problem
+}
+
+func checkPrimitive(value any, code string) string {
+	s, isString := value.(string)
+	number, isNumber := value.(float64)
+	switch code {
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return "expected a boolean"
+		}
+	case "decimal":
+		if !isNumber {
+			return "expected a decimal number"
+		}
+	case "integer", "positiveInt", "unsignedInt":
+		if !isNumber || number != math.Trunc(number) {
+			return "expected an integer"
+		}
+		if (code == "positiveInt" && number < 1) || (code == "unsignedInt" && number < 0) {
+			return "integer out of range"
+		}
+	case "string", "markdown", "uri", "url", "canonical":
+		if !isString || strings.TrimSpace(s) == "" {
+			return "expected a non-empty string"
+		}
+	case "code":
+		if !isString || !codePattern.MatchString(s) {
+			return "expected a code"
+		}
+	case "id":
+		if !isString || !idPattern.MatchString(s) {
+			return "expected an id"
+		}
+	case "date":
+		if !isString || !datePattern.MatchString(s) {
+			return "expected a date (YYYY, YYYY-MM or YYYY-MM-DD)"
+		}
+	case "dateTime":
+		if !isString || !dateTimePattern.MatchString(s) {
+			return "expected a dateTime; times need seconds and a time zone"
+		}
+	default:
+		// Tipos complejos: solo se comprueba que sean objetos
+		if _, ok := value.(map[string]any); !ok {
+			return "expected a " + code + " object"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,117 @@
+package importer
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Registros de frecuencia cardíaca del export.xml de Apple Health
+const appleHeartRateType = "HKQuantityTypeIdentifierHeartRate"
+
+// Formato de las fechas de Apple Health, siempre con desplazamiento
+const appleDateLayout = "2006-01-02 15:04:05 -0700"
+
+type appleRecord struct {
+	Type      string `xml:"type,attr"`
+	Unit      string `xml:"unit,attr"`
+	StartDate string `xml:"startDate,attr"`
+	Value     string `xml:"value,attr"`
+	Metadata  []struct {
+		Key   string `xml:"key,attr"`
+		Value string `xml:"value,attr"`
+	} `xml:"MetadataEntry"`
+}
+
+// parseAppleHealth reads the heart rate records of an Apple Health
+// export.xml; every other record type is skipped
+func parseAppleHealth(r io.Reader, options Options, fn RecordFunc) error {
+	decoder := xml.NewDecoder(r)
+	found := false
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			if !found {
+				return errors.New("no Apple Health data found")
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid Apple Health export: %w", err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "HealthData":
+			found = true
+			continue
+		case "Record":
+			if attr(start, "type") == appleHeartRateType {
+				break
+			}
+			fallthrough
+		default:
+			if err := decoder.Skip(); err != nil {
+				return fmt.Errorf("invalid Apple Health export: %w", err)
+			}
+			continue
+		}
+
+		line, _ := decoder.InputPos()
+		var element appleRecord
+		if err := decoder.DecodeElement(&element, &start); err != nil {
+			return fmt.Errorf("invalid Apple Health export at line %d: %w", line, err)
+		}
+		record, err := appleHealthRecord(&element, options)
+		if err != nil {
+			err = &RecordError{Position: line, Err: err}
+		} else {
+			record.Position = line
+		}
+		if err := fn(record, err); err != nil {
+			return err
+		}
+	}
+}
+
+func appleHealthRecord(element *appleRecord, options Options) (*Record, error) {
+	if element.Unit != "" && element.Unit != "count/min" {
+		return nil, fmt.Errorf("unsupported heart rate unit %q", element.Unit)
+	}
+	t, err := time.ParseInLocation(appleDateLayout, element.StartDate, options.Location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start date %q", element.StartDate)
+	}
+	bpm, err := parseBPM(element.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &Record{Time: t, BPM: bpm}
+	// Contexto de movimiento del Apple Watch: 1 sedentario, 2 activo
+	for _, entry := range element.Metadata {
+		if entry.Key == "HKMetadataKeyHeartRateMotionContext" {
+			switch entry.Value {
+			case "1":
+				record.ReadingType = "resting"
+			case "2":
+				record.ReadingType = "active"
+			}
+		}
+	}
+	return record, nil
+}
+
+func attr(element xml.StartElement, name string) string {
+	for _, a := range element.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,188 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Campos de una lectura que se pueden leer de una columna CSV
+var csvFields = []string{
+	"time", "bpm", "reading_type", "source", "variability", "oxygen_level",
+	"systolic_pressure", "diastolic_pressure", "temperature", "activity_level",
+	"reliability_score", "notes",
+}
+
+// CSVFields lists the reading fields that can be mapped to CSV columns
+func CSVFields() []string {
+	return slices.Clone(csvFields)
+}
+
+// ValidateMapping checks that a CSV column mapping only names known fields
+func ValidateMapping(mapping map[string]string) error {
+	for field, column := range mapping {
+		if !slices.Contains(csvFields, field) {
+			return fmt.Errorf("unknown field %q", field)
+		}
+		if strings.TrimSpace(column) == "" {
+			return fmt.Errorf("field %q is mapped to an empty column", field)
+		}
+	}
+	return nil
+}
+
+// parseCSV reads a CSV file with a header row. time and bpm are required;
+// other mapped columns are read when present.
+func parseCSV(r io.Reader, options Options, fn RecordFunc) error {
+	if err := ValidateMapping(options.Mapping); err != nil {
+		return err
+	}
+
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+	reader.FieldsPerRecord = -1 // las columnas finales vacías pueden faltar
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return errors.New("the file is empty")
+		}
+		return fmt.Errorf("failed to read the header: %w", err)
+	}
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff") // BOM de Excel
+		}
+		positions[strings.TrimSpace(name)] = i
+	}
+
+	// Posición de la columna de cada campo presente en el archivo
+	columns := make(map[string]int, len(csvFields))
+	for _, field := range csvFields {
+		name, mapped := options.Mapping[field]
+		if !mapped {
+			name = field
+		}
+		i, ok := positions[name]
+		if !ok {
+			if mapped || field == "time" || field == "bpm" {
+				return fmt.Errorf("column %q for %s not found in the header", name, field)
+			}
+			continue
+		}
+		columns[field] = i
+	}
+
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		record, err := csvRecord(row, columns, options)
+		if err != nil {
+			err = &RecordError{Position: line, Err: err}
+		} else {
+			record.Position = line
+		}
+		if err := fn(record, err); err != nil {
+			return err
+		}
+	}
+}
+
+// csvRecord reads the mapped fields of one CSV row
+func csvRecord(row []string, columns map[string]int, options Options) (*Record, error) {
+	value := func(field string) string {
+		if i, ok := columns[field]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	var err error
+	record := &Record{
+		ReadingType: value("reading_type"),
+		Source:      value("source"),
+	}
+	if record.Time, err = parseCSVTime(value("time"), options); err != nil {
+		return nil, err
+	}
+	if record.BPM, err = parseBPM(value("bpm")); err != nil {
+		return nil, err
+	}
+
+	for field, target := range map[string]**int{
+		"oxygen_level":       &record.OxygenLevel,
+		"systolic_pressure":  &record.SystolicPressure,
+		"diastolic_pressure": &record.DiastolicPressure,
+		"activity_level":     &record.ActivityLevel,
+	} {
+		if text := value(field); text != "" {
+			n, err := strconv.Atoi(text)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %q", field, text)
+			}
+			*target = &n
+		}
+	}
+	for field, target := range map[string]**float64{
+		"variability":       &record.Variability,
+		"temperature":       &record.Temperature,
+		"reliability_score": &record.ReliabilityScore,
+	} {
+		if text := value(field); text != "" {
+			f, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %q", field, text)
+			}
+			*target = &f
+		}
+	}
+	if notes := value("notes"); notes != "" {
+		record.Notes = &notes
+	}
+	return record, nil
+}
+
+// parseCSVTime reads a time in the configured format. Layouts without an
+// offset are read in options.Location.
+func parseCSVTime(value string, options Options) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errors.New("time is empty")
+	}
+	switch options.TimeFormat {
+	case "unix", "unix_ms":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time %q", value)
+		}
+		if options.TimeFormat == "unix" {
+			return time.Unix(n, 0), nil
+		}
+		return time.UnixMilli(n), nil
+	case "":
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time %q, expected RFC 3339", value)
+		}
+		return t, nil
+	default:
+		t, err := time.ParseInLocation(options.TimeFormat, value, options.Location)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time %q for format %q", value, options.TimeFormat)
+		}
+		return t, nil
+	}
+}
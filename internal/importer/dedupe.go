@@ -0,0 +1,35 @@
+package importer
+
+import "time"
+
+// DedupeKey identifies a heart reading for deduplication: a patient's
+// readings with the same BPM in the same second are the same reading
+type DedupeKey struct {
+	Second int64
+	BPM    int
+}
+
+// KeyOf returns the deduplication key of a reading
+func KeyOf(t time.Time, bpm int) DedupeKey {
+	return DedupeKey{Second: t.Unix(), BPM: bpm}
+}
+
+// Deduplicator remembers the readings already read from a file
+type Deduplicator struct {
+	seen map[DedupeKey]bool
+}
+
+func NewDeduplicator() *Deduplicator {
+	return &Deduplicator{seen: map[DedupeKey]bool{}}
+}
+
+// Seen reports whether a reading with the same key was already read, and
+// remembers this one
+func (d *Deduplicator) Seen(t time.Time, bpm int) bool {
+	key := KeyOf(t, bpm)
+	if d.seen[key] {
+		return true
+	}
+	d.seen[key] = true
+	return false
+}
@@ -0,0 +1,162 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Formatos de fecha de Fitbit: Takeout escribe la hora en UTC; la API
+// intradía da la fecha del día y la hora local del usuario
+const (
+	fitbitTakeoutLayout = "01/02/06 15:04:05"
+	fitbitDateLayout    = time.DateOnly
+	fitbitTimeLayout    = time.TimeOnly
+)
+
+// fitbitSample is an entry of a Takeout heart_rate-YYYY-MM-DD.json file
+type fitbitSample struct {
+	DateTime string `json:"dateTime"`
+	Value    struct {
+		BPM        float64 `json:"bpm"`
+		Confidence *int    `json:"confidence"`
+	} `json:"value"`
+}
+
+// fitbitIntradaySample is an entry of the intraday heart rate dataset of the
+// Fitbit Web API
+type fitbitIntradaySample struct {
+	Time  string  `json:"time"`
+	Value float64 `json:"value"`
+}
+
+// parseFitbit reads a Fitbit heart rate dump: a Takeout heart_rate file (a
+// JSON array) or an intraday heart rate response of the Web API (an object
+// whose times are local to options.Location)
+func parseFitbit(r io.Reader, options Options, fn RecordFunc) error {
+	decoder := json.NewDecoder(r)
+	token, err := decoder.Token()
+	if err != nil {
+		return fmt.Errorf("invalid Fitbit export: %w", err)
+	}
+
+	switch token {
+	case json.Delim('['):
+		err = decodeElements(decoder, func(position int, sample *fitbitSample, err error) error {
+			if err != nil {
+				return fn(nil, err)
+			}
+			record, err := fitbitRecord(sample)
+			if err != nil {
+				return fn(nil, &RecordError{Position: position, Err: err})
+			}
+			record.Position = position
+			return fn(record, nil)
+		})
+	case json.Delim('{'):
+		err = parseFitbitIntraday(decoder, options, fn)
+	default:
+		return errors.New("invalid Fitbit export: expected an array or an object")
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid Fitbit export: %w", err)
+	}
+	return nil
+}
+
+func fitbitRecord(sample *fitbitSample) (*Record, error) {
+	t, err := time.Parse(fitbitTakeoutLayout, sample.DateTime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dateTime %q", sample.DateTime)
+	}
+	bpm, err := bpmFromFloat(sample.Value.BPM)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &Record{Time: t, BPM: bpm}
+	// Confianza de Fitbit de 0 a 3
+	if c := sample.Value.Confidence; c != nil && *c >= 0 && *c <= 3 {
+		reliability := float64(*c) / 3
+		record.ReliabilityScore = &reliability
+	}
+	return record, nil
+}
+
+// parseFitbitIntraday reads an intraday response. The day comes from
+// "activities-heart", which the API writes before the dataset.
+func parseFitbitIntraday(decoder *json.Decoder, options Options, fn RecordFunc) error {
+	var day *time.Time
+	for {
+		key, err := seekKey(decoder, "activities-heart", "activities-heart-intraday")
+		if err != nil {
+			return err
+		}
+		switch key {
+		case "":
+			if day == nil {
+				return errors.New("no Fitbit heart rate data found")
+			}
+			return nil
+		case "activities-heart":
+			var summaries []struct {
+				DateTime string `json:"dateTime"`
+			}
+			if err := decoder.Decode(&summaries); err != nil {
+				return err
+			}
+			if len(summaries) != 1 {
+				return errors.New("the intraday response must cover exactly one day")
+			}
+			parsed, err := time.ParseInLocation(fitbitDateLayout, summaries[0].DateTime, options.Location)
+			if err != nil {
+				return fmt.Errorf("invalid dateTime %q", summaries[0].DateTime)
+			}
+			day = &parsed
+		case "activities-heart-intraday":
+			if day == nil {
+				return errors.New(`"activities-heart" must come before the intraday dataset`)
+			}
+			if err := parseFitbitDataset(decoder, *day, fn); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// parseFitbitDataset reads the "dataset" of an intraday object
+func parseFitbitDataset(decoder *json.Decoder, day time.Time, fn RecordFunc) error {
+	if err := expectDelim(decoder, json.Delim('{')); err != nil {
+		return err
+	}
+	for {
+		key, err := seekKey(decoder, "dataset")
+		if err != nil || key == "" {
+			return err
+		}
+		if err := expectDelim(decoder, json.Delim('[')); err != nil {
+			return err
+		}
+		err = decodeElements(decoder, func(position int, sample *fitbitIntradaySample, err error) error {
+			if err != nil {
+				return fn(nil, err)
+			}
+			clock, err := time.Parse(fitbitTimeLayout, sample.Time)
+			if err != nil {
+				return fn(nil, &RecordError{Position: position, Err: fmt.Errorf("invalid time %q", sample.Time)})
+			}
+			bpm, err := bpmFromFloat(sample.Value)
+			if err != nil {
+				return fn(nil, &RecordError{Position: position, Err: err})
+			}
+			t := time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, day.Location())
+			return fn(&Record{Position: position, Time: t, BPM: bpm}, nil)
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
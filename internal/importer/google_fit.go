@@ -0,0 +1,111 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tipo de dato de Google Fit para la frecuencia cardíaca
+const googleFitHeartRateType = "com.google.heart_rate.bpm"
+
+// fitPoint is a data point of Google Fit. Takeout writes the value in
+// fitValue and numbers as JSON numbers; the REST API writes it in value and
+// the times as strings.
+type fitPoint struct {
+	DataTypeName   string     `json:"dataTypeName"`
+	StartTimeNanos fitInt64   `json:"startTimeNanos"`
+	Value          []fitValue `json:"value"`
+	FitValue       []struct {
+		Value fitValue `json:"value"`
+	} `json:"fitValue"`
+}
+
+type fitValue struct {
+	FpVal  *float64 `json:"fpVal"`
+	IntVal *int64   `json:"intVal"`
+}
+
+// fitInt64 accepts an integer written as a number or as a string
+type fitInt64 int64
+
+func (n *fitInt64) UnmarshalJSON(data []byte) error {
+	value, err := strconv.ParseInt(strings.Trim(string(data), `"`), 10, 64)
+	if err != nil {
+		// Como error de tipo, el punto se rechaza sin abandonar el archivo
+		return &json.UnmarshalTypeError{Value: string(data), Type: reflect.TypeFor[int64]()}
+	}
+	*n = fitInt64(value)
+	return nil
+}
+
+// parseGoogleFit reads the heart rate points of a Google Fit dataset, either
+// a Takeout "All Data" file ("Data Points") or a REST API response ("point")
+func parseGoogleFit(r io.Reader, fn RecordFunc) error {
+	decoder := json.NewDecoder(r)
+	if err := expectDelim(decoder, json.Delim('{')); err != nil {
+		return fmt.Errorf("invalid Google Fit export: %w", err)
+	}
+	key, err := seekKey(decoder, "Data Points", "point")
+	if err != nil {
+		return fmt.Errorf("invalid Google Fit export: %w", err)
+	}
+	if key == "" {
+		return errors.New("no Google Fit data points found")
+	}
+	if err := expectDelim(decoder, json.Delim('[')); err != nil {
+		return fmt.Errorf("invalid Google Fit export: %w", err)
+	}
+
+	err = decodeElements(decoder, func(position int, point *fitPoint, err error) error {
+		if err != nil {
+			return fn(nil, err)
+		}
+		if point.DataTypeName != "" && point.DataTypeName != googleFitHeartRateType {
+			return nil
+		}
+		record, err := googleFitRecord(point)
+		if err != nil {
+			return fn(nil, &RecordError{Position: position, Err: err})
+		}
+		record.Position = position
+		return fn(record, nil)
+	})
+	if err != nil {
+		return fmt.Errorf("invalid Google Fit export: %w", err)
+	}
+	return nil
+}
+
+func googleFitRecord(point *fitPoint) (*Record, error) {
+	if point.StartTimeNanos <= 0 {
+		return nil, errors.New("startTimeNanos is missing")
+	}
+	values := point.Value
+	for _, v := range point.FitValue {
+		values = append(values, v.Value)
+	}
+	if len(values) == 0 {
+		return nil, errors.New("the data point has no value")
+	}
+
+	var bpm int
+	var err error
+	switch {
+	case values[0].FpVal != nil:
+		bpm, err = bpmFromFloat(*values[0].FpVal)
+	case values[0].IntVal != nil:
+		bpm = int(*values[0].IntVal)
+	default:
+		err = errors.New("the data point has no numeric value")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Record{Time: time.Unix(0, int64(point.StartTimeNanos)), BPM: bpm}, nil
+}
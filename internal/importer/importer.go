@@ -0,0 +1,133 @@
+// Package importer reads historical heart readings from files: CSV with a
+// column mapping and the heart rate exports of Apple Health (export.xml),
+// Google Fit and Fitbit (JSON). Files are read one record at a time so that
+// exports of any size run in constant memory.
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatCSV         = "csv"
+	FormatAppleHealth = "apple_health"
+	FormatGoogleFit   = "google_fit"
+	FormatFitbit      = "fitbit"
+)
+
+// Formats lists the supported import formats
+var Formats = []string{FormatCSV, FormatAppleHealth, FormatGoogleFit, FormatFitbit}
+
+// Sources gives the reading source recorded for each vendor format. CSV
+// readings take theirs from the file or from the import request.
+var Sources = map[string]string{
+	FormatAppleHealth: "apple_health",
+	FormatGoogleFit:   "google_fit",
+	FormatFitbit:      "fitbit",
+}
+
+// Record is one heart rate sample read from a file. Optional fields are nil
+// when the file does not provide them.
+type Record struct {
+	Position          int // línea en CSV y XML, número de punto en JSON
+	Time              time.Time
+	BPM               int
+	ReadingType       string // vacío si el archivo no lo indica
+	Source            string // vacío si el archivo no lo indica
+	Variability       *float64
+	OxygenLevel       *int
+	SystolicPressure  *int
+	DiastolicPressure *int
+	Temperature       *float64
+	ActivityLevel     *int
+	ReliabilityScore  *float64
+	Notes             *string
+}
+
+// Options controls how a file is read
+type Options struct {
+	// Mapping maps reading fields to CSV column names; fields left out are
+	// read from the column with the field's own name
+	Mapping map[string]string
+	// TimeFormat is the CSV time layout: a Go layout, "unix" or "unix_ms".
+	// RFC 3339 by default.
+	TimeFormat string
+	// Location is the zone of times written without an offset
+	Location *time.Location
+}
+
+// RecordError reports a record that could not be read. The rest of the file
+// is still read.
+type RecordError struct {
+	Position int
+	Err      error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("record %d: %v", e.Position, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// RecordFunc receives each record read, or a *RecordError for one that could
+// not be read. Returning an error stops the import.
+type RecordFunc func(record *Record, err error) error
+
+// Parse reads the records of a file in the given format and passes them to fn
+// in file order. It returns an error when the file itself is malformed, or
+// the error with which fn stopped the import.
+func Parse(r io.Reader, format string, options Options, fn RecordFunc) error {
+	if options.Location == nil {
+		options.Location = time.UTC
+	}
+
+	// Los errores de fn se devuelven tal cual, sin el contexto del formato
+	var stopped error
+	visit := func(record *Record, err error) error {
+		stopped = fn(record, err)
+		return stopped
+	}
+
+	var err error
+	switch format {
+	case FormatCSV:
+		err = parseCSV(r, options, visit)
+	case FormatAppleHealth:
+		err = parseAppleHealth(r, options, visit)
+	case FormatGoogleFit:
+		err = parseGoogleFit(r, visit)
+	case FormatFitbit:
+		err = parseFitbit(r, options, visit)
+	default:
+		return fmt.Errorf("unknown import format %q", format)
+	}
+	if stopped != nil {
+		return stopped
+	}
+	return err
+}
+
+// parseBPM reads a heart rate; vendors write it as a decimal number
+func parseBPM(value string) (int, error) {
+	bpm, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || math.IsNaN(bpm) || math.IsInf(bpm, 0) {
+		return 0, fmt.Errorf("invalid heart rate %q", value)
+	}
+	return int(math.Round(bpm)), nil
+}
+
+// bpmFromFloat rounds a heart rate given as a JSON number
+func bpmFromFloat(value float64) (int, error) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, errors.New("invalid heart rate")
+	}
+	return int(math.Round(value)), nil
+}
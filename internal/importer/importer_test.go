@@ -0,0 +1,354 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// want is what a test expects of one record read from a file
+type want struct {
+	position    int
+	time        time.Time
+	bpm         int
+	readingType string
+}
+
+type parseCase struct {
+	name    string
+	input   string
+	options Options
+	records []want
+	issues  []int // posiciones de los registros rechazados
+	err     bool  // el archivo entero no se puede leer
+}
+
+func runParseCases(t *testing.T, format string, tests []parseCase) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, issues, err := parseAll(format, tt.input, tt.options)
+			if tt.err {
+				if err == nil {
+					t.Fatal("Parse() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			if len(records) != len(tt.records) {
+				t.Fatalf("got %d records, want %d: %+v", len(records), len(tt.records), records)
+			}
+			for i, r := range records {
+				w := tt.records[i]
+				if r.Position != w.position || !r.Time.Equal(w.time) || r.BPM != w.bpm || r.ReadingType != w.readingType {
+					t.Errorf("record %d = {%d %s %d %q}, want {%d %s %d %q}", i,
+						r.Position, r.Time.Format(time.RFC3339), r.BPM, r.ReadingType,
+						w.position, w.time.Format(time.RFC3339), w.bpm, w.readingType)
+				}
+			}
+
+			if len(issues) != len(tt.issues) {
+				t.Fatalf("got record errors %v, want at positions %v", issues, tt.issues)
+			}
+			for i, issue := range issues {
+				if issue.Position != tt.issues[i] {
+					t.Errorf("record error %d at position %d (%v), want %d", i, issue.Position, issue.Err, tt.issues[i])
+				}
+			}
+		})
+	}
+}
+
+// parseAll reads a whole file, collecting its records and record errors
+func parseAll(format, input string, options Options) ([]*Record, []*RecordError, error) {
+	var records []*Record
+	var issues []*RecordError
+	err := Parse(strings.NewReader(input), format, options, func(record *Record, err error) error {
+		if err != nil {
+			var recordErr *RecordError
+			if !errors.As(err, &recordErr) {
+				return err
+			}
+			issues = append(issues, recordErr)
+			return nil
+		}
+		records = append(records, record)
+		return nil
+	})
+	return records, issues, err
+}
+
+func utc(hour, minute, second int) time.Time {
+	return time.Date(2026, 10, 19, hour, minute, second, 0, time.UTC)
+}
+
+func TestParseCSV(t *testing.T) {
+	lima := time.FixedZone("America/Lima", -5*60*60)
+
+	runParseCases(t, FormatCSV, []parseCase{
+		{
+			name: "default columns",
+			input: "time,bpm,reading_type,oxygen_level\n" +
+				"2026-10-19T08:00:00Z,72,resting,98\n" +
+				"2026-10-19T08:01:00Z,74.6\n" +
+				"2026-10-19T03:02:00-05:00, 80 ,active,\n",
+			records: []want{
+				{2, utc(8, 0, 0), 72, "resting"},
+				{3, utc(8, 1, 0), 75, ""},
+				{4, utc(8, 2, 0), 80, "active"},
+			},
+		},
+		{
+			name: "malformed rows",
+			input: "time,bpm,oxygen_level\n" +
+				"2026-10-19T08:00:00Z,72,98\n" +
+				"19/10/2026 08:01,70,\n" +
+				"2026-10-19T08:02:00Z,fast,\n" +
+				",70,\n" +
+				"2026-10-19T08:04:00Z,NaN,\n" +
+				"2026-10-19T08:05:00Z,75,97.5\n" +
+				"2026-10-19T08:06:00Z,76,97\n",
+			records: []want{{2, utc(8, 0, 0), 72, ""}, {8, utc(8, 6, 0), 76, ""}},
+			issues:  []int{3, 4, 5, 6, 7},
+		},
+		{
+			name:    "mapping and time format",
+			input:   "\ufeffFecha,Pulso,Tipo\n2026-10-19 08:00,64,sleep\n",
+			options: Options{Mapping: map[string]string{"time": "Fecha", "bpm": "Pulso", "reading_type": "Tipo"}, TimeFormat: "2006-01-02 15:04", Location: lima},
+			records: []want{{2, utc(13, 0, 0), 64, "sleep"}},
+		},
+		{
+			name:    "unix milliseconds",
+			input:   "time,bpm\n1792396800000,60\n1792396800x,61\n",
+			options: Options{TimeFormat: "unix_ms"},
+			records: []want{{2, utc(8, 0, 0), 60, ""}},
+			issues:  []int{3},
+		},
+		{"empty file", "", Options{}, nil, nil, true},
+		{"missing bpm column", "time,heart_rate\n2026-10-19T08:00:00Z,72\n", Options{}, nil, nil, true},
+		{"mapped column not found", "time,bpm\n", Options{Mapping: map[string]string{"notes": "Comentario"}}, nil, nil, true},
+		{"unknown mapped field", "time,bpm\n", Options{Mapping: map[string]string{"pulse": "bpm"}}, nil, nil, true},
+		{"unterminated quote", "time,bpm,notes\n2026-10-19T08:00:00Z,72,\"open\n", Options{}, nil, nil, true},
+	})
+}
+
+func TestParseCSVOptionalFields(t *testing.T) {
+	input := "time,bpm,variability,systolic_pressure,diastolic_pressure,temperature,activity_level,reliability_score,source,notes\n" +
+		"2026-10-19T08:00:00Z,72,41.5,120,80,36.6,1,0.9,chest_strap,after coffee\n" +
+		"2026-10-19T08:01:00Z,73,,,,,,,,\n"
+
+	records, issues, err := parseAll(FormatCSV, input, Options{})
+	if err != nil || len(issues) != 0 || len(records) != 2 {
+		t.Fatalf("Parse() = %d records, issues %v, error %v", len(records), issues, err)
+	}
+
+	r := records[0]
+	if *r.Variability != 41.5 || *r.SystolicPressure != 120 || *r.DiastolicPressure != 80 || *r.Temperature != 36.6 ||
+		*r.ActivityLevel != 1 || *r.ReliabilityScore != 0.9 || r.Source != "chest_strap" || *r.Notes != "after coffee" {
+		t.Errorf("record = %+v, want every optional field read", r)
+	}
+
+	r = records[1]
+	if r.Variability != nil || r.SystolicPressure != nil || r.DiastolicPressure != nil || r.Temperature != nil ||
+		r.ActivityLevel != nil || r.ReliabilityScore != nil || r.Source != "" || r.Notes != nil {
+		t.Errorf("record = %+v, want empty cells left nil", r)
+	}
+}
+
+func TestParseAppleHealth(t *testing.T) {
+	const header = `<?xml version="1.0" encoding="UTF-8"?>` + "\n"
+
+	runParseCases(t, FormatAppleHealth, []parseCase{
+		{
+			name: "heart rate records",
+			input: header +
+				`<HealthData locale="en_US">` + "\n" +
+				` <Me HKCharacteristicTypeIdentifierDateOfBirth="1970-01-01"/>` + "\n" +
+				` <Record type="HKQuantityTypeIdentifierStepCount" unit="count" startDate="2026-10-19 03:00:00 -0500" value="100"/>` + "\n" +
+				` <Record type="HKQuantityTypeIdentifierHeartRate" unit="count/min" startDate="2026-10-19 03:00:00 -0500" value="71.5">` + "\n" +
+				`  <MetadataEntry key="HKMetadataKeyHeartRateMotionContext" value="1"/>` + "\n" +
+				` </Record>` + "\n" +
+				` <Record type="HKQuantityTypeIdentifierHeartRate" unit="count/min" startDate="2026-10-19 03:05:00 -0500" value="120">` + "\n" +
+				`  <MetadataEntry key="HKMetadataKeyHeartRateMotionContext" value="2"/>` + "\n" +
+				` </Record>` + "\n" +
+				` <Record type="HKQuantityTypeIdentifierHeartRate" unit="count/min" startDate="2026-10-19 08:10:00 +0000" value="64"/>` + "\n" +
+				`</HealthData>` + "\n",
+			records: []want{
+				{5, utc(8, 0, 0), 72, "resting"},
+				{8, utc(8, 5, 0), 120, "active"},
+				{11, utc(8, 10, 0), 64, ""},
+			},
+		},
+		{
+			name: "malformed records",
+			input: header +
+				`<HealthData locale="en_US">` + "\n" +
+				` <Record type="HKQuantityTypeIdentifierHeartRate" unit="count/s" startDate="2026-10-19 08:00:00 +0000" value="1.2"/>` + "\n" +
+				` <Record type="HKQuantityTypeIdentifierHeartRate" unit="count/min" startDate="2026-10-19T08:01:00Z" value="70"/>` + "\n" +
+				` <Record type="HKQuantityTypeIdentifierHeartRate" unit="count/min" startDate="2026-10-19 08:02:00 +0000" value=""/>` + "\n" +
+				` <Record type="HKQuantityTypeIdentifierHeartRate" unit="count/min" startDate="2026-10-19 08:03:00 +0000" value="66"/>` + "\n" +
+				`</HealthData>` + "\n",
+			records: []want{{6, utc(8, 3, 0), 66, ""}},
+			issues:  []int{3, 4, 5},
+		},
+		{"not an Apple Health export", header + "<Export/>\n", Options{}, nil, nil, true},
+		{"malformed XML", header + `<HealthData><Record type="HKQuantityTypeIdentifierHeartRate" value="70">` + "\n", Options{}, nil, nil, true},
+	})
+}
+
+func TestParseGoogleFit(t *testing.T) {
+	runParseCases(t, FormatGoogleFit, []parseCase{
+		{
+			name: "takeout",
+			input: `{"Data Source": "raw:com.google.heart_rate.bpm:watch", "Data Points": [
+				{"dataTypeName": "com.google.heart_rate.bpm", "startTimeNanos": 1792396800000000000, "fitValue": [{"value": {"fpVal": 64.4}}]},
+				{"dataTypeName": "com.google.step_count.delta", "startTimeNanos": 1792396800000000000, "fitValue": [{"value": {"intVal": 12}}]},
+				{"dataTypeName": "com.google.heart_rate.bpm", "startTimeNanos": 1792396860000000000, "fitValue": [{"value": {"intVal": 80}}]}
+			]}`,
+			records: []want{{1, utc(8, 0, 0), 64, ""}, {3, utc(8, 1, 0), 80, ""}},
+		},
+		{
+			name: "REST API",
+			input: `{"minStartTimeNs": "1792396800000000000", "dataSourceId": "derived:com.google.heart_rate.bpm", "point": [
+				{"startTimeNanos": "1792396800000000000", "endTimeNanos": "1792396800000000000", "dataTypeName": "com.google.heart_rate.bpm", "value": [{"fpVal": 90}]}
+			]}`,
+			records: []want{{1, utc(8, 0, 0), 90, ""}},
+		},
+		{
+			name: "malformed points",
+			input: `{"Data Points": [
+				{"dataTypeName": "com.google.heart_rate.bpm", "startTimeNanos": "soon", "fitValue": [{"value": {"fpVal": 70}}]},
+				{"dataTypeName": "com.google.heart_rate.bpm", "startTimeNanos": 1792396800000000000, "fitValue": []},
+				{"dataTypeName": "com.google.heart_rate.bpm", "fitValue": [{"value": {"fpVal": 70}}]},
+				{"dataTypeName": "com.google.heart_rate.bpm", "startTimeNanos": 1792396800000000000, "fitValue": [{"value": {"stringVal": "70"}}]},
+				{"dataTypeName": "com.google.heart_rate.bpm", "startTimeNanos": 1792396920000000000, "fitValue": [{"value": {"fpVal": 71}}]}
+			]}`,
+			records: []want{{5, utc(8, 2, 0), 71, ""}},
+			issues:  []int{1, 2, 3, 4},
+		},
+		{"no data points", `{"Data Source": "raw:com.google.heart_rate.bpm:watch"}`, Options{}, nil, nil, true},
+		{"not an object", `[]`, Options{}, nil, nil, true},
+		{"truncated", `{"Data Points": [{"startTimeNanos": 1792396800000000000`, Options{}, nil, nil, true},
+	})
+}
+
+func TestParseFitbit(t *testing.T) {
+	lima := time.FixedZone("America/Lima", -5*60*60)
+
+	runParseCases(t, FormatFitbit, []parseCase{
+		{
+			name: "takeout",
+			input: `[
+				{"dateTime": "10/19/26 08:00:00", "value": {"bpm": 61, "confidence": 3}},
+				{"dateTime": "10/19/26 08:00:05", "value": {"bpm": 62.6, "confidence": 0}}
+			]`,
+			records: []want{{1, utc(8, 0, 0), 61, ""}, {2, utc(8, 0, 5), 63, ""}},
+		},
+		{
+			name: "takeout malformed samples",
+			input: `[
+				{"dateTime": "2026-10-19 08:00:00", "value": {"bpm": 60, "confidence": 2}},
+				{"dateTime": "10/19/26 08:00:10", "value": {"bpm": "fast"}},
+				{"dateTime": "10/19/26 08:00:15", "value": {"bpm": 64}}
+			]`,
+			records: []want{{3, utc(8, 0, 15), 64, ""}},
+			issues:  []int{1, 2},
+		},
+		{
+			name: "intraday",
+			input: `{
+				"activities-heart": [{"dateTime": "2026-10-19", "value": {"restingHeartRate": 58}}],
+				"activities-heart-intraday": {
+					"dataset": [{"time": "03:00:00", "value": 64}, {"time": "25:00:00", "value": 65}, {"time": "03:01:00", "value": 66}],
+					"datasetInterval": 1,
+					"datasetType": "minute"
+				}
+			}`,
+			options: Options{Location: lima},
+			records: []want{{1, utc(8, 0, 0), 64, ""}, {3, utc(8, 1, 0), 66, ""}},
+			issues:  []int{2},
+		},
+		{"intraday without day", `{"activities-heart-intraday": {"dataset": [{"time": "08:00:00", "value": 64}]}}`, Options{}, nil, nil, true},
+		{"intraday over several days", `{"activities-heart": [{"dateTime": "2026-10-19"}, {"dateTime": "2026-10-20"}]}`, Options{}, nil, nil, true},
+		{"no heart rate data", `{"activities-steps": []}`, Options{}, nil, nil, true},
+		{"not JSON", `dateTime,bpm`, Options{}, nil, nil, true},
+	})
+}
+
+func TestFitbitConfidence(t *testing.T) {
+	input := `[
+		{"dateTime": "10/19/26 08:00:00", "value": {"bpm": 61, "confidence": 3}},
+		{"dateTime": "10/19/26 08:00:05", "value": {"bpm": 62, "confidence": 0}},
+		{"dateTime": "10/19/26 08:00:10", "value": {"bpm": 63, "confidence": 7}},
+		{"dateTime": "10/19/26 08:00:15", "value": {"bpm": 64}}
+	]`
+	records, _, err := parseAll(FormatFitbit, input, Options{})
+	if err != nil || len(records) != 4 {
+		t.Fatalf("Parse() = %d records, error %v", len(records), err)
+	}
+
+	for i, want := range []*float64{ptr(1.0), ptr(0.0), nil, nil} {
+		got := records[i].ReliabilityScore
+		if (got == nil) != (want == nil) || (got != nil && *got != *want) {
+			t.Errorf("record %d reliability = %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestParseStops(t *testing.T) {
+	stop := errors.New("stop")
+	input := "time,bpm\n2026-10-19T08:00:00Z,60\n2026-10-19T08:01:00Z,61\n"
+
+	calls := 0
+	err := Parse(strings.NewReader(input), FormatCSV, Options{}, func(*Record, error) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("Parse() = %v after %d records, want the callback error after 1", err, calls)
+	}
+
+	if err := Parse(strings.NewReader(input), "xlsx", Options{}, nil); err == nil {
+		t.Error("Parse(xlsx) error = nil, want an error")
+	}
+}
+
+func TestDeduplicator(t *testing.T) {
+	base := utc(8, 0, 0)
+	lima := time.FixedZone("America/Lima", -5*60*60)
+
+	tests := []struct {
+		name      string
+		time      time.Time
+		bpm       int
+		duplicate bool
+	}{
+		{"first", base, 70, false},
+		{"same reading", base, 70, true},
+		{"same second", base.Add(999 * time.Millisecond), 70, true},
+		{"same instant in another zone", base.In(lima), 70, true},
+		{"other heart rate", base, 71, false},
+		{"next second", base.Add(time.Second), 70, false},
+		{"next second again", base.Add(1500 * time.Millisecond), 70, true},
+	}
+
+	d := NewDeduplicator()
+	for _, tt := range tests {
+		if got := d.Seen(tt.time, tt.bpm); got != tt.duplicate {
+			t.Errorf("%s: Seen(%s, %d) = %v, want %v", tt.name, tt.time.Format(time.RFC3339Nano), tt.bpm, got, tt.duplicate)
+		}
+	}
+
+	// La misma clave que buscan los lotes en las lecturas guardadas
+	if KeyOf(base.Add(500*time.Millisecond), 70) != KeyOf(base, 70) {
+		t.Error("KeyOf() differs within the same second")
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,69 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// seekKey reads the keys of the object being decoded, skipping their values,
+// until one of keys. It returns the key found, or "" once the object ends.
+func seekKey(decoder *json.Decoder, keys ...string) (string, error) {
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return "", fmt.Errorf("unexpected %v", token)
+		}
+		if slices.Contains(keys, key) {
+			return key, nil
+		}
+		var skip json.RawMessage
+		if err := decoder.Decode(&skip); err != nil {
+			return "", err
+		}
+	}
+	// Cierre del objeto
+	if _, err := decoder.Token(); err != nil {
+		return "", err
+	}
+	return "", nil
+}
+
+// expectDelim reads the next token and checks that it opens or closes an
+// array or object
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("expected %v, found %v", delim, token)
+	}
+	return nil
+}
+
+// decodeElements decodes the elements of the array being read one at a time.
+// A value of the wrong type is reported as a record error; any other decoding
+// error ends the file.
+func decodeElements[T any](decoder *json.Decoder, fn func(position int, element *T, err error) error) error {
+	for position := 1; decoder.More(); position++ {
+		var element T
+		err := decoder.Decode(&element)
+		var typeErr *json.UnmarshalTypeError
+		if err != nil && !errors.As(err, &typeErr) {
+			return err
+		}
+		if err != nil {
+			err = &RecordError{Position: position, Err: err}
+		}
+		if err := fn(position, &element, err); err != nil {
+			return err
+		}
+	}
+	return expectDelim(decoder, json.Delim(']'))
+}
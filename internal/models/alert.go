@@ -9,6 +9,8 @@ import (
 type Alert struct {
 	ID             uuid.UUID  `json:"id"`
 	PatientID      uuid.UUID  `json:"patient_id"`
+	DeviceID       *uuid.UUID `json:"device_id,omitempty"`  // solo en alertas de dispositivo
+	ReadingID      *uuid.UUID `json:"reading_id,omitempty"` // lectura que originó la alerta
 	AlertType      string     `json:"alert_type"`
 	Severity       string     `json:"severity"` // low, medium, high, critical
 	Message        string     `json:"message"`
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Categoría de las anotaciones que marcan datos a descartar en las estadísticas
+const AnnotationArtifact = "artifact"
+
+// Annotation is a clinician's note attached to a heart reading or to a time
+// range of a patient. Reading annotations span the reading's time.
+type Annotation struct {
+	ID        uuid.UUID  `json:"id"`
+	PatientID uuid.UUID  `json:"patient_id"`
+	ReadingID *uuid.UUID `json:"reading_id,omitempty"`
+	StartTime time.Time  `json:"start_time"`
+	EndTime   time.Time  `json:"end_time"`
+	Category  string     `json:"category"` // 'note', 'medication', 'artifact', 'diagnosis', 'event'
+	Text      string     `json:"text"`
+	AuthorID  *uuid.UUID `json:"author_id,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// AnnotationCreateRequest represents the request to annotate a reading
+// (reading_id) or a time range (start_time and, optionally, end_time)
+type AnnotationCreateRequest struct {
+	PatientID uuid.UUID  `json:"patient_id" validate:"required"`
+	ReadingID *uuid.UUID `json:"reading_id,omitempty"`
+	StartTime *time.Time `json:"start_time,omitempty"`
+	EndTime   *time.Time `json:"end_time,omitempty"`
+	Category  string     `json:"category" validate:"required,oneof=note medication artifact diagnosis event"`
+	Text      string     `json:"text" validate:"required,max=2000"`
+}
+
+// AnnotationUpdateRequest represents the request to update an annotation.
+// The time range can only be changed on range annotations.
+type AnnotationUpdateRequest struct {
+	StartTime *time.Time `json:"start_time,omitempty"`
+	EndTime   *time.Time `json:"end_time,omitempty"`
+	Category  *string    `json:"category,omitempty" validate:"omitempty,oneof=note medication artifact diagnosis event"`
+	Text      *string    `json:"text,omitempty" validate:"omitempty,min=1,max=2000"`
+}
+
+// AnnotationQueryParams represents the filters of a patient's annotations.
+// An annotation matches when its range overlaps [StartTime, EndTime].
+type AnnotationQueryParams struct {
+	PatientID uuid.UUID  `json:"patient_id" validate:"required"`
+	StartTime *time.Time `json:"start_time,omitempty"`
+	EndTime   *time.Time `json:"end_time,omitempty"`
+	Category  *string    `json:"category,omitempty" validate:"omitempty,oneof=note medication artifact diagnosis event"`
+	ReadingID *uuid.UUID `json:"reading_id,omitempty"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PatientBaseline represents a patient's personal heart rate baseline for a reading type
+type PatientBaseline struct {
+	PatientID   uuid.UUID        `json:"patient_id"`
+	ReadingType string           `json:"reading_type"`
+	WindowDays  int              `json:"window_days"`
+	SampleCount int              `json:"sample_count"`
+	MedianBPM   float64          `json:"median_bpm"`
+	Q1BPM       float64          `json:"q1_bpm"`
+	Q3BPM       float64          `json:"q3_bpm"`
+	IQR         float64          `json:"iqr"`
+	Hourly      []HourlyBaseline `json:"hourly"`
+	ComputedAt  time.Time        `json:"computed_at"`
+}
+
+// HourlyBaseline represents the baseline for one hour of the day (circadian profile)
+type HourlyBaseline struct {
+	Hour        int     `json:"hour"`
+	SampleCount int     `json:"sample_count"`
+	MedianBPM   float64 `json:"median_bpm"`
+	Q1BPM       float64 `json:"q1_bpm"`
+	Q3BPM       float64 `json:"q3_bpm"`
+}
@@ -45,3 +45,41 @@ type DeviceUpdateRequest struct {
 	BatteryLevel    *int       `json:"battery_level" validate:"omitempty,min=0,max=100"`
 	IsActive        *bool      `json:"is_active" validate:"omitempty"`
 }
+
+// DeviceQueryParams represents filters, sorting and pagination for the device fleet listing
+type DeviceQueryParams struct {
+	DeviceType     *string    `json:"device_type,omitempty"`
+	IsActive       *bool      `json:"is_active,omitempty"`
+	Assigned       *bool      `json:"assigned,omitempty"`
+	BatteryBelow   *int       `json:"battery_below,omitempty"`
+	LastSyncBefore *time.Time `json:"last_sync_before,omitempty"` // Incluye dispositivos que nunca sincronizaron
+	Search         *string    `json:"search,omitempty"`           // Búsqueda parcial por número de serie
+	SortBy         string     `json:"sort_by" validate:"oneof=registered_at serial_number last_sync battery_level"`
+	SortOrder      string     `json:"sort_order" validate:"oneof=asc desc"`
+	Cursor         *string    `json:"cursor,omitempty"`
+	Limit          int        `json:"limit" validate:"min=1,max=500"`
+}
+
+// DeviceListResponse represents a page of devices
+type DeviceListResponse struct {
+	Data       []*DeviceResponse `json:"data"`
+	NextCursor *string           `json:"next_cursor,omitempty"`
+}
+
+// DeviceAssignment represents a period during which a device belonged to a patient
+type DeviceAssignment struct {
+	ID           uuid.UUID  `json:"id"`
+	DeviceID     uuid.UUID  `json:"device_id"`
+	PatientID    uuid.UUID  `json:"patient_id"`
+	AssignedFrom time.Time  `json:"assigned_from"`
+	AssignedTo   *time.Time `json:"assigned_to,omitempty"` // NULL mientras siga vigente
+	ChangedBy    *uuid.UUID `json:"changed_by,omitempty"`
+	Reason       *string    `json:"reason,omitempty"`
+}
+
+// DeviceAssignRequest represents the request to assign a device to a patient.
+// A null patient_id unassigns the device.
+type DeviceAssignRequest struct {
+	PatientID *uuid.UUID `json:"patient_id"`
+	Reason    *string    `json:"reason,omitempty"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeviceTelemetry represents a single sync report sent by a device
+type DeviceTelemetry struct {
+	ID              uuid.UUID `json:"id"`
+	DeviceID        uuid.UUID `json:"device_id"`
+	BatteryLevel    *int      `json:"battery_level,omitempty"`
+	FirmwareVersion *string   `json:"firmware_version,omitempty"`
+	SignalStrength  *int      `json:"signal_strength,omitempty"` // RSSI in dBm
+	SyncedAt        time.Time `json:"synced_at"`
+}
+
+// DeviceSyncRequest represents the payload a device sends when it syncs. A
+// sync without battery level keeps the device's last known level.
+type DeviceSyncRequest struct {
+	BatteryLevel    *int    `json:"battery_level,omitempty" validate:"omitempty,min=0,max=100"`
+	FirmwareVersion *string `json:"firmware_version,omitempty"`
+	SignalStrength  *int    `json:"signal_strength,omitempty"`
+	MessageKey      *string `json:"-"` // clave del mensaje MQTT que entregó el estado
+}
+
+// DeviceTelemetryQueryParams represents query parameters for fetching device telemetry
+type DeviceTelemetryQueryParams struct {
+	DeviceID  uuid.UUID  `json:"device_id"`
+	StartTime *time.Time `json:"start_time,omitempty"`
+	EndTime   *time.Time `json:"end_time,omitempty"`
+	Limit     int        `json:"limit"`
+}
+
+// DeviceHealthIssue describes an assigned device that is offline or low on battery
+type DeviceHealthIssue struct {
+	DeviceID     uuid.UUID  `json:"device_id"`
+	PatientID    uuid.UUID  `json:"patient_id"`
+	SerialNumber string     `json:"serial_number"`
+	DeviceType   string     `json:"device_type"`
+	LastSync     *time.Time `json:"last_sync,omitempty"`
+	BatteryLevel *int       `json:"battery_level,omitempty"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EcgRecording represents the metadata of a stored ECG strip
+type EcgRecording struct {
+	ID           uuid.UUID  `json:"id"`
+	ReadingID    uuid.UUID  `json:"reading_id"`
+	PatientID    uuid.UUID  `json:"patient_id"`
+	DeviceID     *uuid.UUID `json:"device_id,omitempty"`
+	Lead         string     `json:"lead"`
+	SampleRateHz float64    `json:"sample_rate_hz"`
+	Gain         float64    `json:"gain"` // ADC counts per mV
+	SampleCount  int        `json:"sample_count"`
+	DurationMs   int64      `json:"duration_ms"`
+	Encoding     string     `json:"encoding"`
+	SizeBytes    int        `json:"size_bytes"`
+	RecordedAt   time.Time  `json:"recorded_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// EcgUploadRequest represents the metadata sent with an ECG upload. The
+// samples travel separately as a multipart file or as the raw request body.
+type EcgUploadRequest struct {
+	SampleRateHz float64    `json:"sample_rate_hz" query:"sample_rate_hz" form:"sample_rate_hz"`
+	Gain         float64    `json:"gain" query:"gain" form:"gain"`
+	Lead         string     `json:"lead" query:"lead" form:"lead"`
+	RecordedAt   *time.Time `json:"recorded_at,omitempty" query:"recorded_at" form:"recorded_at"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReadingExportRequest represents the request to export a patient's heart
+// readings. Without columns the default set is exported; times are written in
+// Timezone, by default the patient's.
+type ReadingExportRequest struct {
+	PatientID uuid.UUID  `json:"-"`
+	Format    string     `json:"format" validate:"required,oneof=csv json ndjson"`
+	Columns   []string   `json:"columns,omitempty"`
+	StartTime *time.Time `json:"start_time,omitempty"`
+	EndTime   *time.Time `json:"end_time,omitempty"`
+	Timezone  string     `json:"timezone,omitempty"`
+}
+
+// ExportJob represents an asynchronous export of a patient's heart readings
+// to a downloadable file
+type ExportJob struct {
+	ID          uuid.UUID  `json:"id"`
+	PatientID   uuid.UUID  `json:"patient_id"`
+	RequestedBy *uuid.UUID `json:"requested_by,omitempty"`
+	Format      string     `json:"format"`
+	Columns     []string   `json:"columns"`
+	StartTime   *time.Time `json:"start_time,omitempty"`
+	EndTime     *time.Time `json:"end_time,omitempty"`
+	Timezone    string     `json:"timezone"`
+	Status      string     `json:"status"` // 'pending', 'running', 'completed', 'failed', 'expired'
+	RowCount    *int64     `json:"row_count,omitempty"`
+	FileSize    *int64     `json:"file_size,omitempty"`
+	Error       *string    `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
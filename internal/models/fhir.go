@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Origen de una medición expuesta como Observation FHIR
+const (
+	MeasurementHeartRate     = "hr"    // bpm de una lectura
+	MeasurementSpO2          = "spo2"  // saturación guardada con una lectura
+	MeasurementBloodPressure = "bp"    // presión arterial guardada con una lectura
+	MeasurementVital         = "vital" // observación de signo vital independiente
+)
+
+// Measurement is a single measurement taken from a heart reading or a vital
+// observation. Heart readings give one measurement per value they carry.
+type Measurement struct {
+	Kind            string     `json:"kind"`
+	ID              uuid.UUID  `json:"id"` // lectura u observación de origen
+	PatientID       uuid.UUID  `json:"patient_id"`
+	DeviceID        *uuid.UUID `json:"device_id,omitempty"`
+	ObservationType string     `json:"observation_type"` // 'heart_rate', 'spo2', 'blood_pressure'
+	Value           float64    `json:"value"`
+	SecondaryValue  *float64   `json:"secondary_value,omitempty"` // presión diastólica
+	Notes           *string    `json:"notes,omitempty"`
+	ReadingType     *string    `json:"reading_type,omitempty"` // solo frecuencia cardíaca
+	Time            time.Time  `json:"time"`
+}
+
+// MeasurementQueryParams represents filters and pagination for measurements.
+// StartTime is inclusive and EndTime exclusive.
+type MeasurementQueryParams struct {
+	PatientID        *uuid.UUID
+	Kind             *string
+	ID               *uuid.UUID
+	ObservationTypes []string // vacío: todos
+	StartTime        *time.Time
+	EndTime          *time.Time
+	SortOrder        string // por (time, id, kind)
+	Cursor           *string
+	Limit            int
+}
+
+// MeasurementListResponse represents a page of measurements
+type MeasurementListResponse struct {
+	Data       []*Measurement `json:"data"`
+	NextCursor *string        `json:"next_cursor,omitempty"`
+}
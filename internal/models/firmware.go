@@ -0,0 +1,91 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FirmwareRelease represents a firmware build published for a device type
+type FirmwareRelease struct {
+	ID           uuid.UUID  `json:"id"`
+	DeviceType   string     `json:"device_type"`
+	Version      string     `json:"version"`
+	ReleaseNotes *string    `json:"release_notes,omitempty"`
+	Checksum     string     `json:"checksum"`
+	DownloadURL  string     `json:"download_url"`
+	CreatedBy    *uuid.UUID `json:"created_by,omitempty"`
+	ReleasedAt   time.Time  `json:"released_at"`
+}
+
+// FirmwareReleaseCreateRequest represents the request to publish a firmware release
+type FirmwareReleaseCreateRequest struct {
+	DeviceType   string  `json:"device_type" validate:"required"`
+	Version      string  `json:"version" validate:"required"`
+	ReleaseNotes *string `json:"release_notes,omitempty"`
+	Checksum     string  `json:"checksum" validate:"required,len=64,hexadecimal"`
+	DownloadURL  string  `json:"download_url" validate:"required,url"`
+}
+
+// FirmwareRollout represents a staged deployment of a firmware release.
+// A device is targeted when it is listed in DeviceIDs or falls inside Percentage.
+type FirmwareRollout struct {
+	ID         uuid.UUID   `json:"id"`
+	FirmwareID uuid.UUID   `json:"firmware_id"`
+	Percentage *int        `json:"percentage,omitempty"`
+	DeviceIDs  []uuid.UUID `json:"device_ids,omitempty"`
+	Status     string      `json:"status"` // 'active', 'paused', 'completed', 'cancelled'
+	CreatedBy  *uuid.UUID  `json:"created_by,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+// FirmwareRolloutCreateRequest represents the request to start a rollout
+type FirmwareRolloutCreateRequest struct {
+	Percentage *int        `json:"percentage,omitempty" validate:"omitempty,min=0,max=100"`
+	DeviceIDs  []uuid.UUID `json:"device_ids,omitempty"`
+}
+
+// FirmwareRolloutUpdateRequest represents the request to change a rollout status or cohort size
+type FirmwareRolloutUpdateRequest struct {
+	Percentage *int    `json:"percentage,omitempty" validate:"omitempty,min=0,max=100"`
+	Status     *string `json:"status,omitempty" validate:"omitempty,oneof=active paused completed cancelled"`
+}
+
+// FirmwareRolloutSummary extends FirmwareRollout with its release and reported results
+type FirmwareRolloutSummary struct {
+	FirmwareRollout
+	DeviceType   string `json:"device_type"`
+	Version      string `json:"version"`
+	SuccessCount int64  `json:"success_count"`
+	FailureCount int64  `json:"failure_count"`
+}
+
+// ActiveFirmwareRollout is an active rollout joined with its release
+type ActiveFirmwareRollout struct {
+	FirmwareRollout
+	Release FirmwareRelease `json:"release"`
+}
+
+// FirmwareCheckResponse tells a device whether it has an update assigned
+type FirmwareCheckResponse struct {
+	DeviceID        uuid.UUID        `json:"device_id"`
+	CurrentVersion  string           `json:"current_version"`
+	UpdateAvailable bool             `json:"update_available"`
+	RolloutID       *uuid.UUID       `json:"rollout_id,omitempty"`
+	Firmware        *FirmwareRelease `json:"firmware,omitempty"`
+}
+
+// FirmwareUpdateReportRequest represents the result of an update reported by a device
+type FirmwareUpdateReportRequest struct {
+	RolloutID uuid.UUID `json:"rollout_id" validate:"required"`
+	Status    string    `json:"status" validate:"required,oneof=success failure"`
+	Message   *string   `json:"message,omitempty"`
+}
+
+// FirmwareVersionDistribution represents how many devices run each firmware version
+type FirmwareVersionDistribution struct {
+	DeviceType      string `json:"device_type"`
+	FirmwareVersion string `json:"firmware_version"`
+	DeviceCount     int64  `json:"device_count"`
+	ActiveCount     int64  `json:"active_count"`
+}
@@ -6,6 +6,15 @@ import (
 	"github.com/google/uuid"
 )
 
+// Tipos de lectura según la actividad del paciente. ReadingTypeUnknown marca
+// las lecturas importadas que no indican si el paciente estaba en reposo.
+const (
+	ReadingTypeResting = "resting"
+	ReadingTypeActive  = "active"
+	ReadingTypeSleep   = "sleep"
+	ReadingTypeUnknown = "unknown"
+)
+
 // HeartReading represents a heart rate reading record
 type HeartReading struct {
 	ID                   uuid.UUID  `json:"id"`
@@ -13,7 +22,7 @@ type HeartReading struct {
 	DeviceID             *uuid.UUID `json:"device_id,omitempty"`
 	EntryMethod          string     `json:"entry_method"` // 'device', 'manual', 'imported'
 	EnteredBy            *uuid.UUID `json:"entered_by,omitempty"`
-	ReadingType          string     `json:"reading_type"` // 'resting', 'active', 'sleep'
+	ReadingType          string     `json:"reading_type"` // 'resting', 'active', 'sleep', 'unknown'
 	Source               string     `json:"source"`       // 'smartwatch', 'chest_strap', 'manual_entry'
 	BPM                  int        `json:"bpm"`
 	Variability          *float64   `json:"variability,omitempty"`
@@ -25,17 +34,32 @@ type HeartReading struct {
 	ActivityLevel        *int       `json:"activity_level,omitempty"`
 	Notes                *string    `json:"notes,omitempty"`
 	ReliabilityScore     *float64   `json:"reliability_score,omitempty"`
+	QualityScore         *float64   `json:"quality_score,omitempty"` // calculada por el servidor al recibir la lectura
+	QualityFlags         []string   `json:"quality_flags,omitempty"`
 	Processed            bool       `json:"processed"`
 	Time                 time.Time  `json:"time"`
 }
 
+// ReadingQuality is the server-side quality assessment stored with a reading
+type ReadingQuality struct {
+	Score float64  `json:"score"`
+	Flags []string `json:"flags"`
+}
+
+// ReadingFilter selects the readings that statistics and anomaly detection
+// take into account. Readings without a quality score count as full quality.
+type ReadingFilter struct {
+	ExcludeArtifacts bool     // lecturas anotadas como artefacto
+	MinQuality       *float64 // puntuación de calidad mínima
+}
+
 // HeartReadingCreateRequest represents the request to create a new heart reading
 type HeartReadingCreateRequest struct {
 	PatientID            uuid.UUID  `json:"patient_id" validate:"required"`
 	DeviceID             *uuid.UUID `json:"device_id,omitempty"`
 	EntryMethod          string     `json:"entry_method" validate:"required,oneof=device manual imported"`
 	EnteredBy            *uuid.UUID `json:"entered_by,omitempty"`
-	ReadingType          string     `json:"reading_type" validate:"required,oneof=resting active sleep"`
+	ReadingType          string     `json:"reading_type" validate:"required,oneof=resting active sleep unknown"`
 	Source               string     `json:"source" validate:"required"`
 	BPM                  int        `json:"bpm" validate:"required,min=1,max=300"`
 	Variability          *float64   `json:"variability,omitempty"`
@@ -47,32 +71,62 @@ type HeartReadingCreateRequest struct {
 	ActivityLevel        *int       `json:"activity_level,omitempty" validate:"omitempty,min=0,max=10"`
 	Notes                *string    `json:"notes,omitempty"`
 	ReliabilityScore     *float64   `json:"reliability_score,omitempty" validate:"omitempty,min=0,max=1"`
+	RRIntervals          []float64  `json:"rr_intervals,omitempty" validate:"omitempty,max=20000,dive,gt=0,lt=5000"` // ms
+	Time                 *time.Time `json:"time,omitempty"`                                                          // hora de la medición; por defecto la de recepción
+	MessageKey           *string    `json:"-"`                                                                       // clave del mensaje MQTT que entregó la lectura
 }
 
 // HeartReadingUpdateRequest represents the request to update an existing heart reading
 type HeartReadingUpdateRequest struct {
-	ReadingType          *string  `json:"reading_type,omitempty" validate:"omitempty,oneof=resting active sleep"`
+	ReadingType          *string  `json:"reading_type,omitempty" validate:"omitempty,oneof=resting active sleep unknown"`
 	BPM                  *int     `json:"bpm,omitempty" validate:"omitempty,min=1,max=300"`
 	Variability          *float64 `json:"variability,omitempty"`
 	IrregularityDetected *bool    `json:"irregularity_detected,omitempty"`
 	OxygenLevel          *int     `json:"oxygen_level,omitempty" validate:"omitempty,min=0,max=100"`
 	Notes                *string  `json:"notes,omitempty"`
 	ReliabilityScore     *float64 `json:"reliability_score,omitempty" validate:"omitempty,min=0,max=1"`
+	Reason               *string  `json:"reason,omitempty" validate:"omitempty,max=500"` // motivo del cambio, queda en el historial
+}
+
+// HeartReadingDeleteRequest represents the request to soft-delete a heart reading
+type HeartReadingDeleteRequest struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+// HeartReadingVersion is one entry of a reading's edit history. OldValues and
+// NewValues hold only the fields that changed, keyed by their JSON name.
+type HeartReadingVersion struct {
+	Version    int            `json:"version"`
+	ChangeType string         `json:"change_type"` // 'update', 'delete'
+	ChangedBy  *uuid.UUID     `json:"changed_by,omitempty"`
+	ChangedAt  time.Time      `json:"changed_at"`
+	Reason     *string        `json:"reason,omitempty"`
+	OldValues  map[string]any `json:"old_values"`
+	NewValues  map[string]any `json:"new_values"`
 }
 
 // HeartReadingResponse represents a heart reading with additional information
 type HeartReadingResponse struct {
-	ReadingTime          time.Time `json:"reading_time"`
-	DeviceType           *string   `json:"device_type,omitempty"`
-	EntryMethod          string    `json:"entry_method"`
-	ReadingType          string    `json:"reading_type"`
-	Source               string    `json:"source"`
-	BPM                  int       `json:"bpm"`
-	Variability          *float64  `json:"variability,omitempty"`
-	IrregularityDetected bool      `json:"irregularity_detected"`
-	OxygenLevel          *int      `json:"oxygen_level,omitempty"`
-	Notes                *string   `json:"notes,omitempty"`
-	ReliabilityScore     *float64  `json:"reliability_score,omitempty"`
+	ID                   uuid.UUID  `json:"id"`
+	ReadingTime          time.Time  `json:"reading_time"`
+	DeviceID             *uuid.UUID `json:"device_id,omitempty"`
+	DeviceType           *string    `json:"device_type,omitempty"`
+	EntryMethod          string     `json:"entry_method"`
+	EnteredBy            *uuid.UUID `json:"entered_by,omitempty"`
+	ReadingType          string     `json:"reading_type"`
+	Source               string     `json:"source"`
+	BPM                  int        `json:"bpm"`
+	Variability          *float64   `json:"variability,omitempty"`
+	IrregularityDetected bool       `json:"irregularity_detected"`
+	OxygenLevel          *int       `json:"oxygen_level,omitempty"`
+	SystolicPressure     *int       `json:"systolic_pressure,omitempty"`
+	DiastolicPressure    *int       `json:"diastolic_pressure,omitempty"`
+	Temperature          *float64   `json:"temperature,omitempty"`
+	ActivityLevel        *int       `json:"activity_level,omitempty"`
+	Notes                *string    `json:"notes,omitempty"`
+	ReliabilityScore     *float64   `json:"reliability_score,omitempty"`
+	QualityScore         *float64   `json:"quality_score,omitempty"`
+	QualityFlags         []string   `json:"quality_flags,omitempty"`
 }
 
 // HeartRateStats represents statistics about heart rate readings
@@ -98,38 +152,123 @@ type HeartRateAnomaly struct {
 	AnomalyType         string    `json:"anomaly_type"` // 'extreme_low', 'extreme_high', 'low', 'high', 'irregular', 'normal'
 }
 
-// HeartRateTrend represents a trend in heart rate readings over time
+// HeartRateTrend represents a trend in heart rate readings over time. The
+// aggregates are null in the empty periods added by gap filling, and the
+// percentiles are only present when requested.
 type HeartRateTrend struct {
 	PeriodStart  time.Time `json:"period_start"`
 	PeriodEnd    time.Time `json:"period_end"`
-	AvgBPM       float64   `json:"avg_bpm"`
-	MinBPM       int       `json:"min_bpm"`
-	MaxBPM       int       `json:"max_bpm"`
+	AvgBPM       *float64  `json:"avg_bpm"`
+	MinBPM       *int      `json:"min_bpm"`
+	MaxBPM       *int      `json:"max_bpm"`
 	ReadingCount int64     `json:"reading_count"`
+	P5           *float64  `json:"p5,omitempty"`
+	P50          *float64  `json:"p50,omitempty"`
+	P95          *float64  `json:"p95,omitempty"`
+}
+
+// HeartRateTrendQueryParams represents how a patient's heart rate trend is
+// bucketed: Resolution is a calendar unit (minute, hour, day, week, month) or
+// a fixed interval such as "15m" or "6h", laid out in the IANA Timezone
+type HeartRateTrendQueryParams struct {
+	PatientID   uuid.UUID  `json:"patient_id" validate:"required"`
+	StartTime   *time.Time `json:"start_time,omitempty"`
+	EndTime     *time.Time `json:"end_time,omitempty"`
+	Resolution  string     `json:"resolution"`
+	Timezone    string     `json:"timezone,omitempty"`
+	FillGaps    bool       `json:"fill_gaps"`
+	Percentiles bool       `json:"percentiles"`
 }
 
-// HeartReadingQueryParams represents query parameters for fetching heart readings
+// HeartRateTrendsResponse represents heart rate trends with the annotations
+// that overlap the same range
+type HeartRateTrendsResponse struct {
+	Trends      []*HeartRateTrend `json:"trends"`
+	Annotations []*Annotation     `json:"annotations"`
+}
+
+// HeartReadingQueryParams represents filters, sorting and pagination for a
+// patient's heart reading listing
 type HeartReadingQueryParams struct {
-	PatientID  uuid.UUID  `json:"patient_id" validate:"required"`
-	StartTime  *time.Time `json:"start_time,omitempty"`
-	EndTime    *time.Time `json:"end_time,omitempty"`
-	Limit      *int       `json:"limit,omitempty" validate:"omitempty,min=1,max=10000"`
-	Offset     *int       `json:"offset,omitempty" validate:"omitempty,min=0"`
-	HoursBack  *int       `json:"hours_back,omitempty" validate:"omitempty,min=1,max=8760"` // Max 1 year in hours
-	Resolution *string    `json:"resolution,omitempty" validate:"omitempty,oneof=minute hour day week month"`
+	PatientID        uuid.UUID  `json:"patient_id" validate:"required"`
+	StartTime        *time.Time `json:"start_time,omitempty"`
+	EndTime          *time.Time `json:"end_time,omitempty"`
+	ReadingType      *string    `json:"reading_type,omitempty" validate:"omitempty,oneof=resting active sleep unknown"`
+	EntryMethod      *string    `json:"entry_method,omitempty" validate:"omitempty,oneof=device manual imported"`
+	Source           *string    `json:"source,omitempty"`
+	DeviceID         *uuid.UUID `json:"device_id,omitempty"`
+	MinBPM           *int       `json:"min_bpm,omitempty" validate:"omitempty,min=1,max=300"`
+	MaxBPM           *int       `json:"max_bpm,omitempty" validate:"omitempty,min=1,max=300"`
+	IrregularOnly    bool       `json:"irregular_only,omitempty"`
+	MinReliability   *float64   `json:"min_reliability,omitempty" validate:"omitempty,min=0,max=1"`
+	SortOrder        string     `json:"sort_order" validate:"oneof=asc desc"` // por (time, id)
+	Cursor           *string    `json:"cursor,omitempty"`
+	Limit            int        `json:"limit" validate:"min=1,max=1000"`
+	IncludeTotal     bool       `json:"include_total,omitempty"`
+	IncludeDevice    bool       `json:"include_device,omitempty"`                                 // ?include=device
+	IncludeEnteredBy bool       `json:"include_entered_by,omitempty"`                             // ?include=entered_by
+	HoursBack        *int       `json:"hours_back,omitempty" validate:"omitempty,min=1,max=8760"` // Max 1 year in hours
+	Resolution       *string    `json:"resolution,omitempty" validate:"omitempty,oneof=minute hour day week month"`
 }
 
-// HeartReadingWithDeviceResponse extends HeartReadingResponse with device information
+// HeartReadingListResponse represents a page of heart readings. TotalCount is
+// only set when requested and counts every reading matching the filters.
+type HeartReadingListResponse struct {
+	Data       []*HeartReadingWithDeviceResponse `json:"data"`
+	NextCursor *string                           `json:"next_cursor,omitempty"`
+	TotalCount *int64                            `json:"total_count,omitempty"`
+}
+
+// HeartReadingWithDeviceResponse extends HeartReadingResponse with device and
+// entered-by information, embedded on request (?include=device,entered_by)
 type HeartReadingWithDeviceResponse struct {
 	HeartReadingResponse
-	DeviceInfo *struct {
-		ID           uuid.UUID `json:"id,omitempty"`
-		DeviceType   string    `json:"device_type,omitempty"`
-		SerialNumber string    `json:"serial_number,omitempty"`
-	} `json:"device_info,omitempty"`
-	EnteredByInfo *struct {
-		ID        uuid.UUID `json:"id,omitempty"`
-		FirstName string    `json:"first_name,omitempty"`
-		LastName  string    `json:"last_name,omitempty"`
-	} `json:"entered_by_info,omitempty"`
+	DeviceInfo    *HeartReadingDeviceInfo `json:"device_info,omitempty"`
+	EnteredByInfo *HeartReadingUserInfo   `json:"entered_by_info,omitempty"`
+}
+
+// HeartReadingDeviceInfo identifies the device that took a reading
+type HeartReadingDeviceInfo struct {
+	ID           uuid.UUID `json:"id,omitempty"`
+	DeviceType   string    `json:"device_type,omitempty"`
+	SerialNumber string    `json:"serial_number,omitempty"`
+}
+
+// HeartReadingUserInfo identifies the user who entered a reading
+type HeartReadingUserInfo struct {
+	ID        uuid.UUID `json:"id,omitempty"`
+	FirstName string    `json:"first_name,omitempty"`
+	LastName  string    `json:"last_name,omitempty"`
+}
+
+// HeartReadingHRV represents the HRV metrics computed server-side for a reading
+type HeartReadingHRV struct {
+	ReadingID     uuid.UUID `json:"reading_id"`
+	PatientID     uuid.UUID `json:"patient_id"`
+	Time          time.Time `json:"time"`
+	RRIntervals   []float64 `json:"rr_intervals,omitempty"`
+	IntervalCount int       `json:"interval_count"`
+	RejectedCount int       `json:"rejected_count"`
+	MeanRR        float64   `json:"mean_rr"`
+	MeanHR        float64   `json:"mean_hr"`
+	SDNN          float64   `json:"sdnn"`
+	RMSSD         float64   `json:"rmssd"`
+	PNN50         float64   `json:"pnn50"`
+	LFPower       *float64  `json:"lf_power,omitempty"`
+	HFPower       *float64  `json:"hf_power,omitempty"`
+	LFHFRatio     *float64  `json:"lf_hf_ratio,omitempty"`
+}
+
+// HRVSummary represents HRV metrics aggregated over a time window
+type HRVSummary struct {
+	PeriodStart  time.Time `json:"period_start"`
+	PeriodEnd    time.Time `json:"period_end"`
+	ReadingCount int64     `json:"reading_count"`
+	AvgMeanHR    float64   `json:"avg_mean_hr"`
+	AvgSDNN      float64   `json:"avg_sdnn"`
+	AvgRMSSD     float64   `json:"avg_rmssd"`
+	AvgPNN50     float64   `json:"avg_pnn50"`
+	AvgLFPower   *float64  `json:"avg_lf_power,omitempty"`
+	AvgHFPower   *float64  `json:"avg_hf_power,omitempty"`
+	AvgLFHFRatio *float64  `json:"avg_lf_hf_ratio,omitempty"`
 }
@@ -0,0 +1,14 @@
+package models
+
+// LocaleSettings represents the time zone and locale of a patient or a user
+type LocaleSettings struct {
+	Timezone string `json:"timezone"` // IANA, p. ej. "America/Lima"
+	Locale   string `json:"locale"`   // BCP 47, p. ej. "es-PE"
+}
+
+// LocaleSettingsUpdateRequest represents the request to change the time zone
+// and/or the locale
+type LocaleSettingsUpdateRequest struct {
+	Timezone *string `json:"timezone,omitempty" validate:"omitempty,timezone"`
+	Locale   *string `json:"locale,omitempty" validate:"omitempty,bcp47_language_tag"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PatientDailyReport summarizes one day of a patient's readings
+type PatientDailyReport struct {
+	PatientID      uuid.UUID `json:"patient_id"`
+	ReportDate     string    `json:"report_date"` // YYYY-MM-DD
+	ReadingCount   int       `json:"reading_count"`
+	MinBPM         *int      `json:"min_bpm,omitempty"`
+	MaxBPM         *int      `json:"max_bpm,omitempty"`
+	AvgBPM         *float64  `json:"avg_bpm,omitempty"`
+	RestingAvgBPM  *float64  `json:"resting_avg_bpm,omitempty"`
+	LowCount       int64     `json:"low_count"`
+	HighCount      int64     `json:"high_count"`
+	IrregularCount int       `json:"irregular_count"`
+	AlertCount     int       `json:"alert_count"`
+	AvgOxygen      *float64  `json:"avg_oxygen,omitempty"`
+	GeneratedAt    time.Time `json:"generated_at"`
+}
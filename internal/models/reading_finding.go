@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReadingFinding represents a result of the server-side arrhythmia screening
+type ReadingFinding struct {
+	ID            uuid.UUID      `json:"id"`
+	ReadingID     uuid.UUID      `json:"reading_id"`
+	PatientID     uuid.UUID      `json:"patient_id"`
+	Source        string         `json:"source"`       // 'rr', 'ecg'
+	FindingType   string         `json:"finding_type"` // 'atrial_fibrillation_suspected', 'irregular_rhythm', 'bradycardia', 'tachycardia'
+	StartOffsetMs *int64         `json:"start_offset_ms,omitempty"`
+	DurationMs    *int64         `json:"duration_ms,omitempty"`
+	MinBPM        *float64       `json:"min_bpm,omitempty"`
+	MaxBPM        *float64       `json:"max_bpm,omitempty"`
+	MeanBPM       *float64       `json:"mean_bpm,omitempty"`
+	Details       map[string]any `json:"details,omitempty"`
+	ReadingTime   time.Time      `json:"reading_time"`
+	CreatedAt     time.Time      `json:"created_at"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReadingImportRequest describes a file of historical heart readings to
+// import for a patient. Mapping, TimeFormat and Timezone apply to CSV files;
+// Timezone is also the zone of Fitbit intraday times and defaults to the
+// patient's. Source and ReadingType are used when the file does not give
+// them.
+type ReadingImportRequest struct {
+	PatientID   uuid.UUID         `json:"-"`
+	EnteredBy   *uuid.UUID        `json:"-"`
+	Format      string            `json:"format" validate:"required,oneof=csv apple_health google_fit fitbit"`
+	Mapping     map[string]string `json:"mapping,omitempty"`
+	TimeFormat  string            `json:"time_format,omitempty" validate:"max=64"`
+	Timezone    string            `json:"timezone,omitempty"`
+	Source      string            `json:"source,omitempty" validate:"max=50"`
+	ReadingType string            `json:"reading_type,omitempty" validate:"omitempty,oneof=resting active sleep"`
+	DryRun      bool              `json:"dry_run"`
+}
+
+// ReadingImportIssue is a record of an import file that was rejected.
+// Position is the line in CSV and XML files and the data point number in JSON
+// files.
+type ReadingImportIssue struct {
+	Position int    `json:"position"`
+	Message  string `json:"message"`
+}
+
+// ReadingImportReport summarises an import, or what it would do in a dry run
+type ReadingImportReport struct {
+	Format          string               `json:"format"`
+	Source          string               `json:"source"`
+	DryRun          bool                 `json:"dry_run"`
+	Total           int                  `json:"total"`      // registros leídos
+	Valid           int                  `json:"valid"`      // registros válidos y no duplicados
+	Imported        int                  `json:"imported"`   // 0 en un dry run
+	Duplicates      int                  `json:"duplicates"` // ya guardados o repetidos en el archivo
+	Invalid         int                  `json:"invalid"`
+	FirstTime       *time.Time           `json:"first_time,omitempty"`
+	LastTime        *time.Time           `json:"last_time,omitempty"`
+	Issues          []ReadingImportIssue `json:"issues"`
+	IssuesTruncated bool                 `json:"issues_truncated"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// ReadingWorkerStatus describes the state and last run of the background
+// worker that processes unprocessed heart readings on this replica
+type ReadingWorkerStatus struct {
+	Enabled             bool       `json:"enabled"`
+	Running             bool       `json:"running"`
+	IsLeader            bool       `json:"is_leader"`
+	Interval            string     `json:"interval"`
+	BatchSize           int        `json:"batch_size"`
+	MaxBatchesPerRun    int        `json:"max_batches_per_run"`
+	MaxAttempts         int        `json:"max_attempts"`
+	LastRunStartedAt    *time.Time `json:"last_run_started_at,omitempty"`
+	LastRunFinishedAt   *time.Time `json:"last_run_finished_at,omitempty"`
+	LastRunDurationMs   int64      `json:"last_run_duration_ms"`
+	LastRunProcessed    int        `json:"last_run_processed"`
+	LastRunFailed       int        `json:"last_run_failed"`
+	LastError           *string    `json:"last_error,omitempty"`
+	LastErrorAt         *time.Time `json:"last_error_at,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	NextRunAt           *time.Time `json:"next_run_at,omitempty"`
+	TotalRuns           int64      `json:"total_runs"`
+	TotalProcessed      int64      `json:"total_processed"`
+	TotalFailed         int64      `json:"total_failed"`
+	PendingReadings     *int64     `json:"pending_readings,omitempty"`
+	ExhaustedReadings   *int64     `json:"exhausted_readings,omitempty"`
+}
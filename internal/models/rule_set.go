@@ -0,0 +1,18 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RuleSetRecord represents a stored anomaly rule set. PatientID is nil for
+// the default rule set.
+type RuleSetRecord struct {
+	PatientID  *uuid.UUID      `json:"patient_id,omitempty"`
+	Source     string          `json:"source"` // 'patient', 'default', 'builtin'
+	Definition json.RawMessage `json:"definition"`
+	UpdatedBy  *uuid.UUID      `json:"updated_by,omitempty"`
+	UpdatedAt  *time.Time      `json:"updated_at,omitempty"`
+}
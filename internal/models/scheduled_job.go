@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// ScheduledJob represents a recurring job and the state of its last run
+type ScheduledJob struct {
+	Name        string     `json:"name"`
+	Description *string    `json:"description,omitempty"`
+	Schedule    string     `json:"schedule"`
+	Enabled     bool       `json:"enabled"`
+	NextRunAt   time.Time  `json:"next_run_at"`
+	Running     bool       `json:"running"`
+	LockedBy    *string    `json:"locked_by,omitempty"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	LastRunAt   *time.Time `json:"last_run_at,omitempty"`
+	LastStatus  *string    `json:"last_status,omitempty"`
+	LastError   *string    `json:"last_error,omitempty"`
+}
+
+// JobRun represents one execution of a scheduled job
+type JobRun struct {
+	ID         int64      `json:"id"`
+	JobName    string     `json:"job_name"`
+	Trigger    string     `json:"trigger"`
+	Instance   string     `json:"instance"`
+	Status     string     `json:"status"`
+	Summary    *string    `json:"summary,omitempty"`
+	Error      *string    `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	DurationMs *int64     `json:"duration_ms,omitempty"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ThresholdProfile represents heart rate limits that apply to a patient for a
+// reading type and/or a schedule (e.g. night hours or exercise windows)
+type ThresholdProfile struct {
+	ID          uuid.UUID  `json:"id"`
+	PatientID   uuid.UUID  `json:"patient_id"`
+	Name        string     `json:"name"`
+	ReadingType *string    `json:"reading_type,omitempty"` // nil: any reading type
+	DaysOfWeek  []int      `json:"days_of_week,omitempty"` // 0 = Sunday; empty: every day
+	StartTime   *string    `json:"start_time,omitempty"`   // "HH:MM"
+	EndTime     *string    `json:"end_time,omitempty"`     // "HH:MM", may wrap past midnight
+	Timezone    *string    `json:"timezone,omitempty"`     // nil: the patient's time zone
+	MinBPM      int        `json:"min_bpm"`
+	MaxBPM      int        `json:"max_bpm"`
+	Priority    int        `json:"priority"`
+	Active      bool       `json:"active"`
+	SetBy       *uuid.UUID `json:"set_by,omitempty"`
+	SetAt       time.Time  `json:"set_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// ThresholdProfileRequest represents the request to create or replace a threshold profile
+type ThresholdProfileRequest struct {
+	Name        string  `json:"name" validate:"required,max=100"`
+	ReadingType *string `json:"reading_type,omitempty" validate:"omitempty,oneof=resting active sleep"`
+	DaysOfWeek  []int   `json:"days_of_week,omitempty" validate:"omitempty,max=7,dive,min=0,max=6"`
+	StartTime   *string `json:"start_time,omitempty" validate:"required_with=EndTime,omitempty,datetime=15:04"`
+	EndTime     *string `json:"end_time,omitempty" validate:"required_with=StartTime,omitempty,datetime=15:04"`
+	Timezone    string  `json:"timezone" validate:"omitempty,timezone"` // empty: the patient's time zone
+	MinBPM      int     `json:"min_bpm" validate:"required,min=20,max=250"`
+	MaxBPM      int     `json:"max_bpm" validate:"required,gtfield=MinBPM,max=300"`
+	Priority    int     `json:"priority"`
+	Active      *bool   `json:"active,omitempty"`
+}
+
+// EffectiveThresholds represents the heart rate limits that apply to a reading
+type EffectiveThresholds struct {
+	MinBPM      int        `json:"min_bpm"`
+	MaxBPM      int        `json:"max_bpm"`
+	Source      string     `json:"source"` // 'profile', 'patient'
+	ProfileID   *uuid.UUID `json:"profile_id,omitempty"`
+	ProfileName *string    `json:"profile_name,omitempty"`
+}
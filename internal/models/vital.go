@@ -0,0 +1,109 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tipos de observación de signos vitales
+const (
+	VitalSpO2            = "spo2"
+	VitalBloodPressure   = "blood_pressure"
+	VitalTemperature     = "temperature"
+	VitalWeight          = "weight"
+	VitalRespiratoryRate = "respiratory_rate"
+)
+
+// VitalObservation represents a vital sign measured on its own, outside a
+// heart reading. For blood pressure Value is the systolic and SecondaryValue
+// the diastolic pressure.
+type VitalObservation struct {
+	ID              uuid.UUID  `json:"id"`
+	PatientID       uuid.UUID  `json:"patient_id"`
+	DeviceID        *uuid.UUID `json:"device_id,omitempty"`
+	ObservationType string     `json:"observation_type"`
+	Value           float64    `json:"value"`
+	SecondaryValue  *float64   `json:"secondary_value,omitempty"`
+	Unit            string     `json:"unit"`
+	EntryMethod     string     `json:"entry_method"`
+	Source          string     `json:"source"`
+	EnteredBy       *uuid.UUID `json:"entered_by,omitempty"`
+	Notes           *string    `json:"notes,omitempty"`
+	Time            time.Time  `json:"time"`
+}
+
+// VitalObservationCreateRequest represents the request to record a vital sign.
+// The unit is fixed per observation type and filled in by the server.
+type VitalObservationCreateRequest struct {
+	PatientID       uuid.UUID  `json:"patient_id" validate:"required"`
+	DeviceID        *uuid.UUID `json:"device_id,omitempty"`
+	ObservationType string     `json:"observation_type" validate:"required,oneof=spo2 blood_pressure temperature weight respiratory_rate"`
+	Value           float64    `json:"value" validate:"required"`
+	SecondaryValue  *float64   `json:"secondary_value,omitempty"`
+	EntryMethod     string     `json:"entry_method" validate:"required,oneof=device manual imported"`
+	EnteredBy       *uuid.UUID `json:"entered_by,omitempty"`
+	Source          string     `json:"source" validate:"required"`
+	Notes           *string    `json:"notes,omitempty"`
+	Time            *time.Time `json:"time,omitempty"`
+}
+
+// VitalQueryParams represents filters and pagination for a patient's vital signs
+type VitalQueryParams struct {
+	PatientID       uuid.UUID  `json:"patient_id" validate:"required"`
+	ObservationType *string    `json:"observation_type,omitempty" validate:"omitempty,oneof=spo2 blood_pressure temperature weight respiratory_rate"`
+	StartTime       *time.Time `json:"start_time,omitempty"`
+	EndTime         *time.Time `json:"end_time,omitempty"`
+	SortOrder       string     `json:"sort_order" validate:"oneof=asc desc"`
+	Cursor          *string    `json:"cursor,omitempty"`
+	Limit           int        `json:"limit" validate:"min=1,max=1000"`
+}
+
+// VitalListResponse represents a page of vital sign observations
+type VitalListResponse struct {
+	Data       []*VitalObservation `json:"data"`
+	NextCursor *string             `json:"next_cursor,omitempty"`
+}
+
+// VitalStats represents statistics about one vital sign. The secondary
+// fields are only set for blood pressure (diastolic).
+type VitalStats struct {
+	ObservationType  string     `json:"observation_type"`
+	Unit             string     `json:"unit"`
+	Count            int64      `json:"count"`
+	Avg              *float64   `json:"avg,omitempty"`
+	Min              *float64   `json:"min,omitempty"`
+	Max              *float64   `json:"max,omitempty"`
+	StdDev           *float64   `json:"std_dev,omitempty"`
+	SecondaryAvg     *float64   `json:"secondary_avg,omitempty"`
+	SecondaryMin     *float64   `json:"secondary_min,omitempty"`
+	SecondaryMax     *float64   `json:"secondary_max,omitempty"`
+	LowCount         int64      `json:"low_count"`  // por debajo del rango de referencia
+	HighCount        int64      `json:"high_count"` // por encima del rango de referencia
+	FirstObservation *time.Time `json:"first_observation,omitempty"`
+	LastObservation  *time.Time `json:"last_observation,omitempty"`
+}
+
+// VitalTrend represents one vital sign aggregated over a period
+type VitalTrend struct {
+	PeriodStart  time.Time `json:"period_start"`
+	PeriodEnd    time.Time `json:"period_end"`
+	Avg          float64   `json:"avg"`
+	Min          float64   `json:"min"`
+	Max          float64   `json:"max"`
+	SecondaryAvg *float64  `json:"secondary_avg,omitempty"`
+	Count        int64     `json:"count"`
+}
+
+// TimelinePoint is one measurement on a patient's shared timeline. Points
+// come from heart readings (heart rate and the vitals recorded with them) or
+// from standalone vital sign observations.
+type TimelinePoint struct {
+	Time           time.Time `json:"time"`
+	Type           string    `json:"type"` // heart_rate, spo2, blood_pressure, temperature, weight, respiratory_rate
+	Value          float64   `json:"value"`
+	SecondaryValue *float64  `json:"secondary_value,omitempty"`
+	Unit           string    `json:"unit"`
+	Origin         string    `json:"origin"` // heart_reading u observation
+	SourceID       uuid.UUID `json:"source_id"`
+}
@@ -0,0 +1,274 @@
+// Package mqttbridge ingests readings and status updates published by wearable
+// gateways over MQTT. Messages are mapped onto the same service calls used by
+// the HTTP API so they share validation and alerting.
+package mqttbridge
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+)
+
+// ReadingIngester is implemented by services.HeartReadingService
+type ReadingIngester interface {
+	CreateHeartReading(ctx context.Context, reading *models.HeartReadingCreateRequest) (*models.HeartReading, error)
+}
+
+// DeviceSyncer is implemented by services.DeviceService
+type DeviceSyncer interface {
+	GetDeviceBySerial(ctx context.Context, serialNumber string) (*models.DeviceResponse, error)
+	UpdateDeviceSync(ctx context.Context, deviceID uuid.UUID, sync *models.DeviceSyncRequest) error
+}
+
+// Config holds the broker connection settings
+type Config struct {
+	BrokerURL      string
+	ClientID       string
+	Username       string
+	Password       string
+	TopicPrefix    string
+	HandlerTimeout time.Duration
+}
+
+// LoadConfig reads the bridge configuration from the environment
+func LoadConfig() Config {
+	clientID := os.Getenv("MQTT_CLIENT_ID")
+	if clientID == "" {
+		clientID = "medical-heart-bridge"
+	}
+
+	prefix := os.Getenv("MQTT_TOPIC_PREFIX")
+	if prefix == "" {
+		prefix = "devices"
+	}
+
+	return Config{
+		BrokerURL:      os.Getenv("MQTT_BROKER_URL"),
+		ClientID:       clientID,
+		Username:       os.Getenv("MQTT_USERNAME"),
+		Password:       os.Getenv("MQTT_PASSWORD"),
+		TopicPrefix:    strings.TrimSuffix(prefix, "/"),
+		HandlerTimeout: utils.GetEnvDuration("MQTT_HANDLER_TIMEOUT", 10*time.Second),
+	}
+}
+
+// permanentError marks a message that will never succeed (bad topic, bad
+// payload, unknown device). Such messages are acknowledged and dropped.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+func permanent(format string, args ...any) error {
+	return &permanentError{err: fmt.Errorf(format, args...)}
+}
+
+// IsPermanent reports whether err means the message should not be retried
+func IsPermanent(err error) bool {
+	var perr *permanentError
+	var verr *utils.ValidationError
+	return errors.As(err, &perr) || errors.As(err, &verr)
+}
+
+type Bridge struct {
+	config   Config
+	readings ReadingIngester
+	devices  DeviceSyncer
+	client   mqtt.Client
+}
+
+func NewBridge(config Config, readings ReadingIngester, devices DeviceSyncer) *Bridge {
+	return &Bridge{
+		config:   config,
+		readings: readings,
+		devices:  devices,
+	}
+}
+
+// Start connects to the broker and subscribes to the reading and status topics.
+// The subscription uses a persistent session and manual acknowledgements, so a
+// message is only acknowledged once it has been stored or rejected for good.
+func (b *Bridge) Start(ctx context.Context) error {
+	if b.config.BrokerURL == "" {
+		return errors.New("MQTT_BROKER_URL is not set")
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(b.config.BrokerURL).
+		SetClientID(b.config.ClientID).
+		SetUsername(b.config.Username).
+		SetPassword(b.config.Password).
+		SetCleanSession(false).
+		SetAutoReconnect(true).
+		SetAutoAckDisabled(true).
+		SetOrderMatters(false)
+
+	topics := map[string]byte{
+		b.config.TopicPrefix + "/+/readings": 1,
+		b.config.TopicPrefix + "/+/status":   1,
+	}
+
+	// Volver a suscribirse tras cada reconexión
+	opts.SetOnConnectHandler(func(client mqtt.Client) {
+		token := client.SubscribeMultiple(topics, b.messageHandler(ctx))
+		if token.Wait() && token.Error() != nil {
+			log.Printf("MQTT bridge: subscribe failed: %v", token.Error())
+			return
+		}
+		log.Printf("MQTT bridge: subscribed to %s/+/readings and %s/+/status", b.config.TopicPrefix, b.config.TopicPrefix)
+	})
+	opts.SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+		log.Printf("MQTT bridge: connection lost: %v", err)
+	})
+
+	b.client = mqtt.NewClient(opts)
+	token := b.client.Connect()
+	if token.Wait() && token.Error() != nil {
+		return fmt.Errorf("error connecting to MQTT broker: %w", token.Error())
+	}
+
+	log.Printf("MQTT bridge connected to %s", b.config.BrokerURL)
+	return nil
+}
+
+// Stop disconnects from the broker, waiting briefly for in-flight work
+func (b *Bridge) Stop() {
+	if b.client != nil && b.client.IsConnected() {
+		b.client.Disconnect(250)
+		log.Println("MQTT bridge disconnected")
+	}
+}
+
+func (b *Bridge) messageHandler(ctx context.Context) mqtt.MessageHandler {
+	return func(_ mqtt.Client, msg mqtt.Message) {
+		handlerCtx, cancel := context.WithTimeout(ctx, b.config.HandlerTimeout)
+		defer cancel()
+
+		err := b.HandleMessage(handlerCtx, msg.Topic(), msg.Payload())
+		switch {
+		case err == nil:
+			msg.Ack()
+		case IsPermanent(err):
+			log.Printf("MQTT bridge: dropping message on %s: %v", msg.Topic(), err)
+			msg.Ack()
+		default:
+			// Sin ACK el broker vuelve a entregar el mensaje al reconectar
+			log.Printf("MQTT bridge: error handling message on %s: %v", msg.Topic(), err)
+		}
+	}
+}
+
+// HandleMessage processes a single MQTT message. It is independent of the
+// client so it can be driven directly or from any broker. The message key is
+// stored together with the reading or status it delivers, so a redelivered
+// message is acknowledged without being ingested twice, also across restarts.
+func (b *Bridge) HandleMessage(ctx context.Context, topic string, payload []byte) error {
+	serial, kind, err := b.parseTopic(topic)
+	if err != nil {
+		return err
+	}
+
+	switch kind {
+	case "readings":
+		var p ReadingPayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return permanent("invalid reading payload: %v", err)
+		}
+		return ignoreDuplicate(b.handleReading(ctx, serial, messageKey(topic, p.MessageID, payload), &p))
+	case "status":
+		var p StatusPayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return permanent("invalid status payload: %v", err)
+		}
+		return ignoreDuplicate(b.handleStatus(ctx, serial, messageKey(topic, p.MessageID, payload), &p))
+	default:
+		return permanent("unsupported topic %q", topic)
+	}
+}
+
+// ignoreDuplicate turns a message that was already ingested into a success so
+// that the redelivery is acknowledged
+func ignoreDuplicate(err error) error {
+	if errors.Is(err, utils.ErrDuplicateMessage) {
+		return nil
+	}
+	return err
+}
+
+func (b *Bridge) handleReading(ctx context.Context, serial, key string, p *ReadingPayload) error {
+	device, err := b.lookupDevice(ctx, serial)
+	if err != nil {
+		return err
+	}
+	if device.PatientID == nil {
+		return permanent("device %s is not assigned to a patient", serial)
+	}
+
+	reading := p.toCreateRequest(device)
+	reading.MessageKey = &key
+	_, err = b.readings.CreateHeartReading(ctx, reading)
+	return err
+}
+
+func (b *Bridge) handleStatus(ctx context.Context, serial, key string, p *StatusPayload) error {
+	device, err := b.lookupDevice(ctx, serial)
+	if err != nil {
+		return err
+	}
+
+	sync := p.toSyncRequest()
+	sync.MessageKey = &key
+	return b.devices.UpdateDeviceSync(ctx, device.DeviceID, sync)
+}
+
+func (b *Bridge) lookupDevice(ctx context.Context, serial string) (*models.DeviceResponse, error) {
+	device, err := b.devices.GetDeviceBySerial(ctx, serial)
+	if err != nil {
+		return nil, err
+	}
+	if device == nil {
+		return nil, permanent("unknown device %s", serial)
+	}
+	if !device.IsActive {
+		return nil, permanent("device %s is not active", serial)
+	}
+	return device, nil
+}
+
+// parseTopic splits "{prefix}/{serial}/{kind}"
+func (b *Bridge) parseTopic(topic string) (serial, kind string, err error) {
+	rest, ok := strings.CutPrefix(topic, b.config.TopicPrefix+"/")
+	if !ok {
+		return "", "", permanent("unexpected topic %q", topic)
+	}
+
+	parts := strings.Split(rest, "/")
+	if len(parts) != 2 || parts[0] == "" {
+		return "", "", permanent("unexpected topic %q", topic)
+	}
+	return parts[0], parts[1], nil
+}
+
+// messageKey prefers the publisher's message ID and falls back to a hash of the
+// topic and payload, which is identical on a QoS 1 redelivery
+func messageKey(topic, messageID string, payload []byte) string {
+	if messageID != "" {
+		return topic + "#" + messageID
+	}
+	sum := sha256.Sum256(append([]byte(topic+"\x00"), payload...))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,254 @@
+package mqttbridge
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+	"github.com/google/uuid"
+)
+
+// messageStore emula la tabla ingested_messages: la clave solo queda guardada
+// si la escritura que la acompaña se confirma
+type messageStore struct {
+	mu   sync.Mutex
+	keys map[string]bool
+	fail error // siguiente escritura falla y se revierte
+}
+
+func (s *messageStore) commit(key *string, write func()) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key == nil {
+		return errors.New("message key not set")
+	}
+	if s.keys[*key] {
+		return utils.ErrDuplicateMessage
+	}
+	if err := s.fail; err != nil {
+		s.fail = nil
+		return err
+	}
+	s.keys[*key] = true
+	write()
+	return nil
+}
+
+type fakeReadings struct {
+	store    *messageStore
+	readings []*models.HeartReadingCreateRequest
+}
+
+func (f *fakeReadings) CreateHeartReading(_ context.Context, reading *models.HeartReadingCreateRequest) (*models.HeartReading, error) {
+	err := f.store.commit(reading.MessageKey, func() {
+		f.readings = append(f.readings, reading)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &models.HeartReading{PatientID: reading.PatientID, BPM: reading.BPM}, nil
+}
+
+type fakeDevices struct {
+	store   *messageStore
+	devices map[string]*models.DeviceResponse
+	syncs   []*models.DeviceSyncRequest
+}
+
+func (f *fakeDevices) GetDeviceBySerial(_ context.Context, serial string) (*models.DeviceResponse, error) {
+	return f.devices[serial], nil
+}
+
+func (f *fakeDevices) UpdateDeviceSync(_ context.Context, _ uuid.UUID, sync *models.DeviceSyncRequest) error {
+	return f.store.commit(sync.MessageKey, func() {
+		f.syncs = append(f.syncs, sync)
+	})
+}
+
+func newTestBridge(store *messageStore) (*Bridge, *fakeReadings, *fakeDevices) {
+	patientID := uuid.New()
+	readings := &fakeReadings{store: store}
+	devices := &fakeDevices{
+		store: store,
+		devices: map[string]*models.DeviceResponse{
+			"SN1":      {DeviceID: uuid.New(), PatientID: &patientID, DeviceType: "chest_strap", IsActive: true},
+			"SN2":      {DeviceID: uuid.New(), DeviceType: "watch", IsActive: true},
+			"INACTIVE": {DeviceID: uuid.New(), PatientID: &patientID, DeviceType: "watch"},
+		},
+	}
+	return NewBridge(Config{TopicPrefix: "devices"}, readings, devices), readings, devices
+}
+
+func newMessageStore() *messageStore {
+	return &messageStore{keys: make(map[string]bool)}
+}
+
+func TestHandleMessageStoresReading(t *testing.T) {
+	bridge, readings, _ := newTestBridge(newMessageStore())
+
+	err := bridge.HandleMessage(context.Background(), "devices/SN1/readings",
+		[]byte(`{"message_id":"m1","reading_type":"resting","bpm":64}`))
+	if err != nil {
+		t.Fatalf("HandleMessage() error = %v", err)
+	}
+	if len(readings.readings) != 1 {
+		t.Fatalf("stored %d readings, want 1", len(readings.readings))
+	}
+
+	got := readings.readings[0]
+	if got.BPM != 64 || got.EntryMethod != "device" || got.Source != "chest_strap" {
+		t.Errorf("reading = %+v, want bpm 64 from a chest_strap device", got)
+	}
+	if got.MessageKey == nil || *got.MessageKey != "devices/SN1/readings#m1" {
+		t.Errorf("MessageKey = %v, want devices/SN1/readings#m1", got.MessageKey)
+	}
+}
+
+func TestHandleMessageDeduplicates(t *testing.T) {
+	tests := []struct {
+		name   string
+		first  string
+		second string
+		want   int
+	}{
+		{
+			name:   "same message id",
+			first:  `{"message_id":"m1","reading_type":"resting","bpm":64}`,
+			second: `{"message_id":"m1","reading_type":"resting","bpm":65}`,
+			want:   1,
+		},
+		{
+			name:   "same payload without message id",
+			first:  `{"reading_type":"resting","bpm":64}`,
+			second: `{"reading_type":"resting","bpm":64}`,
+			want:   1,
+		},
+		{
+			name:   "different payloads without message id",
+			first:  `{"reading_type":"resting","bpm":64}`,
+			second: `{"reading_type":"resting","bpm":65}`,
+			want:   2,
+		},
+		{
+			name:   "different message ids",
+			first:  `{"message_id":"m1","reading_type":"resting","bpm":64}`,
+			second: `{"message_id":"m2","reading_type":"resting","bpm":64}`,
+			want:   2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bridge, readings, _ := newTestBridge(newMessageStore())
+			ctx := context.Background()
+
+			for _, payload := range []string{tt.first, tt.second} {
+				if err := bridge.HandleMessage(ctx, "devices/SN1/readings", []byte(payload)); err != nil {
+					t.Fatalf("HandleMessage(%s) error = %v", payload, err)
+				}
+			}
+			if len(readings.readings) != tt.want {
+				t.Errorf("stored %d readings, want %d", len(readings.readings), tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleMessageDeduplicatesAcrossRestart(t *testing.T) {
+	store := newMessageStore()
+	payload := []byte(`{"message_id":"m1","reading_type":"resting","bpm":64}`)
+
+	first, readings, _ := newTestBridge(store)
+	if err := first.HandleMessage(context.Background(), "devices/SN1/readings", payload); err != nil {
+		t.Fatalf("HandleMessage() error = %v", err)
+	}
+
+	// Un puente nuevo sobre la misma base reconoce la reentrega
+	restarted := NewBridge(first.config, readings, first.devices)
+	if err := restarted.HandleMessage(context.Background(), "devices/SN1/readings", payload); err != nil {
+		t.Fatalf("HandleMessage() after restart error = %v", err)
+	}
+	if len(readings.readings) != 1 {
+		t.Errorf("stored %d readings, want 1", len(readings.readings))
+	}
+}
+
+func TestHandleMessageRetriesFailedMessage(t *testing.T) {
+	store := newMessageStore()
+	bridge, readings, _ := newTestBridge(store)
+	payload := []byte(`{"message_id":"m1","reading_type":"resting","bpm":64}`)
+
+	store.fail = errors.New("connection reset")
+	err := bridge.HandleMessage(context.Background(), "devices/SN1/readings", payload)
+	if err == nil || IsPermanent(err) {
+		t.Fatalf("HandleMessage() error = %v, want a transient error", err)
+	}
+
+	// La reentrega del broker se procesa: la clave se revirtió con la escritura
+	if err := bridge.HandleMessage(context.Background(), "devices/SN1/readings", payload); err != nil {
+		t.Fatalf("HandleMessage() on redelivery error = %v", err)
+	}
+	if len(readings.readings) != 1 {
+		t.Errorf("stored %d readings, want 1", len(readings.readings))
+	}
+}
+
+func TestHandleMessageStatus(t *testing.T) {
+	bridge, _, devices := newTestBridge(newMessageStore())
+	ctx := context.Background()
+
+	payloads := []string{
+		`{"message_id":"s1","firmware_version":"1.2.0","signal_strength":-60}`,
+		`{"message_id":"s1","firmware_version":"1.2.0","signal_strength":-60}`,
+		`{"message_id":"s2","battery_level":80}`,
+	}
+	for _, payload := range payloads {
+		if err := bridge.HandleMessage(ctx, "devices/SN1/status", []byte(payload)); err != nil {
+			t.Fatalf("HandleMessage(%s) error = %v", payload, err)
+		}
+	}
+
+	if len(devices.syncs) != 2 {
+		t.Fatalf("stored %d syncs, want 2", len(devices.syncs))
+	}
+	if devices.syncs[0].BatteryLevel != nil {
+		t.Errorf("BatteryLevel = %d, want nil when not reported", *devices.syncs[0].BatteryLevel)
+	}
+	if b := devices.syncs[1].BatteryLevel; b == nil || *b != 80 {
+		t.Errorf("BatteryLevel = %v, want 80", b)
+	}
+}
+
+func TestHandleMessagePermanentErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		topic   string
+		payload string
+	}{
+		{"unexpected prefix", "sensors/SN1/readings", `{"reading_type":"resting","bpm":64}`},
+		{"missing serial", "devices//readings", `{"reading_type":"resting","bpm":64}`},
+		{"unsupported kind", "devices/SN1/config", `{}`},
+		{"invalid json", "devices/SN1/readings", `{"bpm":`},
+		{"unknown device", "devices/NOPE/readings", `{"reading_type":"resting","bpm":64}`},
+		{"inactive device", "devices/INACTIVE/readings", `{"reading_type":"resting","bpm":64}`},
+		{"unassigned device", "devices/SN2/readings", `{"reading_type":"resting","bpm":64}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bridge, readings, _ := newTestBridge(newMessageStore())
+
+			err := bridge.HandleMessage(context.Background(), tt.topic, []byte(tt.payload))
+			if !IsPermanent(err) {
+				t.Errorf("HandleMessage() error = %v, want a permanent error", err)
+			}
+			if len(readings.readings) != 0 {
+				t.Errorf("stored %d readings, want 0", len(readings.readings))
+			}
+		})
+	}
+}
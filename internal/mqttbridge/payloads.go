@@ -0,0 +1,78 @@
+package mqttbridge
+
+import (
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/google/uuid"
+)
+
+// ReadingPayload is the JSON body published on devices/{serial}/readings
+type ReadingPayload struct {
+	MessageID            string     `json:"message_id,omitempty"`
+	ReadingType          string     `json:"reading_type"`
+	Source               string     `json:"source,omitempty"`
+	BPM                  int        `json:"bpm"`
+	Variability          *float64   `json:"variability,omitempty"`
+	IrregularityDetected bool       `json:"irregularity_detected"`
+	OxygenLevel          *int       `json:"oxygen_level,omitempty"`
+	SystolicPressure     *int       `json:"systolic_pressure,omitempty"`
+	DiastolicPressure    *int       `json:"diastolic_pressure,omitempty"`
+	Temperature          *float64   `json:"temperature,omitempty"`
+	ActivityLevel        *int       `json:"activity_level,omitempty"`
+	Notes                *string    `json:"notes,omitempty"`
+	ReliabilityScore     *float64   `json:"reliability_score,omitempty"`
+	RRIntervals          []float64  `json:"rr_intervals,omitempty"`
+	Time                 *time.Time `json:"time,omitempty"` // hora de la medición según el reloj del dispositivo
+}
+
+// StatusPayload is the JSON body published on devices/{serial}/status
+type StatusPayload struct {
+	MessageID       string  `json:"message_id,omitempty"`
+	BatteryLevel    *int    `json:"battery_level,omitempty"`
+	FirmwareVersion *string `json:"firmware_version,omitempty"`
+	SignalStrength  *int    `json:"signal_strength,omitempty"`
+}
+
+// toCreateRequest maps a reading published by a device onto the same request
+// the HTTP API receives
+func (p *ReadingPayload) toCreateRequest(device *models.DeviceResponse) *models.HeartReadingCreateRequest {
+	source := p.Source
+	if source == "" {
+		source = device.DeviceType
+	}
+
+	deviceID := device.DeviceID
+	var patientID uuid.UUID
+	if device.PatientID != nil {
+		patientID = *device.PatientID
+	}
+
+	return &models.HeartReadingCreateRequest{
+		PatientID:            patientID,
+		DeviceID:             &deviceID,
+		EntryMethod:          "device",
+		ReadingType:          p.ReadingType,
+		Source:               source,
+		BPM:                  p.BPM,
+		Variability:          p.Variability,
+		IrregularityDetected: p.IrregularityDetected,
+		OxygenLevel:          p.OxygenLevel,
+		SystolicPressure:     p.SystolicPressure,
+		DiastolicPressure:    p.DiastolicPressure,
+		Temperature:          p.Temperature,
+		ActivityLevel:        p.ActivityLevel,
+		Notes:                p.Notes,
+		ReliabilityScore:     p.ReliabilityScore,
+		RRIntervals:          p.RRIntervals,
+		Time:                 p.Time,
+	}
+}
+
+func (p *StatusPayload) toSyncRequest() *models.DeviceSyncRequest {
+	return &models.DeviceSyncRequest{
+		BatteryLevel:    p.BatteryLevel,
+		FirmwareVersion: p.FirmwareVersion,
+		SignalStrength:  p.SignalStrength,
+	}
+}
@@ -0,0 +1,87 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/db"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type AlertRepository struct {
+	db *db.PostgresDB
+}
+
+func NewAlertRepository(database *db.PostgresDB) *AlertRepository {
+	return &AlertRepository{db: database}
+}
+
+// CreateAlert inserts a new alert for a patient and returns its ID. An alert
+// tied to a reading is raised once per reading and type: when the reading
+// already raised it, nothing is inserted and uuid.Nil is returned.
+func (r *AlertRepository) CreateAlert(ctx context.Context, alert *models.Alert) (uuid.UUID, error) {
+	query := `
+		INSERT INTO alerts (patient_id, alert_type, severity, message, reading_time, device_id, reading_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (reading_id, alert_type) WHERE reading_id IS NOT NULL DO NOTHING
+		RETURNING id;`
+
+	var alertID uuid.UUID
+	err := r.db.Pool.QueryRow(ctx, query,
+		alert.PatientID,
+		alert.AlertType,
+		alert.Severity,
+		alert.Message,
+		alert.ReadingTime,
+		alert.DeviceID,
+		alert.ReadingID,
+	).Scan(&alertID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, nil
+		}
+		return uuid.Nil, fmt.Errorf("failed to create alert: %w", err)
+	}
+
+	return alertID, nil
+}
+
+// HasOpenAlert reports whether the patient already has an unacknowledged alert
+// of the given type created after since. It is used to avoid duplicate alerts.
+func (r *AlertRepository) HasOpenAlert(ctx context.Context, patientID uuid.UUID, alertType string, since time.Time) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM alerts
+			WHERE patient_id = $1 AND alert_type = $2
+			  AND NOT acknowledged AND created_at >= $3
+		);`
+
+	var exists bool
+	if err := r.db.Pool.QueryRow(ctx, query, patientID, alertType, since).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check open alerts: %w", err)
+	}
+
+	return exists, nil
+}
+
+// HasOpenDeviceAlert reports whether the device already has an unacknowledged
+// alert of the given type created after since
+func (r *AlertRepository) HasOpenDeviceAlert(ctx context.Context, deviceID uuid.UUID, alertType string, since time.Time) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM alerts
+			WHERE device_id = $1 AND alert_type = $2
+			  AND NOT acknowledged AND created_at >= $3
+		);`
+
+	var exists bool
+	if err := r.db.Pool.QueryRow(ctx, query, deviceID, alertType, since).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check open device alerts: %w", err)
+	}
+
+	return exists, nil
+}
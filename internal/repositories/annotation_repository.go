@@ -0,0 +1,171 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/db"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type AnnotationRepository struct {
+	db *db.PostgresDB
+}
+
+func NewAnnotationRepository(database *db.PostgresDB) *AnnotationRepository {
+	return &AnnotationRepository{db: database}
+}
+
+const annotationColumns = `id, patient_id, reading_id, start_time, end_time, category, text,
+		       author_id, created_at, updated_at`
+
+// notArtifactCondition excluye de una consulta sobre heart_readings las
+// lecturas marcadas como artefacto, ya sea directamente o por un intervalo
+const notArtifactCondition = `NOT EXISTS (
+			SELECT 1 FROM annotations a
+			WHERE a.patient_id = heart_readings.patient_id
+			  AND a.category = 'artifact'
+			  AND (a.reading_id = heart_readings.id
+			       OR (a.reading_id IS NULL AND heart_readings.time BETWEEN a.start_time AND a.end_time))
+		)`
+
+// CreateAnnotation stores an annotation
+func (r *AnnotationRepository) CreateAnnotation(ctx context.Context, annotation *models.Annotation) (*models.Annotation, error) {
+	query := `
+		INSERT INTO annotations (patient_id, reading_id, start_time, end_time, category, text, author_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING ` + annotationColumns + `;`
+
+	created, err := scanAnnotation(r.db.Pool.QueryRow(ctx, query,
+		annotation.PatientID,
+		annotation.ReadingID,
+		annotation.StartTime,
+		annotation.EndTime,
+		annotation.Category,
+		annotation.Text,
+		annotation.AuthorID,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create annotation: %w", err)
+	}
+	return created, nil
+}
+
+// GetAnnotation returns an annotation by ID, or nil when it does not exist
+func (r *AnnotationRepository) GetAnnotation(ctx context.Context, annotationID uuid.UUID) (*models.Annotation, error) {
+	query := `SELECT ` + annotationColumns + ` FROM annotations WHERE id = $1;`
+
+	annotation, err := scanAnnotation(r.db.Pool.QueryRow(ctx, query, annotationID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get annotation: %w", err)
+	}
+	return annotation, nil
+}
+
+// GetAnnotations returns a patient's annotations whose range overlaps the
+// requested one, ordered by start time
+func (r *AnnotationRepository) GetAnnotations(ctx context.Context, params *models.AnnotationQueryParams) ([]*models.Annotation, error) {
+	var args []any
+	addArg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conditions := []string{"patient_id = " + addArg(params.PatientID)}
+	if params.StartTime != nil {
+		conditions = append(conditions, "end_time >= "+addArg(*params.StartTime))
+	}
+	if params.EndTime != nil {
+		conditions = append(conditions, "start_time <= "+addArg(*params.EndTime))
+	}
+	if params.Category != nil {
+		conditions = append(conditions, "category = "+addArg(*params.Category))
+	}
+	if params.ReadingID != nil {
+		conditions = append(conditions, "reading_id = "+addArg(*params.ReadingID))
+	}
+
+	query := `SELECT ` + annotationColumns + ` FROM annotations
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY start_time, id;`
+
+	rows, err := r.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get annotations: %w", err)
+	}
+	defer rows.Close()
+
+	annotations := []*models.Annotation{}
+	for rows.Next() {
+		annotation, err := scanAnnotation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		annotations = append(annotations, annotation)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return annotations, nil
+}
+
+// UpdateAnnotation stores the range, category and text of an annotation. It
+// returns nil when the annotation does not exist.
+func (r *AnnotationRepository) UpdateAnnotation(ctx context.Context, annotation *models.Annotation) (*models.Annotation, error) {
+	query := `
+		UPDATE annotations
+		SET start_time = $2, end_time = $3, category = $4, text = $5, updated_at = NOW()
+		WHERE id = $1
+		RETURNING ` + annotationColumns + `;`
+
+	updated, err := scanAnnotation(r.db.Pool.QueryRow(ctx, query,
+		annotation.ID,
+		annotation.StartTime,
+		annotation.EndTime,
+		annotation.Category,
+		annotation.Text,
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to update annotation: %w", err)
+	}
+	return updated, nil
+}
+
+// DeleteAnnotation deletes an annotation. It returns false when it did not exist.
+func (r *AnnotationRepository) DeleteAnnotation(ctx context.Context, annotationID uuid.UUID) (bool, error) {
+	tag, err := r.db.Pool.Exec(ctx, `DELETE FROM annotations WHERE id = $1;`, annotationID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete annotation: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func scanAnnotation(row pgx.Row) (*models.Annotation, error) {
+	var annotation models.Annotation
+	if err := row.Scan(
+		&annotation.ID,
+		&annotation.PatientID,
+		&annotation.ReadingID,
+		&annotation.StartTime,
+		&annotation.EndTime,
+		&annotation.Category,
+		&annotation.Text,
+		&annotation.AuthorID,
+		&annotation.CreatedAt,
+		&annotation.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &annotation, nil
+}
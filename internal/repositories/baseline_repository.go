@@ -0,0 +1,197 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/db"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/google/uuid"
+)
+
+type BaselineRepository struct {
+	db *db.PostgresDB
+}
+
+func NewBaselineRepository(database *db.PostgresDB) *BaselineRepository {
+	return &BaselineRepository{db: database}
+}
+
+// GetPatientsWithReadingsSince returns the patients that have readings after since
+func (r *BaselineRepository) GetPatientsWithReadingsSince(ctx context.Context, since time.Time) ([]uuid.UUID, error) {
+	rows, err := r.db.Pool.Query(ctx, `SELECT DISTINCT patient_id FROM heart_readings WHERE time >= $1 AND deleted_at IS NULL;`, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patients with readings: %w", err)
+	}
+	defer rows.Close()
+
+	var patientIDs []uuid.UUID
+	for rows.Next() {
+		var patientID uuid.UUID
+		if err := rows.Scan(&patientID); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		patientIDs = append(patientIDs, patientID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return patientIDs, nil
+}
+
+// ComputeBaselines calculates the quartiles of a patient's heart rate since a
+// given time, per reading type and per reading type and hour of the day in loc.
+// Hours with fewer than minHourSamples readings are left out of the profile.
+func (r *BaselineRepository) ComputeBaselines(
+	ctx context.Context,
+	patientID uuid.UUID,
+	since time.Time,
+	minHourSamples int,
+	loc *time.Location,
+) ([]*models.PatientBaseline, error) {
+	query := `
+		SELECT reading_type,
+		       CASE WHEN GROUPING(hour) = 1 THEN -1 ELSE hour END,
+		       COUNT(*),
+		       percentile_cont(0.25) WITHIN GROUP (ORDER BY bpm),
+		       percentile_cont(0.50) WITHIN GROUP (ORDER BY bpm),
+		       percentile_cont(0.75) WITHIN GROUP (ORDER BY bpm)
+		FROM (
+			SELECT reading_type, bpm, EXTRACT(HOUR FROM time AT TIME ZONE $3)::int AS hour
+			FROM heart_readings
+			WHERE patient_id = $1 AND time >= $2 AND deleted_at IS NULL
+		) r
+		GROUP BY GROUPING SETS ((reading_type), (reading_type, hour))
+		ORDER BY reading_type, 2;`
+
+	rows, err := r.db.Pool.Query(ctx, query, patientID, since, loc.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute baselines: %w", err)
+	}
+	defer rows.Close()
+
+	var baselines []*models.PatientBaseline
+	byType := make(map[string]*models.PatientBaseline)
+	for rows.Next() {
+		var readingType string
+		var hour, count int
+		var q1, median, q3 float64
+		if err := rows.Scan(&readingType, &hour, &count, &q1, &median, &q3); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+
+		baseline, ok := byType[readingType]
+		if !ok {
+			baseline = &models.PatientBaseline{
+				PatientID:   patientID,
+				ReadingType: readingType,
+				Hourly:      []models.HourlyBaseline{},
+			}
+			byType[readingType] = baseline
+			baselines = append(baselines, baseline)
+		}
+
+		if hour < 0 {
+			baseline.SampleCount = count
+			baseline.Q1BPM, baseline.MedianBPM, baseline.Q3BPM = q1, median, q3
+			baseline.IQR = q3 - q1
+		} else if count >= minHourSamples {
+			baseline.Hourly = append(baseline.Hourly, models.HourlyBaseline{
+				Hour:        hour,
+				SampleCount: count,
+				MedianBPM:   median,
+				Q1BPM:       q1,
+				Q3BPM:       q3,
+			})
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return baselines, nil
+}
+
+// ReplaceBaselines stores a patient's freshly computed baselines, dropping
+// those of reading types that no longer have enough data
+func (r *BaselineRepository) ReplaceBaselines(ctx context.Context, patientID uuid.UUID, baselines []*models.PatientBaseline) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM patient_baselines WHERE patient_id = $1;`, patientID); err != nil {
+		return fmt.Errorf("failed to clear baselines: %w", err)
+	}
+
+	query := `
+		INSERT INTO patient_baselines (
+			patient_id, reading_type, window_days, sample_count,
+			median_bpm, q1_bpm, q3_bpm, hourly, computed_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);`
+	for _, b := range baselines {
+		if _, err := tx.Exec(ctx, query,
+			patientID,
+			b.ReadingType,
+			b.WindowDays,
+			b.SampleCount,
+			b.MedianBPM,
+			b.Q1BPM,
+			b.Q3BPM,
+			b.Hourly,
+			b.ComputedAt,
+		); err != nil {
+			return fmt.Errorf("failed to store baseline: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit baselines: %w", err)
+	}
+	return nil
+}
+
+// GetBaselines returns the stored baselines of a patient
+func (r *BaselineRepository) GetBaselines(ctx context.Context, patientID uuid.UUID) ([]*models.PatientBaseline, error) {
+	query := `
+		SELECT patient_id, reading_type, window_days, sample_count,
+		       median_bpm, q1_bpm, q3_bpm, hourly, computed_at
+		FROM patient_baselines
+		WHERE patient_id = $1
+		ORDER BY reading_type;`
+
+	rows, err := r.db.Pool.Query(ctx, query, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get baselines: %w", err)
+	}
+	defer rows.Close()
+
+	baselines := []*models.PatientBaseline{}
+	for rows.Next() {
+		var b models.PatientBaseline
+		if err := rows.Scan(
+			&b.PatientID,
+			&b.ReadingType,
+			&b.WindowDays,
+			&b.SampleCount,
+			&b.MedianBPM,
+			&b.Q1BPM,
+			&b.Q3BPM,
+			&b.Hourly,
+			&b.ComputedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		b.IQR = b.Q3BPM - b.Q1BPM
+		baselines = append(baselines, &b)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return baselines, nil
+}
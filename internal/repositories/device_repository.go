@@ -2,13 +2,22 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Waldir-TG/api-medical-heart-v1/internal/db"
 	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
+// likeEscaper escapes the LIKE wildcards in user supplied search terms
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
 type DeviceRepository struct {
 	db *db.PostgresDB
 }
@@ -17,15 +26,144 @@ func NewDeviceRepository(database *db.PostgresDB) *DeviceRepository {
 	return &DeviceRepository{db: database}
 }
 
-func (r *DeviceRepository) GetDevices(ctx context.Context) ([]*models.DeviceResponse, error) {
-	query := `SELECT * FROM get_devices($1,$2)`
-	rows, err := r.db.Pool.Query(ctx, query, nil, nil)
+// deviceSortColumns maps the allowed sort options to their SQL expression and
+// type, how a device writes its cursor value and how that value is parsed back
+// into a query argument. Nullable columns are coalesced so that keyset
+// comparisons stay well defined.
+var deviceSortColumns = map[string]struct {
+	expr    string
+	sqlType string
+	value   func(d *models.DeviceResponse) string
+	parse   func(value string) (any, error)
+}{
+	"registered_at": {
+		expr:    "registered_at",
+		sqlType: "timestamptz",
+		value:   func(d *models.DeviceResponse) string { return d.RegisteredAt.Format(time.RFC3339Nano) },
+		parse:   func(value string) (any, error) { return utils.ParseCursorTime(value) },
+	},
+	"serial_number": {
+		expr:    "serial_number",
+		sqlType: "text",
+		value:   func(d *models.DeviceResponse) string { return d.SerialNumber },
+		parse:   func(value string) (any, error) { return value, nil },
+	},
+	"last_sync": {
+		// Un cursor vacío es un dispositivo que nunca sincronizó: se compara
+		// con '-infinity' igual que la columna
+		expr:    "COALESCE(last_sync, '-infinity'::timestamptz)",
+		sqlType: "timestamptz",
+		value: func(d *models.DeviceResponse) string {
+			if d.LastSync == nil {
+				return ""
+			}
+			return d.LastSync.Format(time.RFC3339Nano)
+		},
+		parse: func(value string) (any, error) {
+			if value == "" {
+				return "-infinity", nil
+			}
+			return utils.ParseCursorTime(value)
+		},
+	},
+	"battery_level": {
+		expr:    "COALESCE(battery_level, -1)",
+		sqlType: "integer",
+		value: func(d *models.DeviceResponse) string {
+			if d.BatteryLevel == nil {
+				return "-1"
+			}
+			return strconv.Itoa(*d.BatteryLevel)
+		},
+		parse: func(value string) (any, error) {
+			level, err := strconv.Atoi(value)
+			if err != nil || level < -1 || level > 100 {
+				return nil, utils.ErrInvalidCursor
+			}
+			return level, nil
+		},
+	},
+}
+
+// ListDevices returns a page of devices matching the filters, using keyset
+// pagination on (sort column, id)
+func (r *DeviceRepository) ListDevices(ctx context.Context, params *models.DeviceQueryParams) (*models.DeviceListResponse, error) {
+	sortCol, ok := deviceSortColumns[params.SortBy]
+	if !ok {
+		return nil, fmt.Errorf("invalid sort column %q", params.SortBy)
+	}
+
+	var conditions []string
+	var args []any
+	addArg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if params.DeviceType != nil {
+		conditions = append(conditions, "device_type = "+addArg(*params.DeviceType))
+	}
+	if params.IsActive != nil {
+		conditions = append(conditions, "is_active = "+addArg(*params.IsActive))
+	}
+	if params.Assigned != nil {
+		if *params.Assigned {
+			conditions = append(conditions, "patient_id IS NOT NULL")
+		} else {
+			conditions = append(conditions, "patient_id IS NULL")
+		}
+	}
+	if params.BatteryBelow != nil {
+		conditions = append(conditions, "battery_level < "+addArg(*params.BatteryBelow))
+	}
+	if params.LastSyncBefore != nil {
+		conditions = append(conditions, "(last_sync IS NULL OR last_sync < "+addArg(*params.LastSyncBefore)+")")
+	}
+	if params.Search != nil {
+		pattern := "%" + likeEscaper.Replace(*params.Search) + "%"
+		conditions = append(conditions, "serial_number ILIKE "+addArg(pattern))
+	}
+
+	direction, comparator := "ASC", ">"
+	if params.SortOrder == "desc" {
+		direction, comparator = "DESC", "<"
+	}
+
+	if params.Cursor != nil {
+		cursor, err := utils.DecodeCursor(*params.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		value, err := sortCol.parse(cursor.Value)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s, id) %s (%s::%s, %s::uuid)",
+			sortCol.expr, comparator, addArg(value), sortCol.sqlType, addArg(cursor.ID)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	// Se pide una fila extra para saber si hay una página siguiente
+	query := fmt.Sprintf(`
+		SELECT id, patient_id, device_type, serial_number, COALESCE(firmware_version, ''),
+		       last_sync, battery_level, is_active, registered_at
+		FROM devices
+		%s
+		ORDER BY %s %s, id %s
+		LIMIT %s;`,
+		where, sortCol.expr, direction, direction, addArg(params.Limit+1))
+
+	rows, err := r.db.Pool.Query(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("error listing devices: %w", err)
 	}
 	defer rows.Close()
 
-	var devices []*models.DeviceResponse
+	response := &models.DeviceListResponse{Data: []*models.DeviceResponse{}}
 	for rows.Next() {
 		var device models.DeviceResponse
 
@@ -43,46 +181,46 @@ func (r *DeviceRepository) GetDevices(ctx context.Context) ([]*models.DeviceResp
 			return nil, fmt.Errorf("scan failed: %w", err)
 		}
 
-		devices = append(devices, &device)
+		if len(response.Data) == params.Limit {
+			last := response.Data[len(response.Data)-1]
+			next := utils.EncodeCursor(utils.Cursor{Value: sortCol.value(last), ID: last.DeviceID})
+			response.NextCursor = &next
+			break
+		}
+
+		response.Data = append(response.Data, &device)
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("rows error: %w", err)
 	}
-	return devices, nil
+	return response, nil
 }
 
-func (r *DeviceRepository) GetDeviceByID(ctx context.Context, id uuid.UUID) ([]*models.DeviceResponse, error) {
+// GetDeviceByID returns a single device, or nil if it does not exist
+func (r *DeviceRepository) GetDeviceByID(ctx context.Context, id uuid.UUID) (*models.DeviceResponse, error) {
 	query := `SELECT * FROM get_devices($1, $2);`
-	fmt.Println(id)
-	rows, err := r.db.Pool.Query(ctx, query, id, nil)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var devices []*models.DeviceResponse
-	for rows.Next() {
-		var device models.DeviceResponse
+	var device models.DeviceResponse
 
-		if err := rows.Scan(
-			&device.DeviceID,
-			&device.PatientID,
-			&device.DeviceType,
-			&device.SerialNumber,
-			&device.FirmwareVersion,
-			&device.LastSync,
-			&device.BatteryLevel,
-			&device.IsActive,
-			&device.RegisteredAt,
-		); err != nil {
-			return nil, fmt.Errorf("scan failed: %w", err)
+	err := r.db.Pool.QueryRow(ctx, query, id, nil).Scan(
+		&device.DeviceID,
+		&device.PatientID,
+		&device.DeviceType,
+		&device.SerialNumber,
+		&device.FirmwareVersion,
+		&device.LastSync,
+		&device.BatteryLevel,
+		&device.IsActive,
+		&device.RegisteredAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
 		}
-
-		devices = append(devices, &device)
+		return nil, fmt.Errorf("error getting device: %w", err)
 	}
-	fmt.Println(devices)
-	return devices, nil
+
+	return &device, nil
 }
 
 func (r *DeviceRepository) GetDevicesByPatientID(ctx context.Context, patientID uuid.UUID) ([]*models.DeviceResponse, error) {
@@ -120,7 +258,37 @@ func (r *DeviceRepository) GetDevicesByPatientID(ctx context.Context, patientID
 	return devices, nil
 }
 
-func (r *DeviceRepository) RegisterDevice(ctx context.Context, device *models.DeviceRegisterRequest) (uuid.UUID, error) {
+// GetDeviceBySerial returns the device with the given serial number, or nil if it does not exist
+func (r *DeviceRepository) GetDeviceBySerial(ctx context.Context, serialNumber string) (*models.DeviceResponse, error) {
+	query := `
+		SELECT id, patient_id, device_type, serial_number, COALESCE(firmware_version, ''),
+		       last_sync, battery_level, is_active, registered_at
+		FROM devices
+		WHERE serial_number = $1;`
+
+	var device models.DeviceResponse
+	err := r.db.Pool.QueryRow(ctx, query, serialNumber).Scan(
+		&device.DeviceID,
+		&device.PatientID,
+		&device.DeviceType,
+		&device.SerialNumber,
+		&device.FirmwareVersion,
+		&device.LastSync,
+		&device.BatteryLevel,
+		&device.IsActive,
+		&device.RegisteredAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting device by serial: %w", err)
+	}
+
+	return &device, nil
+}
+
+func (r *DeviceRepository) RegisterDevice(ctx context.Context, device *models.DeviceRegisterRequest, registeredBy *uuid.UUID) (uuid.UUID, error) {
 	var deviceID uuid.UUID
 	var patientUUID *uuid.UUID
 
@@ -133,8 +301,14 @@ func (r *DeviceRepository) RegisterDevice(ctx context.Context, device *models.De
 		patientUUID = &parsedUUID
 	}
 
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	query := `CALL register_device(NULL, $1, $2, $3, $4);`
-	err := r.db.Pool.QueryRow(ctx, query,
+	err = tx.QueryRow(ctx, query,
 		patientUUID,
 		device.DeviceType,
 		device.SerialNumber,
@@ -144,15 +318,30 @@ func (r *DeviceRepository) RegisterDevice(ctx context.Context, device *models.De
 	if err != nil {
 		return uuid.Nil, err
 	}
+
+	// Abrir la primera asignación si el dispositivo se registra ya asignado
+	if patientUUID != nil {
+		query := `
+			INSERT INTO device_assignments (device_id, patient_id, changed_by, reason)
+			VALUES ($1, $2, $3, 'registered');`
+		if _, err := tx.Exec(ctx, query, deviceID, patientUUID, registeredBy); err != nil {
+			return uuid.Nil, fmt.Errorf("error recording device assignment: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return uuid.Nil, err
+	}
 	return deviceID, nil
 }
 
 func (r *DeviceRepository) UpdateDevice(ctx context.Context, deviceID uuid.UUID, device *models.DeviceUpdateRequest) (string, error) {
 
+	// El paciente solo cambia mediante AssignDevice, que guarda el historial
 	query := `CALL update_device($1, $2, $3, $4, $5, $6);`
 	_, err := r.db.Pool.Exec(ctx, query,
 		deviceID,
-		device.PatientID,
+		nil,
 		device.DeviceType,
 		device.FirmwareVersion,
 		device.BatteryLevel,
@@ -165,13 +354,256 @@ func (r *DeviceRepository) UpdateDevice(ctx context.Context, deviceID uuid.UUID,
 	return "Device updated successfully", nil
 }
 
-func (r *DeviceRepository) UpdateDeviceSync(ctx context.Context, deviceID uuid.UUID, batteryLevel int) error {
-	query := `CALL update_device_sync($1, $2);`
-	_, err := r.db.Pool.Exec(ctx, query, deviceID, batteryLevel)
+// UpdateDeviceSync records a device sync and its telemetry. When the request
+// carries a message key it is recorded in the same transaction, and
+// utils.ErrDuplicateMessage is returned if it was already ingested.
+func (r *DeviceRepository) UpdateDeviceSync(ctx context.Context, deviceID uuid.UUID, sync *models.DeviceSyncRequest) error {
+	tx, err := r.db.Pool.Begin(ctx)
 	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := claimMessageKey(ctx, tx, sync.MessageKey); err != nil {
+		return err
+	}
+
+	// Sin batería reportada se conserva el último nivel conocido del dispositivo
+	query := `CALL update_device_sync($1, COALESCE($2::integer, (SELECT battery_level FROM devices WHERE id = $1)));`
+	if _, err := tx.Exec(ctx, query, deviceID, sync.BatteryLevel); err != nil {
 		return fmt.Errorf("error updating device sync: %w", err)
 	}
-	return nil
+
+	if sync.FirmwareVersion != nil {
+		if _, err := tx.Exec(ctx, `UPDATE devices SET firmware_version = $2 WHERE id = $1;`,
+			deviceID, *sync.FirmwareVersion); err != nil {
+			return fmt.Errorf("error updating device firmware: %w", err)
+		}
+	}
+
+	query = `
+		INSERT INTO device_telemetry (device_id, battery_level, firmware_version, signal_strength)
+		VALUES ($1, $2, $3, $4);`
+	if _, err := tx.Exec(ctx, query, deviceID, sync.BatteryLevel, sync.FirmwareVersion, sync.SignalStrength); err != nil {
+		return fmt.Errorf("error recording device telemetry: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetDeviceTelemetry returns the telemetry history of a device, newest first
+func (r *DeviceRepository) GetDeviceTelemetry(ctx context.Context, params *models.DeviceTelemetryQueryParams) ([]*models.DeviceTelemetry, error) {
+	query := `
+		SELECT id, device_id, battery_level, firmware_version, signal_strength, synced_at
+		FROM device_telemetry
+		WHERE device_id = $1
+		  AND ($2::timestamptz IS NULL OR synced_at >= $2)
+		  AND ($3::timestamptz IS NULL OR synced_at <= $3)
+		ORDER BY synced_at DESC
+		LIMIT $4;`
+
+	rows, err := r.db.Pool.Query(ctx, query, params.DeviceID, params.StartTime, params.EndTime, params.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("error getting device telemetry: %w", err)
+	}
+	defer rows.Close()
+
+	telemetry := []*models.DeviceTelemetry{}
+	for rows.Next() {
+		var t models.DeviceTelemetry
+		if err := rows.Scan(
+			&t.ID,
+			&t.DeviceID,
+			&t.BatteryLevel,
+			&t.FirmwareVersion,
+			&t.SignalStrength,
+			&t.SyncedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		telemetry = append(telemetry, &t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return telemetry, nil
+}
+
+// GetOfflineDevices returns active, assigned devices that have not synced since the cutoff
+func (r *DeviceRepository) GetOfflineDevices(ctx context.Context, cutoff time.Time) ([]*models.DeviceHealthIssue, error) {
+	query := `
+		SELECT id, patient_id, serial_number, device_type, last_sync, battery_level
+		FROM devices
+		WHERE is_active AND patient_id IS NOT NULL
+		  AND COALESCE(last_sync, registered_at) < $1;`
+	return r.queryDeviceHealthIssues(ctx, query, cutoff)
+}
+
+// GetLowBatteryDevices returns active, assigned devices whose last reported battery is below the threshold
+func (r *DeviceRepository) GetLowBatteryDevices(ctx context.Context, threshold int) ([]*models.DeviceHealthIssue, error) {
+	query := `
+		SELECT id, patient_id, serial_number, device_type, last_sync, battery_level
+		FROM devices
+		WHERE is_active AND patient_id IS NOT NULL
+		  AND battery_level IS NOT NULL AND battery_level < $1;`
+	return r.queryDeviceHealthIssues(ctx, query, threshold)
+}
+
+func (r *DeviceRepository) queryDeviceHealthIssues(ctx context.Context, query string, arg any) ([]*models.DeviceHealthIssue, error) {
+	rows, err := r.db.Pool.Query(ctx, query, arg)
+	if err != nil {
+		return nil, fmt.Errorf("error checking device health: %w", err)
+	}
+	defer rows.Close()
+
+	var issues []*models.DeviceHealthIssue
+	for rows.Next() {
+		var issue models.DeviceHealthIssue
+		if err := rows.Scan(
+			&issue.DeviceID,
+			&issue.PatientID,
+			&issue.SerialNumber,
+			&issue.DeviceType,
+			&issue.LastSync,
+			&issue.BatteryLevel,
+		); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		issues = append(issues, &issue)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return issues, nil
+}
+
+// AssignDevice closes the device's open assignment and, unless patientID is nil,
+// opens a new one for patientID. Assigning a device to the patient it already
+// has changes nothing. It returns pgx.ErrNoRows if the device does not exist and
+// patientFound false if patientID does not.
+func (r *DeviceRepository) AssignDevice(ctx context.Context, deviceID uuid.UUID, patientID *uuid.UUID, changedBy *uuid.UUID, reason *string) (patientFound bool, err error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Bloquear el dispositivo para serializar reasignaciones concurrentes
+	var current *uuid.UUID
+	err = tx.QueryRow(ctx, `SELECT patient_id FROM devices WHERE id = $1 FOR UPDATE;`, deviceID).Scan(&current)
+	if err != nil {
+		return false, err
+	}
+
+	if patientID != nil {
+		// FOR SHARE: el paciente no puede borrarse antes de confirmar la asignación
+		var exists int
+		err := tx.QueryRow(ctx, `SELECT 1 FROM patients WHERE id = $1 FOR SHARE;`, *patientID).Scan(&exists)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("error checking patient: %w", err)
+		}
+	}
+
+	// Sin cambios no se cierra la asignación abierta ni se abre otra
+	if (current == nil && patientID == nil) || (current != nil && patientID != nil && *current == *patientID) {
+		return true, nil
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE device_assignments
+		SET assigned_to = NOW()
+		WHERE device_id = $1 AND assigned_to IS NULL;`, deviceID); err != nil {
+		return false, fmt.Errorf("error closing device assignment: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE devices SET patient_id = $2 WHERE id = $1;`, deviceID, patientID); err != nil {
+		return false, fmt.Errorf("error updating device patient: %w", err)
+	}
+
+	if patientID != nil {
+		query := `
+			INSERT INTO device_assignments (device_id, patient_id, changed_by, reason)
+			VALUES ($1, $2, $3, $4);`
+		if _, err := tx.Exec(ctx, query, deviceID, patientID, changedBy, reason); err != nil {
+			return false, fmt.Errorf("error recording device assignment: %w", err)
+		}
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+// GetDeviceAssignments returns the assignment history of a device, newest first
+func (r *DeviceRepository) GetDeviceAssignments(ctx context.Context, deviceID uuid.UUID) ([]*models.DeviceAssignment, error) {
+	query := `
+		SELECT id, device_id, patient_id, assigned_from, assigned_to, changed_by, reason
+		FROM device_assignments
+		WHERE device_id = $1
+		ORDER BY assigned_from DESC;`
+
+	rows, err := r.db.Pool.Query(ctx, query, deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting device assignments: %w", err)
+	}
+	defer rows.Close()
+
+	assignments := []*models.DeviceAssignment{}
+	for rows.Next() {
+		var a models.DeviceAssignment
+		if err := rows.Scan(
+			&a.ID,
+			&a.DeviceID,
+			&a.PatientID,
+			&a.AssignedFrom,
+			&a.AssignedTo,
+			&a.ChangedBy,
+			&a.Reason,
+		); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		assignments = append(assignments, &a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return assignments, nil
+}
+
+// GetDeviceAssignmentAt returns the assignment that was in effect at time t, or nil if
+// the device was unassigned then
+func (r *DeviceRepository) GetDeviceAssignmentAt(ctx context.Context, deviceID uuid.UUID, t time.Time) (*models.DeviceAssignment, error) {
+	query := `
+		SELECT id, device_id, patient_id, assigned_from, assigned_to, changed_by, reason
+		FROM device_assignments
+		WHERE device_id = $1
+		  AND assigned_from <= $2
+		  AND (assigned_to IS NULL OR assigned_to > $2)
+		ORDER BY assigned_from DESC
+		LIMIT 1;`
+
+	var a models.DeviceAssignment
+	err := r.db.Pool.QueryRow(ctx, query, deviceID, t).Scan(
+		&a.ID,
+		&a.DeviceID,
+		&a.PatientID,
+		&a.AssignedFrom,
+		&a.AssignedTo,
+		&a.ChangedBy,
+		&a.Reason,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting device assignment: %w", err)
+	}
+
+	return &a, nil
 }
 
 func (r *DeviceRepository) DeactivateDevice(ctx context.Context, deviceID uuid.UUID) (string, error) {
@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+)
+
+func TestDeviceSortCursorValues(t *testing.T) {
+	lastSync := time.Date(2026, 10, 19, 8, 30, 15, 123456000, time.UTC)
+	battery := 42
+	device := &models.DeviceResponse{
+		SerialNumber: "HR-0001",
+		LastSync:     &lastSync,
+		BatteryLevel: &battery,
+		RegisteredAt: time.Date(2026, 1, 2, 3, 4, 5, 6000, time.UTC),
+	}
+
+	tests := []struct {
+		sortBy string
+		device *models.DeviceResponse
+		want   any
+	}{
+		{"registered_at", device, device.RegisteredAt},
+		{"serial_number", device, "HR-0001"},
+		{"last_sync", device, lastSync},
+		{"last_sync", &models.DeviceResponse{}, "-infinity"},
+		{"battery_level", device, 42},
+		{"battery_level", &models.DeviceResponse{}, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sortBy, func(t *testing.T) {
+			sortCol := deviceSortColumns[tt.sortBy]
+			got, err := sortCol.parse(sortCol.value(tt.device))
+			if err != nil {
+				t.Fatalf("parse() error = %v", err)
+			}
+			if want, ok := tt.want.(time.Time); ok {
+				if gotTime, ok := got.(time.Time); !ok || !gotTime.Equal(want) {
+					t.Errorf("parse() = %v, want %v", got, want)
+				}
+			} else if got != tt.want {
+				t.Errorf("parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeviceSortCursorInvalid(t *testing.T) {
+	tests := []struct {
+		sortBy, value string
+	}{
+		{"registered_at", ""},
+		{"registered_at", "2026-10-19"},
+		{"last_sync", "now"},
+		{"battery_level", ""},
+		{"battery_level", "12.5"},
+		{"battery_level", "101"},
+		{"battery_level", "1; DROP TABLE devices"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sortBy+"/"+tt.value, func(t *testing.T) {
+			if _, err := deviceSortColumns[tt.sortBy].parse(tt.value); !errors.Is(err, utils.ErrInvalidCursor) {
+				t.Errorf("parse(%q) error = %v, want ErrInvalidCursor", tt.value, err)
+			}
+		})
+	}
+}
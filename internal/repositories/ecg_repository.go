@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/db"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type EcgRepository struct {
+	db *db.PostgresDB
+}
+
+func NewEcgRepository(database *db.PostgresDB) *EcgRepository {
+	return &EcgRepository{db: database}
+}
+
+const ecgColumns = `id, reading_id, patient_id, device_id, lead, sample_rate_hz, gain,
+	sample_count, duration_ms, encoding, octet_length(data), recorded_at, created_at`
+
+// CreateRecording stores an encoded ECG strip and returns its ID
+func (r *EcgRepository) CreateRecording(ctx context.Context, recording *models.EcgRecording, data []byte) (uuid.UUID, error) {
+	query := `
+		INSERT INTO ecg_recordings (
+			reading_id, patient_id, device_id, lead, sample_rate_hz, gain,
+			sample_count, duration_ms, encoding, data, recorded_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id;`
+
+	var recordingID uuid.UUID
+	err := r.db.Pool.QueryRow(ctx, query,
+		recording.ReadingID,
+		recording.PatientID,
+		recording.DeviceID,
+		recording.Lead,
+		recording.SampleRateHz,
+		recording.Gain,
+		recording.SampleCount,
+		recording.DurationMs,
+		recording.Encoding,
+		data,
+		recording.RecordedAt,
+	).Scan(&recordingID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to store ECG recording: %w", err)
+	}
+
+	return recordingID, nil
+}
+
+// GetRecordingsByReading returns the metadata of every strip attached to a reading
+func (r *EcgRepository) GetRecordingsByReading(ctx context.Context, readingID uuid.UUID) ([]*models.EcgRecording, error) {
+	query := `SELECT ` + ecgColumns + ` FROM ecg_recordings WHERE reading_id = $1 ORDER BY recorded_at;`
+
+	rows, err := r.db.Pool.Query(ctx, query, readingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ECG recordings: %w", err)
+	}
+	defer rows.Close()
+
+	recordings := []*models.EcgRecording{}
+	for rows.Next() {
+		recording, err := scanEcgRecording(rows)
+		if err != nil {
+			return nil, err
+		}
+		recordings = append(recordings, recording)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return recordings, nil
+}
+
+// GetRecording returns a strip's metadata and encoded data, or nil if it does not exist
+func (r *EcgRepository) GetRecording(ctx context.Context, recordingID uuid.UUID) (*models.EcgRecording, []byte, error) {
+	query := `SELECT ` + ecgColumns + `, data FROM ecg_recordings WHERE id = $1;`
+
+	var recording models.EcgRecording
+	var data []byte
+	err := r.db.Pool.QueryRow(ctx, query, recordingID).Scan(
+		&recording.ID,
+		&recording.ReadingID,
+		&recording.PatientID,
+		&recording.DeviceID,
+		&recording.Lead,
+		&recording.SampleRateHz,
+		&recording.Gain,
+		&recording.SampleCount,
+		&recording.DurationMs,
+		&recording.Encoding,
+		&recording.SizeBytes,
+		&recording.RecordedAt,
+		&recording.CreatedAt,
+		&data,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("failed to get ECG recording: %w", err)
+	}
+
+	return &recording, data, nil
+}
+
+func scanEcgRecording(row pgx.Row) (*models.EcgRecording, error) {
+	var recording models.EcgRecording
+	if err := row.Scan(
+		&recording.ID,
+		&recording.ReadingID,
+		&recording.PatientID,
+		&recording.DeviceID,
+		&recording.Lead,
+		&recording.SampleRateHz,
+		&recording.Gain,
+		&recording.SampleCount,
+		&recording.DurationMs,
+		&recording.Encoding,
+		&recording.SizeBytes,
+		&recording.RecordedAt,
+		&recording.CreatedAt,
+	); err != nil {
+		return nil, fmt.Errorf("scan failed: %w", err)
+	}
+	return &recording, nil
+}
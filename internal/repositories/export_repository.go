@@ -0,0 +1,209 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/db"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type ExportRepository struct {
+	db *db.PostgresDB
+}
+
+func NewExportRepository(database *db.PostgresDB) *ExportRepository {
+	return &ExportRepository{db: database}
+}
+
+const exportJobColumns = `
+	id, patient_id, requested_by, format, columns, start_time, end_time, timezone,
+	status, row_count, file_size, error, created_at, started_at, finished_at, expires_at`
+
+// CreateExportJob queues an export requested by requestedBy
+func (r *ExportRepository) CreateExportJob(
+	ctx context.Context,
+	request *models.ReadingExportRequest,
+	requestedBy *uuid.UUID,
+) (*models.ExportJob, error) {
+	query := `
+		INSERT INTO export_jobs (patient_id, requested_by, format, columns, start_time, end_time, timezone)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING ` + exportJobColumns + `;`
+
+	job, err := scanExportJob(r.db.Pool.QueryRow(ctx, query,
+		request.PatientID,
+		requestedBy,
+		request.Format,
+		request.Columns,
+		request.StartTime,
+		request.EndTime,
+		request.Timezone,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export job: %w", err)
+	}
+	return job, nil
+}
+
+// GetExportJob returns an export job, or nil if it does not exist
+func (r *ExportRepository) GetExportJob(ctx context.Context, jobID uuid.UUID) (*models.ExportJob, error) {
+	job, err := scanExportJob(r.db.Pool.QueryRow(ctx,
+		`SELECT `+exportJobColumns+` FROM export_jobs WHERE id = $1;`, jobID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get export job: %w", err)
+	}
+	return job, nil
+}
+
+// GetPatientExportJobs returns a patient's most recent export jobs
+func (r *ExportRepository) GetPatientExportJobs(ctx context.Context, patientID uuid.UUID, limit int) ([]*models.ExportJob, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT `+exportJobColumns+`
+		FROM export_jobs
+		WHERE patient_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2;`, patientID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get export jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []*models.ExportJob{}
+	for rows.Next() {
+		job, err := scanExportJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return jobs, nil
+}
+
+// ClaimExportJob marks the oldest pending job as running and returns it, or
+// nil when there is none. SKIP LOCKED lets several replicas claim jobs at
+// the same time.
+func (r *ExportRepository) ClaimExportJob(ctx context.Context) (*models.ExportJob, error) {
+	query := `
+		UPDATE export_jobs
+		SET status = 'running', started_at = NOW()
+		WHERE id = (
+			SELECT id FROM export_jobs
+			WHERE status = 'pending'
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + exportJobColumns + `;`
+
+	job, err := scanExportJob(r.db.Pool.QueryRow(ctx, query))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim export job: %w", err)
+	}
+	return job, nil
+}
+
+// CompleteExportJob records the file produced by a running job
+func (r *ExportRepository) CompleteExportJob(ctx context.Context, jobID uuid.UUID, rowCount, fileSize int64, expiresAt time.Time) error {
+	_, err := r.db.Pool.Exec(ctx, `
+		UPDATE export_jobs
+		SET status = 'completed', row_count = $2, file_size = $3, expires_at = $4, finished_at = NOW()
+		WHERE id = $1;`, jobID, rowCount, fileSize, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to complete export job: %w", err)
+	}
+	return nil
+}
+
+// FailExportJob records why a running job failed
+func (r *ExportRepository) FailExportJob(ctx context.Context, jobID uuid.UUID, message string) error {
+	_, err := r.db.Pool.Exec(ctx, `
+		UPDATE export_jobs
+		SET status = 'failed', error = $2, finished_at = NOW()
+		WHERE id = $1;`, jobID, message)
+	if err != nil {
+		return fmt.Errorf("failed to record export job failure: %w", err)
+	}
+	return nil
+}
+
+// RequeueExportJobs returns to pending the running jobs started before
+// startedBefore, left behind by a replica that stopped, and the given job if
+// set (interrupted on this replica). It returns the number of jobs requeued.
+func (r *ExportRepository) RequeueExportJobs(ctx context.Context, startedBefore time.Time, jobID *uuid.UUID) (int64, error) {
+	tag, err := r.db.Pool.Exec(ctx, `
+		UPDATE export_jobs
+		SET status = 'pending', started_at = NULL
+		WHERE status = 'running' AND (started_at < $1 OR id = $2);`, startedBefore, jobID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue export jobs: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ExpireExportJobs marks the completed jobs past their expiry as expired and
+// returns them, so that their files can be deleted
+func (r *ExportRepository) ExpireExportJobs(ctx context.Context, now time.Time) ([]*models.ExportJob, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		UPDATE export_jobs
+		SET status = 'expired'
+		WHERE status = 'completed' AND expires_at <= $1
+		RETURNING `+exportJobColumns+`;`, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire export jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []*models.ExportJob
+	for rows.Next() {
+		job, err := scanExportJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return jobs, nil
+}
+
+func scanExportJob(row pgx.Row) (*models.ExportJob, error) {
+	var job models.ExportJob
+	if err := row.Scan(
+		&job.ID,
+		&job.PatientID,
+		&job.RequestedBy,
+		&job.Format,
+		&job.Columns,
+		&job.StartTime,
+		&job.EndTime,
+		&job.Timezone,
+		&job.Status,
+		&job.RowCount,
+		&job.FileSize,
+		&job.Error,
+		&job.CreatedAt,
+		&job.StartedAt,
+		&job.FinishedAt,
+		&job.ExpiresAt,
+	); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
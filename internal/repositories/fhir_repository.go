@@ -0,0 +1,208 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/db"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+	"github.com/google/uuid"
+)
+
+// FHIRRepository reads and writes the measurements exposed through the FHIR
+// facade, which span heart readings and vital observations
+type FHIRRepository struct {
+	db *db.PostgresDB
+}
+
+func NewFHIRRepository(database *db.PostgresDB) *FHIRRepository {
+	return &FHIRRepository{db: database}
+}
+
+// Cada lectura da una medición por valor; de las observaciones independientes
+// solo se exponen SpO2 y presión arterial
+const measurementsQuery = `
+	SELECT 'hr' AS kind, id, patient_id, device_id, 'heart_rate' AS observation_type,
+	       bpm::float8 AS value, NULL::float8 AS secondary_value, notes, reading_type::text, time
+	FROM heart_readings
+	WHERE deleted_at IS NULL
+	UNION ALL
+	SELECT 'spo2', id, patient_id, device_id, 'spo2', oxygen_level::float8, NULL, NULL, NULL, time
+	FROM heart_readings
+	WHERE deleted_at IS NULL AND oxygen_level IS NOT NULL
+	UNION ALL
+	SELECT 'bp', id, patient_id, device_id, 'blood_pressure', systolic_pressure::float8,
+	       diastolic_pressure::float8, NULL, NULL, time
+	FROM heart_readings
+	WHERE deleted_at IS NULL AND systolic_pressure IS NOT NULL AND diastolic_pressure IS NOT NULL
+	UNION ALL
+	SELECT 'vital', id, patient_id, device_id, observation_type, value, secondary_value, notes, NULL, time
+	FROM vital_observations
+	WHERE observation_type IN ('spo2', 'blood_pressure')`
+
+// SearchMeasurements returns a page of measurements matching the filters,
+// using keyset pagination on (time, id, kind). The cursor value holds the
+// time and the kind separated by "|".
+func (r *FHIRRepository) SearchMeasurements(ctx context.Context, params *models.MeasurementQueryParams) (*models.MeasurementListResponse, error) {
+	var args []any
+	addArg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conditions := []string{"TRUE"}
+	if params.PatientID != nil {
+		conditions = append(conditions, "patient_id = "+addArg(*params.PatientID))
+	}
+	if params.Kind != nil {
+		conditions = append(conditions, "kind = "+addArg(*params.Kind))
+	}
+	if params.ID != nil {
+		conditions = append(conditions, "id = "+addArg(*params.ID))
+	}
+	if len(params.ObservationTypes) > 0 {
+		conditions = append(conditions, "observation_type = ANY("+addArg(params.ObservationTypes)+")")
+	}
+	if params.StartTime != nil {
+		conditions = append(conditions, "time >= "+addArg(*params.StartTime))
+	}
+	if params.EndTime != nil {
+		conditions = append(conditions, "time < "+addArg(*params.EndTime))
+	}
+
+	direction, comparator := "DESC", "<"
+	if params.SortOrder == "asc" {
+		direction, comparator = "ASC", ">"
+	}
+	if params.Cursor != nil {
+		cursor, err := utils.DecodeCursor(*params.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		value, kind, ok := strings.Cut(cursor.Value, "|")
+		if !ok {
+			return nil, utils.ErrInvalidCursor
+		}
+		cursorTime, err := utils.ParseCursorTime(value)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, fmt.Sprintf("(time, id, kind) %s (%s::timestamptz, %s::uuid, %s::text)",
+			comparator, addArg(cursorTime), addArg(cursor.ID), addArg(kind)))
+	}
+
+	// Se pide una fila extra para saber si hay una página siguiente
+	query := fmt.Sprintf(`
+		SELECT kind, id, patient_id, device_id, observation_type, value, secondary_value, notes, reading_type, time
+		FROM (%s) m
+		WHERE %s
+		ORDER BY time %s, id %s, kind %s
+		LIMIT %s;`,
+		measurementsQuery, strings.Join(conditions, " AND "), direction, direction, direction, addArg(params.Limit+1))
+
+	rows, err := r.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search measurements: %w", err)
+	}
+	defer rows.Close()
+
+	response := &models.MeasurementListResponse{Data: []*models.Measurement{}}
+	for rows.Next() {
+		var m models.Measurement
+		if err := rows.Scan(
+			&m.Kind,
+			&m.ID,
+			&m.PatientID,
+			&m.DeviceID,
+			&m.ObservationType,
+			&m.Value,
+			&m.SecondaryValue,
+			&m.Notes,
+			&m.ReadingType,
+			&m.Time,
+		); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+
+		if len(response.Data) == params.Limit {
+			last := response.Data[len(response.Data)-1]
+			next := utils.EncodeCursor(utils.Cursor{Value: last.Time.Format(time.RFC3339Nano) + "|" + last.Kind, ID: last.ID})
+			response.NextCursor = &next
+			break
+		}
+		response.Data = append(response.Data, &m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return response, nil
+}
+
+// ImportMeasurements stores the heart readings and vital observations of a
+// FHIR transaction in one database transaction: either all are stored or
+// none. Heart readings are imported as by ImportHeartReadings, skipping
+// duplicates; it returns which readings were duplicates and the stored
+// vital observations.
+func (r *FHIRRepository) ImportMeasurements(
+	ctx context.Context,
+	readings []*models.HeartReading,
+	vitals []*models.VitalObservation,
+) ([]bool, []*models.VitalObservation, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Las lecturas se importan por paciente, conservando su posición
+	duplicates := make([]bool, len(readings))
+	positions := map[uuid.UUID][]int{}
+	var patients []uuid.UUID
+	for i, reading := range readings {
+		if _, ok := positions[reading.PatientID]; !ok {
+			patients = append(patients, reading.PatientID)
+		}
+		positions[reading.PatientID] = append(positions[reading.PatientID], i)
+	}
+	for _, patientID := range patients {
+		batch := make([]*models.HeartReading, len(positions[patientID]))
+		for n, i := range positions[patientID] {
+			batch[n] = readings[i]
+		}
+		batchDuplicates, err := importHeartReadings(ctx, tx, patientID, batch, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		for n, i := range positions[patientID] {
+			duplicates[i] = batchDuplicates[n]
+		}
+	}
+
+	created := make([]*models.VitalObservation, len(vitals))
+	for i, vital := range vitals {
+		request := &models.VitalObservationCreateRequest{
+			PatientID:       vital.PatientID,
+			DeviceID:        vital.DeviceID,
+			ObservationType: vital.ObservationType,
+			Value:           vital.Value,
+			SecondaryValue:  vital.SecondaryValue,
+			EntryMethod:     vital.EntryMethod,
+			EnteredBy:       vital.EnteredBy,
+			Source:          vital.Source,
+			Notes:           vital.Notes,
+			Time:            &vital.Time,
+		}
+		if created[i], err = insertVitalObservation(ctx, tx, request, vital.Unit); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit FHIR transaction: %w", err)
+	}
+	return duplicates, created, nil
+}
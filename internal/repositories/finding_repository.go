@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/db"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/google/uuid"
+)
+
+type FindingRepository struct {
+	db *db.PostgresDB
+}
+
+func NewFindingRepository(database *db.PostgresDB) *FindingRepository {
+	return &FindingRepository{db: database}
+}
+
+// ReplaceFindings stores the findings of a screening run, replacing earlier
+// findings of the same source for the reading. When irregular is not nil it
+// is written back to the reading's irregularity_detected flag.
+func (r *FindingRepository) ReplaceFindings(
+	ctx context.Context,
+	readingID uuid.UUID,
+	source string,
+	findings []*models.ReadingFinding,
+	irregular *bool,
+) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM reading_findings WHERE reading_id = $1 AND source = $2;`,
+		readingID, source,
+	); err != nil {
+		return fmt.Errorf("failed to clear previous findings: %w", err)
+	}
+
+	query := `
+		INSERT INTO reading_findings (
+			reading_id, patient_id, source, finding_type, start_offset_ms, duration_ms,
+			min_bpm, max_bpm, mean_bpm, details, reading_time
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, created_at;`
+
+	for _, finding := range findings {
+		if err := tx.QueryRow(ctx, query,
+			readingID,
+			finding.PatientID,
+			source,
+			finding.FindingType,
+			finding.StartOffsetMs,
+			finding.DurationMs,
+			finding.MinBPM,
+			finding.MaxBPM,
+			finding.MeanBPM,
+			finding.Details,
+			finding.ReadingTime,
+		).Scan(&finding.ID, &finding.CreatedAt); err != nil {
+			return fmt.Errorf("failed to store finding: %w", err)
+		}
+	}
+
+	if irregular != nil {
+		if _, err := tx.Exec(ctx,
+			`UPDATE heart_readings SET irregularity_detected = $2 WHERE id = $1;`,
+			readingID, *irregular,
+		); err != nil {
+			return fmt.Errorf("failed to update reading irregularity: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit findings: %w", err)
+	}
+	return nil
+}
+
+// GetFindingsByReading returns every finding recorded for a reading
+func (r *FindingRepository) GetFindingsByReading(ctx context.Context, readingID uuid.UUID) ([]*models.ReadingFinding, error) {
+	query := `
+		SELECT id, reading_id, patient_id, source, finding_type, start_offset_ms, duration_ms,
+		       min_bpm, max_bpm, mean_bpm, details, reading_time, created_at
+		FROM reading_findings
+		WHERE reading_id = $1
+		ORDER BY source, start_offset_ms NULLS FIRST, finding_type;`
+
+	rows, err := r.db.Pool.Query(ctx, query, readingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get findings: %w", err)
+	}
+	defer rows.Close()
+
+	findings := []*models.ReadingFinding{}
+	for rows.Next() {
+		var finding models.ReadingFinding
+		if err := rows.Scan(
+			&finding.ID,
+			&finding.ReadingID,
+			&finding.PatientID,
+			&finding.Source,
+			&finding.FindingType,
+			&finding.StartOffsetMs,
+			&finding.DurationMs,
+			&finding.MinBPM,
+			&finding.MaxBPM,
+			&finding.MeanBPM,
+			&finding.Details,
+			&finding.ReadingTime,
+			&finding.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		findings = append(findings, &finding)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return findings, nil
+}
@@ -0,0 +1,301 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/db"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type FirmwareRepository struct {
+	db *db.PostgresDB
+}
+
+func NewFirmwareRepository(database *db.PostgresDB) *FirmwareRepository {
+	return &FirmwareRepository{db: database}
+}
+
+// CreateRelease inserts a firmware release into the catalog
+func (r *FirmwareRepository) CreateRelease(ctx context.Context, release *models.FirmwareReleaseCreateRequest, createdBy *uuid.UUID) (uuid.UUID, error) {
+	query := `
+		INSERT INTO firmware_releases (device_type, version, release_notes, checksum, download_url, created_by)
+		VALUES ($1, $2, $3, LOWER($4), $5, $6)
+		RETURNING id;`
+
+	var releaseID uuid.UUID
+	err := r.db.Pool.QueryRow(ctx, query,
+		release.DeviceType,
+		release.Version,
+		release.ReleaseNotes,
+		release.Checksum,
+		release.DownloadURL,
+		createdBy,
+	).Scan(&releaseID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create firmware release: %w", err)
+	}
+
+	return releaseID, nil
+}
+
+// GetReleases returns the firmware catalog, optionally filtered by device type
+func (r *FirmwareRepository) GetReleases(ctx context.Context, deviceType *string) ([]*models.FirmwareRelease, error) {
+	query := `
+		SELECT id, device_type, version, release_notes, checksum, download_url, created_by, released_at
+		FROM firmware_releases
+		WHERE ($1::varchar IS NULL OR device_type = $1)
+		ORDER BY device_type, released_at DESC;`
+
+	rows, err := r.db.Pool.Query(ctx, query, deviceType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get firmware releases: %w", err)
+	}
+	defer rows.Close()
+
+	releases := []*models.FirmwareRelease{}
+	for rows.Next() {
+		var release models.FirmwareRelease
+		if err := rows.Scan(
+			&release.ID,
+			&release.DeviceType,
+			&release.Version,
+			&release.ReleaseNotes,
+			&release.Checksum,
+			&release.DownloadURL,
+			&release.CreatedBy,
+			&release.ReleasedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		releases = append(releases, &release)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return releases, nil
+}
+
+// GetReleaseByID returns a firmware release, or nil if it does not exist
+func (r *FirmwareRepository) GetReleaseByID(ctx context.Context, releaseID uuid.UUID) (*models.FirmwareRelease, error) {
+	query := `
+		SELECT id, device_type, version, release_notes, checksum, download_url, created_by, released_at
+		FROM firmware_releases
+		WHERE id = $1;`
+
+	var release models.FirmwareRelease
+	err := r.db.Pool.QueryRow(ctx, query, releaseID).Scan(
+		&release.ID,
+		&release.DeviceType,
+		&release.Version,
+		&release.ReleaseNotes,
+		&release.Checksum,
+		&release.DownloadURL,
+		&release.CreatedBy,
+		&release.ReleasedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get firmware release: %w", err)
+	}
+
+	return &release, nil
+}
+
+// CreateRollout starts a staged rollout of a firmware release
+func (r *FirmwareRepository) CreateRollout(ctx context.Context, firmwareID uuid.UUID, rollout *models.FirmwareRolloutCreateRequest, createdBy *uuid.UUID) (uuid.UUID, error) {
+	query := `
+		INSERT INTO firmware_rollouts (firmware_id, percentage, device_ids, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id;`
+
+	var deviceIDs []uuid.UUID
+	if len(rollout.DeviceIDs) > 0 {
+		deviceIDs = rollout.DeviceIDs
+	}
+
+	var rolloutID uuid.UUID
+	err := r.db.Pool.QueryRow(ctx, query, firmwareID, rollout.Percentage, deviceIDs, createdBy).Scan(&rolloutID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create firmware rollout: %w", err)
+	}
+
+	return rolloutID, nil
+}
+
+// UpdateRollout changes the status or percentage of a rollout
+func (r *FirmwareRepository) UpdateRollout(ctx context.Context, rolloutID uuid.UUID, update *models.FirmwareRolloutUpdateRequest) error {
+	query := `
+		UPDATE firmware_rollouts
+		SET percentage = COALESCE($2, percentage),
+		    status = COALESCE($3, status)
+		WHERE id = $1;`
+
+	tag, err := r.db.Pool.Exec(ctx, query, rolloutID, update.Percentage, update.Status)
+	if err != nil {
+		return fmt.Errorf("failed to update firmware rollout: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetRolloutSummary returns a rollout with its success and failure counts, or nil if it does not exist
+func (r *FirmwareRepository) GetRolloutSummary(ctx context.Context, rolloutID uuid.UUID) (*models.FirmwareRolloutSummary, error) {
+	query := `
+		SELECT fr.id, fr.firmware_id, fr.percentage, fr.device_ids, fr.status, fr.created_by, fr.created_at,
+		       rel.device_type, rel.version,
+		       COUNT(DISTINCT rep.device_id) FILTER (WHERE rep.status = 'success'),
+		       COUNT(DISTINCT rep.device_id) FILTER (WHERE rep.status = 'failure')
+		FROM firmware_rollouts fr
+		JOIN firmware_releases rel ON rel.id = fr.firmware_id
+		LEFT JOIN firmware_update_reports rep ON rep.rollout_id = fr.id
+		WHERE fr.id = $1
+		GROUP BY fr.id, rel.device_type, rel.version;`
+
+	var summary models.FirmwareRolloutSummary
+	err := r.db.Pool.QueryRow(ctx, query, rolloutID).Scan(
+		&summary.ID,
+		&summary.FirmwareID,
+		&summary.Percentage,
+		&summary.DeviceIDs,
+		&summary.Status,
+		&summary.CreatedBy,
+		&summary.CreatedAt,
+		&summary.DeviceType,
+		&summary.Version,
+		&summary.SuccessCount,
+		&summary.FailureCount,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get firmware rollout: %w", err)
+	}
+
+	return &summary, nil
+}
+
+// GetActiveRollouts returns the active rollouts for a device type that the device
+// has not already completed successfully
+func (r *FirmwareRepository) GetActiveRollouts(ctx context.Context, deviceType string, deviceID uuid.UUID) ([]*models.ActiveFirmwareRollout, error) {
+	query := `
+		SELECT fr.id, fr.firmware_id, fr.percentage, fr.device_ids, fr.status, fr.created_by, fr.created_at,
+		       rel.id, rel.device_type, rel.version, rel.release_notes, rel.checksum, rel.download_url,
+		       rel.created_by, rel.released_at
+		FROM firmware_rollouts fr
+		JOIN firmware_releases rel ON rel.id = fr.firmware_id
+		WHERE fr.status = 'active' AND rel.device_type = $1
+		  AND NOT EXISTS (
+			SELECT 1 FROM firmware_update_reports rep
+			WHERE rep.rollout_id = fr.id AND rep.device_id = $2 AND rep.status = 'success'
+		  )
+		ORDER BY fr.created_at DESC;`
+
+	rows, err := r.db.Pool.Query(ctx, query, deviceType, deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active firmware rollouts: %w", err)
+	}
+	defer rows.Close()
+
+	var rollouts []*models.ActiveFirmwareRollout
+	for rows.Next() {
+		var rollout models.ActiveFirmwareRollout
+		if err := rows.Scan(
+			&rollout.ID,
+			&rollout.FirmwareID,
+			&rollout.Percentage,
+			&rollout.DeviceIDs,
+			&rollout.Status,
+			&rollout.CreatedBy,
+			&rollout.CreatedAt,
+			&rollout.Release.ID,
+			&rollout.Release.DeviceType,
+			&rollout.Release.Version,
+			&rollout.Release.ReleaseNotes,
+			&rollout.Release.Checksum,
+			&rollout.Release.DownloadURL,
+			&rollout.Release.CreatedBy,
+			&rollout.Release.ReleasedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		rollouts = append(rollouts, &rollout)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return rollouts, nil
+}
+
+// CreateUpdateReport records the result of an update. On success the device's
+// firmware version is updated to the rollout's release in the same transaction.
+func (r *FirmwareRepository) CreateUpdateReport(ctx context.Context, deviceID uuid.UUID, report *models.FirmwareUpdateReportRequest) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO firmware_update_reports (rollout_id, device_id, status, message)
+		VALUES ($1, $2, $3, $4);`
+	if _, err := tx.Exec(ctx, query, report.RolloutID, deviceID, report.Status, report.Message); err != nil {
+		return fmt.Errorf("failed to record firmware update report: %w", err)
+	}
+
+	if report.Status == "success" {
+		query := `
+			UPDATE devices d
+			SET firmware_version = rel.version
+			FROM firmware_rollouts fr
+			JOIN firmware_releases rel ON rel.id = fr.firmware_id
+			WHERE fr.id = $1 AND d.id = $2 AND d.device_type = rel.device_type;`
+		if _, err := tx.Exec(ctx, query, report.RolloutID, deviceID); err != nil {
+			return fmt.Errorf("failed to update device firmware: %w", err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetVersionDistribution returns how many devices run each firmware version
+func (r *FirmwareRepository) GetVersionDistribution(ctx context.Context, deviceType *string) ([]*models.FirmwareVersionDistribution, error) {
+	query := `
+		SELECT device_type, COALESCE(NULLIF(firmware_version, ''), 'unknown'),
+		       COUNT(*), COUNT(*) FILTER (WHERE is_active)
+		FROM devices
+		WHERE ($1::varchar IS NULL OR device_type = $1)
+		GROUP BY 1, 2
+		ORDER BY 1, 3 DESC;`
+
+	rows, err := r.db.Pool.Query(ctx, query, deviceType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get firmware distribution: %w", err)
+	}
+	defer rows.Close()
+
+	distribution := []*models.FirmwareVersionDistribution{}
+	for rows.Next() {
+		var d models.FirmwareVersionDistribution
+		if err := rows.Scan(&d.DeviceType, &d.FirmwareVersion, &d.DeviceCount, &d.ActiveCount); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		distribution = append(distribution, &d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return distribution, nil
+}
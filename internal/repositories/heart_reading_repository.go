@@ -2,12 +2,18 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Waldir-TG/api-medical-heart-v1/internal/db"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/importer"
 	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
 type HeartReadingRepository struct {
@@ -18,11 +24,40 @@ func NewHeartReadingRepository(database *db.PostgresDB) *HeartReadingRepository
 	return &HeartReadingRepository{db: database}
 }
 
-// CreateHeartReading inserts a new heart reading record
-func (r *HeartReadingRepository) CreateHeartReading(ctx context.Context, reading *models.HeartReadingCreateRequest) error {
-	query := `CALL insert_heart_reading($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16);`
+// readingFilterCondition returns the SQL condition of a models.ReadingFilter
+// on heart_readings, whose ExcludeArtifacts and MinQuality are the query
+// arguments $first and $first+1
+func readingFilterCondition(first int) string {
+	return fmt.Sprintf(`(NOT $%d OR %s)
+		  AND ($%d::float8 IS NULL OR COALESCE(quality_score, 1) >= $%d)`,
+		first, notArtifactCondition, first+1, first+1)
+}
 
-	_, err := r.db.Pool.Exec(ctx, query,
+// CreateHeartReading stores a new heart reading through the insert_heart_reading
+// procedure and, in the same transaction, completes it with its quality
+// assessment and measurement time, counts it in the heart rate rollups and
+// stores its server-computed HRV metrics, if any. It returns the stored
+// reading. A nil reading time means now. When the request carries a message
+// key it is recorded in the same transaction, and utils.ErrDuplicateMessage is
+// returned if it was already ingested.
+func (r *HeartReadingRepository) CreateHeartReading(
+	ctx context.Context,
+	reading *models.HeartReadingCreateRequest,
+	hrv *models.HeartReadingHRV,
+	quality *models.ReadingQuality,
+) (*models.HeartReading, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := claimMessageKey(ctx, tx, reading.MessageKey); err != nil {
+		return nil, err
+	}
+
+	query := `CALL insert_heart_reading($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16);`
+	_, err = tx.Exec(ctx, query,
 		reading.PatientID,
 		reading.DeviceID,
 		reading.EntryMethod,
@@ -40,127 +75,580 @@ func (r *HeartReadingRepository) CreateHeartReading(ctx context.Context, reading
 		reading.Notes,
 		reading.ReliabilityScore,
 	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create heart reading: %w", err)
+	}
+
+	created := &models.HeartReading{
+		PatientID:            reading.PatientID,
+		DeviceID:             reading.DeviceID,
+		EntryMethod:          reading.EntryMethod,
+		EnteredBy:            reading.EnteredBy,
+		ReadingType:          reading.ReadingType,
+		Source:               reading.Source,
+		BPM:                  reading.BPM,
+		Variability:          reading.Variability,
+		IrregularityDetected: reading.IrregularityDetected,
+		OxygenLevel:          reading.OxygenLevel,
+		SystolicPressure:     reading.SystolicPressure,
+		DiastolicPressure:    reading.DiastolicPressure,
+		Temperature:          reading.Temperature,
+		ActivityLevel:        reading.ActivityLevel,
+		Notes:                reading.Notes,
+		ReliabilityScore:     reading.ReliabilityScore,
+	}
+	if quality != nil {
+		created.QualityScore = &quality.Score
+		created.QualityFlags = quality.Flags
+	}
+
+	// El procedimiento no devuelve la fila: es la única del paciente escrita por
+	// esta transacción (xmin) y su hora, que el procedimiento toma de la
+	// recepción, no es anterior al inicio de la transacción
+	query = `
+		UPDATE heart_readings
+		SET quality_score = $2,
+		    quality_flags = COALESCE($3, '{}'::text[]),
+		    time = COALESCE($4, time)
+		WHERE patient_id = $1
+		  AND time >= now()
+		  AND xmin = pg_current_xact_id()::xid
+		RETURNING id, processed, time;`
+
+	rows, err := tx.Query(ctx, query, reading.PatientID, created.QualityScore, created.QualityFlags, reading.Time)
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete heart reading: %w", err)
+	}
+	found := 0
+	for rows.Next() {
+		if err := rows.Scan(&created.ID, &created.Processed, &created.Time); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan heart reading: %w", err)
+		}
+		found++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to complete heart reading: %w", err)
+	}
+	if found != 1 {
+		return nil, fmt.Errorf("insert_heart_reading stored %d readings, expected 1", found)
+	}
+
+	if err := addToRollups(ctx, tx, created.PatientID, created.Time, created.BPM); err != nil {
+		return nil, err
+	}
 
+	if hrv != nil {
+		hrv.ReadingID = created.ID
+		hrv.PatientID = created.PatientID
+		hrv.Time = created.Time
+
+		query := `
+			INSERT INTO heart_reading_hrv (
+				reading_id, patient_id, time, rr_intervals, interval_count, rejected_count,
+				mean_rr, mean_hr, sdnn, rmssd, pnn50, lf_power, hf_power, lf_hf_ratio
+			)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14);`
+		_, err := tx.Exec(ctx, query,
+			hrv.ReadingID,
+			hrv.PatientID,
+			hrv.Time,
+			hrv.RRIntervals,
+			hrv.IntervalCount,
+			hrv.RejectedCount,
+			hrv.MeanRR,
+			hrv.MeanHR,
+			hrv.SDNN,
+			hrv.RMSSD,
+			hrv.PNN50,
+			hrv.LFPower,
+			hrv.HFPower,
+			hrv.LFHFRatio,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store heart rate variability: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit heart reading: %w", err)
+	}
+
+	return created, nil
+}
+
+// ImportHeartReadings stores a batch of a patient's historical readings and
+// counts them in the rollups. A reading is a duplicate, and is skipped, when
+// the patient already has one with the same BPM in the same second. Imported
+// readings are stored as processed and evaluated: history does not raise
+// alerts. With
+// dryRun only the duplicates are looked up. It returns which readings were
+// duplicates.
+func (r *HeartReadingRepository) ImportHeartReadings(
+	ctx context.Context,
+	patientID uuid.UUID,
+	readings []*models.HeartReading,
+	dryRun bool,
+) ([]bool, error) {
+	if len(readings) == 0 {
+		return []bool{}, nil
+	}
+
+	tx, err := r.db.Pool.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to create heart reading: %w", err)
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
 	}
+	defer tx.Rollback(ctx)
 
-	return nil
+	duplicates, err := importHeartReadings(ctx, tx, patientID, readings, dryRun)
+	if err != nil || dryRun {
+		return duplicates, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit imported readings: %w", err)
+	}
+	return duplicates, nil
+}
+
+// importHeartReadings stores a batch of a patient's readings within tx,
+// skipping the duplicates as described in ImportHeartReadings
+func importHeartReadings(
+	ctx context.Context,
+	tx pgx.Tx,
+	patientID uuid.UUID,
+	readings []*models.HeartReading,
+	dryRun bool,
+) ([]bool, error) {
+	duplicates := make([]bool, len(readings))
+	if len(readings) == 0 {
+		return duplicates, nil
+	}
+
+	// Dos importaciones simultáneas del mismo paciente no pueden colarse el mismo duplicado
+	if !dryRun {
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('reading-import'), hashtext($1::text));`, patientID); err != nil {
+			return nil, fmt.Errorf("failed to lock patient readings: %w", err)
+		}
+	}
+
+	first, last := readings[0].Time, readings[0].Time
+	for _, reading := range readings[1:] {
+		if reading.Time.Before(first) {
+			first = reading.Time
+		}
+		if reading.Time.After(last) {
+			last = reading.Time
+		}
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT time, bpm
+		FROM heart_readings
+		WHERE patient_id = $1 AND deleted_at IS NULL AND time >= $2 AND time < $3;`,
+		patientID, first.Truncate(time.Second), last.Truncate(time.Second).Add(time.Second),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up existing readings: %w", err)
+	}
+	stored := map[importer.DedupeKey]bool{}
+	for rows.Next() {
+		var t time.Time
+		var bpm int
+		if err := rows.Scan(&t, &bpm); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		stored[importer.KeyOf(t, bpm)] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+
+	var ids []uuid.UUID
+	var copyRows [][]any
+	for i, reading := range readings {
+		if stored[importer.KeyOf(reading.Time, reading.BPM)] {
+			duplicates[i] = true
+			continue
+		}
+		if dryRun {
+			continue
+		}
+		reading.ID = uuid.New()
+		reading.PatientID = patientID
+		reading.Processed = true
+		if reading.QualityFlags == nil {
+			reading.QualityFlags = []string{}
+		}
+		ids = append(ids, reading.ID)
+		copyRows = append(copyRows, []any{
+			reading.ID, reading.PatientID, reading.DeviceID, reading.EntryMethod, reading.EnteredBy,
+			reading.ReadingType, reading.Source, reading.BPM, reading.Variability,
+			reading.IrregularityDetected, reading.OxygenLevel, reading.SystolicPressure,
+			reading.DiastolicPressure, reading.Temperature, reading.ActivityLevel, reading.Notes,
+			reading.ReliabilityScore, reading.QualityScore, reading.QualityFlags, reading.Processed,
+			true, reading.Time,
+		})
+	}
+	if len(copyRows) == 0 {
+		return duplicates, nil
+	}
+
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"heart_readings"},
+		[]string{
+			"id", "patient_id", "device_id", "entry_method", "entered_by",
+			"reading_type", "source", "bpm", "variability",
+			"irregularity_detected", "oxygen_level", "systolic_pressure",
+			"diastolic_pressure", "temperature", "activity_level", "notes",
+			"reliability_score", "quality_score", "quality_flags", "processed",
+			"alerts_evaluated", "time",
+		},
+		pgx.CopyFromRows(copyRows),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import heart readings: %w", err)
+	}
+
+	if err := addReadingsToRollups(ctx, tx, patientID, ids); err != nil {
+		return nil, err
+	}
+	return duplicates, nil
 }
 
-// GetPatientHeartReadings retrieves heart readings for a specific patient
+// GetPatientHeartReadings returns a page of a patient's heart readings
+// matching the filters, using keyset pagination on (time, id)
 func (r *HeartReadingRepository) GetPatientHeartReadings(
 	ctx context.Context,
 	params *models.HeartReadingQueryParams,
-) ([]*models.HeartReadingResponse, error) {
-	query := `SELECT * FROM get_patient_heart_readings($1, $2, $3, $4, $5);`
+) (*models.HeartReadingListResponse, error) {
+	var args []any
+	addArg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conditions := []string{"hr.patient_id = " + addArg(params.PatientID), "hr.deleted_at IS NULL"}
+	if params.StartTime != nil {
+		conditions = append(conditions, "hr.time >= "+addArg(*params.StartTime))
+	}
+	if params.EndTime != nil {
+		conditions = append(conditions, "hr.time <= "+addArg(*params.EndTime))
+	}
+	if params.ReadingType != nil {
+		conditions = append(conditions, "hr.reading_type = "+addArg(*params.ReadingType))
+	}
+	if params.EntryMethod != nil {
+		conditions = append(conditions, "hr.entry_method = "+addArg(*params.EntryMethod))
+	}
+	if params.Source != nil {
+		conditions = append(conditions, "hr.source = "+addArg(*params.Source))
+	}
+	if params.DeviceID != nil {
+		conditions = append(conditions, "hr.device_id = "+addArg(*params.DeviceID))
+	}
+	if params.MinBPM != nil {
+		conditions = append(conditions, "hr.bpm >= "+addArg(*params.MinBPM))
+	}
+	if params.MaxBPM != nil {
+		conditions = append(conditions, "hr.bpm <= "+addArg(*params.MaxBPM))
+	}
+	if params.IrregularOnly {
+		conditions = append(conditions, "hr.irregularity_detected")
+	}
+	if params.MinReliability != nil {
+		conditions = append(conditions, "hr.reliability_score >= "+addArg(*params.MinReliability))
+	}
 
-	limit := 1000
-	offset := 0
+	response := &models.HeartReadingListResponse{Data: []*models.HeartReadingWithDeviceResponse{}}
 
-	if params.Limit != nil {
-		limit = *params.Limit
+	// El total ignora el cursor: cuenta todas las lecturas que cumplen los filtros
+	if params.IncludeTotal {
+		countQuery := "SELECT COUNT(*) FROM heart_readings hr WHERE " + strings.Join(conditions, " AND ")
+		var total int64
+		if err := r.db.Pool.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+			return nil, fmt.Errorf("failed to count heart readings: %w", err)
+		}
+		response.TotalCount = &total
 	}
 
-	if params.Offset != nil {
-		offset = *params.Offset
+	direction, comparator := "DESC", "<"
+	if params.SortOrder == "asc" {
+		direction, comparator = "ASC", ">"
 	}
 
-	rows, err := r.db.Pool.Query(ctx, query,
-		params.PatientID,
-		params.StartTime,
-		params.EndTime,
-		limit,
-		offset,
-	)
+	if params.Cursor != nil {
+		cursor, err := utils.DecodeCursor(*params.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursorTime, err := utils.ParseCursorTime(cursor.Value)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, fmt.Sprintf("(hr.time, hr.id) %s (%s::timestamptz, %s::uuid)",
+			comparator, addArg(cursorTime), addArg(cursor.ID)))
+	}
 
+	// Se pide una fila extra para saber si hay una página siguiente
+	query := fmt.Sprintf(`
+		SELECT hr.id, hr.time, hr.device_id, d.device_type, COALESCE(d.serial_number, ''), hr.entry_method,
+		       hr.entered_by, u.id IS NOT NULL, COALESCE(u.first_name, ''), COALESCE(u.last_name, ''),
+		       hr.reading_type, hr.source, hr.bpm,
+		       hr.variability, hr.irregularity_detected, hr.oxygen_level, hr.systolic_pressure,
+		       hr.diastolic_pressure, hr.temperature, hr.activity_level, hr.notes, hr.reliability_score,
+		       hr.quality_score, hr.quality_flags
+		FROM heart_readings hr
+		LEFT JOIN devices d ON d.id = hr.device_id
+		LEFT JOIN users u ON u.id = hr.entered_by
+		WHERE %s
+		ORDER BY hr.time %s, hr.id %s
+		LIMIT %s;`,
+		strings.Join(conditions, " AND "), direction, direction, addArg(params.Limit+1))
+
+	rows, err := r.db.Pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get patient heart readings: %w", err)
 	}
 	defer rows.Close()
 
-	var readings []*models.HeartReadingResponse
-
 	for rows.Next() {
-		var reading models.HeartReadingResponse
-		var deviceType *string
-		var variability *float64
-		var oxygenLevel *int
-		var notes *string
-		var reliabilityScore *float64
+		var reading models.HeartReadingWithDeviceResponse
+		var serialNumber, firstName, lastName string
+		var userFound bool
 
 		if err := rows.Scan(
+			&reading.ID,
 			&reading.ReadingTime,
-			&deviceType,
+			&reading.DeviceID,
+			&reading.DeviceType,
+			&serialNumber,
 			&reading.EntryMethod,
+			&reading.EnteredBy,
+			&userFound,
+			&firstName,
+			&lastName,
 			&reading.ReadingType,
 			&reading.Source,
 			&reading.BPM,
-			&variability,
+			&reading.Variability,
 			&reading.IrregularityDetected,
-			&oxygenLevel,
-			&notes,
-			&reliabilityScore,
+			&reading.OxygenLevel,
+			&reading.SystolicPressure,
+			&reading.DiastolicPressure,
+			&reading.Temperature,
+			&reading.ActivityLevel,
+			&reading.Notes,
+			&reading.ReliabilityScore,
+			&reading.QualityScore,
+			&reading.QualityFlags,
 		); err != nil {
 			return nil, fmt.Errorf("scan failed: %w", err)
 		}
 
-		reading.DeviceType = deviceType
-		reading.Variability = variability
-		reading.OxygenLevel = oxygenLevel
-		reading.Notes = notes
-		reading.ReliabilityScore = reliabilityScore
+		if len(response.Data) == params.Limit {
+			last := response.Data[len(response.Data)-1]
+			next := utils.EncodeCursor(utils.Cursor{Value: last.ReadingTime.Format(time.RFC3339Nano), ID: last.ID})
+			response.NextCursor = &next
+			break
+		}
 
-		readings = append(readings, &reading)
+		if params.IncludeDevice && reading.DeviceID != nil && reading.DeviceType != nil {
+			reading.DeviceInfo = &models.HeartReadingDeviceInfo{
+				ID:           *reading.DeviceID,
+				DeviceType:   *reading.DeviceType,
+				SerialNumber: serialNumber,
+			}
+		}
+		if params.IncludeEnteredBy && userFound {
+			reading.EnteredByInfo = &models.HeartReadingUserInfo{
+				ID:        *reading.EnteredBy,
+				FirstName: firstName,
+				LastName:  lastName,
+			}
+		}
+
+		response.Data = append(response.Data, &reading)
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("rows iteration failed: %w", err)
 	}
 
-	return readings, nil
+	return response, nil
 }
 
-// GetHeartRateStats calculates statistics for heart readings
+// GetHeartRateStats calculates statistics for the readings selected by filter
+// with the calculate_heart_rate_stats function. Deleted readings are always
+// left out.
 func (r *HeartReadingRepository) GetHeartRateStats(
 	ctx context.Context,
 	patientID uuid.UUID,
 	startTime *time.Time,
 	endTime *time.Time,
+	filter models.ReadingFilter,
 ) (*models.HeartRateStats, error) {
-	query := `SELECT * FROM calculate_heart_rate_stats($1, $2, $3);`
+	var stats models.HeartRateStats
 
-	row := r.db.Pool.QueryRow(ctx, query, patientID, startTime, endTime)
+	err := r.withReadingFilter(ctx, filter, func(tx pgx.Tx) error {
+		query := `SELECT * FROM calculate_heart_rate_stats($1, $2, $3);`
 
-	var stats models.HeartRateStats
+		// Sin lecturas la media y los extremos son NULL
+		var avgBPM, variabilityAvg *float64
+		var minBPM, maxBPM *int
+		if err := tx.QueryRow(ctx, query, patientID, startTime, endTime).Scan(
+			&avgBPM,
+			&minBPM,
+			&maxBPM,
+			&variabilityAvg,
+			&stats.ReadingCount,
+			&stats.IrregularityCount,
+			&stats.LowReadingsCount,
+			&stats.HighReadingsCount,
+		); err != nil {
+			return fmt.Errorf("scan failed: %w", err)
+		}
 
-	if err := row.Scan(
-		&stats.AvgBPM,
-		&stats.MinBPM,
-		&stats.MaxBPM,
-		&stats.VariabilityAvg,
-		&stats.ReadingCount,
-		&stats.IrregularityCount,
-		&stats.LowReadingsCount,
-		&stats.HighReadingsCount,
-	); err != nil {
-		return nil, fmt.Errorf("scan failed: %w", err)
+		if avgBPM != nil {
+			stats.AvgBPM = *avgBPM
+		}
+		if minBPM != nil {
+			stats.MinBPM = *minBPM
+		}
+		if maxBPM != nil {
+			stats.MaxBPM = *maxBPM
+		}
+		if variabilityAvg != nil {
+			stats.VariabilityAvg = *variabilityAvg
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return &stats, nil
 }
 
-// GetHeartRateTrends retrieves heart rate trends over time
+// GetHeartRateTrends retrieves heart rate trends over time, grouping the
+// readings in periods of resolution laid out in the local time of loc. It
+// reads the coarsest rollup that answers the range exactly and falls back to
+// the raw readings otherwise, or when percentiles are requested. end_time is
+// inclusive either way: rollups serve a range whose end is the instant before
+// a bucket boundary, such as the last instant of a day. fillGaps adds empty
+// periods between start_time and end_time, which must then be set.
 func (r *HeartReadingRepository) GetHeartRateTrends(
 	ctx context.Context,
 	patientID uuid.UUID,
 	startTime *time.Time,
 	endTime *time.Time,
-	resolution string,
+	resolution utils.TrendResolution,
+	loc *time.Location,
+	fillGaps bool,
+	percentiles bool,
+) ([]*models.HeartRateTrend, error) {
+	// Los cubos llegan hasta el instante siguiente al final (Postgres guarda microsegundos)
+	rollupEnd := endTime
+	if endTime != nil {
+		next := endTime.Truncate(time.Microsecond).Add(time.Microsecond)
+		rollupEnd = &next
+	}
+
+	level := pickRollup(resolution, loc, startTime, rollupEnd)
+	if level == nil || percentiles {
+		return r.GetHeartRateTrendsFromReadings(ctx, patientID, startTime, endTime, resolution, loc, fillGaps, percentiles)
+	}
+
+	aggregate := fmt.Sprintf(`
+		SELECT %s AS local_start,
+		       SUM(bpm_sum)::float8 / SUM(reading_count) AS avg_bpm,
+		       MIN(bpm_min) AS min_bpm, MAX(bpm_max) AS max_bpm,
+		       SUM(reading_count)::bigint AS reading_count,
+		       NULL::float8[] AS percentiles
+		FROM %s
+		WHERE patient_id = $1
+		  AND ($2::timestamptz IS NULL OR bucket_start >= $2)
+		  AND ($3::timestamptz IS NULL OR bucket_start < $3)
+		GROUP BY 1`, trendBucketSQL(resolution, "bucket_start"), level.table)
+
+	return r.queryHeartRateTrends(ctx, aggregate, patientID, startTime, rollupEnd, resolution, loc, fillGaps)
+}
+
+// GetHeartRateTrendsFromReadings aggregates the raw readings for a trend,
+// without the rollups. percentiles adds p5, p50 and p95 to each period.
+// Each period has the aggregates of get_heart_rate_trend (average, minimum,
+// maximum and count of the non-deleted readings), but periods follow the local
+// time of loc and may be any interval, which the function cannot do, so the
+// aggregation is done here.
+func (r *HeartReadingRepository) GetHeartRateTrendsFromReadings(
+	ctx context.Context,
+	patientID uuid.UUID,
+	startTime *time.Time,
+	endTime *time.Time,
+	resolution utils.TrendResolution,
+	loc *time.Location,
+	fillGaps bool,
+	percentiles bool,
+) ([]*models.HeartRateTrend, error) {
+	percentileColumn := "NULL::float8[]"
+	if percentiles {
+		percentileColumn = "percentile_cont(ARRAY[0.05, 0.5, 0.95]) WITHIN GROUP (ORDER BY bpm)"
+	}
+
+	aggregate := fmt.Sprintf(`
+		SELECT %s AS local_start,
+		       AVG(bpm)::float8 AS avg_bpm, MIN(bpm) AS min_bpm, MAX(bpm) AS max_bpm,
+		       COUNT(*) AS reading_count,
+		       %s AS percentiles
+		FROM active_heart_readings
+		WHERE patient_id = $1
+		  AND ($2::timestamptz IS NULL OR time >= $2)
+		  AND ($3::timestamptz IS NULL OR time <= $3)
+		GROUP BY 1`, trendBucketSQL(resolution, "time"), percentileColumn)
+
+	return r.queryHeartRateTrends(ctx, aggregate, patientID, startTime, endTime, resolution, loc, fillGaps)
+}
+
+// queryHeartRateTrends runs the per-period aggregate query and turns its
+// local period starts back into instants, optionally adding the empty
+// periods of the range
+func (r *HeartReadingRepository) queryHeartRateTrends(
+	ctx context.Context,
+	aggregate string,
+	patientID uuid.UUID,
+	startTime *time.Time,
+	endTime *time.Time,
+	resolution utils.TrendResolution,
+	loc *time.Location,
+	fillGaps bool,
 ) ([]*models.HeartRateTrend, error) {
-	query := `SELECT * FROM get_heart_rate_trend($1, $2, $3, $4);`
+	step := trendStepSQL(resolution)
 
-	rows, err := r.db.Pool.Query(ctx, query, patientID, startTime, endTime, resolution)
+	periods := "SELECT local_start FROM agg"
+	if fillGaps {
+		periods = fmt.Sprintf(`
+			SELECT generate_series(%s, %s, %s) AS local_start
+			UNION
+			SELECT local_start FROM agg`,
+			trendBucketSQL(resolution, "$2::timestamptz"),
+			trendBucketSQL(resolution, "($3::timestamptz - interval '1 microsecond')"),
+			step,
+		)
+	}
+
+	query := fmt.Sprintf(`
+		WITH agg AS (%s),
+		periods AS (%s)
+		SELECT p.local_start AT TIME ZONE $5 AS period_start,
+		       (p.local_start + %s) AT TIME ZONE $5 AS period_end,
+		       a.avg_bpm, a.min_bpm, a.max_bpm, COALESCE(a.reading_count, 0), a.percentiles
+		FROM periods p
+		LEFT JOIN agg a ON a.local_start = p.local_start
+		ORDER BY 1;`, aggregate, periods, step)
+
+	rows, err := r.db.Pool.Query(ctx, query, patientID, startTime, endTime, trendStepArg(resolution), loc.String())
 	if err != nil {
 		return nil, fmt.Errorf("failed to get heart rate trends: %w", err)
 	}
@@ -170,6 +658,7 @@ func (r *HeartReadingRepository) GetHeartRateTrends(
 
 	for rows.Next() {
 		var trend models.HeartRateTrend
+		var percentiles []float64
 
 		if err := rows.Scan(
 			&trend.PeriodStart,
@@ -178,9 +667,13 @@ func (r *HeartReadingRepository) GetHeartRateTrends(
 			&trend.MinBPM,
 			&trend.MaxBPM,
 			&trend.ReadingCount,
+			&percentiles,
 		); err != nil {
 			return nil, fmt.Errorf("scan failed: %w", err)
 		}
+		if len(percentiles) == 3 {
+			trend.P5, trend.P50, trend.P95 = &percentiles[0], &percentiles[1], &percentiles[2]
+		}
 
 		trends = append(trends, &trend)
 	}
@@ -192,72 +685,158 @@ func (r *HeartReadingRepository) GetHeartRateTrends(
 	return trends, nil
 }
 
-// GetHeartRateAnomalies detects anomalies in heart rate readings
+// GetHeartRateAnomalies detects anomalies in the readings selected by filter
+// with the detect_heart_rate_anomalies function. Deleted readings are always
+// left out.
 func (r *HeartReadingRepository) GetHeartRateAnomalies(
 	ctx context.Context,
 	patientID uuid.UUID,
 	startTime *time.Time,
 	endTime *time.Time,
 	threshold float64,
+	filter models.ReadingFilter,
 ) ([]*models.HeartRateAnomaly, error) {
-	query := `SELECT * FROM detect_heart_rate_anomalies($1, $2, $3, $4);`
-
-	rows, err := r.db.Pool.Query(ctx, query, patientID, startTime, endTime, threshold)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get heart rate anomalies: %w", err)
-	}
-	defer rows.Close()
-
 	var anomalies []*models.HeartRateAnomaly
 
-	for rows.Next() {
-		var anomaly models.HeartRateAnomaly
+	err := r.withReadingFilter(ctx, filter, func(tx pgx.Tx) error {
+		query := `SELECT * FROM detect_heart_rate_anomalies($1, $2, $3, $4);`
 
-		if err := rows.Scan(
-			&anomaly.ReadingTime,
-			&anomaly.BPM,
-			&anomaly.ExpectedRangeLow,
-			&anomaly.ExpectedRangeHigh,
-			&anomaly.DeviationPercentage,
-			&anomaly.IsIrregular,
-			&anomaly.AnomalyType,
-		); err != nil {
-			return nil, fmt.Errorf("scan failed: %w", err)
+		rows, err := tx.Query(ctx, query, patientID, startTime, endTime, threshold)
+		if err != nil {
+			return fmt.Errorf("failed to get heart rate anomalies: %w", err)
 		}
+		defer rows.Close()
 
-		anomalies = append(anomalies, &anomaly)
-	}
+		for rows.Next() {
+			var anomaly models.HeartRateAnomaly
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("rows iteration failed: %w", err)
+			if err := rows.Scan(
+				&anomaly.ReadingTime,
+				&anomaly.BPM,
+				&anomaly.ExpectedRangeLow,
+				&anomaly.ExpectedRangeHigh,
+				&anomaly.DeviationPercentage,
+				&anomaly.IsIrregular,
+				&anomaly.AnomalyType,
+			); err != nil {
+				return fmt.Errorf("scan failed: %w", err)
+			}
+
+			anomalies = append(anomalies, &anomaly)
+		}
+
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("rows iteration failed: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return anomalies, nil
 }
 
-// ProcessUnprocessedReadings processes any unprocessed heart readings
-func (r *HeartReadingRepository) ProcessUnprocessedReadings(ctx context.Context) error {
-	query := `CALL process_unprocessed_readings();`
+// withReadingFilter runs fn in a transaction in which the active_heart_readings
+// view, read by the stats and anomaly functions, applies filter
+func (r *HeartReadingRepository) withReadingFilter(ctx context.Context, filter models.ReadingFilter, fn func(tx pgx.Tx) error) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	excludeArtifacts := "off"
+	if filter.ExcludeArtifacts {
+		excludeArtifacts = "on"
+	}
+	minQuality := ""
+	if filter.MinQuality != nil {
+		minQuality = strconv.FormatFloat(*filter.MinQuality, 'g', -1, 64)
+	}
 
-	_, err := r.db.Pool.Exec(ctx, query)
+	_, err = tx.Exec(ctx, `SELECT set_config('heart.exclude_artifacts', $1, true), set_config('heart.min_quality', $2, true);`,
+		excludeArtifacts, minQuality)
 	if err != nil {
-		return fmt.Errorf("failed to process unprocessed readings: %w", err)
+		return fmt.Errorf("failed to apply reading filter: %w", err)
 	}
 
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// MarkEvaluated records that a reading's threshold and rule alerts were
+// evaluated on ingest, so the reading worker does not evaluate it again.
+// processed is left to the process_unprocessed_readings procedure.
+func (r *HeartReadingRepository) MarkEvaluated(ctx context.Context, readingID uuid.UUID) error {
+	_, err := r.db.Pool.Exec(ctx,
+		`UPDATE heart_readings SET alerts_evaluated = true WHERE id = $1 AND NOT alerts_evaluated;`,
+		readingID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to mark reading evaluated: %w", err)
+	}
 	return nil
 }
 
-// UpdateHeartReading updates an existing heart reading
+// UpdateHeartReading updates an existing heart reading and records the
+// changed fields as a new version in its history. It returns false when the
+// reading does not exist, belongs to another patient or was deleted.
 func (r *HeartReadingRepository) UpdateHeartReading(
 	ctx context.Context,
 	readingID uuid.UUID,
 	patientID uuid.UUID,
 	update *models.HeartReadingUpdateRequest,
-) error {
-	query := `CALL update_heart_reading($1, $2, $3, $4, $5, $6, $7, $8);`
+	changedBy *uuid.UUID,
+) (bool, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
 
-	_, err := r.db.Pool.Exec(ctx, query,
-		patientID,
+	var current models.HeartReading
+	err = tx.QueryRow(ctx, `
+		SELECT reading_type, bpm, variability, irregularity_detected, oxygen_level, notes, reliability_score, time
+		FROM heart_readings
+		WHERE id = $1 AND patient_id = $2 AND deleted_at IS NULL
+		FOR UPDATE;`,
+		readingID, patientID,
+	).Scan(
+		&current.ReadingType,
+		&current.BPM,
+		&current.Variability,
+		&current.IrregularityDetected,
+		&current.OxygenLevel,
+		&current.Notes,
+		&current.ReliabilityScore,
+		&current.Time,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get heart reading: %w", err)
+	}
+
+	oldValues, newValues := readingChanges(&current, update)
+	if len(newValues) == 0 {
+		// Nada cambia: no se genera una versión vacía
+		return true, nil
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE heart_readings
+		SET reading_type = COALESCE($2, reading_type),
+		    bpm = COALESCE($3, bpm),
+		    variability = COALESCE($4, variability),
+		    irregularity_detected = COALESCE($5, irregularity_detected),
+		    oxygen_level = COALESCE($6, oxygen_level),
+		    notes = COALESCE($7, notes),
+		    reliability_score = COALESCE($8, reliability_score)
+		WHERE id = $1;`,
 		readingID,
 		update.ReadingType,
 		update.BPM,
@@ -265,70 +844,445 @@ func (r *HeartReadingRepository) UpdateHeartReading(
 		update.IrregularityDetected,
 		update.OxygenLevel,
 		update.Notes,
+		update.ReliabilityScore,
 	)
+	if err != nil {
+		return false, fmt.Errorf("failed to update heart reading: %w", err)
+	}
+
+	if _, changed := newValues["bpm"]; changed {
+		if err := refreshRollups(ctx, tx, patientID, current.Time); err != nil {
+			return false, err
+		}
+	}
+
+	if err := insertReadingVersion(ctx, tx, readingID, "update", changedBy, update.Reason, oldValues, newValues); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
 
+// SoftDeleteHeartReading marks a reading as deleted and records the deletion
+// in its history. It returns false when the reading does not exist, belongs
+// to another patient or was already deleted.
+func (r *HeartReadingRepository) SoftDeleteHeartReading(
+	ctx context.Context,
+	readingID uuid.UUID,
+	patientID uuid.UUID,
+	deletedBy *uuid.UUID,
+	reason string,
+) (bool, error) {
+	tx, err := r.db.Pool.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to update heart reading: %w", err)
+		return false, fmt.Errorf("error starting transaction: %w", err)
 	}
+	defer tx.Rollback(ctx)
 
+	var deletedAt, readingTime time.Time
+	err = tx.QueryRow(ctx, `
+		UPDATE heart_readings
+		SET deleted_at = NOW(), deleted_by = $3, deletion_reason = $4
+		WHERE id = $1 AND patient_id = $2 AND deleted_at IS NULL
+		RETURNING deleted_at, time;`,
+		readingID, patientID, deletedBy, reason,
+	).Scan(&deletedAt, &readingTime)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to delete heart reading: %w", err)
+	}
+
+	if err := refreshRollups(ctx, tx, patientID, readingTime); err != nil {
+		return false, err
+	}
+
+	err = insertReadingVersion(ctx, tx, readingID, "delete", deletedBy, &reason,
+		map[string]any{"deleted_at": nil},
+		map[string]any{"deleted_at": deletedAt},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+// insertReadingVersion appends a version to a reading's history. The reading
+// row must be locked by tx so that version numbers do not collide.
+func insertReadingVersion(
+	ctx context.Context,
+	tx pgx.Tx,
+	readingID uuid.UUID,
+	changeType string,
+	changedBy *uuid.UUID,
+	reason *string,
+	oldValues map[string]any,
+	newValues map[string]any,
+) error {
+	query := `
+		INSERT INTO heart_reading_versions (reading_id, version, change_type, changed_by, reason, old_values, new_values)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4, $5, $6
+		FROM heart_reading_versions
+		WHERE reading_id = $1;`
+
+	if _, err := tx.Exec(ctx, query, readingID, changeType, changedBy, reason, oldValues, newValues); err != nil {
+		return fmt.Errorf("failed to record heart reading version: %w", err)
+	}
 	return nil
 }
 
-// GetHeartReadingByID retrieves a specific heart reading by ID
+// readingChanges returns the previous and new values of the fields the
+// update actually changes, keyed by their JSON name
+func readingChanges(current *models.HeartReading, update *models.HeartReadingUpdateRequest) (map[string]any, map[string]any) {
+	oldValues, newValues := map[string]any{}, map[string]any{}
+	track := func(name string, changed bool, old any, new any) {
+		if changed {
+			oldValues[name], newValues[name] = old, new
+		}
+	}
+
+	if v := update.ReadingType; v != nil {
+		track("reading_type", *v != current.ReadingType, current.ReadingType, *v)
+	}
+	if v := update.BPM; v != nil {
+		track("bpm", *v != current.BPM, current.BPM, *v)
+	}
+	if v := update.Variability; v != nil {
+		track("variability", current.Variability == nil || *v != *current.Variability, current.Variability, *v)
+	}
+	if v := update.IrregularityDetected; v != nil {
+		track("irregularity_detected", *v != current.IrregularityDetected, current.IrregularityDetected, *v)
+	}
+	if v := update.OxygenLevel; v != nil {
+		track("oxygen_level", current.OxygenLevel == nil || *v != *current.OxygenLevel, current.OxygenLevel, *v)
+	}
+	if v := update.Notes; v != nil {
+		track("notes", current.Notes == nil || *v != *current.Notes, current.Notes, *v)
+	}
+	if v := update.ReliabilityScore; v != nil {
+		track("reliability_score", current.ReliabilityScore == nil || *v != *current.ReliabilityScore, current.ReliabilityScore, *v)
+	}
+	return oldValues, newValues
+}
+
+// HeartReadingExists reports whether a reading exists, deleted or not
+func (r *HeartReadingRepository) HeartReadingExists(ctx context.Context, readingID uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.Pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM heart_readings WHERE id = $1);`, readingID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check heart reading: %w", err)
+	}
+	return exists, nil
+}
+
+// GetHeartReadingHistory returns the versions of a reading, oldest first
+func (r *HeartReadingRepository) GetHeartReadingHistory(ctx context.Context, readingID uuid.UUID) ([]*models.HeartReadingVersion, error) {
+	query := `
+		SELECT version, change_type, changed_by, changed_at, reason, old_values, new_values
+		FROM heart_reading_versions
+		WHERE reading_id = $1
+		ORDER BY version;`
+
+	rows, err := r.db.Pool.Query(ctx, query, readingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get heart reading history: %w", err)
+	}
+	defer rows.Close()
+
+	versions := []*models.HeartReadingVersion{}
+	for rows.Next() {
+		var version models.HeartReadingVersion
+		if err := rows.Scan(
+			&version.Version,
+			&version.ChangeType,
+			&version.ChangedBy,
+			&version.ChangedAt,
+			&version.Reason,
+			&version.OldValues,
+			&version.NewValues,
+		); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		versions = append(versions, &version)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return versions, nil
+}
+
+// GetHeartReadingByID retrieves a specific heart reading by ID. Deleted
+// readings are not returned.
 func (r *HeartReadingRepository) GetHeartReadingByID(
 	ctx context.Context,
 	readingID uuid.UUID,
 ) (*models.HeartReading, error) {
-	query := `SELECT * FROM get_heart_reading_by_id($1);`
+	query := `
+		SELECT id, patient_id, device_id, entry_method, entered_by, reading_type, source, bpm,
+		       variability, irregularity_detected, oxygen_level, systolic_pressure,
+		       diastolic_pressure, temperature, activity_level, notes, reliability_score,
+		       quality_score, quality_flags, processed, time
+		FROM heart_readings
+		WHERE id = $1 AND deleted_at IS NULL;`
 
 	row := r.db.Pool.QueryRow(ctx, query, readingID)
 
 	var reading models.HeartReading
-	var deviceID *uuid.UUID
-	var enteredBy *uuid.UUID
-	var variability *float64
-	var oxygenLevel *int
-	var systolicPressure *int
-	var diastolicPressure *int
-	var temperature *float64
-	var activityLevel *int
-	var notes *string
-	var reliabilityScore *float64
 
 	if err := row.Scan(
 		&reading.ID,
 		&reading.PatientID,
-		&deviceID,
+		&reading.DeviceID,
 		&reading.EntryMethod,
-		&enteredBy,
+		&reading.EnteredBy,
 		&reading.ReadingType,
 		&reading.Source,
 		&reading.BPM,
-		&variability,
+		&reading.Variability,
 		&reading.IrregularityDetected,
-		&oxygenLevel,
-		&systolicPressure,
-		&diastolicPressure,
-		&temperature,
-		&activityLevel,
-		&notes,
-		&reliabilityScore,
+		&reading.OxygenLevel,
+		&reading.SystolicPressure,
+		&reading.DiastolicPressure,
+		&reading.Temperature,
+		&reading.ActivityLevel,
+		&reading.Notes,
+		&reading.ReliabilityScore,
+		&reading.QualityScore,
+		&reading.QualityFlags,
 		&reading.Processed,
 		&reading.Time,
 	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
 		return nil, fmt.Errorf("scan failed: %w", err)
 	}
 
-	reading.DeviceID = deviceID
-	reading.EnteredBy = enteredBy
-	reading.Variability = variability
-	reading.OxygenLevel = oxygenLevel
-	reading.SystolicPressure = systolicPressure
-	reading.DiastolicPressure = diastolicPressure
-	reading.Temperature = temperature
-	reading.ActivityLevel = activityLevel
-	reading.Notes = notes
-	reading.ReliabilityScore = reliabilityScore
-
 	return &reading, nil
 }
+
+// GetHRVSummaries aggregates the stored HRV metrics of a patient per time
+// window, laid out in the local time of loc
+func (r *HeartReadingRepository) GetHRVSummaries(
+	ctx context.Context,
+	patientID uuid.UUID,
+	startTime *time.Time,
+	endTime *time.Time,
+	window string,
+	loc *time.Location,
+) ([]*models.HRVSummary, error) {
+	query := `
+		SELECT date_trunc($4, time AT TIME ZONE $5) AT TIME ZONE $5 AS period_start,
+		       (date_trunc($4, time AT TIME ZONE $5) + ('1 ' || $4)::interval) AT TIME ZONE $5 AS period_end,
+		       COUNT(*), AVG(mean_hr), AVG(sdnn), AVG(rmssd), AVG(pnn50),
+		       AVG(lf_power), AVG(hf_power), AVG(lf_hf_ratio)
+		FROM heart_reading_hrv h
+		WHERE patient_id = $1
+		  AND NOT EXISTS (SELECT 1 FROM heart_readings hr WHERE hr.id = h.reading_id AND hr.deleted_at IS NOT NULL)
+		  AND ($2::timestamptz IS NULL OR time >= $2)
+		  AND ($3::timestamptz IS NULL OR time <= $3)
+		GROUP BY 1
+		ORDER BY 1;`
+
+	rows, err := r.db.Pool.Query(ctx, query, patientID, startTime, endTime, window, loc.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get heart rate variability: %w", err)
+	}
+	defer rows.Close()
+
+	summaries := []*models.HRVSummary{}
+	for rows.Next() {
+		var summary models.HRVSummary
+
+		if err := rows.Scan(
+			&summary.PeriodStart,
+			&summary.PeriodEnd,
+			&summary.ReadingCount,
+			&summary.AvgMeanHR,
+			&summary.AvgSDNN,
+			&summary.AvgRMSSD,
+			&summary.AvgPNN50,
+			&summary.AvgLFPower,
+			&summary.AvgHFPower,
+			&summary.AvgLFHFRatio,
+		); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+
+		summaries = append(summaries, &summary)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+
+	return summaries, nil
+}
+
+// GetReadingsInRange returns a patient's complete readings between two
+// instants (inclusive), oldest first. It is used by the Go-side analyses that
+// need every field of the reading.
+func (r *HeartReadingRepository) GetReadingsInRange(
+	ctx context.Context,
+	patientID uuid.UUID,
+	startTime time.Time,
+	endTime time.Time,
+) ([]*models.HeartReading, error) {
+	readings := []*models.HeartReading{}
+	_, err := r.StreamHeartReadings(ctx, patientID, &startTime, &endTime, func(reading *models.HeartReading) error {
+		readings = append(readings, reading)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return readings, nil
+}
+
+// StreamHeartReadings passes a patient's complete readings between two
+// optional instants (inclusive) to fn one at a time, oldest first, without
+// holding them in memory. An error from fn stops the query and is returned.
+func (r *HeartReadingRepository) StreamHeartReadings(
+	ctx context.Context,
+	patientID uuid.UUID,
+	startTime *time.Time,
+	endTime *time.Time,
+	fn func(*models.HeartReading) error,
+) (int64, error) {
+	query := `
+		SELECT id, patient_id, device_id, entry_method, entered_by, reading_type, source, bpm,
+		       variability, irregularity_detected, oxygen_level, systolic_pressure,
+		       diastolic_pressure, temperature, activity_level, notes, reliability_score,
+		       quality_score, quality_flags, processed, time
+		FROM heart_readings
+		WHERE patient_id = $1 AND deleted_at IS NULL
+		  AND ($2::timestamptz IS NULL OR time >= $2)
+		  AND ($3::timestamptz IS NULL OR time <= $3)
+		ORDER BY time, id;`
+
+	rows, err := r.db.Pool.Query(ctx, query, patientID, startTime, endTime)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get heart readings: %w", err)
+	}
+	defer rows.Close()
+
+	var count int64
+	for rows.Next() {
+		var reading models.HeartReading
+		if err := rows.Scan(
+			&reading.ID,
+			&reading.PatientID,
+			&reading.DeviceID,
+			&reading.EntryMethod,
+			&reading.EnteredBy,
+			&reading.ReadingType,
+			&reading.Source,
+			&reading.BPM,
+			&reading.Variability,
+			&reading.IrregularityDetected,
+			&reading.OxygenLevel,
+			&reading.SystolicPressure,
+			&reading.DiastolicPressure,
+			&reading.Temperature,
+			&reading.ActivityLevel,
+			&reading.Notes,
+			&reading.ReliabilityScore,
+			&reading.QualityScore,
+			&reading.QualityFlags,
+			&reading.Processed,
+			&reading.Time,
+		); err != nil {
+			return count, fmt.Errorf("scan failed: %w", err)
+		}
+		if err := fn(&reading); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	if err := rows.Err(); err != nil {
+		return count, fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return count, nil
+}
+
+// StreamHeartRatePoints passes the time, reading type and BPM of the
+// patient's readings between two instants (inclusive) selected by filter to
+// fn one at a time, oldest first. Only those fields are filled in. An error
+// from fn stops the query and is returned.
+func (r *HeartReadingRepository) StreamHeartRatePoints(
+	ctx context.Context,
+	patientID uuid.UUID,
+	startTime time.Time,
+	endTime time.Time,
+	filter models.ReadingFilter,
+	fn func(*models.HeartReading) error,
+) error {
+	query := `
+		SELECT id, reading_type, bpm, time
+		FROM heart_readings
+		WHERE patient_id = $1 AND deleted_at IS NULL
+		  AND time >= $2 AND time <= $3
+		  AND ` + readingFilterCondition(4) + `
+		ORDER BY time;`
+
+	rows, err := r.db.Pool.Query(ctx, query, patientID, startTime, endTime, filter.ExcludeArtifacts, filter.MinQuality)
+	if err != nil {
+		return fmt.Errorf("failed to get heart rate points: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		reading := models.HeartReading{PatientID: patientID}
+		if err := rows.Scan(&reading.ID, &reading.ReadingType, &reading.BPM, &reading.Time); err != nil {
+			return fmt.Errorf("scan failed: %w", err)
+		}
+		if err := fn(&reading); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return nil
+}
+
+// GetRecentDeviceReadings returns the time and BPM of the last readings of a
+// device up to until, newest first. Only those fields are filled in.
+func (r *HeartReadingRepository) GetRecentDeviceReadings(
+	ctx context.Context,
+	deviceID uuid.UUID,
+	until time.Time,
+	limit int,
+) ([]*models.HeartReading, error) {
+	query := `
+		SELECT id, bpm, time
+		FROM heart_readings
+		WHERE device_id = $1 AND time <= $2 AND deleted_at IS NULL
+		ORDER BY time DESC
+		LIMIT $3;`
+
+	rows, err := r.db.Pool.Query(ctx, query, deviceID, until, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent device readings: %w", err)
+	}
+	defer rows.Close()
+
+	readings := []*models.HeartReading{}
+	for rows.Next() {
+		reading := models.HeartReading{DeviceID: &deviceID}
+		if err := rows.Scan(&reading.ID, &reading.BPM, &reading.Time); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		readings = append(readings, &reading)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return readings, nil
+}
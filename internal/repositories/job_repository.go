@@ -0,0 +1,255 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/db"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// JobRepository stores the scheduler's jobs and run history. It implements
+// scheduler.Store.
+type JobRepository struct {
+	db *db.PostgresDB
+}
+
+func NewJobRepository(database *db.PostgresDB) *JobRepository {
+	return &JobRepository{db: database}
+}
+
+// RegisterJob creates a job or updates its description and schedule. The
+// next run is only reset when the schedule changed.
+func (r *JobRepository) RegisterJob(ctx context.Context, name, description, schedule string, nextRun time.Time) error {
+	query := `
+		INSERT INTO scheduled_jobs (name, description, schedule, next_run_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (name) DO UPDATE SET
+			description = EXCLUDED.description,
+			next_run_at = CASE WHEN scheduled_jobs.schedule = EXCLUDED.schedule
+			                   THEN scheduled_jobs.next_run_at ELSE EXCLUDED.next_run_at END,
+			schedule    = EXCLUDED.schedule,
+			updated_at  = NOW();`
+
+	if _, err := r.db.Pool.Exec(ctx, query, name, description, schedule, nextRun); err != nil {
+		return fmt.Errorf("failed to register job: %w", err)
+	}
+	return nil
+}
+
+// ClaimDueJob takes the job's lease when it is enabled, due and not leased,
+// and moves its next run
+func (r *JobRepository) ClaimDueJob(ctx context.Context, name, owner string, leaseFor time.Duration, nextRun time.Time) (bool, error) {
+	query := `
+		UPDATE scheduled_jobs
+		SET locked_by = $2, locked_until = NOW() + make_interval(secs => $3),
+		    next_run_at = $4, updated_at = NOW()
+		WHERE name = $1 AND enabled AND next_run_at <= NOW()
+		  AND (locked_until IS NULL OR locked_until < NOW());`
+
+	tag, err := r.db.Pool.Exec(ctx, query, name, owner, leaseFor.Seconds(), nextRun)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim job: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ClaimJob takes the job's lease when it is not leased, whatever its schedule
+func (r *JobRepository) ClaimJob(ctx context.Context, name, owner string, leaseFor time.Duration) (bool, error) {
+	query := `
+		UPDATE scheduled_jobs
+		SET locked_by = $2, locked_until = NOW() + make_interval(secs => $3), updated_at = NOW()
+		WHERE name = $1 AND (locked_until IS NULL OR locked_until < NOW());`
+
+	tag, err := r.db.Pool.Exec(ctx, query, name, owner, leaseFor.Seconds())
+	if err != nil {
+		return false, fmt.Errorf("failed to claim job: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// StartRun records a run in progress
+func (r *JobRepository) StartRun(ctx context.Context, name, trigger, owner string) (int64, error) {
+	var runID int64
+	err := r.db.Pool.QueryRow(ctx, `
+		INSERT INTO job_runs (job_name, trigger, instance, status)
+		VALUES ($1, $2, $3, 'running')
+		RETURNING id;`,
+		name, trigger, owner,
+	).Scan(&runID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start job run: %w", err)
+	}
+	return runID, nil
+}
+
+// FinishRun records the outcome of a run and releases the job's lease. A
+// runID of 0 only releases the lease.
+func (r *JobRepository) FinishRun(ctx context.Context, runID int64, name, status, summary, errMessage string) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if runID != 0 {
+		if _, err := tx.Exec(ctx, `
+			UPDATE job_runs
+			SET status = $2, summary = NULLIF($3, ''), error = NULLIF($4, ''), finished_at = NOW()
+			WHERE id = $1;`,
+			runID, status, summary, errMessage,
+		); err != nil {
+			return fmt.Errorf("failed to finish job run: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE scheduled_jobs
+		SET locked_by = NULL, locked_until = NULL, last_run_at = NOW(),
+		    last_status = $2, last_error = NULLIF($3, ''), updated_at = NOW()
+		WHERE name = $1;`,
+		name, status, errMessage,
+	); err != nil {
+		return fmt.Errorf("failed to release job: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetJobs lists every job
+func (r *JobRepository) GetJobs(ctx context.Context) ([]*models.ScheduledJob, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT name, description, schedule, enabled, next_run_at,
+		       COALESCE(locked_until > NOW(), false), locked_by, locked_until,
+		       last_run_at, last_status, last_error
+		FROM scheduled_jobs
+		ORDER BY name;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []*models.ScheduledJob{}
+	for rows.Next() {
+		job, err := scanScheduledJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return jobs, nil
+}
+
+// GetJob returns a job, or nil if it does not exist
+func (r *JobRepository) GetJob(ctx context.Context, name string) (*models.ScheduledJob, error) {
+	row := r.db.Pool.QueryRow(ctx, `
+		SELECT name, description, schedule, enabled, next_run_at,
+		       COALESCE(locked_until > NOW(), false), locked_by, locked_until,
+		       last_run_at, last_status, last_error
+		FROM scheduled_jobs
+		WHERE name = $1;`, name)
+
+	job, err := scanScheduledJob(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return job, nil
+}
+
+// SetJobEnabled enables or disables a job's schedule. Disabled jobs can
+// still be triggered manually.
+func (r *JobRepository) SetJobEnabled(ctx context.Context, name string, enabled bool) (bool, error) {
+	tag, err := r.db.Pool.Exec(ctx,
+		`UPDATE scheduled_jobs SET enabled = $2, updated_at = NOW() WHERE name = $1;`,
+		name, enabled,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to update job: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// GetJobRuns returns the most recent runs of a job, newest first
+func (r *JobRepository) GetJobRuns(ctx context.Context, name string, limit int) ([]*models.JobRun, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT id, job_name, trigger, instance, status, summary, error, started_at, finished_at
+		FROM job_runs
+		WHERE job_name = $1
+		ORDER BY started_at DESC
+		LIMIT $2;`, name, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []*models.JobRun{}
+	for rows.Next() {
+		var run models.JobRun
+		if err := rows.Scan(
+			&run.ID,
+			&run.JobName,
+			&run.Trigger,
+			&run.Instance,
+			&run.Status,
+			&run.Summary,
+			&run.Error,
+			&run.StartedAt,
+			&run.FinishedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		if run.FinishedAt != nil {
+			duration := run.FinishedAt.Sub(run.StartedAt).Milliseconds()
+			run.DurationMs = &duration
+		}
+		runs = append(runs, &run)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return runs, nil
+}
+
+// DeleteRunsBefore removes the run history older than before
+func (r *JobRepository) DeleteRunsBefore(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.db.Pool.Exec(ctx,
+		`DELETE FROM job_runs WHERE started_at < $1 AND status <> 'running';`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete job runs: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func scanScheduledJob(row pgx.Row) (*models.ScheduledJob, error) {
+	var job models.ScheduledJob
+	if err := row.Scan(
+		&job.Name,
+		&job.Description,
+		&job.Schedule,
+		&job.Enabled,
+		&job.NextRunAt,
+		&job.Running,
+		&job.LockedBy,
+		&job.LockedUntil,
+		&job.LastRunAt,
+		&job.LastStatus,
+		&job.LastError,
+	); err != nil {
+		return nil, fmt.Errorf("scan failed: %w", err)
+	}
+	return &job, nil
+}
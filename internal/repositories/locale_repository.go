@@ -0,0 +1,82 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/db"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// LocaleRepository reads and updates the time zone and locale stored on
+// patients and users
+type LocaleRepository struct {
+	db *db.PostgresDB
+}
+
+func NewLocaleRepository(database *db.PostgresDB) *LocaleRepository {
+	return &LocaleRepository{db: database}
+}
+
+// GetPatientLocale returns a patient's settings, or nil if the patient does not exist
+func (r *LocaleRepository) GetPatientLocale(ctx context.Context, patientID uuid.UUID) (*models.LocaleSettings, error) {
+	return r.getLocale(ctx, "patients", patientID)
+}
+
+// UpdatePatientLocale changes the given settings of a patient and returns the
+// result, or nil if the patient does not exist
+func (r *LocaleRepository) UpdatePatientLocale(
+	ctx context.Context,
+	patientID uuid.UUID,
+	update *models.LocaleSettingsUpdateRequest,
+) (*models.LocaleSettings, error) {
+	return r.updateLocale(ctx, "patients", patientID, update)
+}
+
+// GetUserLocale returns a user's settings, or nil if the user does not exist
+func (r *LocaleRepository) GetUserLocale(ctx context.Context, userID uuid.UUID) (*models.LocaleSettings, error) {
+	return r.getLocale(ctx, "users", userID)
+}
+
+// UpdateUserLocale changes the given settings of a user and returns the
+// result, or nil if the user does not exist
+func (r *LocaleRepository) UpdateUserLocale(
+	ctx context.Context,
+	userID uuid.UUID,
+	update *models.LocaleSettingsUpdateRequest,
+) (*models.LocaleSettings, error) {
+	return r.updateLocale(ctx, "users", userID, update)
+}
+
+func (r *LocaleRepository) getLocale(ctx context.Context, table string, id uuid.UUID) (*models.LocaleSettings, error) {
+	query := fmt.Sprintf(`SELECT timezone, locale FROM %s WHERE id = $1;`, table)
+	return scanLocaleSettings(r.db.Pool.QueryRow(ctx, query, id))
+}
+
+func (r *LocaleRepository) updateLocale(
+	ctx context.Context,
+	table string,
+	id uuid.UUID,
+	update *models.LocaleSettingsUpdateRequest,
+) (*models.LocaleSettings, error) {
+	query := fmt.Sprintf(`
+		UPDATE %s
+		SET timezone = COALESCE($2, timezone), locale = COALESCE($3, locale)
+		WHERE id = $1
+		RETURNING timezone, locale;`, table)
+	return scanLocaleSettings(r.db.Pool.QueryRow(ctx, query, id, update.Timezone, update.Locale))
+}
+
+func scanLocaleSettings(row pgx.Row) (*models.LocaleSettings, error) {
+	var settings models.LocaleSettings
+	if err := row.Scan(&settings.Timezone, &settings.Locale); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get locale settings: %w", err)
+	}
+	return &settings, nil
+}
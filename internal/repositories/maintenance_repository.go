@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/db"
+)
+
+// MaintenanceRepository removes expired and out-of-retention rows
+type MaintenanceRepository struct {
+	db *db.PostgresDB
+}
+
+func NewMaintenanceRepository(database *db.PostgresDB) *MaintenanceRepository {
+	return &MaintenanceRepository{db: database}
+}
+
+// DeleteExpiredSessions removes the sessions that expired or were
+// invalidated before the given time
+func (r *MaintenanceRepository) DeleteExpiredSessions(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.db.Pool.Exec(ctx, `
+		DELETE FROM sessions
+		WHERE expires_at < $1 OR (NOT is_valid AND created_at < $1);`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// DeleteTelemetryBefore removes device telemetry synced before the given time
+func (r *MaintenanceRepository) DeleteTelemetryBefore(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.db.Pool.Exec(ctx, `DELETE FROM device_telemetry WHERE synced_at < $1;`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete device telemetry: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// DeleteMessageKeysBefore removes the keys of MQTT messages ingested before
+// the given time
+func (r *MaintenanceRepository) DeleteMessageKeysBefore(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.db.Pool.Exec(ctx, `DELETE FROM ingested_messages WHERE received_at < $1;`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete ingested message keys: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,31 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+	"github.com/jackc/pgx/v5"
+)
+
+// claimMessageKey records the key of the message being ingested inside tx. It
+// returns utils.ErrDuplicateMessage when the key was already stored. A
+// concurrent transaction holding the same key blocks the insert until it
+// commits or rolls back, so a redelivery is never dropped while the first
+// attempt can still fail. A nil key is not tracked.
+func claimMessageKey(ctx context.Context, tx pgx.Tx, key *string) error {
+	if key == nil {
+		return nil
+	}
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO ingested_messages (message_key) VALUES ($1)
+		ON CONFLICT (message_key) DO NOTHING;`, *key)
+	if err != nil {
+		return fmt.Errorf("failed to record message key: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return utils.ErrDuplicateMessage
+	}
+	return nil
+}
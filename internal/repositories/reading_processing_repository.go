@@ -0,0 +1,240 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/db"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ReadingProcessingRepository claims unevaluated heart readings in batches,
+// runs the database processing procedure and coordinates the replicas that
+// process them
+type ReadingProcessingRepository struct {
+	db *db.PostgresDB
+}
+
+func NewReadingProcessingRepository(database *db.PostgresDB) *ReadingProcessingRepository {
+	return &ReadingProcessingRepository{db: database}
+}
+
+// BatchResult summarizes one processed batch
+type BatchResult struct {
+	Claimed   int
+	Processed int
+	Failed    int
+}
+
+// ProcessUnprocessedReadings runs the process_unprocessed_readings procedure,
+// which handles the readings whose processed flag is still false
+func (r *ReadingProcessingRepository) ProcessUnprocessedReadings(ctx context.Context) error {
+	if _, err := r.db.Pool.Exec(ctx, `CALL process_unprocessed_readings();`); err != nil {
+		return fmt.Errorf("failed to process unprocessed readings: %w", err)
+	}
+	return nil
+}
+
+// ProcessBatch claims up to limit readings whose alerts were not evaluated,
+// oldest first, and calls process for each of them. Readings locked by
+// another transaction are skipped, as are failed readings whose next attempt
+// is not due yet or that already failed maxAttempts times. Successful
+// readings are marked evaluated; failed ones are retried after
+// retryBase·2^(attempts-1), capped at retryMax. process raises its alerts
+// outside the claiming transaction; they are raised once per reading, so a
+// batch that rolls back and is claimed again does not repeat them.
+func (r *ReadingProcessingRepository) ProcessBatch(
+	ctx context.Context,
+	limit int,
+	maxAttempts int,
+	retryBase time.Duration,
+	retryMax time.Duration,
+	process func(ctx context.Context, reading *models.HeartReading) error,
+) (*BatchResult, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		SELECT hr.id, hr.patient_id, hr.device_id, hr.entry_method, hr.entered_by, hr.reading_type,
+		       hr.source, hr.bpm, hr.variability, hr.irregularity_detected, hr.oxygen_level,
+		       hr.systolic_pressure, hr.diastolic_pressure, hr.temperature, hr.activity_level,
+		       hr.notes, hr.reliability_score, hr.quality_score, hr.quality_flags, hr.processed, hr.time
+		FROM heart_readings hr
+		LEFT JOIN reading_processing_failures f ON f.reading_id = hr.id
+		WHERE NOT hr.alerts_evaluated AND hr.deleted_at IS NULL
+		  AND (f.reading_id IS NULL OR (f.attempts < $2 AND f.next_attempt_at <= NOW()))
+		ORDER BY hr.time
+		LIMIT $1
+		FOR UPDATE OF hr SKIP LOCKED;`
+
+	rows, err := tx.Query(ctx, query, limit, maxAttempts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim unprocessed readings: %w", err)
+	}
+	readings := []*models.HeartReading{}
+	for rows.Next() {
+		var reading models.HeartReading
+		if err := rows.Scan(
+			&reading.ID,
+			&reading.PatientID,
+			&reading.DeviceID,
+			&reading.EntryMethod,
+			&reading.EnteredBy,
+			&reading.ReadingType,
+			&reading.Source,
+			&reading.BPM,
+			&reading.Variability,
+			&reading.IrregularityDetected,
+			&reading.OxygenLevel,
+			&reading.SystolicPressure,
+			&reading.DiastolicPressure,
+			&reading.Temperature,
+			&reading.ActivityLevel,
+			&reading.Notes,
+			&reading.ReliabilityScore,
+			&reading.QualityScore,
+			&reading.QualityFlags,
+			&reading.Processed,
+			&reading.Time,
+		); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		readings = append(readings, &reading)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+
+	result := &BatchResult{Claimed: len(readings)}
+	succeeded := []uuid.UUID{}
+	for _, reading := range readings {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err := process(ctx, reading); err != nil {
+			result.Failed++
+			if _, err := tx.Exec(ctx, `
+				INSERT INTO reading_processing_failures (reading_id, attempts, last_error, next_attempt_at)
+				VALUES ($1, 1, $2, NOW() + make_interval(secs => $3))
+				ON CONFLICT (reading_id) DO UPDATE SET
+					attempts        = reading_processing_failures.attempts + 1,
+					last_error      = EXCLUDED.last_error,
+					next_attempt_at = NOW() + make_interval(secs => LEAST(
+						$3 * power(2, reading_processing_failures.attempts), $4)),
+					updated_at      = NOW();`,
+				reading.ID, err.Error(), retryBase.Seconds(), retryMax.Seconds(),
+			); err != nil {
+				return nil, fmt.Errorf("failed to record processing failure: %w", err)
+			}
+			continue
+		}
+		succeeded = append(succeeded, reading.ID)
+	}
+
+	if len(succeeded) > 0 {
+		if _, err := tx.Exec(ctx,
+			`UPDATE heart_readings SET alerts_evaluated = true WHERE id = ANY($1) AND NOT alerts_evaluated;`,
+			succeeded,
+		); err != nil {
+			return nil, fmt.Errorf("failed to mark readings evaluated: %w", err)
+		}
+		if _, err := tx.Exec(ctx,
+			`DELETE FROM reading_processing_failures WHERE reading_id = ANY($1);`,
+			succeeded,
+		); err != nil {
+			return nil, fmt.Errorf("failed to clear processing failures: %w", err)
+		}
+		result.Processed = len(succeeded)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
+
+// GetBacklog counts the readings waiting to be evaluated and those that
+// exhausted their retries
+func (r *ReadingProcessingRepository) GetBacklog(ctx context.Context, maxAttempts int) (pending int64, exhausted int64, err error) {
+	query := `
+		SELECT COUNT(*) FILTER (WHERE f.reading_id IS NULL OR f.attempts < $1),
+		       COUNT(*) FILTER (WHERE f.attempts >= $1)
+		FROM heart_readings hr
+		LEFT JOIN reading_processing_failures f ON f.reading_id = hr.id
+		WHERE NOT hr.alerts_evaluated AND hr.deleted_at IS NULL;`
+
+	if err := r.db.Pool.QueryRow(ctx, query, maxAttempts).Scan(&pending, &exhausted); err != nil {
+		return 0, 0, fmt.Errorf("failed to count unevaluated readings: %w", err)
+	}
+	return pending, exhausted, nil
+}
+
+// LeaderLock is a session-level Postgres advisory lock. It is held on a
+// dedicated connection, so it is released automatically if the replica dies.
+type LeaderLock struct {
+	pool *pgxpool.Pool
+	key  int64
+	conn *pgxpool.Conn
+}
+
+// NewLeaderLock returns an advisory lock identified by key
+func (r *ReadingProcessingRepository) NewLeaderLock(key int64) *LeaderLock {
+	return &LeaderLock{pool: r.db.Pool, key: key}
+}
+
+// TryAcquire takes the lock if no other session holds it. It reports whether
+// this replica holds the lock afterwards; a lock whose connection was lost is
+// given up and acquired again.
+func (l *LeaderLock) TryAcquire(ctx context.Context) (bool, error) {
+	if l.conn != nil {
+		if err := l.conn.Ping(ctx); err == nil {
+			return true, nil
+		}
+		// Conexión perdida: el servidor ya liberó el bloqueo
+		l.conn.Release()
+		l.conn = nil
+	}
+
+	conn, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+
+	var acquired bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1);`, l.key).Scan(&acquired); err != nil {
+		conn.Release()
+		return false, fmt.Errorf("failed to try advisory lock: %w", err)
+	}
+	if !acquired {
+		conn.Release()
+		return false, nil
+	}
+
+	l.conn = conn
+	return true, nil
+}
+
+// Release gives up the lock if it is held. When the unlock fails the
+// connection is closed instead, which releases the lock on the server.
+func (l *LeaderLock) Release(ctx context.Context) error {
+	if l.conn == nil {
+		return nil
+	}
+	conn := l.conn
+	l.conn = nil
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_unlock($1);`, l.key); err != nil {
+		conn.Conn().Close(context.Background())
+		return fmt.Errorf("failed to release advisory lock: %w", err)
+	}
+	return nil
+}
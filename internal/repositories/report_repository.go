@@ -0,0 +1,165 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/db"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/google/uuid"
+)
+
+type ReportRepository struct {
+	db *db.PostgresDB
+}
+
+func NewReportRepository(database *db.PostgresDB) *ReportRepository {
+	return &ReportRepository{db: database}
+}
+
+// GetPatientsWithReadingsBetween returns the patients that have readings in [start, end)
+func (r *ReportRepository) GetPatientsWithReadingsBetween(ctx context.Context, start, end time.Time) ([]uuid.UUID, error) {
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT DISTINCT patient_id FROM heart_readings WHERE time >= $1 AND time < $2 AND deleted_at IS NULL;`, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patients with readings: %w", err)
+	}
+	defer rows.Close()
+
+	var patientIDs []uuid.UUID
+	for rows.Next() {
+		var patientID uuid.UUID
+		if err := rows.Scan(&patientID); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		patientIDs = append(patientIDs, patientID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return patientIDs, nil
+}
+
+// ComputeDailyReport aggregates a patient's readings and alerts in [start, end).
+// The low and high counts are left to the caller, which knows the limits that
+// applied to each reading.
+func (r *ReportRepository) ComputeDailyReport(
+	ctx context.Context,
+	patientID uuid.UUID,
+	start time.Time,
+	end time.Time,
+) (*models.PatientDailyReport, error) {
+	query := `
+		SELECT COUNT(*), MIN(bpm), MAX(bpm), AVG(bpm)::float8,
+		       (AVG(bpm) FILTER (WHERE reading_type = 'resting'))::float8,
+		       COUNT(*) FILTER (WHERE irregularity_detected),
+		       AVG(oxygen_level)::float8,
+		       (SELECT COUNT(*) FROM alerts
+		        WHERE patient_id = $1 AND reading_time >= $2 AND reading_time < $3)
+		FROM heart_readings
+		WHERE patient_id = $1 AND time >= $2 AND time < $3 AND deleted_at IS NULL;`
+
+	report := &models.PatientDailyReport{PatientID: patientID}
+	if err := r.db.Pool.QueryRow(ctx, query, patientID, start, end).Scan(
+		&report.ReadingCount,
+		&report.MinBPM,
+		&report.MaxBPM,
+		&report.AvgBPM,
+		&report.RestingAvgBPM,
+		&report.IrregularCount,
+		&report.AvgOxygen,
+		&report.AlertCount,
+	); err != nil {
+		return nil, fmt.Errorf("failed to compute daily report: %w", err)
+	}
+	return report, nil
+}
+
+// SaveDailyReport stores a report, replacing an earlier one for the same day
+func (r *ReportRepository) SaveDailyReport(ctx context.Context, report *models.PatientDailyReport) error {
+	query := `
+		INSERT INTO patient_daily_reports (
+			patient_id, report_date, reading_count, min_bpm, max_bpm, avg_bpm, resting_avg_bpm,
+			low_count, high_count, irregular_count, alert_count, avg_oxygen
+		)
+		VALUES ($1, $2::date, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (patient_id, report_date) DO UPDATE SET
+			reading_count   = EXCLUDED.reading_count,
+			min_bpm         = EXCLUDED.min_bpm,
+			max_bpm         = EXCLUDED.max_bpm,
+			avg_bpm         = EXCLUDED.avg_bpm,
+			resting_avg_bpm = EXCLUDED.resting_avg_bpm,
+			low_count       = EXCLUDED.low_count,
+			high_count      = EXCLUDED.high_count,
+			irregular_count = EXCLUDED.irregular_count,
+			alert_count     = EXCLUDED.alert_count,
+			avg_oxygen      = EXCLUDED.avg_oxygen,
+			generated_at    = NOW()
+		RETURNING generated_at;`
+
+	if err := r.db.Pool.QueryRow(ctx, query,
+		report.PatientID,
+		report.ReportDate,
+		report.ReadingCount,
+		report.MinBPM,
+		report.MaxBPM,
+		report.AvgBPM,
+		report.RestingAvgBPM,
+		report.LowCount,
+		report.HighCount,
+		report.IrregularCount,
+		report.AlertCount,
+		report.AvgOxygen,
+	).Scan(&report.GeneratedAt); err != nil {
+		return fmt.Errorf("failed to save daily report: %w", err)
+	}
+	return nil
+}
+
+// GetDailyReports returns a patient's reports between two dates (YYYY-MM-DD,
+// inclusive), newest first
+func (r *ReportRepository) GetDailyReports(ctx context.Context, patientID uuid.UUID, from, to string) ([]*models.PatientDailyReport, error) {
+	query := `
+		SELECT patient_id, to_char(report_date, 'YYYY-MM-DD'), reading_count, min_bpm, max_bpm,
+		       avg_bpm, resting_avg_bpm, low_count, high_count, irregular_count, alert_count,
+		       avg_oxygen, generated_at
+		FROM patient_daily_reports
+		WHERE patient_id = $1 AND report_date BETWEEN $2::date AND $3::date
+		ORDER BY report_date DESC;`
+
+	rows, err := r.db.Pool.Query(ctx, query, patientID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily reports: %w", err)
+	}
+	defer rows.Close()
+
+	reports := []*models.PatientDailyReport{}
+	for rows.Next() {
+		var report models.PatientDailyReport
+		if err := rows.Scan(
+			&report.PatientID,
+			&report.ReportDate,
+			&report.ReadingCount,
+			&report.MinBPM,
+			&report.MaxBPM,
+			&report.AvgBPM,
+			&report.RestingAvgBPM,
+			&report.LowCount,
+			&report.HighCount,
+			&report.IrregularCount,
+			&report.AlertCount,
+			&report.AvgOxygen,
+			&report.GeneratedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		reports = append(reports, &report)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return reports, nil
+}
@@ -0,0 +1,319 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/db"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// rollupLevel is one of the pre-aggregated heart rate tables
+type rollupLevel struct {
+	unit   string // unidad de date_trunc del cubo
+	table  string
+	bucket time.Duration
+}
+
+// Del más fino al más grueso
+var rollupLevels = []rollupLevel{
+	{unit: "minute", table: "heart_rate_rollup_minute", bucket: time.Minute},
+	{unit: "hour", table: "heart_rate_rollup_hour", bucket: time.Hour},
+	{unit: "day", table: "heart_rate_rollup_day", bucket: 24 * time.Hour},
+}
+
+// pickRollup returns the coarsest rollup whose buckets fit in the trend
+// resolution and line up with both ends of the range and with the local
+// period boundaries in loc, or nil when only the raw readings give an exact
+// answer. Day rollups therefore only serve UTC trends, and hour rollups zones
+// with whole-hour offsets.
+func pickRollup(resolution utils.TrendResolution, loc *time.Location, startTime, endTime *time.Time) *rollupLevel {
+	aligned := func(t *time.Time, bucket time.Duration) bool {
+		return t == nil || t.UTC().Truncate(bucket).Equal(*t)
+	}
+	for i := len(rollupLevels) - 1; i >= 0; i-- {
+		level := &rollupLevels[i]
+		if level.bucket > resolution.Duration() || resolution.Step%level.bucket != 0 {
+			continue
+		}
+		if aligned(startTime, level.bucket) && aligned(endTime, level.bucket) &&
+			offsetsAligned(loc, startTime, endTime, level.bucket) {
+			return level
+		}
+	}
+	return nil
+}
+
+// offsetsAligned reports whether the UTC offset of loc is a whole number of
+// buckets over the range, so that local boundaries fall on UTC rollup
+// boundaries. Sampling the offset once a day is enough to see every DST
+// period; an unbounded range is only trusted in UTC or with minute buckets.
+func offsetsAligned(loc *time.Location, startTime, endTime *time.Time, bucket time.Duration) bool {
+	if loc == time.UTC {
+		return true
+	}
+	if startTime == nil || endTime == nil {
+		return bucket == time.Minute
+	}
+	for t := *startTime; ; t = t.Add(24 * time.Hour) {
+		if t.After(*endTime) {
+			t = *endTime
+		}
+		_, offset := t.In(loc).Zone()
+		if (time.Duration(offset)*time.Second)%bucket != 0 {
+			return false
+		}
+		if t.Equal(*endTime) {
+			return true
+		}
+	}
+}
+
+// trendBucketSQL returns the local (wall clock) start of the trend period
+// containing the timestamptz expression column. Queries using it take the
+// resolution as $4 (a date_trunc unit or an interval) and the zone as $5;
+// fixed intervals are counted from the local epoch.
+func trendBucketSQL(resolution utils.TrendResolution, column string) string {
+	if resolution.Unit != "" {
+		return fmt.Sprintf(`date_trunc($4, %s AT TIME ZONE $5)`, column)
+	}
+	return fmt.Sprintf(`to_timestamp(floor(extract(epoch FROM %[1]s AT TIME ZONE $5) / extract(epoch FROM ($4::text)::interval))
+		* extract(epoch FROM ($4::text)::interval)) AT TIME ZONE 'UTC'`, column)
+}
+
+// trendStepSQL returns the length of one trend period, to add to a local start
+func trendStepSQL(resolution utils.TrendResolution) string {
+	if resolution.Unit != "" {
+		return `('1 ' || $4)::interval`
+	}
+	return `($4::text)::interval`
+}
+
+// trendStepArg is the value bound to $4 by trendBucketSQL and trendStepSQL
+func trendStepArg(resolution utils.TrendResolution) string {
+	if resolution.Unit != "" {
+		return resolution.Unit
+	}
+	return fmt.Sprintf("%d seconds", int64(resolution.Step/time.Second))
+}
+
+// lockPatientRollups takes the transaction-level advisory lock on a patient's
+// rollups. Every transaction that writes them takes it first, so a rebuild
+// never runs alongside an incremental update of the same patient.
+func lockPatientRollups(ctx context.Context, tx pgx.Tx, patientID uuid.UUID) error {
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('heart-rollups'), hashtext($1::text));`, patientID); err != nil {
+		return fmt.Errorf("failed to lock patient rollups: %w", err)
+	}
+	return nil
+}
+
+// addToRollups counts a new reading in every rollup. It runs in the
+// transaction that inserts the reading.
+func addToRollups(ctx context.Context, tx pgx.Tx, patientID uuid.UUID, t time.Time, bpm int) error {
+	if err := lockPatientRollups(ctx, tx, patientID); err != nil {
+		return err
+	}
+	for _, level := range rollupLevels {
+		query := fmt.Sprintf(`
+			INSERT INTO %[1]s AS r (patient_id, bucket_start, reading_count, bpm_sum, bpm_min, bpm_max)
+			VALUES ($1, date_trunc('%[2]s', $2::timestamptz AT TIME ZONE 'UTC') AT TIME ZONE 'UTC', 1, $3, $3, $3)
+			ON CONFLICT (patient_id, bucket_start) DO UPDATE
+			SET reading_count = r.reading_count + 1,
+			    bpm_sum = r.bpm_sum + EXCLUDED.bpm_sum,
+			    bpm_min = LEAST(r.bpm_min, EXCLUDED.bpm_min),
+			    bpm_max = GREATEST(r.bpm_max, EXCLUDED.bpm_max);`, level.table, level.unit)
+		if _, err := tx.Exec(ctx, query, patientID, t, bpm); err != nil {
+			return fmt.Errorf("failed to update %s: %w", level.table, err)
+		}
+	}
+	return nil
+}
+
+// addReadingsToRollups counts the given stored readings of a patient in the
+// rollups, one upsert per level and bucket
+func addReadingsToRollups(ctx context.Context, tx pgx.Tx, patientID uuid.UUID, readingIDs []uuid.UUID) error {
+	if err := lockPatientRollups(ctx, tx, patientID); err != nil {
+		return err
+	}
+	for _, level := range rollupLevels {
+		query := fmt.Sprintf(`
+			INSERT INTO %[1]s AS r (patient_id, bucket_start, reading_count, bpm_sum, bpm_min, bpm_max)
+			SELECT patient_id, date_trunc('%[2]s', time AT TIME ZONE 'UTC') AT TIME ZONE 'UTC',
+			       COUNT(*), SUM(bpm), MIN(bpm), MAX(bpm)
+			FROM heart_readings
+			WHERE id = ANY($1)
+			GROUP BY 1, 2
+			ON CONFLICT (patient_id, bucket_start) DO UPDATE
+			SET reading_count = r.reading_count + EXCLUDED.reading_count,
+			    bpm_sum = r.bpm_sum + EXCLUDED.bpm_sum,
+			    bpm_min = LEAST(r.bpm_min, EXCLUDED.bpm_min),
+			    bpm_max = GREATEST(r.bpm_max, EXCLUDED.bpm_max);`, level.table, level.unit)
+		if _, err := tx.Exec(ctx, query, readingIDs); err != nil {
+			return fmt.Errorf("failed to update %s: %w", level.table, err)
+		}
+	}
+	return nil
+}
+
+// refreshRollups recomputes from the readings the buckets containing t. Edits
+// and deletions use it because a minimum or maximum cannot be subtracted.
+func refreshRollups(ctx context.Context, tx pgx.Tx, patientID uuid.UUID, t time.Time) error {
+	if err := lockPatientRollups(ctx, tx, patientID); err != nil {
+		return err
+	}
+	for _, level := range rollupLevels {
+		bucket := fmt.Sprintf(`date_trunc('%s', $2::timestamptz AT TIME ZONE 'UTC') AT TIME ZONE 'UTC'`, level.unit)
+
+		if _, err := tx.Exec(ctx,
+			fmt.Sprintf(`DELETE FROM %s WHERE patient_id = $1 AND bucket_start = %s;`, level.table, bucket),
+			patientID, t,
+		); err != nil {
+			return fmt.Errorf("failed to refresh %s: %w", level.table, err)
+		}
+
+		query := fmt.Sprintf(`
+			INSERT INTO %[1]s (patient_id, bucket_start, reading_count, bpm_sum, bpm_min, bpm_max)
+			SELECT $1, %[2]s, COUNT(*), SUM(bpm), MIN(bpm), MAX(bpm)
+			FROM heart_readings
+			WHERE patient_id = $1 AND deleted_at IS NULL
+			  AND time >= %[2]s AND time < %[2]s + interval '1 %[3]s'
+			HAVING COUNT(*) > 0;`, level.table, bucket, level.unit)
+		if _, err := tx.Exec(ctx, query, patientID, t); err != nil {
+			return fmt.Errorf("failed to refresh %s: %w", level.table, err)
+		}
+	}
+	return nil
+}
+
+type RollupRepository struct {
+	db *db.PostgresDB
+}
+
+func NewRollupRepository(database *db.PostgresDB) *RollupRepository {
+	return &RollupRepository{db: database}
+}
+
+// GetReadingTimeRange returns the time of the first and last stored reading,
+// of one patient or of all of them. ok is false when there are none.
+func (r *RollupRepository) GetReadingTimeRange(ctx context.Context, patientID *uuid.UUID) (first, last time.Time, ok bool, err error) {
+	var firstTime, lastTime *time.Time
+	err = r.db.Pool.QueryRow(ctx, `
+		SELECT MIN(time), MAX(time)
+		FROM heart_readings
+		WHERE deleted_at IS NULL AND ($1::uuid IS NULL OR patient_id = $1);`,
+		patientID,
+	).Scan(&firstTime, &lastTime)
+	if err != nil {
+		return time.Time{}, time.Time{}, false, fmt.Errorf("failed to get reading time range: %w", err)
+	}
+	if firstTime == nil || lastTime == nil {
+		return time.Time{}, time.Time{}, false, nil
+	}
+	return *firstTime, *lastTime, true, nil
+}
+
+// RebuildRollups recomputes every rollup bucket in [from, to) from the
+// readings, for one patient or for all of them, and returns the number of
+// day buckets written. from and to must be whole UTC days. Each patient is
+// rebuilt in its own transaction under the patient's rollup lock, so ingest
+// for the other patients goes on and readings arriving meanwhile are counted
+// exactly once.
+func (r *RollupRepository) RebuildRollups(ctx context.Context, patientID *uuid.UUID, from, to time.Time) (int64, error) {
+	var patients []uuid.UUID
+	if patientID != nil {
+		patients = []uuid.UUID{*patientID}
+	} else {
+		var err error
+		if patients, err = r.getRollupPatients(ctx, from, to); err != nil {
+			return 0, err
+		}
+	}
+
+	var days int64
+	for _, id := range patients {
+		n, err := r.rebuildPatientRollups(ctx, id, from, to)
+		if err != nil {
+			return 0, err
+		}
+		days += n
+	}
+	return days, nil
+}
+
+// getRollupPatients returns the patients with readings or rollup buckets in
+// [from, to); the buckets of a patient without readings left are cleared too
+func (r *RollupRepository) getRollupPatients(ctx context.Context, from, to time.Time) ([]uuid.UUID, error) {
+	query := `SELECT patient_id FROM heart_readings WHERE time >= $1 AND time < $2 AND deleted_at IS NULL`
+	for _, level := range rollupLevels {
+		query += fmt.Sprintf(`
+		UNION
+		SELECT patient_id FROM %s WHERE bucket_start >= $1 AND bucket_start < $2`, level.table)
+	}
+
+	rows, err := r.db.Pool.Query(ctx, query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rollup patients: %w", err)
+	}
+	defer rows.Close()
+
+	var patients []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		patients = append(patients, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return patients, nil
+}
+
+// rebuildPatientRollups recomputes one patient's rollup buckets in [from, to)
+// and returns the number of day buckets written
+func (r *RollupRepository) rebuildPatientRollups(ctx context.Context, patientID uuid.UUID, from, to time.Time) (int64, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Las consultas siguientes ven las lecturas confirmadas hasta obtener el bloqueo
+	if err := lockPatientRollups(ctx, tx, patientID); err != nil {
+		return 0, err
+	}
+
+	var days int64
+	for _, level := range rollupLevels {
+		if _, err := tx.Exec(ctx, fmt.Sprintf(`
+			DELETE FROM %s
+			WHERE patient_id = $3 AND bucket_start >= $1 AND bucket_start < $2;`, level.table),
+			from, to, patientID,
+		); err != nil {
+			return 0, fmt.Errorf("failed to clear %s: %w", level.table, err)
+		}
+
+		tag, err := tx.Exec(ctx, fmt.Sprintf(`
+			INSERT INTO %[1]s (patient_id, bucket_start, reading_count, bpm_sum, bpm_min, bpm_max)
+			SELECT patient_id, date_trunc('%[2]s', time AT TIME ZONE 'UTC') AT TIME ZONE 'UTC',
+			       COUNT(*), SUM(bpm), MIN(bpm), MAX(bpm)
+			FROM heart_readings
+			WHERE patient_id = $3 AND time >= $1 AND time < $2 AND deleted_at IS NULL
+			GROUP BY 1, 2;`, level.table, level.unit),
+			from, to, patientID,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to rebuild %s: %w", level.table, err)
+		}
+		days = tag.RowsAffected()
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit rollups: %w", err)
+	}
+	return days, nil
+}
@@ -0,0 +1,328 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/db"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Tablas que se copian (solo estructura) al esquema desechable de las pruebas
+var rollupTestTables = []string{"heart_readings", "heart_rate_rollup_minute", "heart_rate_rollup_hour", "heart_rate_rollup_day"}
+
+// newRollupTestDB connects to TEST_DATABASE_URL, a database with the schema
+// applied, and creates a throwaway schema with empty copies of the reading
+// and rollup tables that comes first in the search path. The schema is
+// dropped when the test ends; the real tables are only read for their
+// definition.
+func newRollupTestDB(tb testing.TB) *db.PostgresDB {
+	tb.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		tb.Skip("TEST_DATABASE_URL not set")
+	}
+
+	ctx := context.Background()
+	schema := "rollup_test_" + strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		tb.Fatalf("invalid TEST_DATABASE_URL: %v", err)
+	}
+	config.ConnConfig.RuntimeParams["search_path"] = schema + ", public"
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		tb.Fatalf("connecting to the test database: %v", err)
+	}
+	tb.Cleanup(func() {
+		if _, err := pool.Exec(context.Background(), fmt.Sprintf(`DROP SCHEMA IF EXISTS %s CASCADE;`, schema)); err != nil {
+			tb.Errorf("dropping schema %s: %v", schema, err)
+		}
+		pool.Close()
+	})
+
+	// La definición de la vista sin calificar, para recrearla sobre las copias
+	var viewDef string
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `SET LOCAL search_path TO public;`); err != nil {
+			return err
+		}
+		return tx.QueryRow(ctx, `SELECT pg_get_viewdef('active_heart_readings'::regclass);`).Scan(&viewDef)
+	})
+	if err != nil {
+		tb.Fatalf("reading the active_heart_readings definition: %v", err)
+	}
+
+	if _, err := pool.Exec(ctx, fmt.Sprintf(`CREATE SCHEMA %s;`, schema)); err != nil {
+		tb.Fatalf("creating schema %s: %v", schema, err)
+	}
+	for _, table := range rollupTestTables {
+		if _, err := pool.Exec(ctx, fmt.Sprintf(`CREATE TABLE %[1]s.%[2]s (LIKE public.%[2]s INCLUDING ALL);`, schema, table)); err != nil {
+			tb.Fatalf("creating %s.%s: %v", schema, table, err)
+		}
+	}
+	if _, err := pool.Exec(ctx, fmt.Sprintf(`CREATE VIEW %s.active_heart_readings AS %s`, schema, viewDef)); err != nil {
+		tb.Fatalf("creating %s.active_heart_readings: %v", schema, err)
+	}
+
+	return &db.PostgresDB{Pool: pool}
+}
+
+// insertSyntheticReadings copies n readings with a daily rhythm and
+// correlated noise, one every interval from start
+func insertSyntheticReadings(tb testing.TB, database *db.PostgresDB, patientID uuid.UUID, start time.Time, interval time.Duration, n int) {
+	tb.Helper()
+
+	rng := rand.New(rand.NewSource(1))
+	noise := 0.0
+	const batch = 100_000
+
+	for offset := 0; offset < n; offset += batch {
+		rows := make([][]any, min(batch, n-offset))
+		for i := range rows {
+			t := start.Add(time.Duration(offset+i) * interval)
+			hour := float64(t.Hour()) + float64(t.Minute())/60
+			noise = 0.95*noise + rng.NormFloat64()*3
+			bpm := 68 + 10*math.Sin(2*math.Pi*(hour-10)/24) + noise
+			rows[i] = []any{patientID, "imported", "resting", "synthetic",
+				int(math.Max(40, math.Min(170, math.Round(bpm)))), false, true, t}
+		}
+
+		_, err := database.Pool.CopyFrom(context.Background(), pgx.Identifier{"heart_readings"},
+			[]string{"patient_id", "entry_method", "reading_type", "source", "bpm", "irregularity_detected", "processed", "time"},
+			pgx.CopyFromRows(rows))
+		if err != nil {
+			tb.Fatalf("inserting synthetic readings: %v", err)
+		}
+	}
+}
+
+type trendCase struct {
+	name       string
+	from, to   time.Time
+	resolution string
+	loc        *time.Location
+}
+
+// rollupTrendCases covers every rollup level and the ranges that fall back to
+// the raw readings (zones with other offsets, unaligned ends)
+func rollupTrendCases(tb testing.TB, start, end time.Time) []trendCase {
+	tb.Helper()
+
+	lima, err := time.LoadLocation("America/Lima")
+	if err != nil {
+		tb.Skipf("time zone America/Lima not available: %v", err)
+	}
+	day := 24 * time.Hour
+	month := end.AddDate(0, 0, -30)
+
+	return []trendCase{
+		{"1d", end.Add(-day), end, "minute", time.UTC},
+		{"1d", end.Add(-day), end, "hour", time.UTC},
+		{"1d", end.Add(-day), end, "15m", time.UTC},
+		{"30d", month, end, "hour", time.UTC},
+		{"30d", month, end, "6h", time.UTC},
+		{"30d", month, end, "day", time.UTC},
+		{"30d_lima", month, end, "day", lima},
+		{"30d_plus_30m", month.Add(30 * time.Minute), end, "day", time.UTC},
+		{"30d_plus_30s", month.Add(30 * time.Second), end, "day", time.UTC},
+		{"all", start, end, "day", time.UTC},
+		{"all", start, end, "week", time.UTC},
+		{"all", start, end, "month", time.UTC},
+	}
+}
+
+// setupRollupTrends stores n synthetic readings ending at the start of
+// today (UTC) for a new patient and builds their rollups
+func setupRollupTrends(tb testing.TB, n int, interval time.Duration) (*HeartReadingRepository, uuid.UUID, time.Time, time.Time) {
+	tb.Helper()
+
+	database := newRollupTestDB(tb)
+	patientID := uuid.New()
+
+	day := 24 * time.Hour
+	end := time.Now().UTC().Truncate(day)
+	start := end.Add(-time.Duration(n) * interval).Truncate(day)
+
+	insertSyntheticReadings(tb, database, patientID, start, interval, n)
+	if _, err := NewRollupRepository(database).RebuildRollups(context.Background(), &patientID, start, end.Add(day)); err != nil {
+		tb.Fatalf("RebuildRollups() error = %v", err)
+	}
+	return NewHeartReadingRepository(database), patientID, start, end
+}
+
+func TestHeartRateTrendsMatchReadings(t *testing.T) {
+	repo, patientID, start, end := setupRollupTrends(t, 60_000, time.Minute)
+	ctx := context.Background()
+
+	for _, c := range rollupTrendCases(t, start, end) {
+		t.Run(c.name+"/"+c.resolution, func(t *testing.T) {
+			resolution, err := utils.ParseTrendResolution(c.resolution)
+			if err != nil {
+				t.Fatalf("ParseTrendResolution(%q) error = %v", c.resolution, err)
+			}
+			// Final inclusivo: el instante anterior al límite de los cubos
+			from, to := c.from, c.to.Add(-time.Nanosecond)
+
+			raw, err := repo.GetHeartRateTrendsFromReadings(ctx, patientID, &from, &to, resolution, c.loc, false, false)
+			if err != nil {
+				t.Fatalf("GetHeartRateTrendsFromReadings() error = %v", err)
+			}
+			rolled, err := repo.GetHeartRateTrends(ctx, patientID, &from, &to, resolution, c.loc, false, false)
+			if err != nil {
+				t.Fatalf("GetHeartRateTrends() error = %v", err)
+			}
+
+			if len(raw) != len(rolled) {
+				t.Fatalf("got %d periods from the rollups, want %d", len(rolled), len(raw))
+			}
+			for i := range raw {
+				a, b := raw[i], rolled[i]
+				if !a.PeriodStart.Equal(b.PeriodStart) || a.ReadingCount != b.ReadingCount ||
+					*a.MinBPM != *b.MinBPM || *a.MaxBPM != *b.MaxBPM || math.Abs(*a.AvgBPM-*b.AvgBPM) > 1e-6 {
+					t.Fatalf("period %s = %s, want %s", a.PeriodStart.Format(time.RFC3339), formatTrend(b), formatTrend(a))
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkHeartRateTrends compares the trends computed from the readings and
+// from the rollups over ROLLUP_BENCH_READINGS synthetic readings (default one
+// million, one every 10 seconds):
+//
+//	TEST_DATABASE_URL=postgres://... go test ./internal/repositories -run '^$' -bench HeartRateTrends
+func BenchmarkHeartRateTrends(b *testing.B) {
+	n := utils.GetEnvInt("ROLLUP_BENCH_READINGS", 1_000_000)
+	repo, patientID, start, end := setupRollupTrends(b, n, 10*time.Second)
+	ctx := context.Background()
+
+	for _, c := range rollupTrendCases(b, start, end) {
+		resolution, err := utils.ParseTrendResolution(c.resolution)
+		if err != nil {
+			b.Fatalf("ParseTrendResolution(%q) error = %v", c.resolution, err)
+		}
+		from, to := c.from, c.to.Add(-time.Nanosecond)
+
+		b.Run(c.name+"/"+c.resolution+"/raw", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := repo.GetHeartRateTrendsFromReadings(ctx, patientID, &from, &to, resolution, c.loc, false, false); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(c.name+"/"+c.resolution+"/rollup", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := repo.GetHeartRateTrends(ctx, patientID, &from, &to, resolution, c.loc, false, false); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkRebuildRollups rebuilds the rollups of one patient with
+// ROLLUP_BENCH_READINGS synthetic readings
+func BenchmarkRebuildRollups(b *testing.B) {
+	n := utils.GetEnvInt("ROLLUP_BENCH_READINGS", 1_000_000)
+	database := newRollupTestDB(b)
+	patientID := uuid.New()
+
+	day := 24 * time.Hour
+	end := time.Now().UTC().Truncate(day)
+	start := end.Add(-time.Duration(n) * 10 * time.Second).Truncate(day)
+	insertSyntheticReadings(b, database, patientID, start, 10*time.Second, n)
+
+	rollupRepo := NewRollupRepository(database)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := rollupRepo.RebuildRollups(context.Background(), &patientID, start, end.Add(day)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestPickRollup(t *testing.T) {
+	lima, err := time.LoadLocation("America/Lima")
+	if err != nil {
+		t.Skipf("time zone America/Lima not available: %v", err)
+	}
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Skipf("time zone Asia/Kolkata not available: %v", err)
+	}
+
+	day := 24 * time.Hour
+	start := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	at := func(t time.Time) *time.Time { return &t }
+
+	tests := []struct {
+		name       string
+		resolution string
+		loc        *time.Location
+		start, end *time.Time
+		want       string // unidad del rollup; vacío para las lecturas
+	}{
+		// Las unidades de calendario no tienen Step: el mes y la semana usan
+		// los rollups diarios
+		{"month", "month", time.UTC, &start, &end, "day"},
+		{"week", "week", time.UTC, &start, &end, "day"},
+		{"day", "day", time.UTC, &start, &end, "day"},
+		{"hour", "hour", time.UTC, &start, &end, "hour"},
+		{"minute", "minute", time.UTC, &start, &end, "minute"},
+		{"unbounded month", "month", time.UTC, nil, nil, "day"},
+
+		// Los intervalos fijos usan el cubo más grueso que los divide
+		{"2 days", "2d", time.UTC, &start, &end, "day"},
+		{"6 hours", "6h", time.UTC, &start, &end, "hour"},
+		{"90 minutes", "90m", time.UTC, &start, &end, "minute"},
+		{"36 hours", "36h", time.UTC, &start, &end, "hour"},
+
+		// Extremos del rango que no coinciden con los cubos
+		{"start on the half hour", "month", time.UTC, at(start.Add(30 * time.Minute)), &end, "minute"},
+		{"start on the hour", "month", time.UTC, at(start.Add(time.Hour)), &end, "hour"},
+		{"start on the second", "month", time.UTC, at(start.Add(time.Second)), &end, ""},
+		{"end not aligned", "day", time.UTC, &start, at(end.Add(-time.Nanosecond)), ""},
+
+		// Zonas cuyo desplazamiento no es un número entero de cubos
+		{"whole-hour offset", "day", lima, at(start.Add(5 * time.Hour)), at(end.Add(5 * time.Hour)), "hour"},
+		{"half-hour offset", "day", kolkata, at(start.Add(-330 * time.Minute)), at(end.Add(-330 * time.Minute)), "minute"},
+		{"unbounded whole-hour offset", "day", lima, nil, nil, "minute"},
+		{"month in a whole-hour offset", "month", lima, at(start.Add(5 * time.Hour)), at(end.Add(day + 5*time.Hour)), "hour"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolution, err := utils.ParseTrendResolution(tt.resolution)
+			if err != nil {
+				t.Fatalf("ParseTrendResolution(%q) error = %v", tt.resolution, err)
+			}
+
+			got := ""
+			if level := pickRollup(resolution, tt.loc, tt.start, tt.end); level != nil {
+				got = level.unit
+			}
+			if got != tt.want {
+				t.Errorf("pickRollup() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func formatTrend(trend *models.HeartRateTrend) string {
+	return fmt.Sprintf("{count %d, min %d, max %d, avg %.3f}", trend.ReadingCount, *trend.MinBPM, *trend.MaxBPM, *trend.AvgBPM)
+}
@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/db"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type RuleRepository struct {
+	db *db.PostgresDB
+}
+
+func NewRuleRepository(database *db.PostgresDB) *RuleRepository {
+	return &RuleRepository{db: database}
+}
+
+// GetRuleSet returns the rule set stored for a patient, or the default rule
+// set when patientID is nil. It returns nil if none is stored.
+func (r *RuleRepository) GetRuleSet(ctx context.Context, patientID *uuid.UUID) (*models.RuleSetRecord, error) {
+	query := `
+		SELECT patient_id, definition, updated_by, updated_at
+		FROM rule_sets
+		WHERE patient_id IS NOT DISTINCT FROM $1;`
+
+	var record models.RuleSetRecord
+	err := r.db.Pool.QueryRow(ctx, query, patientID).Scan(
+		&record.PatientID,
+		&record.Definition,
+		&record.UpdatedBy,
+		&record.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get rule set: %w", err)
+	}
+
+	record.Source = "patient"
+	if patientID == nil {
+		record.Source = "default"
+	}
+	return &record, nil
+}
+
+// SaveRuleSet creates or replaces the rule set of a patient, or the default
+// rule set when patientID is nil
+func (r *RuleRepository) SaveRuleSet(
+	ctx context.Context,
+	patientID *uuid.UUID,
+	definition json.RawMessage,
+	updatedBy *uuid.UUID,
+) (*models.RuleSetRecord, error) {
+	conflict := `(patient_id) WHERE patient_id IS NOT NULL`
+	if patientID == nil {
+		conflict = `((patient_id IS NULL)) WHERE patient_id IS NULL`
+	}
+
+	query := `
+		INSERT INTO rule_sets (patient_id, definition, updated_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT ` + conflict + `
+		DO UPDATE SET definition = EXCLUDED.definition,
+		              updated_by = EXCLUDED.updated_by,
+		              updated_at = NOW()
+		RETURNING patient_id, definition, updated_by, updated_at;`
+
+	var record models.RuleSetRecord
+	err := r.db.Pool.QueryRow(ctx, query, patientID, definition, updatedBy).Scan(
+		&record.PatientID,
+		&record.Definition,
+		&record.UpdatedBy,
+		&record.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save rule set: %w", err)
+	}
+
+	record.Source = "patient"
+	if patientID == nil {
+		record.Source = "default"
+	}
+	return &record, nil
+}
+
+// DeleteRuleSet removes a patient's rule set so the default applies again.
+// It reports whether a rule set existed.
+func (r *RuleRepository) DeleteRuleSet(ctx context.Context, patientID uuid.UUID) (bool, error) {
+	tag, err := r.db.Pool.Exec(ctx, `DELETE FROM rule_sets WHERE patient_id = $1;`, patientID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete rule set: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
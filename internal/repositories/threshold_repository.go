@@ -0,0 +1,184 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/db"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type ThresholdRepository struct {
+	db *db.PostgresDB
+}
+
+func NewThresholdRepository(database *db.PostgresDB) *ThresholdRepository {
+	return &ThresholdRepository{db: database}
+}
+
+const thresholdProfileColumns = `
+	id, patient_id, name, reading_type, days_of_week,
+	to_char(start_time, 'HH24:MI'), to_char(end_time, 'HH24:MI'), timezone,
+	min_bpm, max_bpm, priority, active, set_by, set_at, created_at`
+
+// CreateProfile stores a new threshold profile for a patient
+func (r *ThresholdRepository) CreateProfile(
+	ctx context.Context,
+	patientID uuid.UUID,
+	profile *models.ThresholdProfileRequest,
+	setBy *uuid.UUID,
+) (*models.ThresholdProfile, error) {
+	query := `
+		INSERT INTO patient_threshold_profiles (
+			patient_id, name, reading_type, days_of_week, start_time, end_time,
+			timezone, min_bpm, max_bpm, priority, active, set_by
+		)
+		VALUES ($1, $2, $3, $4, $5::time, $6::time, NULLIF($7, ''), $8, $9, $10, $11, $12)
+		RETURNING ` + thresholdProfileColumns + `;`
+
+	return scanThresholdProfile(r.db.Pool.QueryRow(ctx, query,
+		patientID,
+		profile.Name,
+		profile.ReadingType,
+		profile.DaysOfWeek,
+		profile.StartTime,
+		profile.EndTime,
+		profile.Timezone,
+		profile.MinBPM,
+		profile.MaxBPM,
+		profile.Priority,
+		*profile.Active,
+		setBy,
+	))
+}
+
+// UpdateProfile replaces a patient's threshold profile, recording who set it.
+// It returns nil if the profile does not exist.
+func (r *ThresholdRepository) UpdateProfile(
+	ctx context.Context,
+	patientID uuid.UUID,
+	profileID uuid.UUID,
+	profile *models.ThresholdProfileRequest,
+	setBy *uuid.UUID,
+) (*models.ThresholdProfile, error) {
+	query := `
+		UPDATE patient_threshold_profiles
+		SET name = $3, reading_type = $4, days_of_week = $5, start_time = $6::time,
+		    end_time = $7::time, timezone = NULLIF($8, ''), min_bpm = $9, max_bpm = $10,
+		    priority = $11, active = $12, set_by = $13, set_at = NOW()
+		WHERE id = $1 AND patient_id = $2
+		RETURNING ` + thresholdProfileColumns + `;`
+
+	updated, err := scanThresholdProfile(r.db.Pool.QueryRow(ctx, query,
+		profileID,
+		patientID,
+		profile.Name,
+		profile.ReadingType,
+		profile.DaysOfWeek,
+		profile.StartTime,
+		profile.EndTime,
+		profile.Timezone,
+		profile.MinBPM,
+		profile.MaxBPM,
+		profile.Priority,
+		*profile.Active,
+		setBy,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return updated, err
+}
+
+// DeleteProfile removes a threshold profile and reports whether it existed
+func (r *ThresholdRepository) DeleteProfile(ctx context.Context, patientID uuid.UUID, profileID uuid.UUID) (bool, error) {
+	tag, err := r.db.Pool.Exec(ctx,
+		`DELETE FROM patient_threshold_profiles WHERE id = $1 AND patient_id = $2;`,
+		profileID, patientID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete threshold profile: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetProfiles returns a patient's threshold profiles, optionally only the active ones
+func (r *ThresholdRepository) GetProfiles(ctx context.Context, patientID uuid.UUID, activeOnly bool) ([]*models.ThresholdProfile, error) {
+	query := `
+		SELECT ` + thresholdProfileColumns + `
+		FROM patient_threshold_profiles
+		WHERE patient_id = $1 AND (active OR NOT $2)
+		ORDER BY priority DESC, created_at;`
+
+	rows, err := r.db.Pool.Query(ctx, query, patientID, activeOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get threshold profiles: %w", err)
+	}
+	defer rows.Close()
+
+	profiles := []*models.ThresholdProfile{}
+	for rows.Next() {
+		profile, err := scanThresholdProfile(rows)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, profile)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return profiles, nil
+}
+
+// GetPatientHeartRateRange returns the patient's global heart rate limits,
+// or nil if the patient does not exist
+func (r *ThresholdRepository) GetPatientHeartRateRange(ctx context.Context, patientID uuid.UUID) (*models.EffectiveThresholds, error) {
+	query := `SELECT min_heart_rate, max_heart_rate FROM patients WHERE id = $1;`
+
+	thresholds := models.EffectiveThresholds{Source: "patient"}
+	err := r.db.Pool.QueryRow(ctx, query, patientID).Scan(&thresholds.MinBPM, &thresholds.MaxBPM)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get patient heart rate range: %w", err)
+	}
+	return &thresholds, nil
+}
+
+func scanThresholdProfile(row pgx.Row) (*models.ThresholdProfile, error) {
+	var profile models.ThresholdProfile
+	var days []int16
+	err := row.Scan(
+		&profile.ID,
+		&profile.PatientID,
+		&profile.Name,
+		&profile.ReadingType,
+		&days,
+		&profile.StartTime,
+		&profile.EndTime,
+		&profile.Timezone,
+		&profile.MinBPM,
+		&profile.MaxBPM,
+		&profile.Priority,
+		&profile.Active,
+		&profile.SetBy,
+		&profile.SetAt,
+		&profile.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan threshold profile: %w", err)
+	}
+
+	for _, d := range days {
+		profile.DaysOfWeek = append(profile.DaysOfWeek, int(d))
+	}
+	return &profile, nil
+}
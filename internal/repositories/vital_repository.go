@@ -0,0 +1,302 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/db"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type VitalRepository struct {
+	db *db.PostgresDB
+}
+
+func NewVitalRepository(database *db.PostgresDB) *VitalRepository {
+	return &VitalRepository{db: database}
+}
+
+const vitalColumns = `id, patient_id, device_id, observation_type, value, secondary_value, unit,
+		       entry_method, source, entered_by, notes, time`
+
+// CreateObservation stores a vital sign observation. A nil time means now.
+func (r *VitalRepository) CreateObservation(
+	ctx context.Context,
+	observation *models.VitalObservationCreateRequest,
+	unit string,
+) (*models.VitalObservation, error) {
+	return insertVitalObservation(ctx, r.db.Pool, observation, unit)
+}
+
+// rowQuerier runs a query returning one row, on the pool or in a transaction
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func insertVitalObservation(
+	ctx context.Context,
+	q rowQuerier,
+	observation *models.VitalObservationCreateRequest,
+	unit string,
+) (*models.VitalObservation, error) {
+	query := `
+		INSERT INTO vital_observations (
+			patient_id, device_id, observation_type, value, secondary_value, unit,
+			entry_method, source, entered_by, notes, time
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, COALESCE($11, NOW()))
+		RETURNING ` + vitalColumns + `;`
+
+	created, err := scanVitalObservation(q.QueryRow(ctx, query,
+		observation.PatientID,
+		observation.DeviceID,
+		observation.ObservationType,
+		observation.Value,
+		observation.SecondaryValue,
+		unit,
+		observation.EntryMethod,
+		observation.Source,
+		observation.EnteredBy,
+		observation.Notes,
+		observation.Time,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create vital observation: %w", err)
+	}
+	return created, nil
+}
+
+// GetObservations returns a page of a patient's observations, using keyset
+// pagination on (time, id)
+func (r *VitalRepository) GetObservations(ctx context.Context, params *models.VitalQueryParams) (*models.VitalListResponse, error) {
+	var args []any
+	addArg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conditions := []string{"patient_id = " + addArg(params.PatientID)}
+	if params.ObservationType != nil {
+		conditions = append(conditions, "observation_type = "+addArg(*params.ObservationType))
+	}
+	if params.StartTime != nil {
+		conditions = append(conditions, "time >= "+addArg(*params.StartTime))
+	}
+	if params.EndTime != nil {
+		conditions = append(conditions, "time <= "+addArg(*params.EndTime))
+	}
+
+	direction, comparator := "DESC", "<"
+	if params.SortOrder == "asc" {
+		direction, comparator = "ASC", ">"
+	}
+	if params.Cursor != nil {
+		cursor, err := utils.DecodeCursor(*params.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursorTime, err := utils.ParseCursorTime(cursor.Value)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, fmt.Sprintf("(time, id) %s (%s::timestamptz, %s::uuid)",
+			comparator, addArg(cursorTime), addArg(cursor.ID)))
+	}
+
+	// Se pide una fila extra para saber si hay una página siguiente
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM vital_observations
+		WHERE %s
+		ORDER BY time %s, id %s
+		LIMIT %s;`,
+		vitalColumns, strings.Join(conditions, " AND "), direction, direction, addArg(params.Limit+1))
+
+	rows, err := r.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vital observations: %w", err)
+	}
+	defer rows.Close()
+
+	response := &models.VitalListResponse{Data: []*models.VitalObservation{}}
+	for rows.Next() {
+		observation, err := scanVitalObservation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+
+		if len(response.Data) == params.Limit {
+			last := response.Data[len(response.Data)-1]
+			next := utils.EncodeCursor(utils.Cursor{Value: last.Time.Format(time.RFC3339Nano), ID: last.ID})
+			response.NextCursor = &next
+			break
+		}
+		response.Data = append(response.Data, observation)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return response, nil
+}
+
+// GetObservationsInRange returns a patient's observations of the given types
+// between two instants (inclusive), oldest first. No types means all of them.
+func (r *VitalRepository) GetObservationsInRange(
+	ctx context.Context,
+	patientID uuid.UUID,
+	types []string,
+	startTime time.Time,
+	endTime time.Time,
+) ([]*models.VitalObservation, error) {
+	query := `
+		SELECT ` + vitalColumns + `
+		FROM vital_observations
+		WHERE patient_id = $1 AND time >= $2 AND time <= $3
+		  AND (cardinality($4::text[]) = 0 OR observation_type = ANY($4))
+		ORDER BY time;`
+
+	if types == nil {
+		types = []string{}
+	}
+	rows, err := r.db.Pool.Query(ctx, query, patientID, startTime, endTime, types)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vital observations: %w", err)
+	}
+	defer rows.Close()
+
+	observations := []*models.VitalObservation{}
+	for rows.Next() {
+		observation, err := scanVitalObservation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		observations = append(observations, observation)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return observations, nil
+}
+
+// GetVitalStats calculates statistics for one vital sign of a patient. Values
+// below refLow or above refHigh are counted as low or high; nil disables the
+// count.
+func (r *VitalRepository) GetVitalStats(
+	ctx context.Context,
+	patientID uuid.UUID,
+	observationType string,
+	startTime *time.Time,
+	endTime *time.Time,
+	refLow *float64,
+	refHigh *float64,
+) (*models.VitalStats, error) {
+	query := `
+		SELECT COUNT(*), AVG(value), MIN(value), MAX(value), STDDEV_SAMP(value),
+		       AVG(secondary_value), MIN(secondary_value), MAX(secondary_value),
+		       COUNT(*) FILTER (WHERE value < $5), COUNT(*) FILTER (WHERE value > $6),
+		       MIN(time), MAX(time)
+		FROM vital_observations
+		WHERE patient_id = $1 AND observation_type = $2
+		  AND ($3::timestamptz IS NULL OR time >= $3)
+		  AND ($4::timestamptz IS NULL OR time <= $4);`
+
+	stats := &models.VitalStats{ObservationType: observationType}
+	if err := r.db.Pool.QueryRow(ctx, query, patientID, observationType, startTime, endTime, refLow, refHigh).Scan(
+		&stats.Count,
+		&stats.Avg,
+		&stats.Min,
+		&stats.Max,
+		&stats.StdDev,
+		&stats.SecondaryAvg,
+		&stats.SecondaryMin,
+		&stats.SecondaryMax,
+		&stats.LowCount,
+		&stats.HighCount,
+		&stats.FirstObservation,
+		&stats.LastObservation,
+	); err != nil {
+		return nil, fmt.Errorf("failed to get vital stats: %w", err)
+	}
+	return stats, nil
+}
+
+// GetVitalTrends aggregates one vital sign of a patient per calendar period
+// (minute, hour, day, week or month) of the local time of loc
+func (r *VitalRepository) GetVitalTrends(
+	ctx context.Context,
+	patientID uuid.UUID,
+	observationType string,
+	startTime *time.Time,
+	endTime *time.Time,
+	resolution string,
+	loc *time.Location,
+) ([]*models.VitalTrend, error) {
+	query := `
+		SELECT period_start AT TIME ZONE $6, (period_start + ('1 ' || $5)::interval) AT TIME ZONE $6,
+		       AVG(value), MIN(value), MAX(value), AVG(secondary_value), COUNT(*)
+		FROM (
+			SELECT date_trunc($5, time AT TIME ZONE $6) AS period_start, value, secondary_value
+			FROM vital_observations
+			WHERE patient_id = $1 AND observation_type = $2
+			  AND ($3::timestamptz IS NULL OR time >= $3)
+			  AND ($4::timestamptz IS NULL OR time <= $4)
+		) v
+		GROUP BY period_start
+		ORDER BY period_start;`
+
+	rows, err := r.db.Pool.Query(ctx, query, patientID, observationType, startTime, endTime, resolution, loc.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vital trends: %w", err)
+	}
+	defer rows.Close()
+
+	trends := []*models.VitalTrend{}
+	for rows.Next() {
+		var trend models.VitalTrend
+		if err := rows.Scan(
+			&trend.PeriodStart,
+			&trend.PeriodEnd,
+			&trend.Avg,
+			&trend.Min,
+			&trend.Max,
+			&trend.SecondaryAvg,
+			&trend.Count,
+		); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		trends = append(trends, &trend)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return trends, nil
+}
+
+func scanVitalObservation(row pgx.Row) (*models.VitalObservation, error) {
+	var observation models.VitalObservation
+	if err := row.Scan(
+		&observation.ID,
+		&observation.PatientID,
+		&observation.DeviceID,
+		&observation.ObservationType,
+		&observation.Value,
+		&observation.SecondaryValue,
+		&observation.Unit,
+		&observation.EntryMethod,
+		&observation.Source,
+		&observation.EnteredBy,
+		&observation.Notes,
+		&observation.Time,
+	); err != nil {
+		return nil, err
+	}
+	return &observation, nil
+}
@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/controllers"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/middleware"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupAnnotationRoutes(
+	app *fiber.App,
+	authService *services.AuthService,
+	annotationService *services.AnnotationService,
+	localeService *services.LocaleService,
+) {
+	annotationController := controllers.NewAnnotationController(annotationService, localeService)
+
+	// Group of routes for clinical annotations
+	annotations := app.Group("/api/annotations", middleware.AuthMiddleware(authService))
+
+	// Solo el personal clínico escribe anotaciones
+	clinicians := middleware.RoleMiddleware("admin", "doctor")
+
+	annotations.Post("/", clinicians, annotationController.CreateAnnotation)
+	annotations.Get("/patient/:patientId", annotationController.GetPatientAnnotations)
+	annotations.Get("/:id", annotationController.GetAnnotation)
+	annotations.Put("/:id", clinicians, annotationController.UpdateAnnotation)
+	annotations.Delete("/:id", clinicians, annotationController.DeleteAnnotation)
+}
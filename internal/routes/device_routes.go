@@ -20,6 +20,10 @@ func SetupDeviceRoutes(app *fiber.App, authService *services.AuthService, device
 	devices.Post("/", deviceController.RegisterDevice)
 	devices.Patch("/:id", deviceController.UpdateDevice)
 	devices.Post("/:id/sync", deviceController.UpdateDeviceSync)
+	devices.Get("/:id/telemetry", deviceController.GetDeviceTelemetry)
+	devices.Get("/:id/assignments", deviceController.GetDeviceAssignments)
+	devices.Get("/:id/assignments/at", deviceController.GetDeviceAssignmentAt)
+	devices.Post("/:id/assign", middleware.RoleMiddleware("admin", "doctor"), deviceController.AssignDevice)
 	devices.Delete("/:id", deviceController.DeactivateDevice)
 
 	// Admin-only routes
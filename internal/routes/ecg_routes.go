@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/controllers"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/middleware"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupEcgRoutes(app *fiber.App, heartReadings fiber.Router, authService *services.AuthService, ecgService *services.EcgService) {
+	ecgController := controllers.NewEcgController(ecgService)
+
+	// ECG strips attached to a heart reading
+	heartReadings.Post("/:id/ecg", ecgController.UploadRecording)
+	heartReadings.Get("/:id/ecg", ecgController.GetRecordingsByReading)
+
+	// Group of routes for ECG downloads
+	ecg := app.Group("/api/ecg", middleware.AuthMiddleware(authService))
+	ecg.Get("/:id", ecgController.GetRecording)
+}
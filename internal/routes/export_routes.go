@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/controllers"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/middleware"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupExportRoutes(
+	heartReadings fiber.Router,
+	exportService *services.ExportService,
+	localeService *services.LocaleService,
+) {
+	exportController := controllers.NewExportController(exportService, localeService)
+
+	// Exports of a patient's readings, for clinicians only
+	clinician := middleware.RoleMiddleware("admin", "doctor")
+	heartReadings.Get("/patient/:patientId/export", clinician, exportController.ExportPatientReadings)
+	heartReadings.Post("/patient/:patientId/export-jobs", clinician, exportController.CreateExportJob)
+	heartReadings.Get("/patient/:patientId/export-jobs", clinician, exportController.GetPatientExportJobs)
+	heartReadings.Get("/export-jobs/:id", clinician, exportController.GetExportJob)
+	heartReadings.Get("/export-jobs/:id/download", clinician, exportController.DownloadExportJob)
+}
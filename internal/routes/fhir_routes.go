@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/controllers"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/middleware"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupFHIRRoutes(
+	app *fiber.App,
+	authService *services.AuthService,
+	fhirService *services.FHIRService,
+	localeService *services.LocaleService,
+) {
+	fhirController := controllers.NewFHIRController(fhirService, localeService)
+
+	// La CapabilityStatement es pública, como exige FHIR
+	app.Get("/fhir/metadata", fhirController.Metadata)
+
+	// FHIR R4 facade, for clinicians and integrated hospital systems
+	fhirAPI := app.Group("/fhir", middleware.AuthMiddleware(authService))
+	clinician := middleware.RoleMiddleware("admin", "doctor")
+	fhirAPI.Post("/", clinician, fhirController.Transaction)
+	fhirAPI.Get("/Observation", clinician, fhirController.SearchObservations)
+	fhirAPI.Get("/Observation/:id", clinician, fhirController.GetObservation)
+	fhirAPI.Get("/Patient", clinician, fhirController.SearchPatients)
+	fhirAPI.Get("/Patient/:id", clinician, fhirController.GetPatient)
+	fhirAPI.Get("/Device", clinician, fhirController.SearchDevices)
+	fhirAPI.Get("/Device/:id", clinician, fhirController.GetDevice)
+	fhirAPI.Get("/Practitioner", clinician, fhirController.SearchPractitioners)
+	fhirAPI.Get("/Practitioner/:id", clinician, fhirController.GetPractitioner)
+	fhirAPI.Post("/:type/$validate", clinician, fhirController.Validate)
+}
@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/controllers"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/middleware"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupFirmwareRoutes(app *fiber.App, authService *services.AuthService, firmwareService *services.FirmwareService) {
+	firmwareController := controllers.NewFirmwareController(firmwareService)
+
+	// Group of routes for the firmware catalog and rollouts
+	firmware := app.Group("/api/firmware", middleware.AuthMiddleware(authService))
+
+	firmware.Get("/", firmwareController.GetReleases)
+	firmware.Get("/distribution", firmwareController.GetVersionDistribution)
+	firmware.Get("/rollouts/:rolloutId", firmwareController.GetRollout)
+
+	// Admin-only routes
+	adminOnly := middleware.RoleMiddleware("admin")
+	firmware.Post("/", adminOnly, firmwareController.CreateRelease)
+	firmware.Post("/:id/rollouts", adminOnly, firmwareController.CreateRollout)
+	firmware.Patch("/rollouts/:rolloutId", adminOnly, firmwareController.UpdateRollout)
+
+	// Device-facing routes
+	deviceFirmware := app.Group("/api/devices/:id/firmware", middleware.AuthMiddleware(authService))
+	deviceFirmware.Get("/check", firmwareController.CheckForUpdate)
+	deviceFirmware.Post("/report", firmwareController.ReportUpdate)
+}
@@ -7,8 +7,17 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-func SetupHeartReadingRoutes(app *fiber.App, authService *services.AuthService, heartReadingService *services.HeartReadingService) {
-	heartReadingController := controllers.NewHeartReadingController(heartReadingService)
+// SetupHeartReadingRoutes registers the heart reading routes and returns their
+// authenticated group, where the other features hang their reading routes
+func SetupHeartReadingRoutes(
+	app *fiber.App,
+	authService *services.AuthService,
+	heartReadingService *services.HeartReadingService,
+	readingWorkerService *services.ReadingWorkerService,
+	localeService *services.LocaleService,
+) fiber.Router {
+	heartReadingController := controllers.NewHeartReadingController(heartReadingService, localeService)
+	readingWorkerController := controllers.NewReadingWorkerController(readingWorkerService)
 
 	// Group of routes for heart readings
 	heartReadings := app.Group("/api/heart-readings", middleware.AuthMiddleware(authService))
@@ -19,10 +28,18 @@ func SetupHeartReadingRoutes(app *fiber.App, authService *services.AuthService,
 	heartReadings.Get("/patient/:patientId/stats", heartReadingController.GetHeartRateStats)
 	heartReadings.Get("/patient/:patientId/trends", heartReadingController.GetHeartRateTrends)
 	heartReadings.Get("/patient/:patientId/anomalies", heartReadingController.GetHeartRateAnomalies)
+	heartReadings.Get("/patient/:patientId/hrv", heartReadingController.GetHeartRateVariability)
+	heartReadings.Get("/patient/:patientId/baseline", heartReadingController.GetPatientBaseline)
 	heartReadings.Get("/:id", heartReadingController.GetHeartReadingByID)
+	heartReadings.Get("/:id/findings", heartReadingController.GetHeartReadingFindings)
+	heartReadings.Get("/:id/history", heartReadingController.GetHeartReadingHistory)
 	heartReadings.Put("/patient/:patientId/:id", heartReadingController.UpdateHeartReading)
+	heartReadings.Delete("/patient/:patientId/:id", heartReadingController.DeleteHeartReading)
 
 	// Admin-only routes
 	admin := heartReadings.Group("/admin", middleware.RoleMiddleware("admin"))
-	admin.Post("/process", heartReadingController.ProcessUnprocessedReadings)
-}
\ No newline at end of file
+	admin.Post("/process", readingWorkerController.ProcessUnprocessedReadings)
+	admin.Get("/worker", readingWorkerController.GetStatus)
+
+	return heartReadings
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/controllers"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/middleware"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupImportRoutes(heartReadings fiber.Router, importService *services.ImportService) {
+	importController := controllers.NewImportController(importService)
+
+	// Imports of historical readings, for clinicians only
+	heartReadings.Post("/patient/:patientId/import", middleware.RoleMiddleware("admin", "doctor"), importController.ImportPatientReadings)
+}
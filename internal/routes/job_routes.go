@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/controllers"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/middleware"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupJobRoutes(app *fiber.App, authService *services.AuthService, jobService *services.JobService) {
+	jobController := controllers.NewJobController(jobService)
+
+	// Admin-only routes for the scheduled maintenance jobs
+	jobs := app.Group("/api/admin/jobs", middleware.AuthMiddleware(authService), middleware.RoleMiddleware("admin"))
+
+	jobs.Get("/", jobController.GetJobs)
+	jobs.Get("/:name", jobController.GetJob)
+	jobs.Put("/:name", jobController.UpdateJob)
+	jobs.Post("/:name/run", jobController.TriggerJob)
+	jobs.Get("/:name/runs", jobController.GetJobRuns)
+}
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/controllers"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupLocaleRoutes(patients, users fiber.Router, localeService *services.LocaleService) {
+	localeController := controllers.NewLocaleController(localeService)
+
+	// Time zone and locale of patients and of the authenticated user
+	patients.Get("/:id/locale", localeController.GetPatientLocale)
+	patients.Put("/:id/locale", localeController.UpdatePatientLocale)
+
+	users.Get("/profile/locale", localeController.GetProfileLocale)
+	users.Put("/profile/locale", localeController.UpdateProfileLocale)
+}
@@ -7,7 +7,9 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-func SetupPatientRoutes(app *fiber.App, authService *services.AuthService, patientService *services.PatientService) {
+// SetupPatientRoutes registers the patient routes and returns their
+// authenticated group, where the other features hang their patient routes
+func SetupPatientRoutes(app *fiber.App, authService *services.AuthService, patientService *services.PatientService) fiber.Router {
 	patientController := controllers.NewPatientController(patientService)
 	// Group of routes for patients
 	patients := app.Group("/api/patient", middleware.AuthMiddleware(authService))
@@ -28,4 +30,6 @@ func SetupPatientRoutes(app *fiber.App, authService *services.AuthService, patie
 	doctor := patients.Group("/doctor")
 	doctor.Post("/", patientController.AssignDoctorToPatient)
 	// doctor.Get("/", patientController.GetPatientsByDoctor) // This would need to be implemented
+
+	return patients
 }
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/controllers"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupReportRoutes(patients fiber.Router, reportService *services.ReportService) {
+	reportController := controllers.NewReportController(reportService)
+
+	// Group of routes for a patient's generated reports
+	reports := patients.Group("/:patientId/reports")
+
+	reports.Get("/daily", reportController.GetDailyReports)
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/controllers"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/middleware"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupRuleRoutes(app *fiber.App, authService *services.AuthService, ruleService *services.RuleService) {
+	ruleController := controllers.NewRuleController(ruleService)
+
+	// Group of routes for the anomaly rule sets
+	rules := app.Group("/api/rules", middleware.AuthMiddleware(authService))
+
+	rules.Get("/default", ruleController.GetDefaultRuleSet)
+	rules.Put("/default", middleware.RoleMiddleware("admin"), ruleController.SaveDefaultRuleSet)
+
+	rules.Get("/patient/:patientId", ruleController.GetPatientRuleSet)
+	rules.Put("/patient/:patientId", middleware.RoleMiddleware("admin", "doctor"), ruleController.SavePatientRuleSet)
+	rules.Delete("/patient/:patientId", middleware.RoleMiddleware("admin", "doctor"), ruleController.DeletePatientRuleSet)
+}
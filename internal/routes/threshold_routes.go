@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/controllers"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/middleware"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupThresholdRoutes(patients fiber.Router, thresholdService *services.ThresholdService) {
+	thresholdController := controllers.NewThresholdController(thresholdService)
+
+	// Group of routes for a patient's threshold profiles
+	thresholds := patients.Group("/:patientId/thresholds")
+
+	thresholds.Get("/", thresholdController.GetProfiles)
+	thresholds.Get("/resolve", thresholdController.ResolveThresholds)
+
+	// Only clinicians set thresholds
+	clinicians := middleware.RoleMiddleware("admin", "doctor")
+	thresholds.Post("/", clinicians, thresholdController.CreateProfile)
+	thresholds.Put("/:profileId", clinicians, thresholdController.UpdateProfile)
+	thresholds.Delete("/:profileId", clinicians, thresholdController.DeleteProfile)
+}
@@ -8,7 +8,9 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-func SetupUserRoutes(app *fiber.App, authService *services.AuthService, userService *services.UserService) {
+// SetupUserRoutes registra las rutas de usuarios y devuelve su grupo
+// autenticado, donde otras funcionalidades añaden sus rutas de usuario
+func SetupUserRoutes(app *fiber.App, authService *services.AuthService, userService *services.UserService) fiber.Router {
 	// Crear controlador
 	userController := controllers.NewUserController(userService)
 
@@ -28,4 +30,6 @@ func SetupUserRoutes(app *fiber.App, authService *services.AuthService, userServ
 	// Rutas específicas para médicos
 	doctor := users.Group("/doctor", middleware.RoleMiddleware("doctor"))
 	doctor.Get("/patients", userController.GetDoctorPatients)
+
+	return users
 }
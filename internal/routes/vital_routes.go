@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/controllers"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/middleware"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupVitalRoutes(
+	app *fiber.App,
+	authService *services.AuthService,
+	vitalService *services.VitalService,
+	localeService *services.LocaleService,
+) {
+	vitalController := controllers.NewVitalController(vitalService, localeService)
+
+	// Group of routes for standalone vital sign observations
+	vitals := app.Group("/api/vitals", middleware.AuthMiddleware(authService))
+
+	vitals.Post("/", vitalController.CreateObservation)
+	vitals.Get("/patient/:patientId", vitalController.GetObservations)
+	vitals.Get("/patient/:patientId/timeline", vitalController.GetTimeline)
+	vitals.Get("/patient/:patientId/:type/stats", vitalController.GetVitalStats)
+	vitals.Get("/patient/:patientId/:type/trends", vitalController.GetVitalTrends)
+}
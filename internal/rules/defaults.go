@@ -0,0 +1,42 @@
+package rules
+
+import "time"
+
+// DefaultRuleSet is used for patients without their own rule set when no
+// default has been stored. It has no heart rate limits: out-of-range heart
+// rates are alerted by the patient's threshold profiles, so repeating them
+// here would raise every alert twice.
+func DefaultRuleSet() *RuleSet {
+	rs := &RuleSet{Rules: []Rule{
+		// Cambios bruscos y valores mantenidos en reposo
+		{ID: "rapid-rise-at-rest", Name: "Rapid heart rate rise at rest", Type: TypeRateOfChange, Severity: "medium",
+			When:      []Condition{{MetricActivityLevel, "lt", 2}},
+			Condition: Condition{Metric: MetricBPM, Value: 30}, Direction: "rise", Window: Duration(5 * time.Minute)},
+		{ID: "sustained-tachycardia-at-rest", Name: "Sustained tachycardia at rest", Type: TypeSustained, Severity: "high",
+			When:      []Condition{{MetricActivityLevel, "lt", 2}},
+			Condition: Condition{MetricBPM, "gt", 120}, Duration: Duration(10 * time.Minute)},
+
+		// Saturación de oxígeno
+		{ID: "spo2-low", Name: "Low oxygen saturation", Type: TypeThreshold, Severity: "high",
+			Condition: Condition{MetricOxygenLevel, "lt", 92}},
+		{ID: "spo2-critical", Name: "Critical oxygen saturation", Type: TypeThreshold, Severity: "critical",
+			Condition: Condition{MetricOxygenLevel, "lt", 88}},
+
+		// Presión arterial
+		{ID: "systolic-crisis", Name: "Hypertensive crisis (systolic)", Type: TypeThreshold, Severity: "critical",
+			Condition: Condition{MetricSystolicPressure, "gte", 180}},
+		{ID: "diastolic-crisis", Name: "Hypertensive crisis (diastolic)", Type: TypeThreshold, Severity: "critical",
+			Condition: Condition{MetricDiastolicPressure, "gte", 120}},
+		{ID: "hypotension", Name: "Low blood pressure", Type: TypeThreshold, Severity: "medium",
+			Condition: Condition{MetricSystolicPressure, "lt", 90}},
+
+		{ID: "fever", Name: "Fever", Type: TypeThreshold, Severity: "medium",
+			Condition: Condition{MetricTemperature, "gte", 38}},
+	}}
+
+	// Completa los valores por defecto (max_gap, cooldown, direction)
+	if err := rs.Validate(); err != nil {
+		panic(err)
+	}
+	return rs
+}
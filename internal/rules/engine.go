@@ -0,0 +1,275 @@
+package rules
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/google/uuid"
+)
+
+// Violation is a rule that fired over one or more readings
+type Violation struct {
+	RuleID       string      `json:"rule_id"`
+	RuleName     string      `json:"rule_name,omitempty"`
+	RuleType     string      `json:"rule_type"`
+	Severity     string      `json:"severity"`
+	Metric       string      `json:"metric"`
+	Value        float64     `json:"value"`     // valor observado, extremo de la racha o cambio
+	Threshold    float64     `json:"threshold"` // valor configurado en la regla
+	StartTime    time.Time   `json:"start_time"`
+	EndTime      time.Time   `json:"end_time"`
+	ReadingIDs   []uuid.UUID `json:"reading_ids"`
+	Message      string      `json:"message"`
+	cooldownTime time.Duration
+}
+
+// Cooldown is the minimum time between alerts raised by the same rule
+func (v *Violation) Cooldown() time.Duration {
+	return v.cooldownTime
+}
+
+// Evaluate runs every enabled rule over the readings, which must belong to a
+// single patient and be sorted by time
+func Evaluate(rs *RuleSet, readings []*models.HeartReading) []*Violation {
+	var violations []*Violation
+	for i := range rs.Rules {
+		r := &rs.Rules[i]
+		if r.Disabled {
+			continue
+		}
+		switch r.Type {
+		case TypeThreshold:
+			violations = append(violations, r.evaluateThreshold(readings)...)
+		case TypeRateOfChange:
+			violations = append(violations, r.evaluateRateOfChange(readings)...)
+		case TypeSustained:
+			violations = append(violations, r.evaluateSustained(readings)...)
+		}
+	}
+
+	slices.SortStableFunc(violations, func(a, b *Violation) int {
+		return a.StartTime.Compare(b.StartTime)
+	})
+	return violations
+}
+
+func (r *Rule) evaluateThreshold(readings []*models.HeartReading) []*Violation {
+	var violations []*Violation
+	for _, reading := range readings {
+		value, ok := metricValue(reading, r.Metric)
+		if !ok || !r.applies(reading) || !r.Condition.matches(value) {
+			continue
+		}
+		v := r.newViolation(reading)
+		v.Value = value
+		v.Message = fmt.Sprintf("%s %s %s %g (observed %g)", r.label(), r.Metric, operatorSymbol(r.Operator), r.Value, value)
+		violations = append(violations, v)
+	}
+	return violations
+}
+
+// evaluateRateOfChange compares each reading with the earlier applicable
+// readings within Window. Consecutive readings that exceed the allowed change
+// are merged into a single violation.
+func (r *Rule) evaluateRateOfChange(readings []*models.HeartReading) []*Violation {
+	type point struct {
+		reading *models.HeartReading
+		value   float64
+	}
+	var points []point
+	for _, reading := range readings {
+		if value, ok := metricValue(reading, r.Metric); ok && r.applies(reading) {
+			points = append(points, point{reading, value})
+		}
+	}
+
+	var violations []*Violation
+	var current *Violation
+	window := time.Duration(r.Window)
+	for i, p := range points {
+		largest := 0.0
+		var from *point
+		for j := i - 1; j >= 0 && p.reading.Time.Sub(points[j].reading.Time) <= window; j-- {
+			change := p.value - points[j].value
+			if !r.directionMatches(change) {
+				continue
+			}
+			if math.Abs(change) > math.Abs(largest) {
+				largest = change
+				from = &points[j]
+			}
+		}
+
+		if from == nil || math.Abs(largest) < r.Value {
+			current = nil
+			continue
+		}
+
+		if current == nil {
+			current = r.newViolation(from.reading)
+			violations = append(violations, current)
+		}
+		current.EndTime = p.reading.Time
+		current.ReadingIDs = append(current.ReadingIDs, p.reading.ID)
+		if math.Abs(largest) > math.Abs(current.Value) {
+			current.Value = largest
+		}
+		current.Message = fmt.Sprintf("%s %s changed by %+g within %s (allowed %g)",
+			r.label(), r.Metric, current.Value, window, r.Value)
+	}
+	return violations
+}
+
+// evaluateSustained finds runs of applicable readings that all match the
+// condition and span at least Duration. Readings without the metric are
+// ignored; a reading that does not qualify or a gap longer than MaxGap ends
+// the run.
+func (r *Rule) evaluateSustained(readings []*models.HeartReading) []*Violation {
+	var violations []*Violation
+	var run []*models.HeartReading
+	extreme := 0.0
+
+	flush := func() {
+		if len(run) > 0 && run[len(run)-1].Time.Sub(run[0].Time) >= time.Duration(r.Duration) {
+			v := r.newViolation(run[0])
+			v.EndTime = run[len(run)-1].Time
+			v.ReadingIDs = v.ReadingIDs[:0]
+			for _, reading := range run {
+				v.ReadingIDs = append(v.ReadingIDs, reading.ID)
+			}
+			v.Value = extreme
+			v.Message = fmt.Sprintf("%s %s %s %g for %s (extreme %g)",
+				r.label(), r.Metric, operatorSymbol(r.Operator), r.Value,
+				v.EndTime.Sub(v.StartTime).Round(time.Second), extreme)
+			violations = append(violations, v)
+		}
+		run = run[:0]
+	}
+
+	for _, reading := range readings {
+		value, ok := metricValue(reading, r.Metric)
+		if !ok {
+			continue
+		}
+		if !r.applies(reading) || !r.Condition.matches(value) {
+			flush()
+			continue
+		}
+		if len(run) > 0 && reading.Time.Sub(run[len(run)-1].Time) > time.Duration(r.MaxGap) {
+			flush()
+		}
+		if len(run) == 0 || moreExtreme(r.Operator, value, extreme) {
+			extreme = value
+		}
+		run = append(run, reading)
+	}
+	flush()
+
+	return violations
+}
+
+// applies reports whether the rule's reading types and When conditions hold
+func (r *Rule) applies(reading *models.HeartReading) bool {
+	if len(r.ReadingTypes) > 0 && !slices.Contains(r.ReadingTypes, reading.ReadingType) {
+		return false
+	}
+	for _, c := range r.When {
+		value, ok := metricValue(reading, c.Metric)
+		if !ok || !c.matches(value) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Rule) directionMatches(change float64) bool {
+	switch r.Direction {
+	case "rise":
+		return change > 0
+	case "fall":
+		return change < 0
+	}
+	return change != 0
+}
+
+func (r *Rule) newViolation(reading *models.HeartReading) *Violation {
+	return &Violation{
+		RuleID:       r.ID,
+		RuleName:     r.Name,
+		RuleType:     r.Type,
+		Severity:     r.Severity,
+		Metric:       r.Metric,
+		Threshold:    r.Value,
+		StartTime:    reading.Time,
+		EndTime:      reading.Time,
+		ReadingIDs:   []uuid.UUID{reading.ID},
+		cooldownTime: time.Duration(r.Cooldown),
+	}
+}
+
+func (r *Rule) label() string {
+	if r.Name != "" {
+		return r.Name + ":"
+	}
+	return r.ID + ":"
+}
+
+// metricValue extracts a metric from a reading; ok is false when the reading
+// does not carry it
+func metricValue(reading *models.HeartReading, metric string) (float64, bool) {
+	switch metric {
+	case MetricBPM:
+		return float64(reading.BPM), true
+	case MetricVariability:
+		return derefFloat(reading.Variability)
+	case MetricOxygenLevel:
+		return derefInt(reading.OxygenLevel)
+	case MetricSystolicPressure:
+		return derefInt(reading.SystolicPressure)
+	case MetricDiastolicPressure:
+		return derefInt(reading.DiastolicPressure)
+	case MetricTemperature:
+		return derefFloat(reading.Temperature)
+	case MetricActivityLevel:
+		return derefInt(reading.ActivityLevel)
+	}
+	return 0, false
+}
+
+func moreExtreme(operator string, value, current float64) bool {
+	if operator == "lt" || operator == "lte" {
+		return value < current
+	}
+	return value > current
+}
+
+func operatorSymbol(operator string) string {
+	switch operator {
+	case "lt":
+		return "<"
+	case "lte":
+		return "<="
+	case "gt":
+		return ">"
+	case "gte":
+		return ">="
+	}
+	return operator
+}
+
+func derefInt(v *int) (float64, bool) {
+	if v == nil {
+		return 0, false
+	}
+	return float64(*v), true
+}
+
+func derefFloat(v *float64) (float64, bool) {
+	if v == nil {
+		return 0, false
+	}
+	return *v, true
+}
@@ -0,0 +1,193 @@
+package rules
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/google/uuid"
+)
+
+var testStart = time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+
+// at returns a resting reading with activity level 0 taken the given number
+// of minutes after testStart
+func at(minute int, bpm int) *models.HeartReading {
+	activity := 0
+	return &models.HeartReading{
+		ID:            uuid.New(),
+		ReadingType:   models.ReadingTypeResting,
+		BPM:           bpm,
+		ActivityLevel: &activity,
+		Time:          testStart.Add(time.Duration(minute) * time.Minute),
+	}
+}
+
+// every returns one reading every step minutes from the first minute
+func every(first, step int, bpms ...int) []*models.HeartReading {
+	readings := make([]*models.HeartReading, len(bpms))
+	for i, bpm := range bpms {
+		readings[i] = at(first+i*step, bpm)
+	}
+	return readings
+}
+
+func join(series ...[]*models.HeartReading) []*models.HeartReading {
+	var readings []*models.HeartReading
+	for _, s := range series {
+		readings = append(readings, s...)
+	}
+	return readings
+}
+
+func withOxygen(r *models.HeartReading, oxygen int) *models.HeartReading {
+	r.OxygenLevel = &oxygen
+	return r
+}
+
+func withActivity(r *models.HeartReading, level int) *models.HeartReading {
+	r.ActivityLevel = &level
+	return r
+}
+
+func withType(r *models.HeartReading, readingType string) *models.HeartReading {
+	r.ReadingType = readingType
+	return r
+}
+
+// violation is the part of a Violation the tests check: minutes after
+// testStart, the value and how many readings it covers
+type violation struct {
+	start, end int
+	value      float64
+	readings   int
+}
+
+func TestEvaluate(t *testing.T) {
+	tachycardia := Rule{ID: "tachy", Type: TypeThreshold, Severity: "high", Condition: Condition{MetricBPM, "gte", 100}}
+	spo2 := Rule{ID: "spo2", Type: TypeThreshold, Severity: "high", Condition: Condition{MetricOxygenLevel, "lt", 92}}
+	rise := Rule{ID: "rise", Type: TypeRateOfChange, Severity: "medium",
+		Condition: Condition{Metric: MetricBPM, Value: 30}, Direction: "rise", Window: Duration(5 * time.Minute)}
+	sustained := Rule{ID: "sustained", Type: TypeSustained, Severity: "high",
+		Condition: Condition{MetricBPM, "gt", 120}, Duration: Duration(10 * time.Minute)}
+
+	modify := func(r Rule, fn func(*Rule)) Rule {
+		fn(&r)
+		return r
+	}
+
+	tests := []struct {
+		name     string
+		rule     Rule
+		readings []*models.HeartReading
+		want     []violation
+	}{
+		// Umbral: una violación por lectura
+		{"threshold", tachycardia, every(0, 1, 99, 100, 101, 80),
+			[]violation{{1, 1, 100, 1}, {2, 2, 101, 1}}},
+		{"threshold ignores readings without the metric", spo2,
+			[]*models.HeartReading{withOxygen(at(0, 70), 95), at(1, 70), withOxygen(at(2, 70), 91), withOxygen(at(3, 70), 89)},
+			[]violation{{2, 2, 91, 1}, {3, 3, 89, 1}}},
+		{"threshold reading types", modify(tachycardia, func(r *Rule) { r.ReadingTypes = []string{"sleep"} }),
+			[]*models.HeartReading{at(0, 130), withType(at(1, 130), models.ReadingTypeSleep)},
+			[]violation{{1, 1, 130, 1}}},
+		{"threshold when", modify(tachycardia, func(r *Rule) { r.When = []Condition{{MetricActivityLevel, "lt", 2}} }),
+			[]*models.HeartReading{withActivity(at(0, 130), 3), at(1, 130)},
+			[]violation{{1, 1, 130, 1}}},
+		{"disabled", modify(tachycardia, func(r *Rule) { r.Disabled = true }), every(0, 1, 130), nil},
+
+		// Ritmo de cambio: las lecturas consecutivas que lo superan forman una sola violación
+		{"rate of change rise", rise, every(0, 1, 70, 75, 105, 110, 110, 110, 110, 80),
+			[]violation{{0, 6, 40, 6}}},
+		{"rate of change below limit", rise, every(0, 1, 70, 85, 99), nil},
+		{"rate of change outside window", rise, join(every(0, 1, 70), every(6, 1, 110)), nil},
+		{"rate of change ignores falls", rise, every(0, 1, 110, 70), nil},
+		{"rate of change fall", modify(rise, func(r *Rule) { r.Direction = "fall" }), every(0, 1, 110, 70, 72),
+			[]violation{{0, 2, -40, 3}}},
+		{"rate of change two episodes", rise, join(every(0, 1, 70, 105), every(10, 1, 70, 72, 104)),
+			[]violation{{0, 1, 35, 2}, {10, 12, 34, 2}}},
+		{"rate of change when", modify(rise, func(r *Rule) { r.When = []Condition{{MetricActivityLevel, "lt", 2}} }),
+			[]*models.HeartReading{at(0, 70), withActivity(at(1, 110), 4)}, nil},
+
+		// Mantenida: rachas de al menos Duration sin huecos mayores que MaxGap
+		{"sustained", sustained, every(0, 2, 125, 130, 140, 128, 126, 122),
+			[]violation{{0, 10, 140, 6}}},
+		{"sustained too short", sustained, every(0, 2, 125, 130, 140, 128, 126), nil},
+		{"sustained broken by a normal reading", sustained, join(every(0, 2, 125, 125, 125), every(6, 2, 100, 125, 125, 125, 125, 125, 125)),
+			[]violation{{8, 18, 125, 6}}},
+		{"sustained broken by a gap", sustained, join(every(0, 2, 125, 125, 125), every(10, 2, 130, 130, 130, 130, 130, 130)),
+			[]violation{{10, 20, 130, 6}}},
+		{"sustained lower bound extreme", Rule{ID: "low-spo2", Type: TypeSustained, Severity: "high",
+			Condition: Condition{MetricOxygenLevel, "lt", 92}, Duration: Duration(4 * time.Minute)},
+			[]*models.HeartReading{withOxygen(at(0, 70), 91), at(1, 70), withOxygen(at(2, 70), 87), withOxygen(at(4, 70), 90)},
+			[]violation{{0, 4, 87, 3}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := &RuleSet{Rules: []Rule{tt.rule}}
+			if err := rs.Validate(); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+
+			got := Evaluate(rs, tt.readings)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d violations, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i, v := range got {
+				want := tt.want[i]
+				start, end := testStart.Add(time.Duration(want.start)*time.Minute), testStart.Add(time.Duration(want.end)*time.Minute)
+				if !v.StartTime.Equal(start) || !v.EndTime.Equal(end) || v.Value != want.value || len(v.ReadingIDs) != want.readings {
+					t.Errorf("violation %d = {%s - %s, value %g, %d readings}, want {%s - %s, value %g, %d readings}",
+						i, v.StartTime.Format(time.TimeOnly), v.EndTime.Format(time.TimeOnly), v.Value, len(v.ReadingIDs),
+						start.Format(time.TimeOnly), end.Format(time.TimeOnly), want.value, want.readings)
+				}
+				if v.RuleID != tt.rule.ID || v.Cooldown() != defaultCooldown {
+					t.Errorf("violation %d rule = %s (cooldown %s), want %s (cooldown %s)", i, v.RuleID, v.Cooldown(), tt.rule.ID, defaultCooldown)
+				}
+			}
+		})
+	}
+}
+
+func TestEvaluateSortsViolations(t *testing.T) {
+	rs := &RuleSet{Rules: []Rule{
+		{ID: "late", Type: TypeThreshold, Severity: "low", Condition: Condition{MetricBPM, "gt", 150}},
+		{ID: "early", Type: TypeThreshold, Severity: "low", Condition: Condition{MetricBPM, "lt", 50}},
+	}}
+	if err := rs.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	got := Evaluate(rs, every(0, 1, 45, 160))
+	if len(got) != 2 || got[0].RuleID != "early" || got[1].RuleID != "late" {
+		t.Errorf("Evaluate() = %+v, want early then late", got)
+	}
+}
+
+func TestDefaultRuleSet(t *testing.T) {
+	rs := DefaultRuleSet()
+	if err := rs.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	// Los límites de frecuencia cardiaca son de los perfiles de umbrales
+	for _, r := range rs.Rules {
+		if r.Type == TypeThreshold && r.Metric == MetricBPM {
+			t.Errorf("default rule %s is a heart rate threshold", r.ID)
+		}
+	}
+
+	// Ni una bradicardia ni una taquicardia aisladas en reposo
+	for _, bpm := range []int{35, 180} {
+		if got := Evaluate(rs, every(0, 1, bpm)); len(got) != 0 {
+			t.Errorf("Evaluate(%d bpm) = %+v, want no violations", bpm, got)
+		}
+	}
+
+	// La taquicardia mantenida en reposo sigue siendo una regla por defecto
+	got := Evaluate(rs, every(0, 1, 125, 125, 125, 125, 125, 125, 125, 125, 125, 125, 125))
+	if len(got) != 1 || got[0].RuleID != "sustained-tachycardia-at-rest" {
+		t.Errorf("Evaluate() = %+v, want sustained-tachycardia-at-rest", got)
+	}
+}
@@ -0,0 +1,298 @@
+// Package rules implements the declarative rule sets used to flag anomalous
+// heart readings. Rule sets are plain JSON or YAML documents, so clinicians
+// can tune them per patient without a database migration.
+//
+// Example (YAML):
+//
+//	rules:
+//	  - id: resting-tachycardia
+//	    name: HR > 120 for 10 min at rest
+//	    type: sustained
+//	    severity: high
+//	    metric: bpm
+//	    operator: gt
+//	    value: 120
+//	    duration: 10m
+//	    when:
+//	      - {metric: activity_level, operator: lt, value: 2}
+package rules
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Tipos de regla
+const (
+	TypeThreshold    = "threshold"      // cada lectura que cumple la condición
+	TypeRateOfChange = "rate_of_change" // cambio del valor dentro de una ventana
+	TypeSustained    = "sustained"      // condición mantenida durante un tiempo mínimo
+)
+
+// Métricas de una lectura que pueden evaluarse
+const (
+	MetricBPM               = "bpm"
+	MetricVariability       = "variability"
+	MetricOxygenLevel       = "oxygen_level"
+	MetricSystolicPressure  = "systolic_pressure"
+	MetricDiastolicPressure = "diastolic_pressure"
+	MetricTemperature       = "temperature"
+	MetricActivityLevel     = "activity_level"
+)
+
+const (
+	defaultMaxGap   = 5 * time.Minute
+	defaultCooldown = time.Hour
+	maxRules        = 100
+)
+
+var (
+	validMetrics = []string{
+		MetricBPM, MetricVariability, MetricOxygenLevel, MetricSystolicPressure,
+		MetricDiastolicPressure, MetricTemperature, MetricActivityLevel,
+	}
+	validOperators    = []string{"lt", "lte", "gt", "gte"}
+	validSeverities   = []string{"low", "medium", "high", "critical"}
+	validReadingTypes = []string{"resting", "active", "sleep"}
+	validDirections   = []string{"rise", "fall", "any"}
+
+	ruleIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,39}$`)
+)
+
+var ErrUnknownFormat = errors.New("unsupported rule set format")
+
+// Duration is a time.Duration written as a Go duration string ("10m", "90s")
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"10m\"")
+	}
+	return d.parse(s)
+}
+
+func (d Duration) MarshalYAML() (any, error) {
+	return time.Duration(d).String(), nil
+}
+
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	var s string
+	if err := node.Decode(&s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"10m\"")
+	}
+	return d.parse(s)
+}
+
+func (d *Duration) parse(s string) error {
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", s)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Condition compares one metric of a reading against a value
+type Condition struct {
+	Metric   string  `json:"metric" yaml:"metric"`
+	Operator string  `json:"operator" yaml:"operator"` // lt, lte, gt, gte
+	Value    float64 `json:"value" yaml:"value"`
+}
+
+// Rule is a single declarative anomaly rule.
+//
+// For threshold and sustained rules Condition is what flags a reading; for
+// rate_of_change rules Metric is watched and Value is the largest change
+// allowed within Window. ReadingTypes and When restrict which readings the
+// rule applies to.
+type Rule struct {
+	ID           string      `json:"id" yaml:"id"`
+	Name         string      `json:"name,omitempty" yaml:"name,omitempty"`
+	Type         string      `json:"type" yaml:"type"`
+	Severity     string      `json:"severity" yaml:"severity"`
+	Disabled     bool        `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+	ReadingTypes []string    `json:"reading_types,omitempty" yaml:"reading_types,omitempty"`
+	When         []Condition `json:"when,omitempty" yaml:"when,omitempty"`
+	Condition    `yaml:",inline"`
+	Direction    string   `json:"direction,omitempty" yaml:"direction,omitempty"` // rate_of_change: rise, fall, any
+	Window       Duration `json:"window,omitempty" yaml:"window,omitempty"`       // rate_of_change
+	Duration     Duration `json:"duration,omitempty" yaml:"duration,omitempty"`   // sustained
+	MaxGap       Duration `json:"max_gap,omitempty" yaml:"max_gap,omitempty"`     // sustained: hueco máximo entre lecturas
+	Cooldown     Duration `json:"cooldown,omitempty" yaml:"cooldown,omitempty"`   // tiempo mínimo entre alertas
+}
+
+// RuleSet is the set of rules evaluated for a patient
+type RuleSet struct {
+	Rules []Rule `json:"rules" yaml:"rules"`
+}
+
+// Parse decodes a rule set in JSON or YAML ("json", "yaml") and validates it
+func Parse(data []byte, format string) (*RuleSet, error) {
+	var rs RuleSet
+	switch format {
+	case "json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&rs); err != nil {
+			return nil, &ParseError{Problems: []string{err.Error()}}
+		}
+	case "yaml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&rs); err != nil {
+			return nil, &ParseError{Problems: []string{err.Error()}}
+		}
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownFormat, format)
+	}
+
+	if err := rs.Validate(); err != nil {
+		return nil, err
+	}
+	return &rs, nil
+}
+
+// ParseError lists every problem found in a rule set
+type ParseError struct {
+	Problems []string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("invalid rule set: %v", e.Problems)
+}
+
+// Validate checks every rule and fills in defaults
+func (rs *RuleSet) Validate() error {
+	var problems []string
+	if len(rs.Rules) > maxRules {
+		problems = append(problems, fmt.Sprintf("at most %d rules are allowed", maxRules))
+	}
+
+	seen := make(map[string]bool)
+	for i := range rs.Rules {
+		r := &rs.Rules[i]
+		prefix := fmt.Sprintf("rules[%d]", i)
+		if r.ID != "" {
+			prefix = fmt.Sprintf("rules[%s]", r.ID)
+		}
+		fail := func(format string, args ...any) {
+			problems = append(problems, prefix+": "+fmt.Sprintf(format, args...))
+		}
+
+		if !ruleIDPattern.MatchString(r.ID) {
+			fail("id must be 1-40 lowercase letters, digits, '-' or '_'")
+		} else if seen[r.ID] {
+			fail("duplicate id")
+		}
+		seen[r.ID] = true
+
+		if !slices.Contains(validSeverities, r.Severity) {
+			fail("severity must be one of %v", validSeverities)
+		}
+		for _, rt := range r.ReadingTypes {
+			if !slices.Contains(validReadingTypes, rt) {
+				fail("unknown reading type %q", rt)
+			}
+		}
+		for _, c := range r.When {
+			if err := c.validate(); err != nil {
+				fail("when: %v", err)
+			}
+		}
+
+		switch r.Type {
+		case TypeThreshold, TypeSustained:
+			if err := r.Condition.validate(); err != nil {
+				fail("%v", err)
+			}
+			if r.Type == TypeSustained {
+				if r.Duration <= 0 {
+					fail("sustained rules need a positive duration")
+				}
+				if r.MaxGap == 0 {
+					r.MaxGap = Duration(defaultMaxGap)
+				}
+			}
+		case TypeRateOfChange:
+			if !slices.Contains(validMetrics, r.Metric) {
+				fail("unknown metric %q", r.Metric)
+			}
+			if r.Value <= 0 {
+				fail("rate_of_change value must be the positive change allowed within the window")
+			}
+			if r.Window <= 0 {
+				fail("rate_of_change rules need a positive window")
+			}
+			if r.Direction == "" {
+				r.Direction = "any"
+			} else if !slices.Contains(validDirections, r.Direction) {
+				fail("direction must be one of %v", validDirections)
+			}
+		default:
+			fail("type must be one of %s, %s, %s", TypeThreshold, TypeRateOfChange, TypeSustained)
+		}
+
+		if r.MaxGap < 0 || r.Cooldown < 0 {
+			fail("durations must not be negative")
+		}
+		if r.Cooldown == 0 {
+			r.Cooldown = Duration(defaultCooldown)
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ParseError{Problems: problems}
+	}
+	return nil
+}
+
+// Lookback is how far back readings are needed to evaluate the rule set for
+// a new reading
+func (rs *RuleSet) Lookback() time.Duration {
+	var lookback time.Duration
+	for _, r := range rs.Rules {
+		switch r.Type {
+		case TypeRateOfChange:
+			lookback = max(lookback, time.Duration(r.Window))
+		case TypeSustained:
+			lookback = max(lookback, time.Duration(r.Duration)+time.Duration(r.MaxGap))
+		}
+	}
+	return lookback
+}
+
+func (c Condition) validate() error {
+	if !slices.Contains(validMetrics, c.Metric) {
+		return fmt.Errorf("unknown metric %q", c.Metric)
+	}
+	if !slices.Contains(validOperators, c.Operator) {
+		return fmt.Errorf("operator must be one of %v", validOperators)
+	}
+	return nil
+}
+
+func (c Condition) matches(value float64) bool {
+	switch c.Operator {
+	case "lt":
+		return value < c.Value
+	case "lte":
+		return value <= c.Value
+	case "gt":
+		return value > c.Value
+	case "gte":
+		return value >= c.Value
+	}
+	return false
+}
@@ -0,0 +1,222 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule computes the next activation time of a job
+type Schedule interface {
+	// Next returns the first activation strictly after t
+	Next(t time.Time) time.Time
+}
+
+// Parse parses a schedule specification:
+//
+//   - a five-field cron expression "minute hour day-of-month month day-of-week"
+//     supporting *, lists (1,15), ranges (1-5) and steps (*/10, 0-30/5).
+//     Day-of-week is 0-6 with Sunday as 0 (7 is accepted as Sunday too).
+//   - one of @hourly, @daily (@midnight), @weekly, @monthly, @yearly (@annually)
+//   - "@every <duration>", e.g. "@every 5m"
+//
+// Cron expressions are evaluated in loc.
+func Parse(spec string, loc *time.Location) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+		if d < time.Second {
+			return nil, fmt.Errorf("invalid schedule %q: interval must be at least 1s", spec)
+		}
+		return everySchedule(d), nil
+	}
+
+	switch spec {
+	case "@yearly", "@annually":
+		spec = "0 0 1 1 *"
+	case "@monthly":
+		spec = "0 0 1 * *"
+	case "@weekly":
+		spec = "0 0 * * 0"
+	case "@daily", "@midnight":
+		spec = "0 0 * * *"
+	case "@hourly":
+		spec = "0 * * * *"
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: expected 5 fields, got %d", spec, len(fields))
+	}
+
+	s := &cronSchedule{location: loc}
+	var err error
+	if s.minute, err = parseField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: minute: %w", spec, err)
+	}
+	if s.hour, err = parseField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: hour: %w", spec, err)
+	}
+	if s.dom, err = parseField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: day of month: %w", spec, err)
+	}
+	if s.month, err = parseField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: month: %w", spec, err)
+	}
+	if s.dow, err = parseField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: day of week: %w", spec, err)
+	}
+	// El 7 también es domingo
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domAny = fields[2] == "*"
+	s.dowAny = fields[4] == "*"
+	return s, nil
+}
+
+type everySchedule time.Duration
+
+func (e everySchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// cronSchedule stores each field as a bit set of the allowed values
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+	location                      *time.Location
+}
+
+// Next works on the wall clock of the schedule's location. Times skipped by a
+// DST change never match, and times repeated by one match only once.
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	t = t.In(s.location).Truncate(time.Minute).Add(time.Minute)
+
+	// Si en cinco años no hay coincidencia, la expresión no se cumple nunca (p. ej. 31 de febrero)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = wallTime(t.Year(), t.Month()+1, 1, 0, 0, s.location)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = wallTime(t.Year(), t.Month(), t.Day()+1, 0, 0, s.location)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = wallTime(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, s.location)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = wallTime(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, s.location)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// wallTime returns the instant at which the clock in loc shows the given time
+// (values out of range are normalized as in time.Date). When a DST change skips
+// that time it returns the instant of the change, the first one after the gap.
+func wallTime(year int, month time.Month, day, hour, min int, loc *time.Location) time.Time {
+	t := time.Date(year, month, day, hour, min, 0, 0, loc)
+
+	want := time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+	got := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+	if got.Equal(want) {
+		return t
+	}
+
+	// Hora inexistente: time.Date la interpreta con el desfase de antes o de
+	// después del cambio; con el de antes cae justo en el instante del salto
+	_, offset := t.Zone()
+	if change := want.Add(-time.Duration(offset) * time.Second); change.After(t) {
+		return change.In(loc)
+	}
+	return t
+}
+
+// dayMatches follows the usual cron rule: when both day of month and day of
+// week are restricted, either of them may match
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dowMatch
+	case s.dowAny:
+		return domMatch
+	}
+	return domMatch || dowMatch
+}
+
+func parseField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+			step = n
+		}
+
+		lo, hi := min, max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			from, to, _ := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = parseValue(from, min, max); err != nil {
+				return 0, err
+			}
+			if hi, err = parseValue(to, min, max); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range %q", rangePart)
+			}
+		default:
+			n, err := parseValue(rangePart, min, max)
+			if err != nil {
+				return 0, err
+			}
+			lo = n
+			// "5/15" significa desde 5 hasta el máximo en pasos de 15
+			if !hasStep {
+				hi = n
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func parseValue(s string, min, max int) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	if n < min || n > max {
+		return 0, fmt.Errorf("value %d out of range %d-%d", n, min, max)
+	}
+	return n, nil
+}
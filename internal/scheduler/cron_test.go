@@ -0,0 +1,151 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronScheduleNext(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		zone string
+		from string // RFC 3339
+		want string // RFC 3339; vacío si la expresión no se cumple nunca
+	}{
+		// Desfases que no son horas enteras
+		{"half-hour offset same day", "0 11 * * *", "Asia/Kolkata", "2026-10-19T00:00:00+05:30", "2026-10-19T11:00:00+05:30"},
+		{"half-hour offset next day", "0 11 * * *", "Asia/Kolkata", "2026-10-19T11:00:00+05:30", "2026-10-20T11:00:00+05:30"},
+		{"half-hour offset from UTC", "0 11 * * *", "Asia/Kolkata", "2026-10-19T06:00:00Z", "2026-10-20T11:00:00+05:30"},
+		{"quarter-hour offset", "30 6 * * *", "Asia/Kathmandu", "2026-10-19T07:00:00+05:45", "2026-10-20T06:30:00+05:45"},
+		{"half-hour offset minute step", "*/15 * * * *", "Australia/Adelaide", "2026-07-01T10:50:00+09:30", "2026-07-01T11:00:00+09:30"},
+
+		// Cambio de horario de primavera: la hora saltada no se ejecuta
+		{"spring forward skipped time", "30 2 * * *", "America/New_York", "2026-03-07T03:00:00-05:00", "2026-03-09T02:30:00-04:00"},
+		{"spring forward hour after gap", "0 3 * * *", "America/New_York", "2026-03-08T00:00:00-05:00", "2026-03-08T03:00:00-04:00"},
+		{"spring forward minute step", "*/30 * * * *", "America/New_York", "2026-03-08T01:45:00-05:00", "2026-03-08T03:00:00-04:00"},
+		{"spring forward skipped midnight", "0 0 * * *", "America/Santiago", "2026-09-05T12:00:00-04:00", "2026-09-07T00:00:00-03:00"},
+		{"spring forward day starts at change", "0 1 * * *", "America/Santiago", "2026-09-05T12:00:00-04:00", "2026-09-06T01:00:00-03:00"},
+		{"spring forward skipped midnight time", "15 0 * * *", "America/Santiago", "2026-09-05T12:00:00-04:00", "2026-09-07T00:15:00-03:00"},
+		{"spring forward half-hour gap", "15 2 * * *", "Australia/Lord_Howe", "2026-10-03T12:00:00+10:30", "2026-10-05T02:15:00+11:00"},
+		{"spring forward after half-hour gap", "45 2 * * *", "Australia/Lord_Howe", "2026-10-03T12:00:00+10:30", "2026-10-04T02:45:00+11:00"},
+
+		// Cambio de horario de otoño: la hora repetida se ejecuta una vez
+		{"fall back first occurrence", "30 1 * * *", "America/New_York", "2026-11-01T00:00:00-04:00", "2026-11-01T01:30:00-04:00"},
+		{"fall back runs once", "30 1 * * *", "America/New_York", "2026-11-01T01:30:00-04:00", "2026-11-02T01:30:00-05:00"},
+		{"fall back hourly", "0 * * * *", "America/New_York", "2026-11-01T01:00:00-04:00", "2026-11-01T02:00:00-05:00"},
+		{"fall back at midnight", "0 0 * * *", "America/Santiago", "2026-04-04T12:00:00-03:00", "2026-04-05T00:00:00-04:00"},
+
+		// Día del mes y día de la semana
+		{"day of month", "0 9 1 * *", "UTC", "2026-10-19T00:00:00Z", "2026-11-01T09:00:00Z"},
+		{"day of week", "0 9 * * 1-5", "UTC", "2026-10-24T10:00:00Z", "2026-10-26T09:00:00Z"},
+		{"day of month or day of week", "0 9 13 * 5", "UTC", "2026-10-19T00:00:00Z", "2026-10-23T09:00:00Z"},
+		{"day of month before day of week", "0 9 13 * 5", "UTC", "2026-11-07T00:00:00Z", "2026-11-13T09:00:00Z"},
+		{"sunday as 7", "0 0 * * 7", "UTC", "2026-10-19T00:00:00Z", "2026-10-25T00:00:00Z"},
+		{"day 31 skips short months", "0 0 31 * *", "UTC", "2026-10-31T00:00:00Z", "2026-12-31T00:00:00Z"},
+		{"leap day", "0 0 29 2 *", "UTC", "2026-10-19T00:00:00Z", "2028-02-29T00:00:00Z"},
+		{"never", "0 0 31 2 *", "UTC", "2026-10-19T00:00:00Z", ""},
+
+		// Pasos
+		{"minute step", "*/20 * * * *", "UTC", "2026-10-19T10:05:00Z", "2026-10-19T10:20:00Z"},
+		{"range step", "5-50/15 * * * *", "UTC", "2026-10-19T10:36:00Z", "2026-10-19T10:50:00Z"},
+		{"start step", "10/20 * * * *", "UTC", "2026-10-19T10:51:00Z", "2026-10-19T11:10:00Z"},
+		{"hour step", "0 */6 * * *", "UTC", "2026-10-19T07:00:00Z", "2026-10-19T12:00:00Z"},
+		{"month step", "0 0 1 */3 *", "UTC", "2026-02-10T00:00:00Z", "2026-04-01T00:00:00Z"},
+		{"list", "0 8,20 * * *", "UTC", "2026-10-19T08:00:00Z", "2026-10-19T20:00:00Z"},
+
+		// Atajos
+		{"hourly", "@hourly", "UTC", "2026-10-19T10:00:00Z", "2026-10-19T11:00:00Z"},
+		{"daily", "@daily", "Asia/Kolkata", "2026-10-19T10:00:00+05:30", "2026-10-20T00:00:00+05:30"},
+		{"weekly", "@weekly", "UTC", "2026-10-19T10:00:00Z", "2026-10-25T00:00:00Z"},
+		{"every", "@every 90s", "UTC", "2026-10-19T10:00:30Z", "2026-10-19T10:02:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, err := time.LoadLocation(tt.zone)
+			if err != nil {
+				t.Skipf("time zone %s not available: %v", tt.zone, err)
+			}
+			schedule, err := Parse(tt.spec, loc)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.spec, err)
+			}
+
+			from := mustParseTime(t, tt.from)
+			got := schedule.Next(from)
+
+			if tt.want == "" {
+				if !got.IsZero() {
+					t.Errorf("Next(%s) = %s, want the zero time", tt.from, got)
+				}
+				return
+			}
+			if want := mustParseTime(t, tt.want); !got.Equal(want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got, want.In(loc))
+			}
+		})
+	}
+}
+
+func TestCronScheduleNextAdvancesThroughTransitions(t *testing.T) {
+	// Cada minuto de un año en zonas con cambios de horario a distintas horas:
+	// Next siempre avanza, también dentro de los saltos y repeticiones
+	zones := []string{"America/New_York", "America/Santiago", "Australia/Lord_Howe", "Europe/London", "Asia/Kolkata"}
+
+	for _, zone := range zones {
+		t.Run(zone, func(t *testing.T) {
+			loc, err := time.LoadLocation(zone)
+			if err != nil {
+				t.Skipf("time zone %s not available: %v", zone, err)
+			}
+			schedule, err := Parse("* * * * *", loc)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			from := time.Date(2026, 1, 1, 0, 0, 0, 0, loc)
+			end := from.AddDate(1, 0, 0)
+			for ts := from; ts.Before(end); {
+				next := schedule.Next(ts)
+				// La hora repetida en otoño se salta entera
+				if !next.After(ts) || next.Sub(ts) > time.Hour+time.Minute {
+					t.Fatalf("Next(%s) = %s, want the following minute", ts, next)
+				}
+				ts = next
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	specs := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"a * * * *",
+		"@every 500ms",
+		"@every soon",
+	}
+
+	for _, spec := range specs {
+		if _, err := Parse(spec, time.UTC); err == nil {
+			t.Errorf("Parse(%q) error = nil, want an error", spec)
+		}
+	}
+}
+
+func mustParseTime(t *testing.T, s string) time.Time {
+	t.Helper()
+	ts, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t.Fatalf("invalid time %q: %v", s, err)
+	}
+	return ts
+}
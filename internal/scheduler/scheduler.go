@@ -0,0 +1,283 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Estados de una ejecución y origen del disparo
+const (
+	RunStatusRunning   = "running"
+	RunStatusSucceeded = "succeeded"
+	RunStatusFailed    = "failed"
+	RunStatusCancelled = "cancelled"
+
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobRunning  = errors.New("job is already running")
+	ErrNotRunning  = errors.New("scheduler is not running")
+)
+
+// JobFunc does the work of a job. The returned summary is stored with the
+// run. ctx is cancelled when the job times out or the server shuts down.
+type JobFunc func(ctx context.Context) (string, error)
+
+// Job is a recurring task
+type Job struct {
+	Name        string
+	Description string
+	Schedule    string        // expresión cron, @daily, @every 5m...
+	Timeout     time.Duration // tiempo máximo de una ejecución
+	Run         JobFunc
+}
+
+// Store persists jobs and their runs. Claiming a job takes a lease on its row
+// so that a job runs on a single replica at a time.
+type Store interface {
+	// RegisterJob creates or updates the job's row. nextRun is stored when the
+	// job is new or its schedule changed.
+	RegisterJob(ctx context.Context, name, description, schedule string, nextRun time.Time) error
+	// ClaimDueJob takes the lease if the job is enabled, due and not leased by
+	// another replica, and moves its next run to nextRun
+	ClaimDueJob(ctx context.Context, name, owner string, leaseFor time.Duration, nextRun time.Time) (bool, error)
+	// ClaimJob takes the lease if the job is not leased, regardless of its schedule
+	ClaimJob(ctx context.Context, name, owner string, leaseFor time.Duration) (bool, error)
+	// StartRun records a new run and returns its ID
+	StartRun(ctx context.Context, name, trigger, owner string) (int64, error)
+	// FinishRun records the outcome of a run and releases the job's lease
+	FinishRun(ctx context.Context, runID int64, name, status, summary, errMessage string) error
+}
+
+// Config controls the scheduler
+type Config struct {
+	PollInterval time.Duration
+	Location     *time.Location // zona horaria de las expresiones cron
+}
+
+// Scheduler runs registered jobs on their schedules. Every replica runs a
+// scheduler; the lease taken through the Store keeps each run on one of them.
+type Scheduler struct {
+	store    Store
+	config   Config
+	owner    string
+	jobs     []*registeredJob
+	byName   map[string]*registeredJob
+	mu       sync.Mutex
+	ctx      context.Context // contexto de Run; nil mientras no está en marcha
+	running  sync.WaitGroup
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+type registeredJob struct {
+	Job
+	schedule Schedule
+}
+
+func New(store Store, config Config) *Scheduler {
+	if config.PollInterval <= 0 {
+		config.PollInterval = 15 * time.Second
+	}
+	if config.Location == nil {
+		config.Location = time.UTC
+	}
+	hostname, _ := os.Hostname()
+	return &Scheduler{
+		store:  store,
+		config: config,
+		owner:  fmt.Sprintf("%s:%d", hostname, os.Getpid()),
+		byName: map[string]*registeredJob{},
+		done:   make(chan struct{}),
+	}
+}
+
+// Register adds a job. It must be called before Run.
+func (s *Scheduler) Register(job Job) error {
+	if job.Name == "" || job.Run == nil {
+		return errors.New("job needs a name and a function")
+	}
+	if _, exists := s.byName[job.Name]; exists {
+		return fmt.Errorf("job %s is already registered", job.Name)
+	}
+	schedule, err := Parse(job.Schedule, s.config.Location)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", job.Name, err)
+	}
+	if schedule.Next(time.Now()).IsZero() {
+		return fmt.Errorf("job %s: schedule %q never fires", job.Name, job.Schedule)
+	}
+	if job.Timeout <= 0 {
+		job.Timeout = time.Hour
+	}
+
+	registered := &registeredJob{Job: job, schedule: schedule}
+	s.jobs = append(s.jobs, registered)
+	s.byName[job.Name] = registered
+	return nil
+}
+
+// Jobs returns the registered jobs
+func (s *Scheduler) Jobs() []Job {
+	jobs := make([]Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job.Job)
+	}
+	return jobs
+}
+
+// Run registers the jobs in the store and starts due jobs every PollInterval
+// until ctx is cancelled. Running jobs see their context cancelled and Run
+// waits for them before returning.
+func (s *Scheduler) Run(ctx context.Context) {
+	defer s.doneOnce.Do(func() { close(s.done) })
+
+	now := time.Now()
+	for _, job := range s.jobs {
+		if err := s.store.RegisterJob(ctx, job.Name, job.Description, job.Schedule, job.schedule.Next(now)); err != nil {
+			log.Printf("Scheduler: registering %s: %v", job.Name, err)
+		}
+	}
+
+	s.mu.Lock()
+	s.ctx = ctx
+	s.mu.Unlock()
+
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.startDueJobs(ctx)
+
+		select {
+		case <-ctx.Done():
+			s.mu.Lock()
+			s.ctx = nil
+			s.mu.Unlock()
+			s.running.Wait()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Done is closed once Run has returned
+func (s *Scheduler) Done() <-chan struct{} {
+	return s.done
+}
+
+// Trigger starts a job now, outside its schedule, and returns the run ID
+func (s *Scheduler) Trigger(ctx context.Context, name string) (int64, error) {
+	job, ok := s.byName[name]
+	if !ok {
+		return 0, ErrJobNotFound
+	}
+
+	// Se reserva el hueco en running antes de que Run pueda empezar a esperar
+	s.mu.Lock()
+	runCtx := s.ctx
+	if runCtx != nil {
+		s.running.Add(1)
+	}
+	s.mu.Unlock()
+	if runCtx == nil {
+		return 0, ErrNotRunning
+	}
+
+	claimed, err := s.store.ClaimJob(ctx, job.Name, s.owner, s.leaseFor(job))
+	if err != nil {
+		s.running.Done()
+		return 0, err
+	}
+	if !claimed {
+		s.running.Done()
+		return 0, ErrJobRunning
+	}
+	return s.start(runCtx, job, TriggerManual)
+}
+
+func (s *Scheduler) startDueJobs(ctx context.Context) {
+	for _, job := range s.jobs {
+		if ctx.Err() != nil {
+			return
+		}
+		claimed, err := s.store.ClaimDueJob(ctx, job.Name, s.owner, s.leaseFor(job), job.schedule.Next(time.Now()))
+		if err != nil {
+			log.Printf("Scheduler: claiming %s: %v", job.Name, err)
+			continue
+		}
+		if claimed {
+			s.running.Add(1)
+			if _, err := s.start(ctx, job, TriggerSchedule); err != nil {
+				log.Printf("Scheduler: starting %s: %v", job.Name, err)
+			}
+		}
+	}
+}
+
+// start records the run and executes the job in the background. The job's
+// lease must already be held and the run counted in s.running.
+func (s *Scheduler) start(ctx context.Context, job *registeredJob, trigger string) (int64, error) {
+	runID, err := s.store.StartRun(ctx, job.Name, trigger, s.owner)
+	if err != nil {
+		// Libera el lease para no bloquear el trabajo hasta que caduque
+		s.finish(0, job.Name, RunStatusFailed, "", err.Error())
+		s.running.Done()
+		return 0, err
+	}
+
+	go func() {
+		defer s.running.Done()
+
+		jobCtx, cancel := context.WithTimeout(ctx, job.Timeout)
+		defer cancel()
+
+		summary, status, errMessage := s.execute(jobCtx, job)
+		if ctx.Err() != nil && status == RunStatusFailed {
+			status = RunStatusCancelled
+		}
+		if status != RunStatusSucceeded {
+			log.Printf("Scheduler: %s %s: %s", job.Name, status, errMessage)
+		}
+		s.finish(runID, job.Name, status, summary, errMessage)
+	}()
+	return runID, nil
+}
+
+// execute runs the job, turning a panic into a failed run
+func (s *Scheduler) execute(ctx context.Context, job *registeredJob) (summary, status, errMessage string) {
+	defer func() {
+		if r := recover(); r != nil {
+			summary, status, errMessage = "", RunStatusFailed, fmt.Sprintf("panic: %v", r)
+		}
+	}()
+
+	summary, err := job.Run(ctx)
+	if err != nil {
+		return summary, RunStatusFailed, err.Error()
+	}
+	return summary, RunStatusSucceeded, ""
+}
+
+func (s *Scheduler) finish(runID int64, name, status, summary, errMessage string) {
+	// El contexto de Run puede estar cancelado: el resultado se guarda igualmente
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := s.store.FinishRun(ctx, runID, name, status, summary, errMessage); err != nil {
+		log.Printf("Scheduler: recording run of %s: %v", name, err)
+	}
+}
+
+// leaseFor outlasts the job's timeout so that the lease only expires when the
+// replica running the job died
+func (s *Scheduler) leaseFor(job *registeredJob) time.Duration {
+	return job.Timeout + time.Minute
+}
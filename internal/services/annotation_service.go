@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/repositories"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+	"github.com/google/uuid"
+)
+
+var ErrAnnotationNotFound = errors.New("annotation not found")
+
+// AnnotationService manages clinicians' annotations on readings and time ranges
+type AnnotationService struct {
+	annotationRepo   *repositories.AnnotationRepository
+	heartReadingRepo *repositories.HeartReadingRepository
+}
+
+func NewAnnotationService(
+	annotationRepo *repositories.AnnotationRepository,
+	heartReadingRepo *repositories.HeartReadingRepository,
+) *AnnotationService {
+	return &AnnotationService{
+		annotationRepo:   annotationRepo,
+		heartReadingRepo: heartReadingRepo,
+	}
+}
+
+// CreateAnnotation annotates a reading or a time range of a patient. A range
+// without end_time marks a single instant.
+func (s *AnnotationService) CreateAnnotation(
+	ctx context.Context,
+	request *models.AnnotationCreateRequest,
+	authorID *uuid.UUID,
+) (*models.Annotation, error) {
+	if err := utils.ValidateStruct(request); err != nil {
+		return nil, err
+	}
+
+	annotation := &models.Annotation{
+		PatientID: request.PatientID,
+		ReadingID: request.ReadingID,
+		Category:  request.Category,
+		Text:      request.Text,
+		AuthorID:  authorID,
+	}
+
+	switch {
+	case request.ReadingID != nil:
+		if request.StartTime != nil || request.EndTime != nil {
+			return nil, &utils.ValidationError{Fields: []string{"start_time and end_time are not allowed with reading_id"}}
+		}
+		reading, err := s.heartReadingRepo.GetHeartReadingByID(ctx, *request.ReadingID)
+		if err != nil {
+			return nil, err
+		}
+		if reading == nil || reading.PatientID != request.PatientID {
+			return nil, ErrHeartReadingNotFound
+		}
+		annotation.StartTime, annotation.EndTime = reading.Time, reading.Time
+	case request.StartTime != nil:
+		annotation.StartTime, annotation.EndTime = *request.StartTime, *request.StartTime
+		if request.EndTime != nil {
+			annotation.EndTime = *request.EndTime
+		}
+	default:
+		return nil, &utils.ValidationError{Fields: []string{"either reading_id or start_time is required"}}
+	}
+
+	if annotation.EndTime.Before(annotation.StartTime) {
+		return nil, &utils.ValidationError{Fields: []string{"end_time must not be before start_time"}}
+	}
+	return s.annotationRepo.CreateAnnotation(ctx, annotation)
+}
+
+// GetAnnotation returns an annotation by ID
+func (s *AnnotationService) GetAnnotation(ctx context.Context, annotationID uuid.UUID) (*models.Annotation, error) {
+	annotation, err := s.annotationRepo.GetAnnotation(ctx, annotationID)
+	if err != nil {
+		return nil, err
+	}
+	if annotation == nil {
+		return nil, ErrAnnotationNotFound
+	}
+	return annotation, nil
+}
+
+// GetAnnotations returns a patient's annotations overlapping a time range
+func (s *AnnotationService) GetAnnotations(ctx context.Context, params *models.AnnotationQueryParams) ([]*models.Annotation, error) {
+	if err := utils.ValidateStruct(params); err != nil {
+		return nil, err
+	}
+	if params.StartTime != nil && params.EndTime != nil && params.StartTime.After(*params.EndTime) {
+		return nil, &utils.ValidationError{Fields: []string{"start_time must not be after end_time"}}
+	}
+	return s.annotationRepo.GetAnnotations(ctx, params)
+}
+
+// UpdateAnnotation changes the category, text or, for range annotations, the
+// time range of an annotation
+func (s *AnnotationService) UpdateAnnotation(
+	ctx context.Context,
+	annotationID uuid.UUID,
+	request *models.AnnotationUpdateRequest,
+) (*models.Annotation, error) {
+	if err := utils.ValidateStruct(request); err != nil {
+		return nil, err
+	}
+
+	annotation, err := s.GetAnnotation(ctx, annotationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.StartTime != nil || request.EndTime != nil {
+		if annotation.ReadingID != nil {
+			return nil, &utils.ValidationError{Fields: []string{"the time of a reading annotation cannot be changed"}}
+		}
+		if request.StartTime != nil {
+			annotation.StartTime = *request.StartTime
+		}
+		if request.EndTime != nil {
+			annotation.EndTime = *request.EndTime
+		}
+		if annotation.EndTime.Before(annotation.StartTime) {
+			return nil, &utils.ValidationError{Fields: []string{"end_time must not be before start_time"}}
+		}
+	}
+	if request.Category != nil {
+		annotation.Category = *request.Category
+	}
+	if request.Text != nil {
+		annotation.Text = *request.Text
+	}
+
+	updated, err := s.annotationRepo.UpdateAnnotation(ctx, annotation)
+	if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		return nil, ErrAnnotationNotFound
+	}
+	return updated, nil
+}
+
+// DeleteAnnotation deletes an annotation
+func (s *AnnotationService) DeleteAnnotation(ctx context.Context, annotationID uuid.UUID) error {
+	deleted, err := s.annotationRepo.DeleteAnnotation(ctx, annotationID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrAnnotationNotFound
+	}
+	return nil
+}
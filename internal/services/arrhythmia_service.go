@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/analytics"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/ecg"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/repositories"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+	"github.com/google/uuid"
+)
+
+const (
+	FindingAtrialFibrillation = "atrial_fibrillation_suspected"
+	FindingIrregularRhythm    = "irregular_rhythm"
+	FindingBradycardia        = analytics.EpisodeBradycardia
+	FindingTachycardia        = analytics.EpisodeTachycardia
+
+	FindingSourceRR  = "rr"
+	FindingSourceECG = "ecg"
+)
+
+// Las alertas de arritmia usan el mismo tipo que el hallazgo que las origina
+var findingAlertSeverity = map[string]string{
+	FindingAtrialFibrillation: "high",
+	FindingIrregularRhythm:    "medium",
+	FindingBradycardia:        "high",
+	FindingTachycardia:        "high",
+}
+
+// Una alerta de arritmia abierta suprime las del mismo tipo durante este periodo
+const findingAlertDedupWindow = time.Hour
+
+// LoadScreeningConfig reads the episode limits from the environment
+func LoadScreeningConfig() analytics.ScreeningConfig {
+	config := analytics.DefaultScreeningConfig()
+	config.BradycardiaBPM = float64(utils.GetEnvInt("ARRHYTHMIA_BRADYCARDIA_BPM", int(config.BradycardiaBPM)))
+	config.TachycardiaBPM = float64(utils.GetEnvInt("ARRHYTHMIA_TACHYCARDIA_BPM", int(config.TachycardiaBPM)))
+	config.MinEpisodeDuration = float64(utils.GetEnvDuration("ARRHYTHMIA_MIN_EPISODE", 10*time.Second).Milliseconds())
+	return config
+}
+
+// ArrhythmiaService screens RR intervals and ECG strips, stores the findings
+// on the reading and raises alerts for them
+type ArrhythmiaService struct {
+	findingRepo *repositories.FindingRepository
+	alertRepo   *repositories.AlertRepository
+	config      analytics.ScreeningConfig
+}
+
+func NewArrhythmiaService(
+	findingRepo *repositories.FindingRepository,
+	alertRepo *repositories.AlertRepository,
+	config analytics.ScreeningConfig,
+) *ArrhythmiaService {
+	return &ArrhythmiaService{
+		findingRepo: findingRepo,
+		alertRepo:   alertRepo,
+		config:      config,
+	}
+}
+
+// ScreenRR runs the screening on RR intervals in milliseconds
+func (s *ArrhythmiaService) ScreenRR(rr []float64) (*analytics.RhythmScreening, error) {
+	return analytics.ScreenRR(rr, s.config)
+}
+
+// ScreenECG detects R peaks in an ECG strip, screens the resulting RR series
+// and records the findings on the reading
+func (s *ArrhythmiaService) ScreenECG(
+	ctx context.Context,
+	reading *models.HeartReading,
+	recording *ecg.Recording,
+) ([]*models.ReadingFinding, error) {
+	samples := make([]float64, len(recording.Samples))
+	for i, v := range recording.Samples {
+		samples[i] = recording.Millivolts(v)
+	}
+
+	peaks := analytics.DetectRPeaks(samples, recording.SampleRate)
+	screening, err := s.ScreenRR(analytics.RRFromPeaks(peaks, recording.SampleRate))
+	if err != nil {
+		return nil, fmt.Errorf("ECG screening failed: %w", err)
+	}
+
+	return s.RecordScreening(ctx, reading, FindingSourceECG, screening)
+}
+
+// RecordScreening stores the findings of a screening run for a reading and
+// raises an alert for each new kind of finding. A conclusive screening also
+// overrides the device-reported irregularity flag.
+func (s *ArrhythmiaService) RecordScreening(
+	ctx context.Context,
+	reading *models.HeartReading,
+	source string,
+	screening *analytics.RhythmScreening,
+) ([]*models.ReadingFinding, error) {
+	findings := screeningFindings(reading, source, screening)
+
+	var irregular *bool
+	if screening.Conclusive {
+		irregular = &screening.Irregular
+	}
+	if err := s.findingRepo.ReplaceFindings(ctx, reading.ID, source, findings, irregular); err != nil {
+		return nil, err
+	}
+	if irregular != nil {
+		reading.IrregularityDetected = *irregular
+	}
+
+	for _, finding := range findings {
+		if err := s.raiseAlert(ctx, finding); err != nil {
+			return nil, err
+		}
+	}
+	return findings, nil
+}
+
+func (s *ArrhythmiaService) GetFindingsByReading(ctx context.Context, readingID uuid.UUID) ([]*models.ReadingFinding, error) {
+	return s.findingRepo.GetFindingsByReading(ctx, readingID)
+}
+
+func (s *ArrhythmiaService) raiseAlert(ctx context.Context, finding *models.ReadingFinding) error {
+	open, err := s.alertRepo.HasOpenAlert(ctx, finding.PatientID, finding.FindingType, finding.ReadingTime.Add(-findingAlertDedupWindow))
+	if err != nil {
+		return err
+	}
+	if open {
+		return nil
+	}
+
+	_, err = s.alertRepo.CreateAlert(ctx, &models.Alert{
+		PatientID:   finding.PatientID,
+		ReadingID:   &finding.ReadingID,
+		AlertType:   finding.FindingType,
+		Severity:    findingAlertSeverity[finding.FindingType],
+		Message:     findingMessage(finding),
+		ReadingTime: finding.ReadingTime,
+	})
+	return err
+}
+
+func screeningFindings(reading *models.HeartReading, source string, screening *analytics.RhythmScreening) []*models.ReadingFinding {
+	newFinding := func(findingType string) *models.ReadingFinding {
+		return &models.ReadingFinding{
+			ReadingID:   reading.ID,
+			PatientID:   reading.PatientID,
+			Source:      source,
+			FindingType: findingType,
+			ReadingTime: reading.Time,
+		}
+	}
+
+	var findings []*models.ReadingFinding
+	if screening.Irregular {
+		findingType := FindingIrregularRhythm
+		if screening.AFSuspected {
+			findingType = FindingAtrialFibrillation
+		}
+		finding := newFinding(findingType)
+		meanHR := screening.MeanHR
+		finding.MeanBPM = &meanHR
+		finding.Details = map[string]any{
+			"beat_count":          screening.BeatCount,
+			"ectopic_count":       screening.EctopicCount,
+			"normalized_rmssd":    screening.NormalizedRMSSD,
+			"shannon_entropy":     screening.ShannonEntropy,
+			"turning_point_ratio": screening.TurningPointRatio,
+		}
+		findings = append(findings, finding)
+	}
+
+	for _, episode := range screening.Episodes {
+		finding := newFinding(episode.Type)
+		start := int64(math.Round(episode.StartMs))
+		duration := int64(math.Round(episode.DurationMs))
+		minBPM, maxBPM, meanBPM := episode.MinBPM, episode.MaxBPM, episode.MeanBPM
+		finding.StartOffsetMs = &start
+		finding.DurationMs = &duration
+		finding.MinBPM = &minBPM
+		finding.MaxBPM = &maxBPM
+		finding.MeanBPM = &meanBPM
+		finding.Details = map[string]any{"beats": episode.Beats}
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+func findingMessage(finding *models.ReadingFinding) string {
+	switch finding.FindingType {
+	case FindingAtrialFibrillation:
+		return fmt.Sprintf("Possible atrial fibrillation detected (mean %.0f bpm)", *finding.MeanBPM)
+	case FindingIrregularRhythm:
+		return fmt.Sprintf("Irregular heart rhythm detected (mean %.0f bpm)", *finding.MeanBPM)
+	case FindingBradycardia:
+		return fmt.Sprintf("Bradycardia episode of %ds (min %.0f bpm)", *finding.DurationMs/1000, *finding.MinBPM)
+	case FindingTachycardia:
+		return fmt.Sprintf("Tachycardia episode of %ds (max %.0f bpm)", *finding.DurationMs/1000, *finding.MaxBPM)
+	}
+	return "Arrhythmia finding: " + finding.FindingType
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"log"
+	"math"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/repositories"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+	"github.com/google/uuid"
+)
+
+// BaselineConfig controls how personal baselines are computed
+type BaselineConfig struct {
+	WindowDays     int
+	MinSamples     int // lecturas mínimas por tipo de lectura
+	MinHourSamples int // lecturas mínimas para incluir una hora en el perfil circadiano
+}
+
+// LoadBaselineConfig reads the baseline configuration from the environment
+func LoadBaselineConfig() BaselineConfig {
+	return BaselineConfig{
+		WindowDays:     utils.GetEnvInt("BASELINE_WINDOW_DAYS", 28),
+		MinSamples:     utils.GetEnvInt("BASELINE_MIN_SAMPLES", 20),
+		MinHourSamples: utils.GetEnvInt("BASELINE_MIN_HOUR_SAMPLES", 5),
+	}
+}
+
+// BaselineService learns each patient's personal heart rate baseline and
+// flags readings that deviate from it
+type BaselineService struct {
+	baselineRepo     *repositories.BaselineRepository
+	heartReadingRepo *repositories.HeartReadingRepository
+	localeService    *LocaleService
+	config           BaselineConfig
+}
+
+func NewBaselineService(
+	baselineRepo *repositories.BaselineRepository,
+	heartReadingRepo *repositories.HeartReadingRepository,
+	localeService *LocaleService,
+	config BaselineConfig,
+) *BaselineService {
+	return &BaselineService{
+		baselineRepo:     baselineRepo,
+		heartReadingRepo: heartReadingRepo,
+		localeService:    localeService,
+		config:           config,
+	}
+}
+
+// RecomputeAll recomputes the baseline of every patient with recent readings
+// and returns the number of patients updated
+func (s *BaselineService) RecomputeAll(ctx context.Context) (int, error) {
+	since := time.Now().AddDate(0, 0, -s.config.WindowDays)
+	patientIDs, err := s.baselineRepo.GetPatientsWithReadingsSince(ctx, since)
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for _, patientID := range patientIDs {
+		if ctx.Err() != nil {
+			return updated, ctx.Err()
+		}
+		if _, err := s.RecomputePatient(ctx, patientID); err != nil {
+			log.Printf("Baseline job: patient %s: %v", patientID, err)
+			continue
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+// RecomputePatient computes and stores a patient's baselines over the last
+// WindowDays days, with the hourly profile in the patient's local time.
+// Reading types with too few readings get no baseline.
+func (s *BaselineService) RecomputePatient(ctx context.Context, patientID uuid.UUID) ([]*models.PatientBaseline, error) {
+	now := time.Now()
+	loc := s.localeService.PatientLocation(ctx, patientID)
+	computed, err := s.baselineRepo.ComputeBaselines(ctx, patientID, now.AddDate(0, 0, -s.config.WindowDays), s.config.MinHourSamples, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	baselines := []*models.PatientBaseline{}
+	for _, b := range computed {
+		if b.SampleCount < s.config.MinSamples {
+			continue
+		}
+		b.WindowDays = s.config.WindowDays
+		b.ComputedAt = now
+		baselines = append(baselines, b)
+	}
+
+	if err := s.baselineRepo.ReplaceBaselines(ctx, patientID, baselines); err != nil {
+		return nil, err
+	}
+	return baselines, nil
+}
+
+func (s *BaselineService) GetBaselines(ctx context.Context, patientID uuid.UUID) ([]*models.PatientBaseline, error) {
+	return s.baselineRepo.GetBaselines(ctx, patientID)
+}
+
+// DetectAnomalies flags readings outside the patient's personal range for
+// their reading type and hour of day: [Q1 - k·IQR, Q3 + k·IQR] (Tukey fences).
+// Beyond twice that distance the anomaly is extreme. Readings without a
+// baseline for their reading type and readings scored below minQuality are
+// skipped.
+func (s *BaselineService) DetectAnomalies(
+	ctx context.Context,
+	patientID uuid.UUID,
+	startTime *time.Time,
+	endTime *time.Time,
+	k float64,
+	minQuality *float64,
+) ([]*models.HeartRateAnomaly, error) {
+	end := time.Now()
+	if endTime != nil {
+		end = *endTime
+	}
+	start := end.Add(-defaultRuleEvaluationRange)
+	if startTime != nil {
+		start = *startTime
+	}
+	if !start.Before(end) || end.Sub(start) > maxRuleEvaluationRange {
+		return nil, &utils.ValidationError{Fields: []string{"start_time must be before end_time and the range at most 31 days"}}
+	}
+	if k <= 0 {
+		return nil, &utils.ValidationError{Fields: []string{"k must be greater than zero"}}
+	}
+
+	baselines, err := s.baselineRepo.GetBaselines(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+	byType := make(map[string]*models.PatientBaseline, len(baselines))
+	for _, b := range baselines {
+		byType[b.ReadingType] = b
+	}
+
+	readings, err := s.heartReadingRepo.GetReadingsInRange(ctx, patientID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := s.localeService.PatientLocation(ctx, patientID)
+	anomalies := []*models.HeartRateAnomaly{}
+	for _, reading := range filterByQuality(readings, minQuality) {
+		baseline, ok := byType[reading.ReadingType]
+		if !ok {
+			continue
+		}
+
+		q1, q3 := baseline.Q1BPM, baseline.Q3BPM
+		for _, h := range baseline.Hourly {
+			if h.Hour == reading.Time.In(loc).Hour() {
+				q1, q3 = h.Q1BPM, h.Q3BPM
+				break
+			}
+		}
+		// Un IQR nulo (lecturas idénticas) marcaría cualquier cambio
+		iqr := math.Max(q3-q1, 1)
+
+		low, high := q1-k*iqr, q3+k*iqr
+		bpm := float64(reading.BPM)
+		anomaly := &models.HeartRateAnomaly{
+			ReadingTime:       reading.Time,
+			BPM:               reading.BPM,
+			ExpectedRangeLow:  int(math.Round(low)),
+			ExpectedRangeHigh: int(math.Round(high)),
+			IsIrregular:       reading.IrregularityDetected,
+		}
+		switch {
+		case bpm < low:
+			anomaly.AnomalyType = "low"
+			if bpm < q1-2*k*iqr {
+				anomaly.AnomalyType = "extreme_low"
+			}
+			anomaly.DeviationPercentage = (low - bpm) / low * 100
+		case bpm > high:
+			anomaly.AnomalyType = "high"
+			if bpm > q3+2*k*iqr {
+				anomaly.AnomalyType = "extreme_high"
+			}
+			anomaly.DeviationPercentage = (bpm - high) / high * 100
+		default:
+			continue
+		}
+		anomalies = append(anomalies, anomaly)
+	}
+	return anomalies, nil
+}
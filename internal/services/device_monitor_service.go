@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/repositories"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+)
+
+const (
+	AlertTypeDeviceOffline    = "device_offline"
+	AlertTypeDeviceLowBattery = "device_low_battery"
+)
+
+// DeviceMonitorConfig controls when devices are considered offline or low on
+// battery
+type DeviceMonitorConfig struct {
+	OfflineAfter        time.Duration
+	LowBatteryThreshold int
+}
+
+// LoadDeviceMonitorConfig reads the monitor configuration from the environment
+func LoadDeviceMonitorConfig() DeviceMonitorConfig {
+	return DeviceMonitorConfig{
+		OfflineAfter:        utils.GetEnvDuration("DEVICE_OFFLINE_AFTER", 30*time.Minute),
+		LowBatteryThreshold: utils.GetEnvInt("DEVICE_LOW_BATTERY_THRESHOLD", 15),
+	}
+}
+
+// DeviceMonitorService raises alerts when a patient's active device stops
+// syncing or its battery runs low
+type DeviceMonitorService struct {
+	deviceRepo *repositories.DeviceRepository
+	alertRepo  *repositories.AlertRepository
+	config     DeviceMonitorConfig
+}
+
+func NewDeviceMonitorService(
+	deviceRepo *repositories.DeviceRepository,
+	alertRepo *repositories.AlertRepository,
+	config DeviceMonitorConfig,
+) *DeviceMonitorService {
+	return &DeviceMonitorService{
+		deviceRepo: deviceRepo,
+		alertRepo:  alertRepo,
+		config:     config,
+	}
+}
+
+// CheckDevices looks for offline and low battery devices and raises an alert
+// for each one that does not already have an open alert. It returns the
+// number of alerts raised.
+func (s *DeviceMonitorService) CheckDevices(ctx context.Context) (int, error) {
+	now := time.Now()
+	raised := 0
+
+	offline, err := s.deviceRepo.GetOfflineDevices(ctx, now.Add(-s.config.OfflineAfter))
+	if err != nil {
+		return raised, err
+	}
+	for _, device := range offline {
+		lastSeen := "never"
+		if device.LastSync != nil {
+			lastSeen = device.LastSync.Format(time.RFC3339)
+		}
+		alert := &models.Alert{
+			PatientID:   device.PatientID,
+			DeviceID:    &device.DeviceID,
+			AlertType:   AlertTypeDeviceOffline,
+			Severity:    "high",
+			Message:     fmt.Sprintf("Device %s (%s) has not synced since %s", device.SerialNumber, device.DeviceType, lastSeen),
+			ReadingTime: now,
+		}
+		created, err := s.raiseAlert(ctx, alert)
+		if err != nil {
+			return raised, err
+		}
+		if created {
+			raised++
+		}
+	}
+
+	lowBattery, err := s.deviceRepo.GetLowBatteryDevices(ctx, s.config.LowBatteryThreshold)
+	if err != nil {
+		return raised, err
+	}
+	for _, device := range lowBattery {
+		alert := &models.Alert{
+			PatientID:   device.PatientID,
+			DeviceID:    &device.DeviceID,
+			AlertType:   AlertTypeDeviceLowBattery,
+			Severity:    "medium",
+			Message:     fmt.Sprintf("Device %s (%s) battery is at %d%%", device.SerialNumber, device.DeviceType, *device.BatteryLevel),
+			ReadingTime: now,
+		}
+		created, err := s.raiseAlert(ctx, alert)
+		if err != nil {
+			return raised, err
+		}
+		if created {
+			raised++
+		}
+	}
+
+	return raised, nil
+}
+
+func (s *DeviceMonitorService) raiseAlert(ctx context.Context, alert *models.Alert) (bool, error) {
+	// Evitar alertas duplicadas del mismo dispositivo mientras la anterior siga sin atender
+	open, err := s.alertRepo.HasOpenDeviceAlert(ctx, *alert.DeviceID, alert.AlertType, alert.ReadingTime.Add(-24*time.Hour))
+	if err != nil {
+		return false, err
+	}
+	if open {
+		return false, nil
+	}
+
+	if _, err := s.alertRepo.CreateAlert(ctx, alert); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -2,10 +2,19 @@ package services
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
 	"github.com/Waldir-TG/api-medical-heart-v1/internal/repositories"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrDeviceNotFound          = errors.New("device not found")
+	ErrPatientChangeNotAllowed = errors.New("patient_id cannot be changed with this endpoint, use POST /api/devices/:id/assign")
 )
 
 type DeviceService struct {
@@ -18,11 +27,14 @@ func NewDeviceService(deviceRepo *repositories.DeviceRepository) *DeviceService
 	}
 }
 
-func (s *DeviceService) GetDevices(ctx context.Context) ([]*models.DeviceResponse, error) {
-	return s.deviceRepo.GetDevices(ctx)
+func (s *DeviceService) ListDevices(ctx context.Context, params *models.DeviceQueryParams) (*models.DeviceListResponse, error) {
+	if err := utils.ValidateStruct(params); err != nil {
+		return nil, err
+	}
+	return s.deviceRepo.ListDevices(ctx, params)
 }
 
-func (s *DeviceService) GetDeviceByID(ctx context.Context, id uuid.UUID) ([]*models.DeviceResponse, error) {
+func (s *DeviceService) GetDeviceByID(ctx context.Context, id uuid.UUID) (*models.DeviceResponse, error) {
 	return s.deviceRepo.GetDeviceByID(ctx, id)
 }
 
@@ -30,16 +42,56 @@ func (s *DeviceService) GetDevicesByPatientID(ctx context.Context, patientID uui
 	return s.deviceRepo.GetDevicesByPatientID(ctx, patientID)
 }
 
-func (s *DeviceService) RegisterDevice(ctx context.Context, device *models.DeviceRegisterRequest) (uuid.UUID, error) {
-	return s.deviceRepo.RegisterDevice(ctx, device)
+func (s *DeviceService) RegisterDevice(ctx context.Context, device *models.DeviceRegisterRequest, registeredBy *uuid.UUID) (uuid.UUID, error) {
+	return s.deviceRepo.RegisterDevice(ctx, device, registeredBy)
 }
 
 func (s *DeviceService) UpdateDevice(ctx context.Context, deviceID uuid.UUID, device *models.DeviceUpdateRequest) (string, error) {
+	if device.PatientID != nil {
+		return "", ErrPatientChangeNotAllowed
+	}
 	return s.deviceRepo.UpdateDevice(ctx, deviceID, device)
 }
 
-func (s *DeviceService) UpdateDeviceSync(ctx context.Context, deviceID uuid.UUID, batteryLevel int) error {
-	return s.deviceRepo.UpdateDeviceSync(ctx, deviceID, batteryLevel)
+// AssignDevice moves a device to another patient (or unassigns it), closing the
+// previous assignment so older readings can still be attributed correctly.
+// Assigning it to its current patient keeps the open assignment.
+func (s *DeviceService) AssignDevice(ctx context.Context, deviceID uuid.UUID, request *models.DeviceAssignRequest, changedBy *uuid.UUID) error {
+	patientFound, err := s.deviceRepo.AssignDevice(ctx, deviceID, request.PatientID, changedBy, request.Reason)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrDeviceNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if !patientFound {
+		return ErrPatientNotFound
+	}
+	return nil
+}
+
+func (s *DeviceService) GetDeviceAssignments(ctx context.Context, deviceID uuid.UUID) ([]*models.DeviceAssignment, error) {
+	return s.deviceRepo.GetDeviceAssignments(ctx, deviceID)
+}
+
+// GetDeviceAssignmentAt resolves which patient a device was on at time t
+func (s *DeviceService) GetDeviceAssignmentAt(ctx context.Context, deviceID uuid.UUID, t time.Time) (*models.DeviceAssignment, error) {
+	return s.deviceRepo.GetDeviceAssignmentAt(ctx, deviceID, t)
+}
+
+func (s *DeviceService) GetDeviceBySerial(ctx context.Context, serialNumber string) (*models.DeviceResponse, error) {
+	return s.deviceRepo.GetDeviceBySerial(ctx, serialNumber)
+}
+
+func (s *DeviceService) UpdateDeviceSync(ctx context.Context, deviceID uuid.UUID, sync *models.DeviceSyncRequest) error {
+	if err := utils.ValidateStruct(sync); err != nil {
+		return err
+	}
+	return s.deviceRepo.UpdateDeviceSync(ctx, deviceID, sync)
+}
+
+func (s *DeviceService) GetDeviceTelemetry(ctx context.Context, params *models.DeviceTelemetryQueryParams) ([]*models.DeviceTelemetry, error) {
+	return s.deviceRepo.GetDeviceTelemetry(ctx, params)
 }
 
 func (s *DeviceService) DeactivateDevice(ctx context.Context, deviceID uuid.UUID) (string, error) {
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/ecg"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/repositories"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+	"github.com/google/uuid"
+)
+
+const ecgEncoding = "ecg1-delta-deflate"
+
+var (
+	ErrHeartReadingNotFound = errors.New("heart reading not found")
+	ErrEcgRecordingNotFound = errors.New("ECG recording not found")
+)
+
+type EcgService struct {
+	ecgRepo           *repositories.EcgRepository
+	heartReadingRepo  *repositories.HeartReadingRepository
+	arrhythmiaService *ArrhythmiaService
+}
+
+func NewEcgService(
+	ecgRepo *repositories.EcgRepository,
+	heartReadingRepo *repositories.HeartReadingRepository,
+	arrhythmiaService *ArrhythmiaService,
+) *EcgService {
+	return &EcgService{
+		ecgRepo:           ecgRepo,
+		heartReadingRepo:  heartReadingRepo,
+		arrhythmiaService: arrhythmiaService,
+	}
+}
+
+// UploadRecording stores an ECG strip for a heart reading. The payload is
+// either raw little endian int16 samples, described by the request metadata,
+// or a strip already encoded in the ECG1 format. The strip is then screened
+// for arrhythmias and the findings are recorded on the reading.
+func (s *EcgService) UploadRecording(
+	ctx context.Context,
+	readingID uuid.UUID,
+	request *models.EcgUploadRequest,
+	payload []byte,
+) (*models.EcgRecording, error) {
+	reading, err := s.heartReadingRepo.GetHeartReadingByID(ctx, readingID)
+	if err != nil {
+		return nil, err
+	}
+	if reading == nil {
+		return nil, ErrHeartReadingNotFound
+	}
+
+	recording, err := parseEcgPayload(request, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ecg.Encode(recording)
+	if err != nil {
+		return nil, &utils.ValidationError{Fields: []string{err.Error()}}
+	}
+
+	lead := request.Lead
+	if lead == "" {
+		lead = "I"
+	}
+	if len(lead) > 10 {
+		return nil, &utils.ValidationError{Fields: []string{"lead must be at most 10 characters"}}
+	}
+
+	recordedAt := reading.Time
+	if request.RecordedAt != nil {
+		recordedAt = *request.RecordedAt
+	}
+
+	metadata := &models.EcgRecording{
+		ReadingID:    reading.ID,
+		PatientID:    reading.PatientID,
+		DeviceID:     reading.DeviceID,
+		Lead:         lead,
+		SampleRateHz: recording.SampleRate,
+		Gain:         recording.Gain,
+		SampleCount:  len(recording.Samples),
+		DurationMs:   recording.DurationMs(),
+		Encoding:     ecgEncoding,
+		SizeBytes:    len(data),
+		RecordedAt:   recordedAt,
+	}
+
+	metadata.ID, err = s.ecgRepo.CreateRecording(ctx, metadata, data)
+	if err != nil {
+		return nil, err
+	}
+
+	// El trazado ya está guardado: un fallo del cribado no invalida la subida
+	if _, err := s.arrhythmiaService.ScreenECG(ctx, reading, recording); err != nil {
+		log.Printf("Arrhythmia screening for ECG recording %s: %v", metadata.ID, err)
+	}
+	return metadata, nil
+}
+
+func (s *EcgService) GetRecordingsByReading(ctx context.Context, readingID uuid.UUID) ([]*models.EcgRecording, error) {
+	return s.ecgRepo.GetRecordingsByReading(ctx, readingID)
+}
+
+// GetRecording returns a strip's metadata along with its stored encoding
+func (s *EcgService) GetRecording(ctx context.Context, recordingID uuid.UUID) (*models.EcgRecording, []byte, error) {
+	metadata, data, err := s.ecgRepo.GetRecording(ctx, recordingID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if metadata == nil {
+		return nil, nil, ErrEcgRecordingNotFound
+	}
+	return metadata, data, nil
+}
+
+// DecodeRecording returns the samples of a stored strip
+func (s *EcgService) DecodeRecording(ctx context.Context, recordingID uuid.UUID) (*models.EcgRecording, *ecg.Recording, error) {
+	metadata, data, err := s.GetRecording(ctx, recordingID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	recording, err := ecg.Decode(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode ECG recording %s: %w", recordingID, err)
+	}
+	return metadata, recording, nil
+}
+
+func parseEcgPayload(request *models.EcgUploadRequest, payload []byte) (*ecg.Recording, error) {
+	if len(payload) == 0 {
+		return nil, &utils.ValidationError{Fields: []string{"ECG payload is empty"}}
+	}
+
+	if bytes.HasPrefix(payload, []byte("ECG1")) {
+		recording, err := ecg.Decode(payload)
+		if err != nil {
+			return nil, &utils.ValidationError{Fields: []string{err.Error()}}
+		}
+		return recording, nil
+	}
+
+	samples, err := ecg.ParseInt16LE(payload)
+	if err != nil {
+		return nil, &utils.ValidationError{Fields: []string{err.Error()}}
+	}
+
+	recording := &ecg.Recording{
+		SampleRate: request.SampleRateHz,
+		Gain:       request.Gain,
+		Samples:    samples,
+	}
+	if err := recording.Validate(); err != nil {
+		return nil, &utils.ValidationError{Fields: []string{err.Error()}}
+	}
+	return recording, nil
+}
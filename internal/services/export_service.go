@@ -0,0 +1,303 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/export"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/repositories"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrExportJobNotFound = errors.New("export job not found")
+	ErrExportNotReady    = errors.New("export is not ready for download")
+)
+
+// Número máximo de trabajos devueltos al listar los de un paciente
+const exportJobListLimit = 50
+
+// ExportConfig controls reading exports. Directory must be shared between
+// replicas, since any of them may run a job and serve its file.
+type ExportConfig struct {
+	Directory     string
+	Interval      time.Duration // espera entre búsquedas de trabajos pendientes
+	Retention     time.Duration // tiempo que se conserva un archivo generado
+	StreamTimeout time.Duration // duración máxima de una descarga directa
+	JobTimeout    time.Duration // duración máxima de un trabajo en segundo plano
+}
+
+// LoadExportConfig reads the export configuration from the environment
+func LoadExportConfig() ExportConfig {
+	return ExportConfig{
+		Directory:     utils.GetEnvString("EXPORT_DIR", "exports"),
+		Interval:      utils.GetEnvDuration("EXPORT_WORKER_INTERVAL", 10*time.Second),
+		Retention:     utils.GetEnvDuration("EXPORT_RETENTION", 24*time.Hour),
+		StreamTimeout: utils.GetEnvDuration("EXPORT_STREAM_TIMEOUT", 30*time.Minute),
+		JobTimeout:    utils.GetEnvDuration("EXPORT_JOB_TIMEOUT", 2*time.Hour),
+	}
+}
+
+// ExportService exports a patient's heart readings, either streamed directly
+// to the client or as a background job that writes a downloadable file
+type ExportService struct {
+	exportRepo       *repositories.ExportRepository
+	heartReadingRepo *repositories.HeartReadingRepository
+	localeService    *LocaleService
+	config           ExportConfig
+	done             chan struct{}
+}
+
+func NewExportService(
+	exportRepo *repositories.ExportRepository,
+	heartReadingRepo *repositories.HeartReadingRepository,
+	localeService *LocaleService,
+	config ExportConfig,
+) *ExportService {
+	return &ExportService{
+		exportRepo:       exportRepo,
+		heartReadingRepo: heartReadingRepo,
+		localeService:    localeService,
+		config:           config,
+		done:             make(chan struct{}),
+	}
+}
+
+// ValidateExport checks an export request and fills in its defaults: the
+// default columns and the patient's time zone
+func (s *ExportService) ValidateExport(ctx context.Context, request *models.ReadingExportRequest) error {
+	if err := utils.ValidateStruct(request); err != nil {
+		return err
+	}
+	if len(request.Columns) == 0 {
+		request.Columns = append([]string(nil), export.DefaultColumns...)
+	}
+	if err := export.ValidateColumns(request.Columns); err != nil {
+		return &utils.ValidationError{Fields: []string{err.Error()}}
+	}
+	if request.StartTime != nil && request.EndTime != nil && !request.StartTime.Before(*request.EndTime) {
+		return &utils.ValidationError{Fields: []string{"start_time must be before end_time"}}
+	}
+
+	settings, err := s.localeService.GetPatientLocale(ctx, request.PatientID)
+	if err != nil {
+		return err
+	}
+	if request.Timezone == "" {
+		request.Timezone = settings.Timezone
+	}
+	if _, err := loadTimezone(request.Timezone); err != nil {
+		return err
+	}
+	return nil
+}
+
+// WriteExport writes the readings selected by a validated request to w and
+// returns how many were written
+func (s *ExportService) WriteExport(ctx context.Context, w io.Writer, request *models.ReadingExportRequest) (int64, error) {
+	loc, err := loadTimezone(request.Timezone)
+	if err != nil {
+		return 0, err
+	}
+	writer, err := export.NewReadingWriter(w, request.Format, request.Columns, loc)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := s.heartReadingRepo.StreamHeartReadings(ctx, request.PatientID, request.StartTime, request.EndTime, writer.Write); err != nil {
+		return writer.Count(), err
+	}
+	if err := writer.Close(); err != nil {
+		return writer.Count(), err
+	}
+	return writer.Count(), nil
+}
+
+// StreamTimeout is the longest a direct download may take
+func (s *ExportService) StreamTimeout() time.Duration {
+	return s.config.StreamTimeout
+}
+
+// CreateJob validates an export request and queues it as a background job
+func (s *ExportService) CreateJob(
+	ctx context.Context,
+	request *models.ReadingExportRequest,
+	requestedBy *uuid.UUID,
+) (*models.ExportJob, error) {
+	if err := s.ValidateExport(ctx, request); err != nil {
+		return nil, err
+	}
+	return s.exportRepo.CreateExportJob(ctx, request, requestedBy)
+}
+
+func (s *ExportService) GetJob(ctx context.Context, jobID uuid.UUID) (*models.ExportJob, error) {
+	job, err := s.exportRepo.GetExportJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, ErrExportJobNotFound
+	}
+	return job, nil
+}
+
+func (s *ExportService) GetPatientJobs(ctx context.Context, patientID uuid.UUID) ([]*models.ExportJob, error) {
+	return s.exportRepo.GetPatientExportJobs(ctx, patientID, exportJobListLimit)
+}
+
+// JobFile returns the path of a completed job's file
+func (s *ExportService) JobFile(job *models.ExportJob) (string, error) {
+	if job.Status != "completed" {
+		return "", ErrExportNotReady
+	}
+	return s.jobPath(job), nil
+}
+
+// ExportFileName is the name suggested to the client when downloading an export
+func ExportFileName(patientID uuid.UUID, format string, now time.Time) string {
+	return fmt.Sprintf("heart-readings-%s-%s.%s", patientID, now.UTC().Format("20060102T150405Z"), format)
+}
+
+func (s *ExportService) jobPath(job *models.ExportJob) string {
+	return filepath.Join(s.config.Directory, job.ID.String()+"."+job.Format)
+}
+
+// Run processes pending export jobs every Interval until ctx is cancelled.
+// Jobs are claimed with row locks, so every replica can run it. A job
+// interrupted by shutdown goes back to pending.
+func (s *ExportService) Run(ctx context.Context) {
+	defer close(s.done)
+
+	if err := os.MkdirAll(s.config.Directory, 0o750); err != nil {
+		log.Printf("Export worker: %v", err)
+		return
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		s.expireJobs(ctx)
+		s.requeueStaleJobs(ctx)
+		for ctx.Err() == nil {
+			ran, err := s.runNextJob(ctx)
+			if err != nil {
+				log.Printf("Export worker: %v", err)
+				break
+			}
+			if !ran {
+				break
+			}
+		}
+		timer.Reset(s.config.Interval)
+	}
+}
+
+// Done is closed once Run has returned
+func (s *ExportService) Done() <-chan struct{} {
+	return s.done
+}
+
+// runNextJob claims and runs one pending job, reporting whether there was one
+func (s *ExportService) runNextJob(ctx context.Context) (bool, error) {
+	job, err := s.exportRepo.ClaimExportJob(ctx)
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	jobCtx, cancel := context.WithTimeout(ctx, s.config.JobTimeout)
+	rows, size, err := s.writeJobFile(jobCtx, job)
+	cancel()
+
+	// El estado final se registra aunque el servidor se esté apagando
+	updateCtx, cancelUpdate := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancelUpdate()
+
+	if err != nil {
+		if ctx.Err() != nil {
+			_, requeueErr := s.exportRepo.RequeueExportJobs(updateCtx, time.Time{}, &job.ID)
+			return true, requeueErr
+		}
+		log.Printf("Export job %s failed: %v", job.ID, err)
+		return true, s.exportRepo.FailExportJob(updateCtx, job.ID, err.Error())
+	}
+	return true, s.exportRepo.CompleteExportJob(updateCtx, job.ID, rows, size, time.Now().Add(s.config.Retention))
+}
+
+// writeJobFile writes a job's export to a temporary file and moves it into
+// place once complete, so a download never sees a partial file
+func (s *ExportService) writeJobFile(ctx context.Context, job *models.ExportJob) (rows int64, size int64, err error) {
+	file, err := os.CreateTemp(s.config.Directory, job.ID.String()+".*.tmp")
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}()
+
+	request := &models.ReadingExportRequest{
+		PatientID: job.PatientID,
+		Format:    job.Format,
+		Columns:   job.Columns,
+		StartTime: job.StartTime,
+		EndTime:   job.EndTime,
+		Timezone:  job.Timezone,
+	}
+	if rows, err = s.WriteExport(ctx, file, request); err != nil {
+		return 0, 0, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+	if err = file.Close(); err != nil {
+		return 0, 0, err
+	}
+	if err = os.Rename(file.Name(), s.jobPath(job)); err != nil {
+		return 0, 0, fmt.Errorf("failed to store export file: %w", err)
+	}
+	return rows, info.Size(), nil
+}
+
+// expireJobs deletes the files of jobs past their retention
+func (s *ExportService) expireJobs(ctx context.Context) {
+	jobs, err := s.exportRepo.ExpireExportJobs(ctx, time.Now())
+	if err != nil {
+		log.Printf("Export worker: %v", err)
+		return
+	}
+	for _, job := range jobs {
+		if err := os.Remove(s.jobPath(job)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Export worker: %v", err)
+		}
+	}
+}
+
+// requeueStaleJobs recovers the jobs left running by a replica that stopped
+// without finishing them
+func (s *ExportService) requeueStaleJobs(ctx context.Context) {
+	requeued, err := s.exportRepo.RequeueExportJobs(ctx, time.Now().Add(-s.config.JobTimeout-time.Minute), nil)
+	if err != nil {
+		log.Printf("Export worker: %v", err)
+		return
+	}
+	if requeued > 0 {
+		log.Printf("Export worker: requeued %d stale jobs", requeued)
+	}
+}
@@ -0,0 +1,477 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/analytics"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/fhir"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/repositories"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Fuente de las lecturas y observaciones recibidas por FHIR
+const fhirSource = "fhir"
+
+var (
+	ErrObservationNotFound = errors.New("observation not found")
+	ErrDoctorNotFound      = errors.New("doctor not found")
+)
+
+// FHIRService exposes patients, doctors, devices and measurements as HL7
+// FHIR R4 resources and imports vital signs Observations from transaction
+// Bundles. Heart rate Observations are stored as heart readings, SpO2 and
+// blood pressure ones as vital observations.
+type FHIRService struct {
+	fhirRepo       *repositories.FHIRRepository
+	vitalService   *VitalService
+	patientService *PatientService
+	deviceService  *DeviceService
+	doctorService  *DoctorService
+	localeService  *LocaleService
+	qualityConfig  analytics.QualityConfig
+}
+
+func NewFHIRService(
+	fhirRepo *repositories.FHIRRepository,
+	vitalService *VitalService,
+	patientService *PatientService,
+	deviceService *DeviceService,
+	doctorService *DoctorService,
+	localeService *LocaleService,
+	qualityConfig analytics.QualityConfig,
+) *FHIRService {
+	return &FHIRService{
+		fhirRepo:       fhirRepo,
+		vitalService:   vitalService,
+		patientService: patientService,
+		deviceService:  deviceService,
+		doctorService:  doctorService,
+		localeService:  localeService,
+		qualityConfig:  qualityConfig,
+	}
+}
+
+// GetObservation returns an Observation by id
+func (s *FHIRService) GetObservation(ctx context.Context, id string) (*fhir.Observation, error) {
+	kind, sourceID, err := fhir.ParseObservationID(id)
+	if err != nil {
+		return nil, ErrObservationNotFound
+	}
+	page, err := s.fhirRepo.SearchMeasurements(ctx, &models.MeasurementQueryParams{Kind: &kind, ID: &sourceID, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(page.Data) == 0 {
+		return nil, ErrObservationNotFound
+	}
+	return fhir.ObservationFromMeasurement(page.Data[0]), nil
+}
+
+// SearchObservations returns a page of a patient's Observations and the
+// cursor of the next page
+func (s *FHIRService) SearchObservations(ctx context.Context, params *models.MeasurementQueryParams) ([]*fhir.Observation, *string, error) {
+	if params.PatientID == nil {
+		return nil, nil, &utils.ValidationError{Fields: []string{"patient: search parameter is required"}}
+	}
+	if _, err := s.localeService.GetPatientLocale(ctx, *params.PatientID); err != nil {
+		return nil, nil, err
+	}
+
+	page, err := s.fhirRepo.SearchMeasurements(ctx, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	observations := make([]*fhir.Observation, len(page.Data))
+	for i, m := range page.Data {
+		observations[i] = fhir.ObservationFromMeasurement(m)
+	}
+	return observations, page.NextCursor, nil
+}
+
+// GetPatient returns a Patient by id
+func (s *FHIRService) GetPatient(ctx context.Context, patientID uuid.UUID) (*fhir.Patient, error) {
+	patients, err := s.patientService.GetPatientBasicDetailsByID(ctx, &patientID)
+	if err != nil {
+		return nil, err
+	}
+	if len(patients) == 0 {
+		return nil, ErrPatientNotFound
+	}
+	return fhir.PatientFromDetail(patients[0]), nil
+}
+
+// SearchPatients returns every Patient
+func (s *FHIRService) SearchPatients(ctx context.Context) ([]*fhir.Patient, error) {
+	patients, err := s.patientService.GetAllPatientsBasicDetails(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resources := make([]*fhir.Patient, len(patients))
+	for i, p := range patients {
+		resources[i] = fhir.PatientFromDetail(p)
+	}
+	return resources, nil
+}
+
+// GetDevice returns a Device by id
+func (s *FHIRService) GetDevice(ctx context.Context, deviceID uuid.UUID) (*fhir.Device, error) {
+	device, err := s.deviceService.GetDeviceByID(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if device == nil {
+		return nil, ErrDeviceNotFound
+	}
+	return fhir.DeviceFromResponse(device), nil
+}
+
+// SearchDevices returns the Devices of a patient, or every Device when
+// patientID is nil
+func (s *FHIRService) SearchDevices(ctx context.Context, patientID *uuid.UUID) ([]*fhir.Device, error) {
+	var devices []*models.DeviceResponse
+	if patientID != nil {
+		var err error
+		if devices, err = s.deviceService.GetDevicesByPatientID(ctx, *patientID); err != nil {
+			return nil, err
+		}
+	} else {
+		// La flota se recorre por páginas
+		params := &models.DeviceQueryParams{SortBy: "registered_at", SortOrder: "asc", Limit: 500}
+		for {
+			page, err := s.deviceService.ListDevices(ctx, params)
+			if err != nil {
+				return nil, err
+			}
+			devices = append(devices, page.Data...)
+			if page.NextCursor == nil {
+				break
+			}
+			params.Cursor = page.NextCursor
+		}
+	}
+
+	resources := make([]*fhir.Device, len(devices))
+	for i, d := range devices {
+		resources[i] = fhir.DeviceFromResponse(d)
+	}
+	return resources, nil
+}
+
+// GetPractitioner returns the Practitioner of a doctor
+func (s *FHIRService) GetPractitioner(ctx context.Context, doctorID uuid.UUID) (*fhir.Practitioner, error) {
+	doctor, err := s.doctorService.GetDoctorDetail(ctx, doctorID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrDoctorNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fhir.PractitionerFromDoctor(doctor), nil
+}
+
+// SearchPractitioners returns the Practitioners of every doctor
+func (s *FHIRService) SearchPractitioners(ctx context.Context) ([]*fhir.Practitioner, error) {
+	doctors, err := s.doctorService.GetDoctors(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resources := make([]*fhir.Practitioner, len(doctors))
+	for i, d := range doctors {
+		resources[i] = fhir.PractitionerFromDoctor(d)
+	}
+	return resources, nil
+}
+
+// Validate checks a resource against the bundled profiles. resourceType is
+// the type the resource was posted as; it may be empty.
+func (s *FHIRService) Validate(resourceType string, data []byte) *fhir.OperationOutcome {
+	var header struct {
+		ResourceType string `json:"resourceType"`
+	}
+	if err := json.Unmarshal(data, &header); err == nil && resourceType != "" && header.ResourceType != resourceType {
+		return fhir.NewOperationOutcome(fhir.Issue{
+			Severity:    "error",
+			Code:        "invalid",
+			Diagnostics: fmt.Sprintf("resource type %q does not match %q", header.ResourceType, resourceType),
+		})
+	}
+
+	issues := fhir.Validate(data)
+	if !fhir.HasErrors(issues) {
+		issues = append(issues, fhir.Issue{Severity: "information", Code: "informational", Diagnostics: "validation successful"})
+	}
+	return fhir.NewOperationOutcome(issues...)
+}
+
+// fhirReadingKey identifies a heart reading for deduplication: patient,
+// second and BPM, as in reading imports
+type fhirReadingKey struct {
+	patientID uuid.UUID
+	second    int64
+	bpm       int
+}
+
+// transactionEntry is a validated entry of a transaction Bundle
+type transactionEntry struct {
+	reading   *models.HeartReading // lectura de frecuencia cardiaca
+	position  int                  // posición en las lecturas a importar
+	duplicate bool                 // repetida dentro del propio Bundle
+	vital     int                  // posición en las observaciones a guardar, -1 si es lectura
+}
+
+// ProcessTransaction imports the Observations of a transaction Bundle.
+// Every entry must POST a vital signs Observation valid against the
+// bundled profiles; if any entry is invalid nothing is stored. Heart rate
+// Observations that repeat a stored reading are not stored again and
+// answer "200 OK" with the existing reading.
+func (s *FHIRService) ProcessTransaction(ctx context.Context, data []byte, enteredBy *uuid.UUID) (*fhir.Bundle, error) {
+	var bundle fhir.Bundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, &utils.ValidationError{Fields: []string{"Bundle: " + err.Error()}}
+	}
+	if bundle.ResourceType != "Bundle" || bundle.Type != "transaction" {
+		return nil, &utils.ValidationError{Fields: []string{"Bundle: must be a Bundle of type transaction"}}
+	}
+
+	var problems []string
+	fail := func(i int, format string, args ...any) {
+		problems = append(problems, fmt.Sprintf("Bundle.entry[%d]: ", i)+fmt.Sprintf(format, args...))
+	}
+
+	patients := map[uuid.UUID]error{}
+	seen := map[fhirReadingKey]int{}
+	entries := make([]transactionEntry, len(bundle.Entry))
+	var readings []*models.HeartReading
+	var vitals []*models.VitalObservation
+	now := time.Now()
+
+	for i, entry := range bundle.Entry {
+		if entry.Request == nil || entry.Request.Method != "POST" || strings.Trim(entry.Request.URL, "/") != "Observation" {
+			fail(i, "only POST of an Observation is supported")
+			continue
+		}
+		if len(entry.Resource) == 0 {
+			fail(i, "resource is required")
+			continue
+		}
+		issues := fhir.Validate(entry.Resource)
+		if fhir.HasErrors(issues) {
+			for _, issue := range issues {
+				if issue.Severity == "error" {
+					fail(i, "%s", issue.Diagnostics)
+				}
+			}
+			continue
+		}
+
+		var observation fhir.Observation
+		if err := json.Unmarshal(entry.Resource, &observation); err != nil {
+			fail(i, "%v", err)
+			continue
+		}
+		m, err := fhir.MeasurementFromObservation(&observation)
+		if err != nil {
+			fail(i, "%v", err)
+			continue
+		}
+
+		if _, checked := patients[m.PatientID]; !checked {
+			_, patients[m.PatientID] = s.localeService.GetPatientLocale(ctx, m.PatientID)
+		}
+		if err := patients[m.PatientID]; err != nil {
+			if !errors.Is(err, ErrPatientNotFound) {
+				return nil, err
+			}
+			fail(i, "subject: patient %s not found", m.PatientID)
+			continue
+		}
+		if m.DeviceID != nil {
+			device, err := s.deviceService.GetDeviceByID(ctx, *m.DeviceID)
+			if err != nil {
+				return nil, err
+			}
+			if device == nil {
+				fail(i, "device: device %s not found", *m.DeviceID)
+				continue
+			}
+		}
+
+		if m.ObservationType != "heart_rate" {
+			vital := &models.VitalObservationCreateRequest{
+				PatientID:       m.PatientID,
+				DeviceID:        m.DeviceID,
+				ObservationType: m.ObservationType,
+				Value:           m.Value,
+				SecondaryValue:  m.SecondaryValue,
+				EntryMethod:     "imported",
+				EnteredBy:       enteredBy,
+				Source:          fhirSource,
+				Notes:           m.Notes,
+				Time:            &m.Time,
+			}
+			unit, err := s.vitalService.ValidateObservation(vital)
+			if err != nil {
+				var validationErr *utils.ValidationError
+				if !errors.As(err, &validationErr) {
+					return nil, err
+				}
+				fail(i, "%s", strings.Join(validationErr.Fields, "; "))
+				continue
+			}
+			entries[i] = transactionEntry{vital: len(vitals)}
+			vitals = append(vitals, &models.VitalObservation{
+				PatientID:       vital.PatientID,
+				DeviceID:        vital.DeviceID,
+				ObservationType: vital.ObservationType,
+				Value:           vital.Value,
+				SecondaryValue:  vital.SecondaryValue,
+				Unit:            unit,
+				EntryMethod:     vital.EntryMethod,
+				Source:          vital.Source,
+				EnteredBy:       vital.EnteredBy,
+				Notes:           vital.Notes,
+				Time:            m.Time,
+			})
+			continue
+		}
+
+		reading, err := s.reading(m, enteredBy, now)
+		if err != nil {
+			fail(i, "%v", err)
+			continue
+		}
+		key := fhirReadingKey{reading.PatientID, reading.Time.Unix(), reading.BPM}
+		if first, ok := seen[key]; ok {
+			entries[i] = transactionEntry{reading: reading, position: entries[first].position, duplicate: true, vital: -1}
+			continue
+		}
+		seen[key] = i
+		entries[i] = transactionEntry{reading: reading, position: len(readings), vital: -1}
+		readings = append(readings, reading)
+	}
+	if len(problems) > 0 {
+		return nil, &utils.ValidationError{Fields: problems}
+	}
+
+	duplicates, created, err := s.fhirRepo.ImportMeasurements(ctx, readings, vitals)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &fhir.Bundle{ResourceType: "Bundle", Type: "transaction-response", Entry: make([]fhir.BundleEntry, len(entries))}
+	for i, entry := range entries {
+		if entry.vital >= 0 {
+			vital := created[entry.vital]
+			response.Entry[i] = createdEntry("201 Created", fhir.ObservationID(models.MeasurementVital, vital.ID))
+			continue
+		}
+
+		if !entry.duplicate && !duplicates[entry.position] {
+			response.Entry[i] = createdEntry("201 Created", fhir.ObservationID(models.MeasurementHeartRate, entry.reading.ID))
+			continue
+		}
+		// La lectura ya estaba guardada: se responde con la existente
+		existing, err := s.findReading(ctx, entry.reading)
+		if err != nil {
+			return nil, err
+		}
+		response.Entry[i] = createdEntry("200 OK", existing)
+	}
+	return response, nil
+}
+
+func createdEntry(status, observationID string) fhir.BundleEntry {
+	entry := fhir.BundleEntry{Response: &fhir.BundleResponse{Status: status}}
+	if observationID != "" {
+		entry.Response.Location = "Observation/" + observationID
+	}
+	return entry
+}
+
+// reading builds the heart reading stored for a heart rate measurement,
+// validated and scored like any new reading. The reading type comes from the
+// Observation; one that does not give it is stored as unknown, so that it
+// does not count as a resting reading.
+func (s *FHIRService) reading(m *models.Measurement, enteredBy *uuid.UUID, now time.Time) (*models.HeartReading, error) {
+	if m.Value != math.Trunc(m.Value) {
+		return nil, errors.New("valueQuantity: heart rate must be a whole number of beats per minute")
+	}
+	readingType := models.ReadingTypeUnknown
+	if m.ReadingType != nil {
+		readingType = *m.ReadingType
+	}
+	create := &models.HeartReadingCreateRequest{
+		PatientID:   m.PatientID,
+		DeviceID:    m.DeviceID,
+		EntryMethod: "imported",
+		EnteredBy:   enteredBy,
+		ReadingType: readingType,
+		Source:      fhirSource,
+		BPM:         int(m.Value),
+		Notes:       m.Notes,
+		Time:        &m.Time,
+	}
+	if err := utils.ValidateStruct(create); err != nil {
+		var validationErr *utils.ValidationError
+		if errors.As(err, &validationErr) {
+			return nil, errors.New(strings.Join(validationErr.Fields, "; "))
+		}
+		return nil, err
+	}
+	if m.Time.After(now.Add(s.qualityConfig.MaxClockSkew)) {
+		return nil, fmt.Errorf("effective: time %s is in the future", m.Time.Format(time.RFC3339))
+	}
+
+	assessment := analytics.AssessQuality(analytics.QualityInput{
+		Sample: analytics.QualitySample{Time: m.Time, BPM: create.BPM},
+		Now:    now,
+	}, s.qualityConfig)
+
+	return &models.HeartReading{
+		PatientID:    create.PatientID,
+		DeviceID:     create.DeviceID,
+		EntryMethod:  create.EntryMethod,
+		EnteredBy:    create.EnteredBy,
+		ReadingType:  create.ReadingType,
+		Source:       create.Source,
+		BPM:          create.BPM,
+		Notes:        create.Notes,
+		QualityScore: &assessment.Score,
+		QualityFlags: assessment.Flags,
+		Time:         m.Time,
+	}, nil
+}
+
+// findReading returns the Observation id of the stored reading a duplicate
+// reading repeats, or "" if it cannot be found
+func (s *FHIRService) findReading(ctx context.Context, reading *models.HeartReading) (string, error) {
+	kind := models.MeasurementHeartRate
+	start := reading.Time.Truncate(time.Second)
+	end := start.Add(time.Second)
+	page, err := s.fhirRepo.SearchMeasurements(ctx, &models.MeasurementQueryParams{
+		PatientID: &reading.PatientID,
+		Kind:      &kind,
+		StartTime: &start,
+		EndTime:   &end,
+		SortOrder: "asc",
+		Limit:     100,
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, m := range page.Data {
+		if int(m.Value) == reading.BPM {
+			return fhir.ObservationID(m.Kind, m.ID), nil
+		}
+	}
+	return "", nil
+}
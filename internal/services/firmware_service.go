@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"hash/fnv"
+	"strings"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/repositories"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrFirmwareNotFound   = errors.New("firmware release not found")
+	ErrRolloutNotFound    = errors.New("firmware rollout not found")
+	ErrDeviceNotInRollout = errors.New("device is not targeted by this firmware rollout")
+)
+
+type FirmwareService struct {
+	firmwareRepo *repositories.FirmwareRepository
+	deviceRepo   *repositories.DeviceRepository
+}
+
+func NewFirmwareService(firmwareRepo *repositories.FirmwareRepository, deviceRepo *repositories.DeviceRepository) *FirmwareService {
+	return &FirmwareService{
+		firmwareRepo: firmwareRepo,
+		deviceRepo:   deviceRepo,
+	}
+}
+
+// CreateRelease validates and publishes a firmware release
+func (s *FirmwareService) CreateRelease(ctx context.Context, release *models.FirmwareReleaseCreateRequest, createdBy *uuid.UUID) (uuid.UUID, error) {
+	if release.DeviceType == "" || release.DownloadURL == "" {
+		return uuid.Nil, errors.New("device_type and download_url are required")
+	}
+
+	version, err := utils.ParseSemVer(release.Version)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	release.Version = version.String()
+
+	if checksum, err := hex.DecodeString(release.Checksum); err != nil || len(checksum) != 32 {
+		return uuid.Nil, errors.New("checksum must be a hex-encoded SHA-256 digest")
+	}
+
+	return s.firmwareRepo.CreateRelease(ctx, release, createdBy)
+}
+
+func (s *FirmwareService) GetReleases(ctx context.Context, deviceType *string) ([]*models.FirmwareRelease, error) {
+	return s.firmwareRepo.GetReleases(ctx, deviceType)
+}
+
+// CreateRollout starts a rollout of an existing release
+func (s *FirmwareService) CreateRollout(ctx context.Context, firmwareID uuid.UUID, rollout *models.FirmwareRolloutCreateRequest, createdBy *uuid.UUID) (uuid.UUID, error) {
+	if rollout.Percentage == nil && len(rollout.DeviceIDs) == 0 {
+		return uuid.Nil, errors.New("either percentage or device_ids is required")
+	}
+	if rollout.Percentage != nil && (*rollout.Percentage < 0 || *rollout.Percentage > 100) {
+		return uuid.Nil, errors.New("percentage must be between 0 and 100")
+	}
+
+	release, err := s.firmwareRepo.GetReleaseByID(ctx, firmwareID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if release == nil {
+		return uuid.Nil, ErrFirmwareNotFound
+	}
+
+	return s.firmwareRepo.CreateRollout(ctx, firmwareID, rollout, createdBy)
+}
+
+func (s *FirmwareService) UpdateRollout(ctx context.Context, rolloutID uuid.UUID, update *models.FirmwareRolloutUpdateRequest) error {
+	if err := s.firmwareRepo.UpdateRollout(ctx, rolloutID, update); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrRolloutNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *FirmwareService) GetRolloutSummary(ctx context.Context, rolloutID uuid.UUID) (*models.FirmwareRolloutSummary, error) {
+	return s.firmwareRepo.GetRolloutSummary(ctx, rolloutID)
+}
+
+// CheckForUpdate tells a device whether an active rollout targets it with a
+// version newer than the one it is running. When several rollouts apply the
+// highest version wins.
+func (s *FirmwareService) CheckForUpdate(ctx context.Context, deviceID uuid.UUID) (*models.FirmwareCheckResponse, error) {
+	device, err := s.deviceRepo.GetDeviceByID(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if device == nil {
+		return nil, ErrDeviceNotFound
+	}
+
+	response := &models.FirmwareCheckResponse{
+		DeviceID:       device.DeviceID,
+		CurrentVersion: device.FirmwareVersion,
+	}
+
+	rollouts, err := s.firmwareRepo.GetActiveRollouts(ctx, device.DeviceType, device.DeviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Un dispositivo con versión desconocida acepta cualquier versión publicada
+	current, currentErr := utils.ParseSemVer(device.FirmwareVersion)
+
+	var best *models.ActiveFirmwareRollout
+	var bestVersion utils.SemVer
+	for _, rollout := range rollouts {
+		if !rolloutTargetsDevice(&rollout.FirmwareRollout, device.DeviceID) {
+			continue
+		}
+
+		version, err := utils.ParseSemVer(rollout.Release.Version)
+		if err != nil {
+			continue
+		}
+		if currentErr == nil && version.Compare(current) <= 0 {
+			continue
+		}
+		if best == nil || version.Compare(bestVersion) > 0 {
+			best, bestVersion = rollout, version
+		}
+	}
+
+	if best != nil {
+		response.UpdateAvailable = true
+		response.RolloutID = &best.ID
+		response.Firmware = &best.Release
+	}
+
+	return response, nil
+}
+
+// ReportUpdate records the outcome of an update reported by a device. Only
+// devices of the release's type in the rollout cohort can report.
+func (s *FirmwareService) ReportUpdate(ctx context.Context, deviceID uuid.UUID, report *models.FirmwareUpdateReportRequest) error {
+	report.Status = strings.ToLower(report.Status)
+	if report.Status != "success" && report.Status != "failure" {
+		return errors.New("status must be 'success' or 'failure'")
+	}
+
+	summary, err := s.firmwareRepo.GetRolloutSummary(ctx, report.RolloutID)
+	if err != nil {
+		return err
+	}
+	if summary == nil {
+		return ErrRolloutNotFound
+	}
+
+	device, err := s.deviceRepo.GetDeviceByID(ctx, deviceID)
+	if err != nil {
+		return err
+	}
+	if device == nil {
+		return ErrDeviceNotFound
+	}
+	if device.DeviceType != summary.DeviceType || !rolloutTargetsDevice(&summary.FirmwareRollout, deviceID) {
+		return ErrDeviceNotInRollout
+	}
+
+	return s.firmwareRepo.CreateUpdateReport(ctx, deviceID, report)
+}
+
+func (s *FirmwareService) GetVersionDistribution(ctx context.Context, deviceType *string) ([]*models.FirmwareVersionDistribution, error) {
+	return s.firmwareRepo.GetVersionDistribution(ctx, deviceType)
+}
+
+// rolloutTargetsDevice reports whether a device belongs to a rollout cohort.
+// Percentage cohorts hash the rollout and device IDs so that a device keeps its
+// bucket for the whole rollout and raising the percentage only adds devices.
+func rolloutTargetsDevice(rollout *models.FirmwareRollout, deviceID uuid.UUID) bool {
+	for _, id := range rollout.DeviceIDs {
+		if id == deviceID {
+			return true
+		}
+	}
+
+	if rollout.Percentage == nil {
+		return false
+	}
+
+	h := fnv.New32a()
+	h.Write(rollout.ID[:])
+	h.Write(deviceID[:])
+	bucket := int(h.Sum32() % 100)
+
+	return bucket < *rollout.Percentage
+}
@@ -2,113 +2,455 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"log"
+	"math"
 	"time"
 
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/analytics"
 	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
 	"github.com/Waldir-TG/api-medical-heart-v1/internal/repositories"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/rules"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
 	"github.com/google/uuid"
 )
 
+// LoadQualityConfig reads the limits of the reading quality assessment from the environment
+func LoadQualityConfig() analytics.QualityConfig {
+	config := analytics.DefaultQualityConfig()
+	config.MinBPM = utils.GetEnvInt("QUALITY_MIN_BPM", config.MinBPM)
+	config.MaxBPM = utils.GetEnvInt("QUALITY_MAX_BPM", config.MaxBPM)
+	config.MaxJumpBPM = utils.GetEnvInt("QUALITY_MAX_JUMP_BPM", config.MaxJumpBPM)
+	config.JumpWindow = utils.GetEnvDuration("QUALITY_JUMP_WINDOW", config.JumpWindow)
+	config.FlatlineCount = utils.GetEnvInt("QUALITY_FLATLINE_COUNT", config.FlatlineCount)
+	config.MaxClockSkew = utils.GetEnvDuration("QUALITY_MAX_CLOCK_SKEW", config.MaxClockSkew)
+	return config
+}
+
 type HeartReadingService struct {
-	heartReadingRepo *repositories.HeartReadingRepository
+	heartReadingRepo  *repositories.HeartReadingRepository
+	arrhythmiaService *ArrhythmiaService
+	ruleService       *RuleService
+	thresholdService  *ThresholdService
+	baselineService   *BaselineService
+	annotationService *AnnotationService
+	localeService     *LocaleService
+	qualityConfig     analytics.QualityConfig
 }
 
-func NewHeartReadingService(heartReadingRepo *repositories.HeartReadingRepository) *HeartReadingService {
+func NewHeartReadingService(
+	heartReadingRepo *repositories.HeartReadingRepository,
+	arrhythmiaService *ArrhythmiaService,
+	ruleService *RuleService,
+	thresholdService *ThresholdService,
+	baselineService *BaselineService,
+	annotationService *AnnotationService,
+	localeService *LocaleService,
+	qualityConfig analytics.QualityConfig,
+) *HeartReadingService {
 	return &HeartReadingService{
-		heartReadingRepo: heartReadingRepo,
+		heartReadingRepo:  heartReadingRepo,
+		arrhythmiaService: arrhythmiaService,
+		ruleService:       ruleService,
+		thresholdService:  thresholdService,
+		baselineService:   baselineService,
+		annotationService: annotationService,
+		localeService:     localeService,
+		qualityConfig:     qualityConfig,
+	}
+}
+
+// CreateHeartReading creates a new heart reading.
+// Both the HTTP API and the MQTT bridge go through this method, so validation lives here.
+// When RR intervals are supplied, HRV is computed server-side and replaces the
+// device-reported variability, and the rhythm is screened for arrhythmias: a
+// conclusive screening replaces the device-reported irregularity flag.
+// The reading's quality is assessed against the previous readings of the same
+// device and stored as a server score with quality flags.
+// Once the reading is stored, it is checked against the patient's threshold
+// profiles and anomaly rules and marked evaluated; the reading worker retries
+// the evaluation if it fails.
+func (s *HeartReadingService) CreateHeartReading(ctx context.Context, reading *models.HeartReadingCreateRequest) (*models.HeartReading, error) {
+	// Las bandas de pecho pueden enviar solo intervalos RR: la frecuencia se
+	// deriva de ellos y se valida una vez calculada
+	deriveBPM := len(reading.RRIntervals) > 0 && reading.BPM == 0
+	if deriveBPM {
+		if err := utils.ValidateStructExcept(reading, "BPM"); err != nil {
+			return nil, err
+		}
+	} else if err := utils.ValidateStruct(reading); err != nil {
+		return nil, err
+	}
+
+	var hrv *models.HeartReadingHRV
+	var screening *analytics.RhythmScreening
+	if len(reading.RRIntervals) > 0 {
+		metrics, err := analytics.ComputeHRV(reading.RRIntervals)
+		if err != nil {
+			return nil, &utils.ValidationError{Fields: []string{"HeartReadingCreateRequest.RRIntervals: " + err.Error()}}
+		}
+
+		if deriveBPM {
+			reading.BPM = int(math.Round(metrics.MeanHR))
+			if err := utils.ValidateStruct(reading); err != nil {
+				return nil, err
+			}
+		}
+		rmssd := metrics.RMSSD
+		reading.Variability = &rmssd
+
+		hrv = newHeartReadingHRV(reading.RRIntervals, metrics)
+
+		screening, err = s.arrhythmiaService.ScreenRR(reading.RRIntervals)
+		if err != nil {
+			return nil, &utils.ValidationError{Fields: []string{"HeartReadingCreateRequest.RRIntervals: " + err.Error()}}
+		}
+		if screening.Conclusive {
+			reading.IrregularityDetected = screening.Irregular
+		}
+	}
+
+	quality, err := s.assessQuality(ctx, reading)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.heartReadingRepo.CreateHeartReading(ctx, reading, hrv, quality)
+	if err != nil {
+		return nil, err
+	}
+
+	// La lectura ya está guardada: un fallo al registrar los hallazgos no la invalida
+	if screening != nil {
+		if _, err := s.arrhythmiaService.RecordScreening(ctx, created, FindingSourceRR, screening); err != nil {
+			log.Printf("Arrhythmia screening for reading %s: %v", created.ID, err)
+		}
+	}
+	// Si la evaluación falla, la lectura queda sin evaluar y el worker la reintenta
+	if err := s.ProcessReading(ctx, created); err != nil {
+		log.Printf("Processing reading %s: %v", created.ID, err)
+	} else if err := s.heartReadingRepo.MarkEvaluated(ctx, created.ID); err != nil {
+		log.Printf("Processing reading %s: %v", created.ID, err)
+	}
+	return created, nil
+}
+
+// assessQuality scores a new reading. Device checks (jumps, duplicates,
+// flat-lining) need a device and are skipped for manual entries.
+func (s *HeartReadingService) assessQuality(ctx context.Context, reading *models.HeartReadingCreateRequest) (*models.ReadingQuality, error) {
+	now := time.Now()
+	input := analytics.QualityInput{
+		Sample:            analytics.QualitySample{Time: now, BPM: reading.BPM},
+		OxygenLevel:       reading.OxygenLevel,
+		SystolicPressure:  reading.SystolicPressure,
+		DiastolicPressure: reading.DiastolicPressure,
+		Temperature:       reading.Temperature,
+		Now:               now,
+	}
+	if reading.Time != nil {
+		input.Sample.Time = *reading.Time
+	}
+
+	if reading.DeviceID != nil {
+		previous, err := s.heartReadingRepo.GetRecentDeviceReadings(ctx, *reading.DeviceID,
+			input.Sample.Time.Add(s.qualityConfig.DuplicateTolerance), max(s.qualityConfig.FlatlineCount, 2))
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range previous {
+			input.Previous = append(input.Previous, analytics.QualitySample{Time: p.Time, BPM: p.BPM})
+		}
+	}
+
+	assessment := analytics.AssessQuality(input, s.qualityConfig)
+	return &models.ReadingQuality{Score: assessment.Score, Flags: assessment.Flags}, nil
+}
+
+// filterByQuality drops the readings scored below minQuality. Readings
+// without a score count as full quality.
+func filterByQuality(readings []*models.HeartReading, minQuality *float64) []*models.HeartReading {
+	if minQuality == nil {
+		return readings
+	}
+	filtered := make([]*models.HeartReading, 0, len(readings))
+	for _, reading := range readings {
+		if analytics.MeetsQuality(reading.QualityScore, *minQuality) {
+			filtered = append(filtered, reading)
+		}
+	}
+	return filtered
+}
+
+// ProcessReading checks a stored reading against the patient's threshold
+// profiles and anomaly rules. It runs on ingest and, for readings left
+// unevaluated, from the reading worker. A reading raises each alert type at
+// most once, so a repeated run, such as a rolled back worker batch, does not
+// duplicate its alerts. Readings scored below the alert quality floor
+// (ALERT_MIN_QUALITY) are stored and charted but raise no alerts.
+func (s *HeartReadingService) ProcessReading(ctx context.Context, reading *models.HeartReading) error {
+	if !s.ruleService.alertable(reading) {
+		return nil
+	}
+	if err := s.thresholdService.CheckReading(ctx, reading); err != nil {
+		return fmt.Errorf("threshold check: %w", err)
+	}
+	if err := s.ruleService.EvaluateReading(ctx, reading); err != nil {
+		return fmt.Errorf("rule evaluation: %w", err)
 	}
+	return nil
+}
+
+// GetFindingsByReading returns the arrhythmia screening findings of a reading
+func (s *HeartReadingService) GetFindingsByReading(ctx context.Context, readingID uuid.UUID) ([]*models.ReadingFinding, error) {
+	return s.arrhythmiaService.GetFindingsByReading(ctx, readingID)
+}
+
+// GetHRVSummaries aggregates server-computed HRV metrics per window (hour, day,
+// week or month) of the patient's local time
+func (s *HeartReadingService) GetHRVSummaries(
+	ctx context.Context,
+	patientID uuid.UUID,
+	startTime *time.Time,
+	endTime *time.Time,
+	window string,
+) ([]*models.HRVSummary, error) {
+	return s.heartReadingRepo.GetHRVSummaries(ctx, patientID, startTime, endTime, window, s.localeService.PatientLocation(ctx, patientID))
 }
 
-// CreateHeartReading creates a new heart reading
-func (s *HeartReadingService) CreateHeartReading(ctx context.Context, reading *models.HeartReadingCreateRequest) error {
-	return s.heartReadingRepo.CreateHeartReading(ctx, reading)
+func newHeartReadingHRV(rr []float64, metrics *analytics.HRV) *models.HeartReadingHRV {
+	return &models.HeartReadingHRV{
+		RRIntervals:   rr,
+		IntervalCount: metrics.IntervalCount,
+		RejectedCount: metrics.RejectedCount,
+		MeanRR:        metrics.MeanRR,
+		MeanHR:        metrics.MeanHR,
+		SDNN:          metrics.SDNN,
+		RMSSD:         metrics.RMSSD,
+		PNN50:         metrics.PNN50,
+		LFPower:       metrics.LFPower,
+		HFPower:       metrics.HFPower,
+		LFHFRatio:     metrics.LFHFRatio,
+	}
 }
 
-// GetPatientHeartReadings retrieves heart readings for a specific patient
+// GetPatientHeartReadings returns a filtered page of a patient's heart readings
 func (s *HeartReadingService) GetPatientHeartReadings(
 	ctx context.Context,
 	params *models.HeartReadingQueryParams,
-) ([]*models.HeartReadingResponse, error) {
+) (*models.HeartReadingListResponse, error) {
+	if err := utils.ValidateStruct(params); err != nil {
+		return nil, err
+	}
+	if params.MinBPM != nil && params.MaxBPM != nil && *params.MinBPM > *params.MaxBPM {
+		return nil, &utils.ValidationError{Fields: []string{"min_bpm must not be greater than max_bpm"}}
+	}
+	if params.StartTime != nil && params.EndTime != nil && params.StartTime.After(*params.EndTime) {
+		return nil, &utils.ValidationError{Fields: []string{"start_time must not be after end_time"}}
+	}
 	return s.heartReadingRepo.GetPatientHeartReadings(ctx, params)
 }
 
-// GetHeartRateStats calculates statistics for heart readings
+// GetHeartRateStats calculates statistics for heart readings between two
+// instants (default: the 366 days up to end time, or up to now). Low and high
+// counts use the threshold profile that applied to each reading, so the range
+// may be at most 366 days. The filter can leave out artifacts and low-quality
+// readings.
 func (s *HeartReadingService) GetHeartRateStats(
 	ctx context.Context,
 	patientID uuid.UUID,
 	startTime *time.Time,
 	endTime *time.Time,
+	filter models.ReadingFilter,
 ) (*models.HeartRateStats, error) {
-	return s.heartReadingRepo.GetHeartRateStats(ctx, patientID, startTime, endTime)
+	end := time.Now()
+	if endTime != nil {
+		end = *endTime
+	}
+	start := end.Add(-maxOutOfRangeCountRange)
+	if startTime != nil {
+		start = *startTime
+	}
+
+	// Las bajas y altas primero: validan el rango
+	low, high, err := s.thresholdService.CountOutOfRange(ctx, patientID, start, end, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := s.heartReadingRepo.GetHeartRateStats(ctx, patientID, &start, &end, filter)
+	if err != nil {
+		return nil, err
+	}
+	stats.LowReadingsCount, stats.HighReadingsCount = low, high
+	return stats, nil
 }
 
-// GetHeartRateTrends retrieves heart rate trends over time
+// maxTrendPeriods limita los periodos de una tendencia con huecos rellenados
+const maxTrendPeriods = 10000
+
+// GetHeartRateTrends retrieves heart rate trends over time together with the
+// annotations in the same range, so that charts can show them as markers.
+// Periods follow the local time of params.Timezone, by default the patient's.
 func (s *HeartReadingService) GetHeartRateTrends(
+	ctx context.Context,
+	params *models.HeartRateTrendQueryParams,
+) (*models.HeartRateTrendsResponse, error) {
+	resolution, err := utils.ParseTrendResolution(params.Resolution)
+	if err != nil {
+		return nil, &utils.ValidationError{Fields: []string{
+			"resolution must be one of minute, hour, day, week, month or an interval such as 15m, 6h or 2d",
+		}}
+	}
+
+	loc, err := s.localeService.ResolvePatientLocation(ctx, params.PatientID, params.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	if params.FillGaps {
+		if params.StartTime == nil || params.EndTime == nil || !params.StartTime.Before(*params.EndTime) {
+			return nil, &utils.ValidationError{Fields: []string{"fill_gaps requires start_time before end_time"}}
+		}
+		if params.EndTime.Sub(*params.StartTime)/resolution.Duration() > maxTrendPeriods {
+			return nil, &utils.ValidationError{Fields: []string{
+				fmt.Sprintf("fill_gaps allows at most %d periods; use a coarser resolution", maxTrendPeriods),
+			}}
+		}
+	}
+
+	trends, err := s.heartReadingRepo.GetHeartRateTrends(
+		ctx, params.PatientID, params.StartTime, params.EndTime,
+		resolution, loc, params.FillGaps, params.Percentiles,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	annotations, err := s.annotationService.GetAnnotations(ctx, &models.AnnotationQueryParams{
+		PatientID: params.PatientID,
+		StartTime: params.StartTime,
+		EndTime:   params.EndTime,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if trends == nil {
+		trends = []*models.HeartRateTrend{}
+	}
+	return &models.HeartRateTrendsResponse{Trends: trends, Annotations: annotations}, nil
+}
+
+// EvaluateAnomalyRules evaluates the patient's anomaly rule set over a time
+// range, ignoring readings scored below minQuality
+func (s *HeartReadingService) EvaluateAnomalyRules(
 	ctx context.Context,
 	patientID uuid.UUID,
 	startTime *time.Time,
 	endTime *time.Time,
-	resolution string,
-) ([]*models.HeartRateTrend, error) {
-	return s.heartReadingRepo.GetHeartRateTrends(ctx, patientID, startTime, endTime, resolution)
+	minQuality *float64,
+) ([]*rules.Violation, error) {
+	return s.ruleService.EvaluateRange(ctx, patientID, startTime, endTime, minQuality)
 }
 
-// GetHeartRateAnomalies detects anomalies in heart rate readings
-func (s *HeartReadingService) GetHeartRateAnomalies(
+// GetBaselines returns the patient's personal baselines, recomputing them first when refresh is set
+func (s *HeartReadingService) GetBaselines(ctx context.Context, patientID uuid.UUID, refresh bool) ([]*models.PatientBaseline, error) {
+	if refresh {
+		return s.baselineService.RecomputePatient(ctx, patientID)
+	}
+	return s.baselineService.GetBaselines(ctx, patientID)
+}
+
+// GetBaselineAnomalies flags readings that deviate from the patient's
+// personal baseline, ignoring readings scored below minQuality
+func (s *HeartReadingService) GetBaselineAnomalies(
 	ctx context.Context,
 	patientID uuid.UUID,
 	startTime *time.Time,
 	endTime *time.Time,
-	threshold float64,
+	k float64,
+	minQuality *float64,
 ) ([]*models.HeartRateAnomaly, error) {
-	return s.heartReadingRepo.GetHeartRateAnomalies(ctx, patientID, startTime, endTime, threshold)
+	return s.baselineService.DetectAnomalies(ctx, patientID, startTime, endTime, k, minQuality)
 }
 
-// ProcessUnprocessedReadings processes any unprocessed heart readings
-func (s *HeartReadingService) ProcessUnprocessedReadings(ctx context.Context) error {
-	return s.heartReadingRepo.ProcessUnprocessedReadings(ctx)
+// GetHeartRateAnomalies detects anomalies in heart rate readings against the
+// fixed expected range of the detect_heart_rate_anomalies function
+func (s *HeartReadingService) GetHeartRateAnomalies(
+	ctx context.Context,
+	patientID uuid.UUID,
+	startTime *time.Time,
+	endTime *time.Time,
+	threshold float64,
+	filter models.ReadingFilter,
+) ([]*models.HeartRateAnomaly, error) {
+	return s.heartReadingRepo.GetHeartRateAnomalies(ctx, patientID, startTime, endTime, threshold, filter)
 }
 
-// UpdateHeartReading updates an existing heart reading
+// UpdateHeartReading updates an existing heart reading, keeping the previous
+// values in the reading's history
 func (s *HeartReadingService) UpdateHeartReading(
 	ctx context.Context,
 	readingID uuid.UUID,
 	patientID uuid.UUID,
 	update *models.HeartReadingUpdateRequest,
+	changedBy *uuid.UUID,
 ) error {
-	return s.heartReadingRepo.UpdateHeartReading(ctx, readingID, patientID, update)
+	if err := utils.ValidateStruct(update); err != nil {
+		return err
+	}
+
+	found, err := s.heartReadingRepo.UpdateHeartReading(ctx, readingID, patientID, update, changedBy)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrHeartReadingNotFound
+	}
+	return nil
 }
 
-// GetHeartReadingByID retrieves a specific heart reading by ID
-func (s *HeartReadingService) GetHeartReadingByID(
+// DeleteHeartReading soft-deletes a heart reading. The reading stays in the
+// database, out of listings and statistics, and the deletion is recorded in
+// its history.
+func (s *HeartReadingService) DeleteHeartReading(
 	ctx context.Context,
 	readingID uuid.UUID,
-) (*models.HeartReading, error) {
-	return s.heartReadingRepo.GetHeartReadingByID(ctx, readingID)
+	patientID uuid.UUID,
+	request *models.HeartReadingDeleteRequest,
+	deletedBy *uuid.UUID,
+) error {
+	if err := utils.ValidateStruct(request); err != nil {
+		return err
+	}
+
+	found, err := s.heartReadingRepo.SoftDeleteHeartReading(ctx, readingID, patientID, deletedBy, request.Reason)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrHeartReadingNotFound
+	}
+	return nil
 }
 
-// ScheduleReadingsProcessing schedules the processing of unprocessed readings
-// This could be called by a background worker or scheduler
-func (s *HeartReadingService) ScheduleReadingsProcessing(ctx context.Context) error {
-	return s.ProcessUnprocessedReadings(ctx)
+// GetHeartReadingHistory returns the edit history of a reading, including
+// readings that were deleted
+func (s *HeartReadingService) GetHeartReadingHistory(ctx context.Context, readingID uuid.UUID) ([]*models.HeartReadingVersion, error) {
+	exists, err := s.heartReadingRepo.HeartReadingExists(ctx, readingID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrHeartReadingNotFound
+	}
+	return s.heartReadingRepo.GetHeartReadingHistory(ctx, readingID)
 }
 
-// GetPatientHeartReadingsWithTimeRange is a convenience method that creates a query params object
-func (s *HeartReadingService) GetPatientHeartReadingsWithTimeRange(
+// GetHeartReadingByID retrieves a specific heart reading by ID
+func (s *HeartReadingService) GetHeartReadingByID(
 	ctx context.Context,
-	patientID uuid.UUID,
-	startTime *time.Time,
-	endTime *time.Time,
-	limit *int,
-	offset *int,
-) ([]*models.HeartReadingResponse, error) {
-	params := &models.HeartReadingQueryParams{
-		PatientID: patientID,
-		StartTime: startTime,
-		EndTime:   endTime,
-		Limit:     limit,
-		Offset:    offset,
-	}
-	
-	return s.GetPatientHeartReadings(ctx, params)
-}
\ No newline at end of file
+	readingID uuid.UUID,
+) (*models.HeartReading, error) {
+	return s.heartReadingRepo.GetHeartReadingByID(ctx, readingID)
+}
@@ -0,0 +1,292 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/analytics"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/importer"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/repositories"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+)
+
+// Fuente de las lecturas importadas de un CSV que no la indica
+const csvImportSource = "csv_import"
+
+// ImportConfig controls imports of historical readings
+type ImportConfig struct {
+	BatchSize int // lecturas guardadas por transacción
+	MaxIssues int // registros rechazados detallados en el informe
+}
+
+// LoadImportConfig reads the import configuration from the environment
+func LoadImportConfig() ImportConfig {
+	return ImportConfig{
+		BatchSize: max(utils.GetEnvInt("IMPORT_BATCH_SIZE", 1000), 1),
+		MaxIssues: max(utils.GetEnvInt("IMPORT_MAX_ISSUES", 200), 0),
+	}
+}
+
+// ImportService imports historical heart readings from CSV files and wearable
+// exports. Readings are validated like new ones, scored for quality, stored
+// with entry method "imported" and deduplicated against the stored readings
+// and the rest of the file.
+type ImportService struct {
+	heartReadingRepo *repositories.HeartReadingRepository
+	localeService    *LocaleService
+	qualityConfig    analytics.QualityConfig
+	config           ImportConfig
+}
+
+func NewImportService(
+	heartReadingRepo *repositories.HeartReadingRepository,
+	localeService *LocaleService,
+	qualityConfig analytics.QualityConfig,
+	config ImportConfig,
+) *ImportService {
+	return &ImportService{
+		heartReadingRepo: heartReadingRepo,
+		localeService:    localeService,
+		qualityConfig:    qualityConfig,
+		config:           config,
+	}
+}
+
+// ImportReadings reads a file and stores its readings in batches, or with
+// DryRun only validates it. Rejected records are listed in the report; a
+// malformed file or a database error stops the import, keeping the batches
+// already stored, which a new import of the same file skips as duplicates.
+func (s *ImportService) ImportReadings(
+	ctx context.Context,
+	file io.Reader,
+	request *models.ReadingImportRequest,
+) (*models.ReadingImportReport, error) {
+	if err := utils.ValidateStruct(request); err != nil {
+		return nil, err
+	}
+	if err := importer.ValidateMapping(request.Mapping); err != nil {
+		return nil, &utils.ValidationError{Fields: []string{"mapping: " + err.Error()}}
+	}
+
+	settings, err := s.localeService.GetPatientLocale(ctx, request.PatientID)
+	if err != nil {
+		return nil, err
+	}
+	timezone := request.Timezone
+	if timezone == "" {
+		timezone = settings.Timezone
+	}
+	loc, err := loadTimezone(timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	source := request.Source
+	if source == "" {
+		source = importer.Sources[request.Format]
+	}
+	if source == "" {
+		source = csvImportSource
+	}
+	readingType := request.ReadingType
+	if readingType == "" {
+		readingType = "resting"
+	}
+
+	run := &readingImport{
+		service:     s,
+		request:     request,
+		source:      source,
+		readingType: readingType,
+		seen:        importer.NewDeduplicator(),
+		report: &models.ReadingImportReport{
+			Format: request.Format,
+			Source: source,
+			DryRun: request.DryRun,
+			Issues: []models.ReadingImportIssue{},
+		},
+	}
+
+	options := importer.Options{Mapping: request.Mapping, TimeFormat: request.TimeFormat, Location: loc}
+	err = importer.Parse(file, request.Format, options, func(record *importer.Record, err error) error {
+		return run.add(ctx, record, err)
+	})
+	if err == nil {
+		err = run.flush(ctx)
+	}
+	if run.err != nil {
+		return run.report, run.err
+	}
+	if err != nil {
+		// Cualquier otro error viene del propio archivo
+		return run.report, &utils.ValidationError{Fields: []string{"file: " + err.Error()}}
+	}
+	return run.report, nil
+}
+
+// readingImport is the state of one import
+type readingImport struct {
+	service     *ImportService
+	request     *models.ReadingImportRequest
+	source      string
+	readingType string
+	seen        *importer.Deduplicator
+	previous    []analytics.QualitySample // últimas lecturas del archivo, la más reciente primero
+	batch       []*models.HeartReading
+	report      *models.ReadingImportReport
+	err         error // error al guardar, que detiene la importación
+}
+
+// add validates one record and queues it for the next batch
+func (i *readingImport) add(ctx context.Context, record *importer.Record, err error) error {
+	i.report.Total++
+	if err != nil {
+		var recordErr *importer.RecordError
+		if !errors.As(err, &recordErr) {
+			return err
+		}
+		i.reject(recordErr.Position, recordErr.Err.Error())
+		return nil
+	}
+
+	reading, err := i.reading(record)
+	if err != nil {
+		i.reject(record.Position, err.Error())
+		return nil
+	}
+
+	if i.seen.Seen(reading.Time, reading.BPM) {
+		i.report.Duplicates++
+		return nil
+	}
+
+	i.batch = append(i.batch, reading)
+	if len(i.batch) >= i.service.config.BatchSize {
+		return i.flush(ctx)
+	}
+	return nil
+}
+
+// reading builds the reading stored for a record, validated like a new
+// reading and scored against the previous records of the file
+func (i *readingImport) reading(record *importer.Record) (*models.HeartReading, error) {
+	create := &models.HeartReadingCreateRequest{
+		PatientID:         i.request.PatientID,
+		EntryMethod:       "imported",
+		EnteredBy:         i.request.EnteredBy,
+		ReadingType:       record.ReadingType,
+		Source:            record.Source,
+		BPM:               record.BPM,
+		Variability:       record.Variability,
+		OxygenLevel:       record.OxygenLevel,
+		SystolicPressure:  record.SystolicPressure,
+		DiastolicPressure: record.DiastolicPressure,
+		Temperature:       record.Temperature,
+		ActivityLevel:     record.ActivityLevel,
+		Notes:             record.Notes,
+		ReliabilityScore:  record.ReliabilityScore,
+		Time:              &record.Time,
+	}
+	if create.ReadingType == "" {
+		create.ReadingType = i.readingType
+	}
+	if create.Source == "" {
+		create.Source = i.source
+	}
+
+	if err := utils.ValidateStruct(create); err != nil {
+		var validationErr *utils.ValidationError
+		if errors.As(err, &validationErr) {
+			return nil, errors.New(strings.Join(validationErr.Fields, "; "))
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if record.Time.After(now.Add(i.service.qualityConfig.MaxClockSkew)) {
+		return nil, fmt.Errorf("time %s is in the future", record.Time.Format(time.RFC3339))
+	}
+
+	sample := analytics.QualitySample{Time: record.Time, BPM: record.BPM}
+	assessment := analytics.AssessQuality(analytics.QualityInput{
+		Sample:            sample,
+		Previous:          i.previous,
+		OxygenLevel:       create.OxygenLevel,
+		SystolicPressure:  create.SystolicPressure,
+		DiastolicPressure: create.DiastolicPressure,
+		Temperature:       create.Temperature,
+		Now:               now,
+	}, i.service.qualityConfig)
+
+	// Los archivos de los wearables son la serie de un solo dispositivo
+	keep := max(i.service.qualityConfig.FlatlineCount, 2) - 1
+	i.previous = append([]analytics.QualitySample{sample}, i.previous[:min(len(i.previous), keep)]...)
+
+	return &models.HeartReading{
+		PatientID:         create.PatientID,
+		EntryMethod:       create.EntryMethod,
+		EnteredBy:         create.EnteredBy,
+		ReadingType:       create.ReadingType,
+		Source:            create.Source,
+		BPM:               create.BPM,
+		Variability:       create.Variability,
+		OxygenLevel:       create.OxygenLevel,
+		SystolicPressure:  create.SystolicPressure,
+		DiastolicPressure: create.DiastolicPressure,
+		Temperature:       create.Temperature,
+		ActivityLevel:     create.ActivityLevel,
+		Notes:             create.Notes,
+		ReliabilityScore:  create.ReliabilityScore,
+		QualityScore:      &assessment.Score,
+		QualityFlags:      assessment.Flags,
+		Time:              record.Time,
+	}, nil
+}
+
+// flush deduplicates the queued readings against the stored ones and, unless
+// this is a dry run, stores them
+func (i *readingImport) flush(ctx context.Context) error {
+	if len(i.batch) == 0 {
+		return nil
+	}
+	duplicates, err := i.service.heartReadingRepo.ImportHeartReadings(ctx, i.request.PatientID, i.batch, i.request.DryRun)
+	if err != nil {
+		i.err = err
+		return err
+	}
+
+	for n, reading := range i.batch {
+		if duplicates[n] {
+			i.report.Duplicates++
+			continue
+		}
+		i.report.Valid++
+		if !i.request.DryRun {
+			i.report.Imported++
+		}
+		if i.report.FirstTime == nil || reading.Time.Before(*i.report.FirstTime) {
+			t := reading.Time
+			i.report.FirstTime = &t
+		}
+		if i.report.LastTime == nil || reading.Time.After(*i.report.LastTime) {
+			t := reading.Time
+			i.report.LastTime = &t
+		}
+	}
+	i.batch = i.batch[:0]
+	return nil
+}
+
+// reject records a record that cannot be imported
+func (i *readingImport) reject(position int, message string) {
+	i.report.Invalid++
+	if len(i.report.Issues) >= i.service.config.MaxIssues {
+		i.report.IssuesTruncated = true
+		return
+	}
+	i.report.Issues = append(i.report.Issues, models.ReadingImportIssue{Position: position, Message: message})
+}
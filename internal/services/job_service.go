@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/repositories"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/scheduler"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+)
+
+// Nombres de los trabajos de mantenimiento
+const (
+	JobSessionCleanup = "session-cleanup"
+	JobDeviceMonitor  = "device-monitor"
+	JobBaselines      = "baseline-recompute"
+	JobDailyReports   = "daily-reports"
+	JobDataRetention  = "data-retention"
+)
+
+var (
+	ErrJobNotFound = scheduler.ErrJobNotFound
+	ErrJobRunning  = scheduler.ErrJobRunning
+)
+
+// JobSchedulerConfig holds the scheduler settings and the schedule of each
+// maintenance job (cron expression, @daily or "@every <duration>")
+type JobSchedulerConfig struct {
+	PollInterval   time.Duration
+	Timezone       string
+	SessionCleanup string
+	DeviceMonitor  string
+	Baselines      string
+	DailyReports   string
+	DataRetention  string
+}
+
+// LoadJobSchedulerConfig reads the scheduler configuration from the
+// environment. DEVICE_MONITOR_INTERVAL and BASELINE_INTERVAL still set the
+// default schedule of their jobs.
+func LoadJobSchedulerConfig() JobSchedulerConfig {
+	deviceMonitorInterval := utils.GetEnvDuration("DEVICE_MONITOR_INTERVAL", 5*time.Minute)
+	baselineInterval := utils.GetEnvDuration("BASELINE_INTERVAL", 6*time.Hour)
+
+	return JobSchedulerConfig{
+		PollInterval:   utils.GetEnvDuration("SCHEDULER_POLL_INTERVAL", 15*time.Second),
+		Timezone:       utils.GetEnvString("SCHEDULER_TIMEZONE", "UTC"),
+		SessionCleanup: utils.GetEnvString("JOB_SESSION_CLEANUP_SCHEDULE", "@hourly"),
+		DeviceMonitor:  utils.GetEnvString("JOB_DEVICE_MONITOR_SCHEDULE", "@every "+deviceMonitorInterval.String()),
+		Baselines:      utils.GetEnvString("JOB_BASELINES_SCHEDULE", "@every "+baselineInterval.String()),
+		DailyReports:   utils.GetEnvString("JOB_DAILY_REPORTS_SCHEDULE", "15 0 * * *"),
+		DataRetention:  utils.GetEnvString("JOB_DATA_RETENTION_SCHEDULE", "30 3 * * *"),
+	}
+}
+
+// NewJobScheduler creates the scheduler and registers the maintenance jobs
+func NewJobScheduler(
+	jobRepo *repositories.JobRepository,
+	config JobSchedulerConfig,
+	maintenanceService *MaintenanceService,
+	deviceMonitorService *DeviceMonitorService,
+	baselineService *BaselineService,
+	reportService *ReportService,
+) (*scheduler.Scheduler, error) {
+	location, err := time.LoadLocation(config.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SCHEDULER_TIMEZONE: %w", err)
+	}
+	sched := scheduler.New(jobRepo, scheduler.Config{PollInterval: config.PollInterval, Location: location})
+
+	jobs := []scheduler.Job{
+		{
+			Name:        JobSessionCleanup,
+			Description: "Delete expired and invalidated sessions",
+			Schedule:    config.SessionCleanup,
+			Timeout:     10 * time.Minute,
+			Run: func(ctx context.Context) (string, error) {
+				deleted, err := maintenanceService.CleanupSessions(ctx)
+				return fmt.Sprintf("%d sessions deleted", deleted), err
+			},
+		},
+		{
+			Name:        JobDeviceMonitor,
+			Description: "Raise alerts for offline and low battery devices",
+			Schedule:    config.DeviceMonitor,
+			Timeout:     5 * time.Minute,
+			Run: func(ctx context.Context) (string, error) {
+				raised, err := deviceMonitorService.CheckDevices(ctx)
+				return fmt.Sprintf("%d alerts raised", raised), err
+			},
+		},
+		{
+			Name:        JobBaselines,
+			Description: "Recompute personal heart rate baselines",
+			Schedule:    config.Baselines,
+			Timeout:     time.Hour,
+			Run: func(ctx context.Context) (string, error) {
+				updated, err := baselineService.RecomputeAll(ctx)
+				return fmt.Sprintf("%d patients updated", updated), err
+			},
+		},
+		{
+			Name:        JobDailyReports,
+			Description: "Generate the patient reports of the last two days",
+			Schedule:    config.DailyReports,
+			Timeout:     time.Hour,
+			Run: func(ctx context.Context) (string, error) {
+				// Al oeste de UTC el día local de ayer aún no ha terminado:
+				// esos informes salen en la siguiente ejecución
+				today := time.Now().UTC()
+				generated := 0
+				for _, offset := range []int{-2, -1} {
+					n, err := reportService.GenerateDailyReports(ctx, today.AddDate(0, 0, offset))
+					generated += n
+					if err != nil {
+						return fmt.Sprintf("%d reports generated", generated), err
+					}
+				}
+				return fmt.Sprintf("%d reports generated for %s and %s", generated,
+					today.AddDate(0, 0, -2).Format(time.DateOnly), today.AddDate(0, 0, -1).Format(time.DateOnly)), nil
+			},
+		},
+		{
+			Name:        JobDataRetention,
+			Description: "Delete device telemetry, job history and MQTT message keys past their retention period",
+			Schedule:    config.DataRetention,
+			Timeout:     time.Hour,
+			Run: func(ctx context.Context) (string, error) {
+				result, err := maintenanceService.ApplyRetention(ctx)
+				return fmt.Sprintf("%d telemetry rows, %d job runs and %d message keys deleted",
+					result.Telemetry, result.JobRuns, result.MessageKeys), err
+			},
+		},
+	}
+
+	for _, job := range jobs {
+		if err := sched.Register(job); err != nil {
+			return nil, err
+		}
+	}
+	return sched, nil
+}
+
+// JobService exposes the scheduled jobs and their history to admins
+type JobService struct {
+	jobRepo   *repositories.JobRepository
+	scheduler *scheduler.Scheduler
+}
+
+func NewJobService(jobRepo *repositories.JobRepository, scheduler *scheduler.Scheduler) *JobService {
+	return &JobService{
+		jobRepo:   jobRepo,
+		scheduler: scheduler,
+	}
+}
+
+func (s *JobService) GetJobs(ctx context.Context) ([]*models.ScheduledJob, error) {
+	return s.jobRepo.GetJobs(ctx)
+}
+
+func (s *JobService) GetJob(ctx context.Context, name string) (*models.ScheduledJob, error) {
+	job, err := s.jobRepo.GetJob(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, ErrJobNotFound
+	}
+	return job, nil
+}
+
+// TriggerJob starts a job now and returns the ID of its run
+func (s *JobService) TriggerJob(ctx context.Context, name string) (int64, error) {
+	return s.scheduler.Trigger(ctx, name)
+}
+
+// SetJobEnabled pauses or resumes a job's schedule
+func (s *JobService) SetJobEnabled(ctx context.Context, name string, enabled bool) error {
+	updated, err := s.jobRepo.SetJobEnabled(ctx, name, enabled)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrJobNotFound
+	}
+	return nil
+}
+
+// GetJobRuns returns the latest runs of a job, newest first
+func (s *JobService) GetJobRuns(ctx context.Context, name string, limit int) ([]*models.JobRun, error) {
+	if _, err := s.GetJob(ctx, name); err != nil {
+		return nil, err
+	}
+	return s.jobRepo.GetJobRuns(ctx, name, limit)
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/repositories"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+	"github.com/google/uuid"
+)
+
+var ErrUserNotFound = errors.New("user not found")
+
+// LocaleService manages the time zone and locale of patients and users and
+// resolves the zone in which a patient's days are counted
+type LocaleService struct {
+	localeRepo *repositories.LocaleRepository
+}
+
+func NewLocaleService(localeRepo *repositories.LocaleRepository) *LocaleService {
+	return &LocaleService{localeRepo: localeRepo}
+}
+
+func (s *LocaleService) GetPatientLocale(ctx context.Context, patientID uuid.UUID) (*models.LocaleSettings, error) {
+	settings, err := s.localeRepo.GetPatientLocale(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		return nil, ErrPatientNotFound
+	}
+	return settings, nil
+}
+
+func (s *LocaleService) UpdatePatientLocale(
+	ctx context.Context,
+	patientID uuid.UUID,
+	update *models.LocaleSettingsUpdateRequest,
+) (*models.LocaleSettings, error) {
+	if err := utils.ValidateStruct(update); err != nil {
+		return nil, err
+	}
+	settings, err := s.localeRepo.UpdatePatientLocale(ctx, patientID, update)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		return nil, ErrPatientNotFound
+	}
+	return settings, nil
+}
+
+func (s *LocaleService) GetUserLocale(ctx context.Context, userID uuid.UUID) (*models.LocaleSettings, error) {
+	settings, err := s.localeRepo.GetUserLocale(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		return nil, ErrUserNotFound
+	}
+	return settings, nil
+}
+
+func (s *LocaleService) UpdateUserLocale(
+	ctx context.Context,
+	userID uuid.UUID,
+	update *models.LocaleSettingsUpdateRequest,
+) (*models.LocaleSettings, error) {
+	if err := utils.ValidateStruct(update); err != nil {
+		return nil, err
+	}
+	settings, err := s.localeRepo.UpdateUserLocale(ctx, userID, update)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		return nil, ErrUserNotFound
+	}
+	return settings, nil
+}
+
+// PatientLocation returns the patient's time zone. Analytics must not fail
+// because of it, so an unknown patient, an unknown zone or a database error
+// give UTC.
+func (s *LocaleService) PatientLocation(ctx context.Context, patientID uuid.UUID) *time.Location {
+	settings, err := s.localeRepo.GetPatientLocale(ctx, patientID)
+	if err != nil {
+		log.Printf("Patient %s time zone: %v", patientID, err)
+		return time.UTC
+	}
+	if settings == nil {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		log.Printf("Patient %s time zone %q: %v", patientID, settings.Timezone, err)
+		return time.UTC
+	}
+	return loc
+}
+
+// ResolvePatientLocation returns the zone named by timezone, or the patient's
+// own when it is empty
+func (s *LocaleService) ResolvePatientLocation(ctx context.Context, patientID uuid.UUID, timezone string) (*time.Location, error) {
+	if timezone == "" {
+		return s.PatientLocation(ctx, patientID), nil
+	}
+	return loadTimezone(timezone)
+}
+
+// loadTimezone loads an IANA time zone given in a request
+func loadTimezone(name string) (*time.Location, error) {
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "" || name == "Local" {
+		return nil, &utils.ValidationError{Fields: []string{"timezone must be an IANA time zone such as America/Lima"}}
+	}
+	return loc, nil
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/repositories"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+)
+
+// RetentionConfig controls how long expired and historical rows are kept
+type RetentionConfig struct {
+	SessionGrace   time.Duration // las sesiones expiradas se conservan este tiempo
+	TelemetryDays  int
+	JobRunDays     int
+	MessageKeyDays int // claves de mensajes MQTT; deben cubrir la ventana de reentrega del broker
+}
+
+// LoadRetentionConfig reads the retention configuration from the environment
+func LoadRetentionConfig() RetentionConfig {
+	return RetentionConfig{
+		SessionGrace:   utils.GetEnvDuration("SESSION_CLEANUP_GRACE", 24*time.Hour),
+		TelemetryDays:  utils.GetEnvInt("RETENTION_TELEMETRY_DAYS", 180),
+		JobRunDays:     utils.GetEnvInt("RETENTION_JOB_RUN_DAYS", 30),
+		MessageKeyDays: utils.GetEnvInt("RETENTION_MESSAGE_KEY_DAYS", 7),
+	}
+}
+
+// RetentionResult counts the rows removed by a retention run
+type RetentionResult struct {
+	Telemetry   int64
+	JobRuns     int64
+	MessageKeys int64
+}
+
+// MaintenanceService removes expired sessions and data past its retention
+// period. Heart readings are clinical data and are never removed here.
+type MaintenanceService struct {
+	maintenanceRepo *repositories.MaintenanceRepository
+	jobRepo         *repositories.JobRepository
+	config          RetentionConfig
+}
+
+func NewMaintenanceService(
+	maintenanceRepo *repositories.MaintenanceRepository,
+	jobRepo *repositories.JobRepository,
+	config RetentionConfig,
+) *MaintenanceService {
+	return &MaintenanceService{
+		maintenanceRepo: maintenanceRepo,
+		jobRepo:         jobRepo,
+		config:          config,
+	}
+}
+
+// CleanupSessions removes sessions that expired or were invalidated more than
+// SessionGrace ago
+func (s *MaintenanceService) CleanupSessions(ctx context.Context) (int64, error) {
+	return s.maintenanceRepo.DeleteExpiredSessions(ctx, time.Now().Add(-s.config.SessionGrace))
+}
+
+// ApplyRetention removes device telemetry, job history and ingested message
+// keys older than their retention periods. A period of zero or less keeps the data forever.
+func (s *MaintenanceService) ApplyRetention(ctx context.Context) (*RetentionResult, error) {
+	result := &RetentionResult{}
+	now := time.Now()
+
+	var err error
+	if s.config.TelemetryDays > 0 {
+		if result.Telemetry, err = s.maintenanceRepo.DeleteTelemetryBefore(ctx, now.AddDate(0, 0, -s.config.TelemetryDays)); err != nil {
+			return result, err
+		}
+	}
+	if s.config.JobRunDays > 0 {
+		if result.JobRuns, err = s.jobRepo.DeleteRunsBefore(ctx, now.AddDate(0, 0, -s.config.JobRunDays)); err != nil {
+			return result, err
+		}
+	}
+	if s.config.MessageKeyDays > 0 {
+		if result.MessageKeys, err = s.maintenanceRepo.DeleteMessageKeysBefore(ctx, now.AddDate(0, 0, -s.config.MessageKeyDays)); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,265 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/repositories"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+)
+
+// ErrReadingWorkerBusy is returned when a manual run is requested while the
+// worker is already processing readings on this replica
+var ErrReadingWorkerBusy = errors.New("reading worker is already running")
+
+// Tiempo máximo para terminar el lote en curso al apagar el servidor
+const readingWorkerShutdownGrace = 30 * time.Second
+
+// ReadingWorkerConfig controls how unprocessed readings are processed in the
+// background
+type ReadingWorkerConfig struct {
+	Interval         time.Duration
+	BatchSize        int
+	MaxBatchesPerRun int
+	MaxAttempts      int           // intentos por lectura antes de abandonarla
+	RetryBase        time.Duration // espera tras el primer fallo de una lectura
+	RetryMax         time.Duration
+	MaxBackoff       time.Duration // espera máxima entre ejecuciones fallidas
+	LockKey          int64         // clave del advisory lock de Postgres
+}
+
+// LoadReadingWorkerConfig reads the reading worker configuration from the
+// environment
+func LoadReadingWorkerConfig() ReadingWorkerConfig {
+	return ReadingWorkerConfig{
+		Interval:         utils.GetEnvDuration("READING_WORKER_INTERVAL", 30*time.Second),
+		BatchSize:        max(utils.GetEnvInt("READING_WORKER_BATCH_SIZE", 200), 1),
+		MaxBatchesPerRun: max(utils.GetEnvInt("READING_WORKER_MAX_BATCHES", 10), 1),
+		MaxAttempts:      max(utils.GetEnvInt("READING_WORKER_MAX_ATTEMPTS", 5), 1),
+		RetryBase:        utils.GetEnvDuration("READING_WORKER_RETRY_BASE", time.Minute),
+		RetryMax:         utils.GetEnvDuration("READING_WORKER_RETRY_MAX", time.Hour),
+		MaxBackoff:       utils.GetEnvDuration("READING_WORKER_MAX_BACKOFF", 10*time.Minute),
+		LockKey:          int64(utils.GetEnvInt("READING_WORKER_LOCK_KEY", 734001)),
+	}
+}
+
+// ReadingWorkerService processes new heart readings: it runs the
+// process_unprocessed_readings procedure and evaluates, in bounded batches,
+// the threshold and rule alerts of the readings not evaluated on ingest.
+// Every replica runs it, but only the one holding the Postgres advisory lock
+// processes readings; the others retry the election on each interval.
+type ReadingWorkerService struct {
+	processingRepo      *repositories.ReadingProcessingRepository
+	heartReadingService *HeartReadingService
+	config              ReadingWorkerConfig
+	lock                *repositories.LeaderLock
+
+	runMu    sync.Mutex // una sola ejecución a la vez en esta réplica
+	statusMu sync.Mutex
+	status   models.ReadingWorkerStatus
+	done     chan struct{}
+}
+
+func NewReadingWorkerService(
+	processingRepo *repositories.ReadingProcessingRepository,
+	heartReadingService *HeartReadingService,
+	config ReadingWorkerConfig,
+) *ReadingWorkerService {
+	return &ReadingWorkerService{
+		processingRepo:      processingRepo,
+		heartReadingService: heartReadingService,
+		config:              config,
+		lock:                processingRepo.NewLeaderLock(config.LockKey),
+		status: models.ReadingWorkerStatus{
+			Interval:         config.Interval.String(),
+			BatchSize:        config.BatchSize,
+			MaxBatchesPerRun: config.MaxBatchesPerRun,
+			MaxAttempts:      config.MaxAttempts,
+		},
+		done: make(chan struct{}),
+	}
+}
+
+// Run processes readings every Interval while this replica is the leader,
+// backing off exponentially after failed runs, until ctx is cancelled. A
+// batch in progress is allowed to finish before Run returns.
+func (s *ReadingWorkerService) Run(ctx context.Context) {
+	defer close(s.done)
+	defer func() {
+		releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.lock.Release(releaseCtx); err != nil {
+			log.Printf("Reading worker: %v", err)
+		}
+		s.setLeader(false)
+	}()
+
+	s.statusMu.Lock()
+	s.status.Enabled = true
+	s.statusMu.Unlock()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		delay := s.tick(ctx)
+		next := time.Now().Add(delay)
+		s.statusMu.Lock()
+		s.status.NextRunAt = &next
+		s.statusMu.Unlock()
+		timer.Reset(delay)
+	}
+}
+
+// Done is closed once Run has returned
+func (s *ReadingWorkerService) Done() <-chan struct{} {
+	return s.done
+}
+
+// tick runs the worker once if this replica is the leader and returns the
+// delay until the next attempt
+func (s *ReadingWorkerService) tick(ctx context.Context) time.Duration {
+	leader, err := s.lock.TryAcquire(ctx)
+	s.setLeader(leader)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Reading worker: leader election: %v", err)
+			s.recordFailure(err)
+		}
+		return s.backoff()
+	}
+	if !leader {
+		return s.config.Interval
+	}
+
+	if _, _, err := s.RunOnce(ctx); err != nil {
+		if errors.Is(err, ErrReadingWorkerBusy) {
+			return s.config.Interval
+		}
+		log.Printf("Reading worker: %v", err)
+		return s.backoff()
+	}
+	return s.config.Interval
+}
+
+// RunOnce runs the processing procedure when this replica is the leader and
+// then evaluates up to MaxBatchesPerRun batches, stopping early when a batch
+// comes back short. It is also used for manual runs: row locks keep the
+// batches safe to run alongside the leader on another replica, and the
+// procedure only ever runs on the leader.
+func (s *ReadingWorkerService) RunOnce(ctx context.Context) (processed int, failed int, err error) {
+	if !s.runMu.TryLock() {
+		return 0, 0, ErrReadingWorkerBusy
+	}
+	defer s.runMu.Unlock()
+
+	started := time.Now()
+	s.statusMu.Lock()
+	s.status.Running = true
+	s.status.LastRunStartedAt = &started
+	leader := s.status.IsLeader
+	s.statusMu.Unlock()
+
+	if leader {
+		err = s.processingRepo.ProcessUnprocessedReadings(ctx)
+	}
+
+	for batch := 0; err == nil && batch < s.config.MaxBatchesPerRun && ctx.Err() == nil; batch++ {
+		// El lote en curso no se interrumpe al apagar: se confirma o se descarta entero
+		batchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), readingWorkerShutdownGrace)
+		var result *repositories.BatchResult
+		result, err = s.processingRepo.ProcessBatch(
+			batchCtx,
+			s.config.BatchSize,
+			s.config.MaxAttempts,
+			s.config.RetryBase,
+			s.config.RetryMax,
+			s.heartReadingService.ProcessReading,
+		)
+		cancel()
+		if err != nil {
+			break
+		}
+		processed += result.Processed
+		failed += result.Failed
+		if result.Claimed < s.config.BatchSize {
+			break
+		}
+	}
+
+	finished := time.Now()
+	s.statusMu.Lock()
+	s.status.Running = false
+	s.status.LastRunFinishedAt = &finished
+	s.status.LastRunDurationMs = finished.Sub(started).Milliseconds()
+	s.status.LastRunProcessed = processed
+	s.status.LastRunFailed = failed
+	s.status.TotalRuns++
+	s.status.TotalProcessed += int64(processed)
+	s.status.TotalFailed += int64(failed)
+	if err == nil {
+		s.status.ConsecutiveFailures = 0
+	}
+	s.statusMu.Unlock()
+
+	if err != nil {
+		s.recordFailure(err)
+	}
+	return processed, failed, err
+}
+
+// GetStatus returns the worker's status on this replica together with the
+// current backlog
+func (s *ReadingWorkerService) GetStatus(ctx context.Context) (*models.ReadingWorkerStatus, error) {
+	pending, exhausted, err := s.processingRepo.GetBacklog(ctx, s.config.MaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+
+	s.statusMu.Lock()
+	status := s.status
+	s.statusMu.Unlock()
+
+	status.PendingReadings = &pending
+	status.ExhaustedReadings = &exhausted
+	return &status, nil
+}
+
+func (s *ReadingWorkerService) setLeader(leader bool) {
+	s.statusMu.Lock()
+	s.status.IsLeader = leader
+	s.statusMu.Unlock()
+}
+
+func (s *ReadingWorkerService) recordFailure(err error) {
+	now := time.Now()
+	message := err.Error()
+	s.statusMu.Lock()
+	s.status.LastError = &message
+	s.status.LastErrorAt = &now
+	s.status.ConsecutiveFailures++
+	s.statusMu.Unlock()
+}
+
+// backoff doubles the interval for every consecutive failed run, up to MaxBackoff
+func (s *ReadingWorkerService) backoff() time.Duration {
+	s.statusMu.Lock()
+	failures := s.status.ConsecutiveFailures
+	s.statusMu.Unlock()
+
+	delay := s.config.Interval
+	for i := 1; i < failures && delay < s.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return max(min(delay, s.config.MaxBackoff), s.config.Interval)
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/repositories"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+	"github.com/google/uuid"
+)
+
+// Rango máximo de una consulta de informes diarios
+const maxReportRangeDays = 366
+
+// ReportService generates and serves the daily patient summaries
+type ReportService struct {
+	reportRepo       *repositories.ReportRepository
+	thresholdService *ThresholdService
+	localeService    *LocaleService
+}
+
+func NewReportService(
+	reportRepo *repositories.ReportRepository,
+	thresholdService *ThresholdService,
+	localeService *LocaleService,
+) *ReportService {
+	return &ReportService{
+		reportRepo:       reportRepo,
+		thresholdService: thresholdService,
+		localeService:    localeService,
+	}
+}
+
+// GenerateDailyReports generates the report of the calendar day of day for
+// every patient with readings on it, counted in each patient's time zone, and
+// returns the number of reports stored. Patients whose local day has not
+// ended yet are left for a later run.
+func (s *ReportService) GenerateDailyReports(ctx context.Context, day time.Time) (int, error) {
+	// Los días locales empiezan entre UTC+14 y UTC-12
+	date := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	patientIDs, err := s.reportRepo.GetPatientsWithReadingsBetween(ctx, date.Add(-14*time.Hour), date.AddDate(0, 0, 1).Add(12*time.Hour))
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	generated := 0
+	for _, patientID := range patientIDs {
+		if ctx.Err() != nil {
+			return generated, ctx.Err()
+		}
+		start, end := s.patientDay(ctx, patientID, day)
+		if end.After(now) {
+			continue
+		}
+		report, err := s.generateReport(ctx, patientID, start, end, false)
+		if err != nil {
+			log.Printf("Daily reports: patient %s: %v", patientID, err)
+			continue
+		}
+		if report != nil {
+			generated++
+		}
+	}
+	return generated, nil
+}
+
+// GeneratePatientReport generates and stores a patient's report for the
+// calendar day of day in the patient's time zone
+func (s *ReportService) GeneratePatientReport(ctx context.Context, patientID uuid.UUID, day time.Time) (*models.PatientDailyReport, error) {
+	start, end := s.patientDay(ctx, patientID, day)
+	return s.generateReport(ctx, patientID, start, end, true)
+}
+
+// patientDay returns the patient's local day with the date of day
+func (s *ReportService) patientDay(ctx context.Context, patientID uuid.UUID, day time.Time) (start, end time.Time) {
+	loc := s.localeService.PatientLocation(ctx, patientID)
+	start = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// generateReport computes and stores the report of [start, end). Unless
+// keepEmpty is set, a day without readings is not stored and nil is returned.
+func (s *ReportService) generateReport(
+	ctx context.Context,
+	patientID uuid.UUID,
+	start time.Time,
+	end time.Time,
+	keepEmpty bool,
+) (*models.PatientDailyReport, error) {
+	report, err := s.reportRepo.ComputeDailyReport(ctx, patientID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if report.ReadingCount == 0 && !keepEmpty {
+		return nil, nil
+	}
+	report.ReportDate = start.Format(time.DateOnly)
+
+	// Bajas y altas según los límites que aplicaban a cada lectura
+	last := end.Add(-time.Nanosecond)
+	report.LowCount, report.HighCount, err = s.thresholdService.CountOutOfRange(ctx, patientID, start, last, models.ReadingFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.reportRepo.SaveDailyReport(ctx, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// GetDailyReports returns a patient's daily reports between two dates
+// (default: the last 30 days up to the patient's local today)
+func (s *ReportService) GetDailyReports(ctx context.Context, patientID uuid.UUID, from, to *time.Time) ([]*models.PatientDailyReport, error) {
+	end := time.Now().In(s.localeService.PatientLocation(ctx, patientID))
+	if to != nil {
+		end = *to
+	}
+	start := end.AddDate(0, 0, -30)
+	if from != nil {
+		start = *from
+	}
+	if start.After(end) || end.Sub(start) > maxReportRangeDays*24*time.Hour {
+		return nil, &utils.ValidationError{Fields: []string{"from must not be after to and the range at most 366 days"}}
+	}
+	return s.reportRepo.GetDailyReports(ctx, patientID, start.Format(time.DateOnly), end.Format(time.DateOnly))
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/analytics"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/repositories"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/rules"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+	"github.com/google/uuid"
+)
+
+const (
+	// Rango evaluado por defecto y rango máximo de una evaluación bajo demanda
+	defaultRuleEvaluationRange = 24 * time.Hour
+	maxRuleEvaluationRange     = 31 * 24 * time.Hour
+
+	// Prefijo del tipo de alerta generada por una regla ("rule_<id>")
+	ruleAlertPrefix = "rule_"
+)
+
+var ErrRuleSetNotFound = errors.New("rule set not found")
+
+// LoadAlertMinQuality reads the quality score below which a reading raises no
+// alerts (ALERT_MIN_QUALITY). With the default of 0.5 an implausible value, or
+// a reading with several quality flags, is stored without alerting.
+func LoadAlertMinQuality() float64 {
+	return utils.GetEnvFloat("ALERT_MIN_QUALITY", 0.5)
+}
+
+// RuleService manages the declarative anomaly rule sets and evaluates them on
+// ingest and on demand
+type RuleService struct {
+	ruleRepo         *repositories.RuleRepository
+	heartReadingRepo *repositories.HeartReadingRepository
+	alertRepo        *repositories.AlertRepository
+	alertMinQuality  float64
+}
+
+func NewRuleService(
+	ruleRepo *repositories.RuleRepository,
+	heartReadingRepo *repositories.HeartReadingRepository,
+	alertRepo *repositories.AlertRepository,
+	alertMinQuality float64,
+) *RuleService {
+	return &RuleService{
+		ruleRepo:         ruleRepo,
+		heartReadingRepo: heartReadingRepo,
+		alertRepo:        alertRepo,
+		alertMinQuality:  alertMinQuality,
+	}
+}
+
+// alertable reports whether a reading's quality is high enough to raise
+// alerts. Readings without a score count as full quality.
+func (s *RuleService) alertable(reading *models.HeartReading) bool {
+	return analytics.MeetsQuality(reading.QualityScore, s.alertMinQuality)
+}
+
+// GetRuleSet returns the rule set that applies to a patient: their own, the
+// stored default or the built-in default, in that order. A nil patientID
+// returns the default.
+func (s *RuleService) GetRuleSet(ctx context.Context, patientID *uuid.UUID) (*models.RuleSetRecord, error) {
+	record, _, err := s.effectiveRuleSet(ctx, patientID)
+	return record, err
+}
+
+// SaveRuleSet validates a JSON or YAML rule set and stores it for a patient,
+// or as the default when patientID is nil
+func (s *RuleService) SaveRuleSet(
+	ctx context.Context,
+	patientID *uuid.UUID,
+	data []byte,
+	format string,
+	updatedBy *uuid.UUID,
+) (*models.RuleSetRecord, error) {
+	rs, err := rules.Parse(data, format)
+	if err != nil {
+		var parseErr *rules.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, &utils.ValidationError{Fields: parseErr.Problems}
+		}
+		return nil, err
+	}
+
+	// Se guarda siempre en JSON normalizado, con los valores por defecto aplicados
+	definition, err := json.Marshal(rs)
+	if err != nil {
+		return nil, err
+	}
+	return s.ruleRepo.SaveRuleSet(ctx, patientID, definition, updatedBy)
+}
+
+// DeleteRuleSet removes a patient's rule set so the default applies again
+func (s *RuleService) DeleteRuleSet(ctx context.Context, patientID uuid.UUID) error {
+	deleted, err := s.ruleRepo.DeleteRuleSet(ctx, patientID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrRuleSetNotFound
+	}
+	return nil
+}
+
+// EvaluateRange evaluates the patient's rule set over their readings between
+// startTime and endTime (default: the last 24 hours). Readings scored below
+// minQuality are ignored.
+func (s *RuleService) EvaluateRange(
+	ctx context.Context,
+	patientID uuid.UUID,
+	startTime *time.Time,
+	endTime *time.Time,
+	minQuality *float64,
+) ([]*rules.Violation, error) {
+	end := time.Now()
+	if endTime != nil {
+		end = *endTime
+	}
+	start := end.Add(-defaultRuleEvaluationRange)
+	if startTime != nil {
+		start = *startTime
+	}
+	if !start.Before(end) || end.Sub(start) > maxRuleEvaluationRange {
+		return nil, &utils.ValidationError{Fields: []string{"start_time must be before end_time and the range at most 31 days"}}
+	}
+
+	_, rs, err := s.effectiveRuleSet(ctx, &patientID)
+	if err != nil {
+		return nil, err
+	}
+
+	readings, err := s.heartReadingRepo.GetReadingsInRange(ctx, patientID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	violations := rules.Evaluate(rs, filterByQuality(readings, minQuality))
+	if violations == nil {
+		violations = []*rules.Violation{}
+	}
+	return violations, nil
+}
+
+// EvaluateReading evaluates the patient's rule set for a newly stored reading
+// and raises an alert for every rule the reading takes part in, unless the
+// rule already raised one within its cooldown. Readings scored below the alert
+// quality floor neither raise alerts nor count towards a rule.
+func (s *RuleService) EvaluateReading(ctx context.Context, reading *models.HeartReading) error {
+	if !s.alertable(reading) {
+		return nil
+	}
+
+	_, rs, err := s.effectiveRuleSet(ctx, &reading.PatientID)
+	if err != nil {
+		return err
+	}
+
+	readings, err := s.heartReadingRepo.GetReadingsInRange(ctx, reading.PatientID, reading.Time.Add(-rs.Lookback()), reading.Time)
+	if err != nil {
+		return err
+	}
+
+	for _, violation := range rules.Evaluate(rs, filterByQuality(readings, &s.alertMinQuality)) {
+		if !slices.Contains(violation.ReadingIDs, reading.ID) {
+			continue
+		}
+		if err := s.raiseAlert(ctx, reading, violation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *RuleService) raiseAlert(ctx context.Context, reading *models.HeartReading, violation *rules.Violation) error {
+	alertType := ruleAlertPrefix + violation.RuleID
+	open, err := s.alertRepo.HasOpenAlert(ctx, reading.PatientID, alertType, reading.Time.Add(-violation.Cooldown()))
+	if err != nil {
+		return err
+	}
+	if open {
+		return nil
+	}
+
+	_, err = s.alertRepo.CreateAlert(ctx, &models.Alert{
+		PatientID:   reading.PatientID,
+		ReadingID:   &reading.ID,
+		AlertType:   alertType,
+		Severity:    violation.Severity,
+		Message:     violation.Message,
+		ReadingTime: reading.Time,
+	})
+	return err
+}
+
+// effectiveRuleSet resolves and parses the rule set that applies to a patient
+func (s *RuleService) effectiveRuleSet(ctx context.Context, patientID *uuid.UUID) (*models.RuleSetRecord, *rules.RuleSet, error) {
+	candidates := []*uuid.UUID{nil}
+	if patientID != nil {
+		candidates = []*uuid.UUID{patientID, nil}
+	}
+
+	for _, id := range candidates {
+		record, err := s.ruleRepo.GetRuleSet(ctx, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		if record == nil {
+			continue
+		}
+		rs, err := rules.Parse(record.Definition, "json")
+		if err != nil {
+			return nil, nil, err
+		}
+		return record, rs, nil
+	}
+
+	rs := rules.DefaultRuleSet()
+	definition, err := json.Marshal(rs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &models.RuleSetRecord{Source: "builtin", Definition: definition}, rs, nil
+}
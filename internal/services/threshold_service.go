@@ -0,0 +1,358 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/repositories"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+	"github.com/google/uuid"
+)
+
+const (
+	AlertTypeLowHeartRate  = "low_heart_rate"
+	AlertTypeHighHeartRate = "high_heart_rate"
+
+	// Una alerta de frecuencia abierta suprime las del mismo tipo durante este periodo
+	heartRateAlertDedupWindow = 30 * time.Minute
+	// Desviación respecto al límite a partir de la cual la alerta es crítica (lpm)
+	criticalHeartRateMargin = 20
+	// Rango máximo de CountOutOfRange, que recorre las lecturas una a una
+	maxOutOfRangeCountRange = 366 * 24 * time.Hour
+)
+
+var (
+	ErrPatientNotFound          = errors.New("patient not found")
+	ErrThresholdProfileNotFound = errors.New("threshold profile not found")
+)
+
+// ThresholdService manages per-patient threshold profiles and resolves the
+// heart rate limits that apply to a reading
+type ThresholdService struct {
+	thresholdRepo    *repositories.ThresholdRepository
+	heartReadingRepo *repositories.HeartReadingRepository
+	alertRepo        *repositories.AlertRepository
+	localeService    *LocaleService
+}
+
+func NewThresholdService(
+	thresholdRepo *repositories.ThresholdRepository,
+	heartReadingRepo *repositories.HeartReadingRepository,
+	alertRepo *repositories.AlertRepository,
+	localeService *LocaleService,
+) *ThresholdService {
+	return &ThresholdService{
+		thresholdRepo:    thresholdRepo,
+		heartReadingRepo: heartReadingRepo,
+		alertRepo:        alertRepo,
+		localeService:    localeService,
+	}
+}
+
+func (s *ThresholdService) GetProfiles(ctx context.Context, patientID uuid.UUID) ([]*models.ThresholdProfile, error) {
+	return s.thresholdRepo.GetProfiles(ctx, patientID, false)
+}
+
+// CreateProfile validates and stores a threshold profile set by setBy
+func (s *ThresholdService) CreateProfile(
+	ctx context.Context,
+	patientID uuid.UUID,
+	profile *models.ThresholdProfileRequest,
+	setBy *uuid.UUID,
+) (*models.ThresholdProfile, error) {
+	if err := normalizeThresholdProfile(profile); err != nil {
+		return nil, err
+	}
+	return s.thresholdRepo.CreateProfile(ctx, patientID, profile, setBy)
+}
+
+// UpdateProfile validates and replaces a threshold profile set by setBy
+func (s *ThresholdService) UpdateProfile(
+	ctx context.Context,
+	patientID uuid.UUID,
+	profileID uuid.UUID,
+	profile *models.ThresholdProfileRequest,
+	setBy *uuid.UUID,
+) (*models.ThresholdProfile, error) {
+	if err := normalizeThresholdProfile(profile); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.thresholdRepo.UpdateProfile(ctx, patientID, profileID, profile, setBy)
+	if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		return nil, ErrThresholdProfileNotFound
+	}
+	return updated, nil
+}
+
+func (s *ThresholdService) DeleteProfile(ctx context.Context, patientID uuid.UUID, profileID uuid.UUID) error {
+	deleted, err := s.thresholdRepo.DeleteProfile(ctx, patientID, profileID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrThresholdProfileNotFound
+	}
+	return nil
+}
+
+// ResolveThresholds returns the limits that apply to a reading of the given
+// type taken at t
+func (s *ThresholdService) ResolveThresholds(
+	ctx context.Context,
+	patientID uuid.UUID,
+	readingType string,
+	t time.Time,
+) (*models.EffectiveThresholds, error) {
+	resolver, err := s.newResolver(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+	return resolver.resolve(readingType, t), nil
+}
+
+// CountOutOfRange counts the readings of a patient between two instants
+// (inclusive) below and above the limits that applied to each of them, among
+// the readings selected by filter. The readings are streamed, and the range
+// may be at most 366 days.
+func (s *ThresholdService) CountOutOfRange(
+	ctx context.Context,
+	patientID uuid.UUID,
+	startTime time.Time,
+	endTime time.Time,
+	filter models.ReadingFilter,
+) (low int64, high int64, err error) {
+	if startTime.After(endTime) || endTime.Sub(startTime) > maxOutOfRangeCountRange {
+		return 0, 0, &utils.ValidationError{Fields: []string{"start_time must not be after end_time and the range at most 366 days"}}
+	}
+
+	resolver, err := s.newResolver(ctx, patientID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	err = s.heartReadingRepo.StreamHeartRatePoints(ctx, patientID, startTime, endTime, filter, func(reading *models.HeartReading) error {
+		limits := resolver.resolve(reading.ReadingType, reading.Time)
+		switch {
+		case reading.BPM < limits.MinBPM:
+			low++
+		case reading.BPM > limits.MaxBPM:
+			high++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return low, high, nil
+}
+
+// CheckReading raises a low or high heart rate alert when a new reading is
+// outside the limits that apply to it
+func (s *ThresholdService) CheckReading(ctx context.Context, reading *models.HeartReading) error {
+	limits, err := s.ResolveThresholds(ctx, reading.PatientID, reading.ReadingType, reading.Time)
+	if err != nil {
+		return err
+	}
+
+	alert := &models.Alert{
+		PatientID:   reading.PatientID,
+		ReadingID:   &reading.ID,
+		Severity:    "high",
+		ReadingTime: reading.Time,
+	}
+	switch {
+	case reading.BPM < limits.MinBPM:
+		alert.AlertType = AlertTypeLowHeartRate
+		alert.Message = fmt.Sprintf("Heart rate %d bpm is below the limit of %d bpm (%s)", reading.BPM, limits.MinBPM, describeThresholds(limits))
+		if reading.BPM < limits.MinBPM-criticalHeartRateMargin {
+			alert.Severity = "critical"
+		}
+	case reading.BPM > limits.MaxBPM:
+		alert.AlertType = AlertTypeHighHeartRate
+		alert.Message = fmt.Sprintf("Heart rate %d bpm is above the limit of %d bpm (%s)", reading.BPM, limits.MaxBPM, describeThresholds(limits))
+		if reading.BPM > limits.MaxBPM+criticalHeartRateMargin {
+			alert.Severity = "critical"
+		}
+	default:
+		return nil
+	}
+
+	open, err := s.alertRepo.HasOpenAlert(ctx, reading.PatientID, alert.AlertType, reading.Time.Add(-heartRateAlertDedupWindow))
+	if err != nil {
+		return err
+	}
+	if open {
+		return nil
+	}
+	_, err = s.alertRepo.CreateAlert(ctx, alert)
+	return err
+}
+
+// thresholdResolver picks the profile that applies to a reading. Profiles
+// are tried by priority, then by specificity (reading type, schedule, days);
+// the patient's global limits apply when none matches.
+type thresholdResolver struct {
+	profiles []*compiledProfile
+	fallback *models.EffectiveThresholds
+}
+
+type compiledProfile struct {
+	profile  *models.ThresholdProfile
+	location *time.Location
+	start    int // minutos desde medianoche, -1 sin horario
+	end      int
+}
+
+func (s *ThresholdService) newResolver(ctx context.Context, patientID uuid.UUID) (*thresholdResolver, error) {
+	fallback, err := s.thresholdRepo.GetPatientHeartRateRange(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+	if fallback == nil {
+		return nil, ErrPatientNotFound
+	}
+
+	profiles, err := s.thresholdRepo.GetProfiles(ctx, patientID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// Los horarios sin zona propia se cuentan en la del paciente
+	var patientLoc *time.Location
+	resolver := &thresholdResolver{fallback: fallback}
+	for _, p := range profiles {
+		compiled := &compiledProfile{profile: p, location: time.UTC, start: -1, end: -1}
+		if p.Timezone == nil {
+			if patientLoc == nil {
+				patientLoc = s.localeService.PatientLocation(ctx, patientID)
+			}
+			compiled.location = patientLoc
+		} else if loc, err := time.LoadLocation(*p.Timezone); err == nil {
+			compiled.location = loc
+		}
+		if p.StartTime != nil && p.EndTime != nil {
+			compiled.start, _ = parseClock(*p.StartTime)
+			compiled.end, _ = parseClock(*p.EndTime)
+		}
+		resolver.profiles = append(resolver.profiles, compiled)
+	}
+
+	slices.SortStableFunc(resolver.profiles, func(a, b *compiledProfile) int {
+		if a.profile.Priority != b.profile.Priority {
+			return b.profile.Priority - a.profile.Priority
+		}
+		return b.specificity() - a.specificity()
+	})
+	return resolver, nil
+}
+
+func (r *thresholdResolver) resolve(readingType string, t time.Time) *models.EffectiveThresholds {
+	for _, p := range r.profiles {
+		if p.matches(readingType, t) {
+			return &models.EffectiveThresholds{
+				MinBPM:      p.profile.MinBPM,
+				MaxBPM:      p.profile.MaxBPM,
+				Source:      "profile",
+				ProfileID:   &p.profile.ID,
+				ProfileName: &p.profile.Name,
+			}
+		}
+	}
+	return r.fallback
+}
+
+func (p *compiledProfile) specificity() int {
+	score := 0
+	if p.profile.ReadingType != nil {
+		score += 4
+	}
+	if p.start >= 0 {
+		score += 2
+	}
+	if len(p.profile.DaysOfWeek) > 0 {
+		score++
+	}
+	return score
+}
+
+// matches reports whether the profile applies to a reading. A schedule that
+// wraps past midnight belongs to the day on which it starts.
+func (p *compiledProfile) matches(readingType string, t time.Time) bool {
+	if p.profile.ReadingType != nil && *p.profile.ReadingType != readingType {
+		return false
+	}
+
+	local := t.In(p.location)
+	minute := local.Hour()*60 + local.Minute()
+	day := local.Weekday()
+
+	if p.start >= 0 {
+		switch {
+		case p.start < p.end:
+			if minute < p.start || minute >= p.end {
+				return false
+			}
+		case minute >= p.start:
+			// Tramo anterior a la medianoche
+		case minute < p.end:
+			day = (day + 6) % 7
+		default:
+			return false
+		}
+	}
+
+	return len(p.profile.DaysOfWeek) == 0 || slices.Contains(p.profile.DaysOfWeek, int(day))
+}
+
+// describeThresholds names where the limits of an alert came from
+func describeThresholds(limits *models.EffectiveThresholds) string {
+	if limits.ProfileName != nil {
+		return "profile " + *limits.ProfileName
+	}
+	return "patient limits"
+}
+
+// normalizeThresholdProfile validates a profile request and fills in defaults
+func normalizeThresholdProfile(profile *models.ThresholdProfileRequest) error {
+	if profile.Active == nil {
+		active := true
+		profile.Active = &active
+	}
+	if err := utils.ValidateStruct(profile); err != nil {
+		return err
+	}
+
+	if profile.StartTime != nil && profile.EndTime != nil && *profile.StartTime == *profile.EndTime {
+		return &utils.ValidationError{Fields: []string{"start_time and end_time must differ"}}
+	}
+	slices.Sort(profile.DaysOfWeek)
+	profile.DaysOfWeek = slices.Compact(profile.DaysOfWeek)
+	return nil
+}
+
+// parseClock parses "HH:MM" into minutes since midnight
+func parseClock(s string) (int, error) {
+	hours, minutes, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	h, err := strconv.Atoi(hours)
+	if err != nil {
+		return 0, err
+	}
+	m, err := strconv.Atoi(minutes)
+	if err != nil {
+		return 0, err
+	}
+	return h*60 + m, nil
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/models"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/repositories"
+	"github.com/Waldir-TG/api-medical-heart-v1/internal/utils"
+	"github.com/google/uuid"
+)
+
+// Tipo de la frecuencia cardíaca en la línea de tiempo compartida
+const TimelineHeartRate = "heart_rate"
+
+// Margen aceptado para observaciones con hora futura (relojes desajustados)
+const maxVitalClockSkew = 5 * time.Minute
+
+const (
+	// Rango de la línea de tiempo por defecto y rango máximo
+	defaultTimelineRange = 24 * time.Hour
+	maxTimelineRange     = 31 * 24 * time.Hour
+)
+
+// vitalSpec describes an observation type: its unit, the physiologically
+// plausible values accepted on ingest and the reference range used by stats
+type vitalSpec struct {
+	unit         string
+	min, max     float64
+	secondaryMin float64 // solo presión arterial (diastólica)
+	secondaryMax float64
+	refLow       *float64
+	refHigh      *float64
+}
+
+func ref(v float64) *float64 { return &v }
+
+var vitalSpecs = map[string]vitalSpec{
+	models.VitalSpO2:            {unit: "%", min: 50, max: 100, refLow: ref(92)},
+	models.VitalBloodPressure:   {unit: "mmHg", min: 40, max: 300, secondaryMin: 20, secondaryMax: 200, refLow: ref(90), refHigh: ref(140)},
+	models.VitalTemperature:     {unit: "°C", min: 25, max: 45, refLow: ref(35), refHigh: ref(38)},
+	models.VitalWeight:          {unit: "kg", min: 0.5, max: 500},
+	models.VitalRespiratoryRate: {unit: "breaths/min", min: 1, max: 100, refLow: ref(12), refHigh: ref(20)},
+}
+
+// VitalService records standalone vital sign observations and serves their
+// stats, trends and a timeline shared with heart readings
+type VitalService struct {
+	vitalRepo        *repositories.VitalRepository
+	heartReadingRepo *repositories.HeartReadingRepository
+	localeService    *LocaleService
+}
+
+func NewVitalService(
+	vitalRepo *repositories.VitalRepository,
+	heartReadingRepo *repositories.HeartReadingRepository,
+	localeService *LocaleService,
+) *VitalService {
+	return &VitalService{
+		vitalRepo:        vitalRepo,
+		heartReadingRepo: heartReadingRepo,
+		localeService:    localeService,
+	}
+}
+
+// CreateObservation validates and stores a vital sign observation
+func (s *VitalService) CreateObservation(ctx context.Context, observation *models.VitalObservationCreateRequest) (*models.VitalObservation, error) {
+	unit, err := s.ValidateObservation(observation)
+	if err != nil {
+		return nil, err
+	}
+	return s.vitalRepo.CreateObservation(ctx, observation, unit)
+}
+
+// ValidateObservation checks a vital sign observation against the
+// plausible values of its type and returns the type's unit
+func (s *VitalService) ValidateObservation(observation *models.VitalObservationCreateRequest) (string, error) {
+	if err := utils.ValidateStruct(observation); err != nil {
+		return "", err
+	}
+
+	spec := vitalSpecs[observation.ObservationType]
+	var problems []string
+	if observation.Value < spec.min || observation.Value > spec.max {
+		problems = append(problems, fmt.Sprintf("value must be between %g and %g %s", spec.min, spec.max, spec.unit))
+	}
+	if observation.ObservationType == models.VitalBloodPressure {
+		switch {
+		case observation.SecondaryValue == nil:
+			problems = append(problems, "secondary_value (diastolic pressure) is required for blood_pressure")
+		case *observation.SecondaryValue < spec.secondaryMin || *observation.SecondaryValue > spec.secondaryMax:
+			problems = append(problems, fmt.Sprintf("secondary_value must be between %g and %g mmHg", spec.secondaryMin, spec.secondaryMax))
+		case *observation.SecondaryValue >= observation.Value:
+			problems = append(problems, "diastolic pressure must be lower than systolic pressure")
+		}
+	} else if observation.SecondaryValue != nil {
+		problems = append(problems, "secondary_value is only allowed for blood_pressure")
+	}
+	if observation.Time != nil && observation.Time.After(time.Now().Add(maxVitalClockSkew)) {
+		problems = append(problems, "time must not be in the future")
+	}
+	if len(problems) > 0 {
+		return "", &utils.ValidationError{Fields: problems}
+	}
+	return spec.unit, nil
+}
+
+// GetObservations returns a page of a patient's observations
+func (s *VitalService) GetObservations(ctx context.Context, params *models.VitalQueryParams) (*models.VitalListResponse, error) {
+	if err := utils.ValidateStruct(params); err != nil {
+		return nil, err
+	}
+	return s.vitalRepo.GetObservations(ctx, params)
+}
+
+// GetVitalStats calculates statistics for one vital sign, counting values
+// outside the type's reference range as low or high
+func (s *VitalService) GetVitalStats(
+	ctx context.Context,
+	patientID uuid.UUID,
+	observationType string,
+	startTime *time.Time,
+	endTime *time.Time,
+) (*models.VitalStats, error) {
+	spec, ok := vitalSpecs[observationType]
+	if !ok {
+		return nil, invalidVitalType(observationType)
+	}
+
+	stats, err := s.vitalRepo.GetVitalStats(ctx, patientID, observationType, startTime, endTime, spec.refLow, spec.refHigh)
+	if err != nil {
+		return nil, err
+	}
+	stats.Unit = spec.unit
+	return stats, nil
+}
+
+// GetVitalTrends aggregates one vital sign per minute, hour, day, week or month
+// of the patient's local time
+func (s *VitalService) GetVitalTrends(
+	ctx context.Context,
+	patientID uuid.UUID,
+	observationType string,
+	startTime *time.Time,
+	endTime *time.Time,
+	resolution string,
+) ([]*models.VitalTrend, error) {
+	if _, ok := vitalSpecs[observationType]; !ok {
+		return nil, invalidVitalType(observationType)
+	}
+	if !slices.Contains([]string{"minute", "hour", "day", "week", "month"}, resolution) {
+		return nil, &utils.ValidationError{Fields: []string{"resolution must be one of minute, hour, day, week, month"}}
+	}
+	loc := s.localeService.PatientLocation(ctx, patientID)
+	return s.vitalRepo.GetVitalTrends(ctx, patientID, observationType, startTime, endTime, resolution, loc)
+}
+
+// GetTimeline merges a patient's heart readings and vital sign observations
+// into a single series ordered by time (default: the last 24 hours). Vitals
+// recorded with a heart reading appear as points of their own type. No types
+// means all of them.
+func (s *VitalService) GetTimeline(
+	ctx context.Context,
+	patientID uuid.UUID,
+	startTime *time.Time,
+	endTime *time.Time,
+	types []string,
+) ([]*models.TimelinePoint, error) {
+	end := time.Now()
+	if endTime != nil {
+		end = *endTime
+	}
+	start := end.Add(-defaultTimelineRange)
+	if startTime != nil {
+		start = *startTime
+	}
+	if !start.Before(end) || end.Sub(start) > maxTimelineRange {
+		return nil, &utils.ValidationError{Fields: []string{
+			fmt.Sprintf("start_time must be before end_time and the timeline cover at most %d days", maxTimelineRange/(24*time.Hour)),
+		}}
+	}
+
+	var observationTypes []string
+	for _, t := range types {
+		if t == TimelineHeartRate {
+			continue
+		}
+		if _, ok := vitalSpecs[t]; !ok {
+			return nil, invalidVitalType(t)
+		}
+		observationTypes = append(observationTypes, t)
+	}
+	wants := func(t string) bool {
+		return len(types) == 0 || slices.Contains(types, t)
+	}
+
+	points := []*models.TimelinePoint{}
+
+	if len(types) == 0 || len(observationTypes) < len(types) || slices.ContainsFunc(types, func(t string) bool {
+		return t == models.VitalSpO2 || t == models.VitalBloodPressure || t == models.VitalTemperature
+	}) {
+		readings, err := s.heartReadingRepo.GetReadingsInRange(ctx, patientID, start, end)
+		if err != nil {
+			return nil, err
+		}
+		for _, reading := range readings {
+			points = append(points, readingTimelinePoints(reading, wants)...)
+		}
+	}
+
+	if len(types) == 0 || len(observationTypes) > 0 {
+		observations, err := s.vitalRepo.GetObservationsInRange(ctx, patientID, observationTypes, start, end)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range observations {
+			points = append(points, &models.TimelinePoint{
+				Time:           o.Time,
+				Type:           o.ObservationType,
+				Value:          o.Value,
+				SecondaryValue: o.SecondaryValue,
+				Unit:           o.Unit,
+				Origin:         "observation",
+				SourceID:       o.ID,
+			})
+		}
+	}
+
+	slices.SortStableFunc(points, func(a, b *models.TimelinePoint) int {
+		return a.Time.Compare(b.Time)
+	})
+	return points, nil
+}
+
+// readingTimelinePoints splits a heart reading into its heart rate and the
+// vitals recorded with it
+func readingTimelinePoints(reading *models.HeartReading, wants func(string) bool) []*models.TimelinePoint {
+	point := func(t string, value float64, secondary *float64) *models.TimelinePoint {
+		unit := "bpm"
+		if spec, ok := vitalSpecs[t]; ok {
+			unit = spec.unit
+		}
+		return &models.TimelinePoint{
+			Time:           reading.Time,
+			Type:           t,
+			Value:          value,
+			SecondaryValue: secondary,
+			Unit:           unit,
+			Origin:         "heart_reading",
+			SourceID:       reading.ID,
+		}
+	}
+
+	var points []*models.TimelinePoint
+	if wants(TimelineHeartRate) {
+		points = append(points, point(TimelineHeartRate, float64(reading.BPM), nil))
+	}
+	if reading.OxygenLevel != nil && wants(models.VitalSpO2) {
+		points = append(points, point(models.VitalSpO2, float64(*reading.OxygenLevel), nil))
+	}
+	if reading.SystolicPressure != nil && wants(models.VitalBloodPressure) {
+		var diastolic *float64
+		if reading.DiastolicPressure != nil {
+			diastolic = ref(float64(*reading.DiastolicPressure))
+		}
+		points = append(points, point(models.VitalBloodPressure, float64(*reading.SystolicPressure), diastolic))
+	}
+	if reading.Temperature != nil && wants(models.VitalTemperature) {
+		points = append(points, point(models.VitalTemperature, *reading.Temperature, nil))
+	}
+	return points
+}
+
+func invalidVitalType(observationType string) error {
+	return &utils.ValidationError{Fields: []string{
+		fmt.Sprintf("unknown observation type %q: use spo2, blood_pressure, temperature, weight or respiratory_rate", observationType),
+	}}
+}
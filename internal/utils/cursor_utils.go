@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidCursor se devuelve cuando el cursor recibido no se puede interpretar
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor identifica la última fila devuelta en una paginación por conjunto de claves:
+// el valor de la columna de orden y el ID como desempate
+type Cursor struct {
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+}
+
+// EncodeCursor serializa un cursor como una cadena opaca segura para URLs
+func EncodeCursor(c Cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor interpreta un cursor generado por EncodeCursor
+func DecodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == uuid.Nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// ParseCursorTime interpreta el valor de un cursor sobre una columna de tiempo,
+// escrito con time.RFC3339Nano
+func ParseCursorTime(value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, ErrInvalidCursor
+	}
+	return t, nil
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	when := time.Date(2026, 10, 19, 8, 30, 15, 123456789, time.FixedZone("", -5*3600))
+	want := Cursor{Value: when.Format(time.RFC3339Nano), ID: uuid.New()}
+
+	got, err := DecodeCursor(EncodeCursor(want))
+	if err != nil {
+		t.Fatalf("DecodeCursor() error = %v", err)
+	}
+	if *got != want {
+		t.Fatalf("DecodeCursor() = %+v, want %+v", got, want)
+	}
+
+	parsed, err := ParseCursorTime(got.Value)
+	if err != nil {
+		t.Fatalf("ParseCursorTime() error = %v", err)
+	}
+	if !parsed.Equal(when) {
+		t.Errorf("ParseCursorTime() = %s, want %s", parsed, when)
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	tests := map[string]string{
+		"not base64":   "%%%",
+		"not json":     base64.RawURLEncoding.EncodeToString([]byte("not json")),
+		"missing id":   base64.RawURLEncoding.EncodeToString([]byte(`{"v":"2026-10-19T08:30:00Z"}`)),
+		"malformed id": base64.RawURLEncoding.EncodeToString([]byte(`{"v":"2026-10-19T08:30:00Z","id":"x"}`)),
+	}
+
+	for name, cursor := range tests {
+		if _, err := DecodeCursor(cursor); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: DecodeCursor() error = %v, want ErrInvalidCursor", name, err)
+		}
+	}
+}
+
+func TestParseCursorTimeInvalid(t *testing.T) {
+	values := []string{"", "yesterday", "2026-10-19", "2026-10-19 08:30:00", "2026-13-01T00:00:00Z", "'); DROP TABLE x; --"}
+
+	for _, value := range values {
+		if _, err := ParseCursorTime(value); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("ParseCursorTime(%q) error = %v, want ErrInvalidCursor", value, err)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"time"
+)
+
+// GetEnvDuration lee una duración (por ejemplo "30m") de una variable de entorno
+func GetEnvDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("Warning: invalid duration for %s=%q, using %s", key, value, fallback)
+		return fallback
+	}
+	return d
+}
+
+// GetEnvInt lee un entero de una variable de entorno
+func GetEnvInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Warning: invalid integer for %s=%q, using %d", key, value, fallback)
+		return fallback
+	}
+	return n
+}
+
+// GetEnvString lee una cadena de una variable de entorno
+func GetEnvString(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// GetEnvFloat lee un número decimal de una variable de entorno
+func GetEnvFloat(key string, fallback float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		log.Printf("Warning: invalid number for %s=%q, using %g", key, value, fallback)
+		return fallback
+	}
+	return f
+}
@@ -0,0 +1,7 @@
+package utils
+
+import "errors"
+
+// ErrDuplicateMessage se devuelve cuando la clave de un mensaje ya se procesó;
+// el mensaje se puede confirmar sin volver a guardarlo
+var ErrDuplicateMessage = errors.New("message already ingested")
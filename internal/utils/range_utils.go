@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseDateRange devuelve el día local date (YYYY-MM-DD) en loc: desde su
+// medianoche hasta el instante anterior a la siguiente, ya que las consultas
+// incluyen el final (time <= end)
+func ParseDateRange(date string, loc *time.Location) (start, end time.Time, err error) {
+	day, err := time.ParseInLocation(time.DateOnly, date, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q", date)
+	}
+	return day, lastInstantBefore(day.AddDate(0, 0, 1)), nil
+}
+
+// ParseRelativeRange interpreta un rango relativo a now en loc:
+//   - today, yesterday, this_week (desde el lunes), this_month: días locales
+//     completos; el final es el instante anterior a la medianoche siguiente,
+//     ya que las consultas lo incluyen
+//   - last_<n>h, last_<n>d, last_<n>w: los últimos n periodos hasta now
+func ParseRelativeRange(value string, now time.Time, loc *time.Location) (start, end time.Time, err error) {
+	local := now.In(loc)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+
+	switch value {
+	case "today":
+		return today, lastInstantBefore(today.AddDate(0, 0, 1)), nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), lastInstantBefore(today), nil
+	case "this_week":
+		monday := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+		return monday, lastInstantBefore(monday.AddDate(0, 0, 7)), nil
+	case "this_month":
+		first := today.AddDate(0, 0, 1-today.Day())
+		return first, lastInstantBefore(first.AddDate(0, 1, 0)), nil
+	}
+
+	if amount, ok := strings.CutPrefix(value, "last_"); ok && len(amount) >= 2 {
+		n, err := strconv.Atoi(amount[:len(amount)-1])
+		if err == nil && n > 0 && n <= 3660 {
+			switch amount[len(amount)-1] {
+			case 'h':
+				return local.Add(-time.Duration(n) * time.Hour), now, nil
+			case 'd':
+				return local.AddDate(0, 0, -n), now, nil
+			case 'w':
+				return local.AddDate(0, 0, -7*n), now, nil
+			}
+		}
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("invalid range %q", value)
+}
+
+// lastInstantBefore da el final inclusivo de un periodo que termina en t
+func lastInstantBefore(t time.Time) time.Time {
+	return t.Add(-time.Nanosecond)
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDateRange(t *testing.T) {
+	lima, err := time.LoadLocation("America/Lima")
+	if err != nil {
+		t.Skipf("time zone America/Lima not available: %v", err)
+	}
+
+	start, end, err := ParseDateRange("2026-10-19", lima)
+	if err != nil {
+		t.Fatalf("ParseDateRange() error = %v", err)
+	}
+	if want := time.Date(2026, 10, 19, 0, 0, 0, 0, lima); !start.Equal(want) {
+		t.Errorf("start = %s, want %s", start, want)
+	}
+	// El final es inclusivo: la medianoche siguiente ya es otro día
+	if want := time.Date(2026, 10, 20, 0, 0, 0, 0, lima).Add(-time.Nanosecond); !end.Equal(want) {
+		t.Errorf("end = %s, want %s", end, want)
+	}
+
+	if _, _, err := ParseDateRange("19/10/2026", lima); err == nil {
+		t.Error("ParseDateRange(19/10/2026) error = nil, want an error")
+	}
+}
+
+func TestParseRelativeRange(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone America/New_York not available: %v", err)
+	}
+	// Miércoles
+	now := time.Date(2026, 10, 21, 15, 30, 0, 0, loc)
+	midnight := func(month time.Month, day int) time.Time {
+		return time.Date(2026, month, day, 0, 0, 0, 0, loc)
+	}
+	lastInstant := func(month time.Month, day int) time.Time {
+		return midnight(month, day).Add(-time.Nanosecond)
+	}
+
+	tests := []struct {
+		value      string
+		start, end time.Time
+	}{
+		{"today", midnight(10, 21), lastInstant(10, 22)},
+		{"yesterday", midnight(10, 20), lastInstant(10, 21)},
+		{"this_week", midnight(10, 19), lastInstant(10, 26)},
+		{"this_month", midnight(10, 1), lastInstant(11, 1)},
+		{"last_24h", now.Add(-24 * time.Hour), now},
+		{"last_7d", now.AddDate(0, 0, -7), now},
+		{"last_2w", now.AddDate(0, 0, -14), now},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			start, end, err := ParseRelativeRange(tt.value, now, loc)
+			if err != nil {
+				t.Fatalf("ParseRelativeRange() error = %v", err)
+			}
+			if !start.Equal(tt.start) || !end.Equal(tt.end) {
+				t.Errorf("range = %s - %s, want %s - %s", start, end, tt.start, tt.end)
+			}
+		})
+	}
+
+	for _, value := range []string{"", "tomorrow", "last_0d", "last_5y", "last_d", "last_9999d"} {
+		if _, _, err := ParseRelativeRange(value, now, loc); err == nil {
+			t.Errorf("ParseRelativeRange(%q) error = nil, want an error", value)
+		}
+	}
+}
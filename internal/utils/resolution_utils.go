@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Unidades de calendario admitidas como resolución de una tendencia
+var calendarUnits = map[string]time.Duration{
+	"minute": time.Minute,
+	"hour":   time.Hour,
+	"day":    24 * time.Hour,
+	"week":   7 * 24 * time.Hour,
+	"month":  28 * 24 * time.Hour, // el mes más corto
+}
+
+// Intervalo fijo más largo admitido
+const maxTrendStep = 366 * 24 * time.Hour
+
+// TrendResolution es el tamaño de los periodos de una tendencia: una unidad
+// de calendario (Unit) o un intervalo fijo (Step) como "15m" o "6h"
+type TrendResolution struct {
+	Unit string
+	Step time.Duration
+}
+
+// ParseTrendResolution interpreta "minute", "hour", "day", "week", "month" o
+// un intervalo fijo de la forma <n>m, <n>h o <n>d de hasta un año
+func ParseTrendResolution(resolution string) (TrendResolution, error) {
+	if _, ok := calendarUnits[resolution]; ok {
+		return TrendResolution{Unit: resolution}, nil
+	}
+
+	units := map[byte]time.Duration{'m': time.Minute, 'h': time.Hour, 'd': 24 * time.Hour}
+	if len(resolution) >= 2 {
+		unit, ok := units[resolution[len(resolution)-1]]
+		n, err := strconv.Atoi(resolution[:len(resolution)-1])
+		if ok && err == nil && n > 0 && time.Duration(n) <= maxTrendStep/unit && !strings.HasPrefix(resolution, "+") {
+			return TrendResolution{Step: time.Duration(n) * unit}, nil
+		}
+	}
+	return TrendResolution{}, fmt.Errorf("invalid trend resolution %q", resolution)
+}
+
+// Duration devuelve el tamaño de un periodo; para los meses, el mínimo
+func (r TrendResolution) Duration() time.Duration {
+	if r.Unit != "" {
+		return calendarUnits[r.Unit]
+	}
+	return r.Step
+}
+
+func (r TrendResolution) String() string {
+	switch {
+	case r.Unit != "":
+		return r.Unit
+	case r.Step%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", r.Step/(24*time.Hour))
+	case r.Step%time.Hour == 0:
+		return fmt.Sprintf("%dh", r.Step/time.Hour)
+	default:
+		return fmt.Sprintf("%dm", r.Step/time.Minute)
+	}
+}
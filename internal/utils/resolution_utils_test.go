@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTrendResolution(t *testing.T) {
+	day := 24 * time.Hour
+
+	tests := []struct {
+		value    string
+		step     time.Duration
+		unit     string
+		duration time.Duration
+		str      string
+	}{
+		// Unidades de calendario: sin intervalo fijo, el mes dura lo que el más corto
+		{"minute", 0, "minute", time.Minute, "minute"},
+		{"hour", 0, "hour", time.Hour, "hour"},
+		{"day", 0, "day", day, "day"},
+		{"week", 0, "week", 7 * day, "week"},
+		{"month", 0, "month", 28 * day, "month"},
+
+		// Intervalos fijos, escritos con la unidad más grande exacta
+		{"5m", 5 * time.Minute, "", 5 * time.Minute, "5m"},
+		{"90m", 90 * time.Minute, "", 90 * time.Minute, "90m"},
+		{"120m", 2 * time.Hour, "", 2 * time.Hour, "2h"},
+		{"6h", 6 * time.Hour, "", 6 * time.Hour, "6h"},
+		{"48h", 2 * day, "", 2 * day, "2d"},
+		{"1440m", day, "", day, "1d"},
+		{"366d", 366 * day, "", 366 * day, "366d"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseTrendResolution(tt.value)
+			if err != nil {
+				t.Fatalf("ParseTrendResolution() error = %v", err)
+			}
+			if got.Step != tt.step || got.Unit != tt.unit {
+				t.Errorf("ParseTrendResolution() = %+v, want {Unit:%s Step:%s}", got, tt.unit, tt.step)
+			}
+			if got.Duration() != tt.duration {
+				t.Errorf("Duration() = %s, want %s", got.Duration(), tt.duration)
+			}
+			if got.String() != tt.str {
+				t.Errorf("String() = %q, want %q", got.String(), tt.str)
+			}
+		})
+	}
+
+	for _, value := range []string{"", "m", "5", "0m", "+5m", "-5m", " 5m", "5s", "5M", "1.5h", "367d", "8785h", "year"} {
+		if got, err := ParseTrendResolution(value); err == nil {
+			t.Errorf("ParseTrendResolution(%q) = %+v, want an error", value, got)
+		}
+	}
+}
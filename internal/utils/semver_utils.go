@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SemVer representa una versión semántica MAJOR.MINOR.PATCH[-prerelease]
+type SemVer struct {
+	Major      int
+	Minor      int
+	Patch      int
+	PreRelease string
+}
+
+// ParseSemVer interpreta una versión como "1.4.2", "v2.0.0" o "1.0.0-beta.1".
+// Los metadatos de compilación (+build) se ignoran.
+func ParseSemVer(version string) (SemVer, error) {
+	v := strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.Index(v, "+"); i >= 0 {
+		v = v[:i]
+	}
+
+	var sv SemVer
+	if i := strings.Index(v, "-"); i >= 0 {
+		sv.PreRelease = v[i+1:]
+		v = v[:i]
+	}
+
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return SemVer{}, fmt.Errorf("invalid semantic version %q", version)
+	}
+
+	nums := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return SemVer{}, fmt.Errorf("invalid semantic version %q", version)
+		}
+		nums[i] = n
+	}
+	sv.Major, sv.Minor, sv.Patch = nums[0], nums[1], nums[2]
+
+	return sv, nil
+}
+
+// Compare devuelve -1, 0 o 1 si v es menor, igual o mayor que other
+func (v SemVer) Compare(other SemVer) int {
+	for _, d := range []int{v.Major - other.Major, v.Minor - other.Minor, v.Patch - other.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+
+	// Una versión sin prerelease tiene mayor precedencia que una con prerelease
+	switch {
+	case v.PreRelease == other.PreRelease:
+		return 0
+	case v.PreRelease == "":
+		return 1
+	case other.PreRelease == "":
+		return -1
+	}
+	return comparePreRelease(v.PreRelease, other.PreRelease)
+}
+
+// comparePreRelease compara dos prerelease identificador a identificador
+// (SemVer §11): los numéricos se comparan como números y tienen menor
+// precedencia que los alfanuméricos, que se comparan en ASCII; si todos
+// coinciden, gana el que tiene más identificadores.
+func comparePreRelease(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.ParseUint(as[i], 10, 64)
+		bn, bErr := strconv.ParseUint(bs[i], 10, 64)
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				if an < bn {
+					return -1
+				}
+				return 1
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	}
+	return 0
+}
+
+func (v SemVer) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.PreRelease != "" {
+		s += "-" + v.PreRelease
+	}
+	return s
+}
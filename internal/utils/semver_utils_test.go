@@ -0,0 +1,67 @@
+package utils
+
+import "testing"
+
+func TestSemVerCompare(t *testing.T) {
+	// Orden de precedencia del ejemplo de SemVer §11, más casos numéricos
+	ordered := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.10",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.2.0",
+		"2.0.0",
+	}
+	for i := range ordered {
+		for j := range ordered {
+			a, err := ParseSemVer(ordered[i])
+			if err != nil {
+				t.Fatalf("ParseSemVer(%q): %v", ordered[i], err)
+			}
+			b, err := ParseSemVer(ordered[j])
+			if err != nil {
+				t.Fatalf("ParseSemVer(%q): %v", ordered[j], err)
+			}
+
+			want := 0
+			if i < j {
+				want = -1
+			} else if i > j {
+				want = 1
+			}
+			if got := a.Compare(b); got != want {
+				t.Errorf("%s.Compare(%s) = %d, want %d", ordered[i], ordered[j], got, want)
+			}
+		}
+	}
+}
+
+func TestParseSemVer(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"1.4.2", "1.4.2", false},
+		{"v2.0.0", "2.0.0", false},
+		{"1.0.0-beta.1+build.5", "1.0.0-beta.1", false},
+		{"1.0", "", true},
+		{"1.x.0", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSemVer(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSemVer(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got.String() != tt.want {
+			t.Errorf("ParseSemVer(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var validate = validator.New(validator.WithRequiredStructEnabled())
+
+// ValidationError agrupa los errores de validación de una solicitud
+type ValidationError struct {
+	Fields []string
+}
+
+func (e *ValidationError) Error() string {
+	return "validation failed: " + strings.Join(e.Fields, "; ")
+}
+
+// ValidateStruct valida una estructura según sus etiquetas `validate`
+func ValidateStruct(s any) error {
+	return toValidationError(validate.Struct(s))
+}
+
+// ValidateStructExcept valida una estructura sin los campos indicados, para
+// los que se completan después de validar la solicitud
+func ValidateStructExcept(s any, fields ...string) error {
+	return toValidationError(validate.StructExcept(s, fields...))
+}
+
+func toValidationError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return err
+	}
+
+	verr := &ValidationError{}
+	for _, fe := range fieldErrors {
+		msg := fmt.Sprintf("%s failed '%s'", fe.Namespace(), fe.Tag())
+		if fe.Param() != "" {
+			msg += "=" + fe.Param()
+		}
+		verr.Fields = append(verr.Fields, msg)
+	}
+	return verr
+}